@@ -0,0 +1,73 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// netProceeds returns the value of the sells in trades less the value of the
+// buys.
+func netProceeds(trades map[Asset]Trade, pricelist Pricelist) decimal.Decimal {
+	net := decimal.Zero
+	for _, trade := range trades {
+		if trade.Action == Sell {
+			net = net.Add(trade.Notional(pricelist))
+			continue
+		}
+		net = net.Sub(trade.Notional(pricelist))
+	}
+	return net
+}
+
+// dropDustTrades removes trades whose notional value is below min, then
+// shrinks the remaining sells or buys so that the net proceeds of the trades
+// are the same as before any were removed. Shrinking can leave more trades
+// below min, so it repeats until none are.
+func dropDustTrades(trades map[Asset]Trade, pricelist Pricelist, min decimal.Decimal) {
+	want := netProceeds(trades, pricelist)
+	for {
+		dropped := false
+		for asset, trade := range trades {
+			if trade.Notional(pricelist).LessThan(min) {
+				delete(trades, asset)
+				dropped = true
+			}
+		}
+		if !dropped {
+			return
+		}
+
+		excess := netProceeds(trades, pricelist).Sub(want)
+		if excess.IsPositive() {
+			shrinkSide(trades, pricelist, Sell, excess)
+		}
+		if excess.IsNegative() {
+			shrinkSide(trades, pricelist, Buy, excess.Abs())
+		}
+	}
+}
+
+// shrinkSide scales down every trade with the given action proportionally so
+// that their combined notional value falls by value, or to zero if they are
+// worth less than value.
+func shrinkSide(trades map[Asset]Trade, pricelist Pricelist, action TradeAction, value decimal.Decimal) {
+	total := decimal.Zero
+	for _, trade := range trades {
+		if trade.Action == action {
+			total = total.Add(trade.Notional(pricelist))
+		}
+	}
+	if !total.IsPositive() {
+		return
+	}
+
+	remaining := decimal.Zero
+	if value.LessThan(total) {
+		remaining = total.Sub(value)
+	}
+	for asset, trade := range trades {
+		if trade.Action == action {
+			trade.Amount = trade.Amount.Mul(remaining).Div(total)
+			trades[asset] = trade
+		}
+	}
+}
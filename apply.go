@@ -0,0 +1,109 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInsufficientHoldings is returned when a plan sells more of an asset than
+// the account holds.
+var ErrInsufficientHoldings = errors.New("trade sells more than the account holds")
+
+// ApplyTrades returns the account left after executing the trades of plan in
+// full, valued with the same prices. If the account tracks lots, sells remove
+// the lots recorded on their trades and buys add a lot at their price,
+// acquired at the plan's time.
+func (a Account) ApplyTrades(plan RebalancePlan) (Account, error) {
+	return a.applyTrades(plan, "")
+}
+
+// ApplyTradesWithCash is like ApplyTrades, but also credits the cash raised by
+// the plan, or debits the cash it spends, to the holding of cash, which must
+// be priced by the account's pricelist. Fees are paid from the cash.
+func (a Account) ApplyTradesWithCash(plan RebalancePlan, cash Asset) (Account, error) {
+	if _, ok := a.pricelist[cash]; !ok {
+		return Account{}, ErrAssetMissingFromPricelist
+	}
+	return a.applyTrades(plan, cash)
+}
+
+func (a Account) applyTrades(plan RebalancePlan, cash Asset) (Account, error) {
+	portfolio := applyTrades(a.portfolio, plan.trades)
+	if cash != "" {
+		proceeds := decimal.Zero
+		for _, trade := range plan.trades {
+			if trade.Action == Sell {
+				proceeds = proceeds.Add(trade.Value)
+			} else {
+				proceeds = proceeds.Sub(trade.Value)
+			}
+			proceeds = proceeds.Sub(trade.Fee)
+		}
+		portfolio[cash] = portfolio[cash].Add(proceeds.Div(a.pricelist[cash]))
+	}
+
+	for asset, amount := range portfolio {
+		if _, ok := a.pricelist[asset]; !ok {
+			return Account{}, ErrAssetMissingFromPricelist
+		}
+		// Selling a holding in full may leave a rounding error behind.
+		if amount.IsNegative() && amount.Abs().GreaterThan(a.portfolio[asset].Mul(valueTolerance)) {
+			return Account{}, ErrInsufficientHoldings
+		}
+		if !amount.IsPositive() {
+			delete(portfolio, asset)
+		}
+	}
+
+	account, err := NewAccountWithPricelist(portfolio, a.pricelist)
+	if err != nil {
+		return Account{}, err
+	}
+	account.quotes = a.quotes
+	account.priceTimes = a.PriceTimes()
+	if a.lots != nil {
+		account.lots = a.applyLots(plan)
+	}
+	return account, nil
+}
+
+// applyLots returns the account's lots after the trades of plan: the lots
+// recorded on each sell are removed, or the oldest lots if none were
+// recorded, and each buy adds a new lot.
+func (a Account) applyLots(plan RebalancePlan) Lots {
+	lots := a.Lots()
+	for asset, trade := range plan.trades {
+		if trade.Action == Buy {
+			if trade.Amount.IsPositive() {
+				lots[asset] = append(lots[asset], Lot{Quantity: trade.Amount, CostBasis: trade.Price, Acquired: plan.Time})
+			}
+			continue
+		}
+
+		sold := trade.Lots
+		if sold == nil {
+			sold = FIFO{}.SelectLots(lots[asset], trade.Amount, trade.Price)
+		}
+		for _, s := range sold {
+			for i, lot := range lots[asset] {
+				if lot.Acquired.Equal(s.Acquired) && lot.CostBasis.Equal(s.CostBasis) && lot.Quantity.IsPositive() {
+					lots[asset][i].Quantity = lot.Quantity.Sub(s.Quantity)
+					break
+				}
+			}
+		}
+
+		remaining := lots[asset][:0]
+		for _, lot := range lots[asset] {
+			if lot.Quantity.IsPositive() {
+				remaining = append(remaining, lot)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(lots, asset)
+			continue
+		}
+		lots[asset] = remaining
+	}
+	return lots
+}
@@ -0,0 +1,113 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestAccount_ApplyTrades(t *testing.T) {
+	pricelist := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+		"USD": decimal.NewFromFloat(1),
+	}
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, pricelist)
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("the account achieves the index after its plan is applied", func(t *testing.T) {
+		plan, err := account.Rebalance(index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got, err := account.ApplyTrades(plan)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		allocation := got.Allocation()
+		for asset, weight := range index {
+			if !allocation[asset].Equal(weight) {
+				t.Errorf("got %v want %v for asset %s", allocation[asset], weight, asset)
+			}
+		}
+	})
+	t.Run("the cash raised by a plan can be credited", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithCashReserve(decimal.NewFromFloat(500)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got, err := account.ApplyTradesWithCash(plan, "USD")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if cash := got.Holdings()["USD"]; !cash.Equal(decimal.NewFromFloat(500)) {
+			t.Errorf("got %v want %v", cash, 500)
+		}
+	})
+	t.Run("plans cannot sell more than is held", func(t *testing.T) {
+		plan, _ := account.Rebalance(index)
+
+		other, _ := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(1),
+		}, pricelist)
+
+		_, err := other.ApplyTrades(plan)
+
+		if err != ErrInsufficientHoldings {
+			t.Errorf("got %v, want %s", err, ErrInsufficientHoldings)
+		}
+	})
+	t.Run("lots are sold and bought", func(t *testing.T) {
+		account, err := NewAccountWithLots(map[Asset][]Lot{
+			"ETH": {
+				{Quantity: decimal.NewFromFloat(10), CostBasis: decimal.NewFromFloat(100), Acquired: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{Quantity: decimal.NewFromFloat(10), CostBasis: decimal.NewFromFloat(300), Acquired: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		}, pricelist)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		asOf := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		plan, err := account.Rebalance(index, AsOf(asOf))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got, err := account.ApplyTrades(plan)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		lots := got.Lots()
+		if len(lots["ETH"]) != 1 || !lots["ETH"][0].CostBasis.Equal(decimal.NewFromFloat(300)) {
+			t.Errorf("got %v want the lot costing 300 to remain", lots["ETH"])
+		}
+		if len(lots["BTC"]) != 1 || !lots["BTC"][0].Acquired.Equal(asOf) {
+			t.Errorf("got %v want a BTC lot acquired on %s", lots["BTC"], asOf)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// A Snapshot records the holdings of an account and the prices they were
+// valued at, at a point in time.
+type Snapshot struct {
+	Time      time.Time
+	Portfolio Portfolio
+	Pricelist Pricelist
+	// Flow is the value deposited into the account since the previous
+	// snapshot of its History, negative when it was withdrawn, and included
+	// in the snapshot's value. It separates contributions from returns.
+	Flow decimal.Decimal
+}
+
+// Value returns the total value of the snapshot's portfolio.
+func (s Snapshot) Value() (decimal.Decimal, error) {
+	value, ok := valuePortfolio(s.Portfolio, s.Pricelist)
+	if !ok {
+		return decimal.Zero, ErrAssetMissingFromPricelist
+	}
+	return value, nil
+}
+
+// Activity records what happened to an account between two snapshots other
+// than trading: quantities deposited (positive) or withdrawn (negative), and
+// the total value of fees paid.
+type Activity struct {
+	Flows map[Asset]decimal.Decimal
+	Fees  decimal.Decimal
+}
+
+// An Attribution decomposes the change in value of an account between two
+// snapshots. Market, Trading, Fees and Flows always sum to End - Start.
+type Attribution struct {
+	Start decimal.Decimal
+	End   decimal.Decimal
+	// Market is the change caused by price movements of the assets held at
+	// the start.
+	Market map[Asset]decimal.Decimal
+	// Trading is the change caused by trades, gross of fees.
+	Trading decimal.Decimal
+	// Fees is the value lost to fees, always zero or negative.
+	Fees decimal.Decimal
+	// Flows is the value deposited or withdrawn, at end prices.
+	Flows decimal.Decimal
+}
+
+// Change returns the total change in value between the two snapshots.
+func (a Attribution) Change() decimal.Decimal {
+	return a.End.Sub(a.Start)
+}
+
+// MarketTotal returns the change caused by price movements across all assets.
+func (a Attribution) MarketTotal() decimal.Decimal {
+	total := decimal.Zero
+	for _, change := range a.Market {
+		total = total.Add(change)
+	}
+	return total
+}
+
+// Diff compares the before and after snapshots of an account and attributes
+// the change in its value to market movement per asset, trading, fees and the
+// flows recorded in activity.
+func Diff(before, after Snapshot, activity Activity) (Attribution, error) {
+	start, err := before.Value()
+	if err != nil {
+		return Attribution{}, err
+	}
+	end, err := after.Value()
+	if err != nil {
+		return Attribution{}, err
+	}
+
+	attribution := Attribution{
+		Start:  start,
+		End:    end,
+		Market: map[Asset]decimal.Decimal{},
+		Fees:   activity.Fees.Abs().Neg(),
+	}
+
+	for asset, amount := range before.Portfolio {
+		price, ok := after.Pricelist[asset]
+		if !ok {
+			return Attribution{}, ErrAssetMissingFromPricelist
+		}
+		attribution.Market[asset] = amount.Mul(price.Sub(before.Pricelist[asset]))
+	}
+
+	for asset, amount := range activity.Flows {
+		price, ok := after.Pricelist[asset]
+		if !ok {
+			return Attribution{}, ErrAssetMissingFromPricelist
+		}
+		attribution.Flows = attribution.Flows.Add(amount.Mul(price))
+	}
+
+	attribution.Trading = attribution.Change().
+		Sub(attribution.MarketTotal()).
+		Sub(attribution.Flows).
+		Sub(attribution.Fees)
+
+	return attribution, nil
+}
@@ -0,0 +1,78 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	before := Snapshot{
+		Time: time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC),
+		Portfolio: Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		},
+		Pricelist: Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		},
+	}
+
+	t.Run("value change is attributed to market, trading, fees and flows", func(t *testing.T) {
+		after := Snapshot{
+			Time: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC),
+			Portfolio: Portfolio{
+				"ETH": decimal.NewFromFloat(16),
+				"BTC": decimal.NewFromFloat(0.65),
+			},
+			Pricelist: Pricelist{
+				"ETH": decimal.NewFromFloat(100),
+				"BTC": decimal.NewFromFloat(4000),
+			},
+		}
+
+		got, err := Diff(before, after, Activity{
+			Flows: map[Asset]decimal.Decimal{"ETH": decimal.NewFromFloat(1)},
+			Fees:  decimal.NewFromFloat(10),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		wantMarket := map[Asset]decimal.Decimal{
+			"ETH": decimal.NewFromFloat(-2000),
+			"BTC": decimal.NewFromFloat(-500),
+		}
+		for asset, want := range wantMarket {
+			if !got.Market[asset].Equal(want) {
+				t.Errorf("got market change %s want %s for %s", got.Market[asset], want, asset)
+			}
+		}
+		if want := decimal.NewFromFloat(100); !got.Flows.Equal(want) {
+			t.Errorf("got flows %s want %s", got.Flows, want)
+		}
+		if want := decimal.NewFromFloat(-10); !got.Fees.Equal(want) {
+			t.Errorf("got fees %s want %s", got.Fees, want)
+		}
+
+		sum := got.MarketTotal().Add(got.Trading).Add(got.Fees).Add(got.Flows)
+		if !sum.Equal(got.Change()) {
+			t.Errorf("got components summing to %s want %s", sum, got.Change())
+		}
+	})
+	t.Run("snapshots must price every asset held", func(t *testing.T) {
+		after := Snapshot{
+			Portfolio: Portfolio{"ETH": decimal.NewFromFloat(20)},
+			Pricelist: Pricelist{"BTC": decimal.NewFromFloat(4000)},
+		}
+
+		_, err := Diff(before, after, Activity{})
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v, want %s", err, ErrAssetMissingFromPricelist)
+		}
+	})
+}
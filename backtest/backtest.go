@@ -0,0 +1,183 @@
+// Package backtest simulates rebalancing a portfolio over a history of
+// prices, so that rebalancing rules can be judged on how they would have
+// performed.
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// ErrEmptySeries is returned by Run for a series without any points.
+var ErrEmptySeries = errors.New("backtest: series has no points")
+
+// An Error reports the point of the series a backtest failed at.
+type Error struct {
+	Time time.Time
+	Err  error
+}
+
+// Error formats the error message for Error.
+func (e Error) Error() string {
+	return fmt.Sprintf("backtest: %s: %s", e.Time.Format(time.RFC3339), e.Err)
+}
+
+// A Point is the prices of the assets at a point in time.
+type Point struct {
+	Time      time.Time
+	Pricelist rebalancer.Pricelist
+}
+
+// A Series is a history of prices, ordered by time.
+type Series []Point
+
+// A State is the state of a backtest at a point of its series, given to a
+// Rule to decide whether to rebalance.
+type State struct {
+	Time time.Time
+	// Account is the portfolio valued at the point's prices.
+	Account rebalancer.Account
+	Index   rebalancer.Index
+	// LastRebalance is when the portfolio was last rebalanced, or the zero
+	// time if it never was.
+	LastRebalance time.Time
+}
+
+// A Rule decides at which points of a series the portfolio is rebalanced.
+type Rule interface {
+	Rebalance(s State) bool
+}
+
+// RuleFunc adapts a function to a Rule.
+type RuleFunc func(s State) bool
+
+// Rebalance calls f.
+func (f RuleFunc) Rebalance(s State) bool {
+	return f(s)
+}
+
+// Always rebalances at every point of the series.
+var Always Rule = RuleFunc(func(State) bool { return true })
+
+// Never holds the initial portfolio for the whole series, the buy-and-hold
+// baseline.
+var Never Rule = RuleFunc(func(State) bool { return false })
+
+// A Trade is a trade made by a backtest, along with when it was made.
+type Trade struct {
+	Time time.Time
+	rebalancer.Trade
+}
+
+// A Value is the value of the portfolio at a point of the series, after any
+// rebalance made at it.
+type Value struct {
+	Time  time.Time
+	Value decimal.Decimal
+}
+
+// A Result is the outcome of a backtest.
+type Result struct {
+	// Plans are the plans of every rebalance, in order.
+	Plans []rebalancer.RebalancePlan
+	// Trades are the trades of every rebalance, in order, leaving out those
+	// with a zero amount.
+	Trades []Trade
+	// Values is the value of the portfolio at every point of the series.
+	Values []Value
+	// Holdings is the portfolio at the end of the series.
+	Holdings rebalancer.Portfolio
+	// Stats summarise the performance of the portfolio.
+	Stats Stats
+}
+
+// config holds the settings applied by Options.
+type config struct {
+	rebalanceOpts []rebalancer.RebalanceOption
+	cash          rebalancer.Asset
+	riskFree      decimal.Decimal
+}
+
+// An Option configures a backtest.
+type Option func(*config)
+
+// WithRebalanceOptions rebalances with opts, for instance to apply fees or
+// drift bands.
+func WithRebalanceOptions(opts ...rebalancer.RebalanceOption) Option {
+	return func(c *config) {
+		c.rebalanceOpts = append(c.rebalanceOpts, opts...)
+	}
+}
+
+// WithCash settles trades in cash, which must be priced at every point: the
+// proceeds of sells are credited to it and the cost of buys and every fee
+// debited from it. Without it trades are swapped for each other directly and
+// fees are not paid.
+func WithCash(cash rebalancer.Asset) Option {
+	return func(c *config) {
+		c.cash = cash
+	}
+}
+
+// WithRiskFreeRate measures the Sharpe and Sortino ratios of the Stats against
+// an annual risk-free rate, for example 0.02 for 2%. It defaults to zero.
+func WithRiskFreeRate(rate decimal.Decimal) Option {
+	return func(c *config) {
+		c.riskFree = rate
+	}
+}
+
+// Run simulates holding portfolio over series, rebalancing it onto index at
+// the points chosen by rule. Every asset held or in the index must be priced
+// at every point.
+func Run(series Series, portfolio map[rebalancer.Asset]decimal.Decimal, index rebalancer.Index, rule Rule, opts ...Option) (Result, error) {
+	if len(series) == 0 {
+		return Result{}, ErrEmptySeries
+	}
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	result := Result{Holdings: rebalancer.Portfolio{}}
+	for asset, amount := range portfolio {
+		result.Holdings[asset] = amount
+	}
+	var last time.Time
+	for _, point := range series {
+		account, err := rebalancer.NewAccountWithPricelist(result.Holdings, point.Pricelist)
+		if err != nil {
+			return Result{}, Error{Time: point.Time, Err: err}
+		}
+
+		if rule.Rebalance(State{Time: point.Time, Account: account, Index: index, LastRebalance: last}) {
+			plan, err := account.Rebalance(index, append([]rebalancer.RebalanceOption{rebalancer.AsOf(point.Time)}, c.rebalanceOpts...)...)
+			if err != nil {
+				return Result{}, Error{Time: point.Time, Err: err}
+			}
+			if c.cash != "" {
+				account, err = account.ApplyTradesWithCash(plan, c.cash)
+			} else {
+				account, err = account.ApplyTrades(plan)
+			}
+			if err != nil {
+				return Result{}, Error{Time: point.Time, Err: err}
+			}
+			result.Plans = append(result.Plans, plan)
+			for _, trade := range plan.SortedTrades() {
+				if trade.Amount.IsPositive() {
+					result.Trades = append(result.Trades, Trade{Time: point.Time, Trade: trade})
+				}
+			}
+			last = point.Time
+		}
+
+		result.Holdings = account.Holdings()
+		result.Values = append(result.Values, Value{Time: point.Time, Value: account.Value()})
+	}
+	result.Stats = newStats(result, c.riskFree)
+	return result, nil
+}
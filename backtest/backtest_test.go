@@ -0,0 +1,84 @@
+package backtest_test
+
+import (
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/backtest"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+// prices returns a series with a point a month apart for each of the given
+// ETH and BTC prices.
+func prices(eth, btc []float64) Series {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := make(Series, len(eth))
+	for i := range eth {
+		series[i] = Point{
+			Time: start.AddDate(0, i, 0),
+			Pricelist: rebalancer.Pricelist{
+				"ETH": decimal.NewFromFloat(eth[i]),
+				"BTC": decimal.NewFromFloat(btc[i]),
+			},
+		}
+	}
+	return series
+}
+
+func TestRun(t *testing.T) {
+	series := prices([]float64{200, 400, 200}, []float64{5000, 5000, 5000})
+	portfolio := rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(25),
+		"BTC": decimal.NewFromFloat(1),
+	}
+	index := rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("the portfolio is rebalanced at every point chosen by the rule", func(t *testing.T) {
+		got, err := Run(series, portfolio, index, Always)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(got.Plans) != 3 || len(got.Trades) != 4 {
+			t.Errorf("got %d plans and %d trades want 3 and 4", len(got.Plans), len(got.Trades))
+		}
+		if got.Trades[0].Asset != "ETH" || !got.Trades[0].Amount.Equal(decimal.NewFromFloat(6.25)) || !got.Trades[0].Time.Equal(series[1].Time) {
+			t.Errorf("got %v want 6.25 ETH sold at the second point", got.Trades[0])
+		}
+		if !got.Values[2].Value.Equal(decimal.NewFromFloat(11250)) {
+			t.Errorf("got final value %v want %v", got.Values[2].Value, 11250)
+		}
+	})
+
+	t.Run("a portfolio which is never rebalanced is held", func(t *testing.T) {
+		got, err := Run(series, portfolio, index, Never)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(got.Trades) != 0 || !got.Holdings["ETH"].Equal(decimal.NewFromFloat(25)) || !got.Values[2].Value.Equal(decimal.NewFromFloat(10000)) {
+			t.Errorf("got %v worth %v want the initial portfolio worth 10000", got.Holdings, got.Values[2].Value)
+		}
+	})
+
+	t.Run("a point missing a price is an error", func(t *testing.T) {
+		broken := append(Series{}, series...)
+		broken[1] = Point{Time: series[1].Time, Pricelist: rebalancer.Pricelist{"ETH": decimal.NewFromFloat(400)}}
+
+		_, err := Run(broken, portfolio, index, Always)
+
+		want := Error{Time: series[1].Time, Err: rebalancer.ErrAssetMissingFromPricelist}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+
+	t.Run("an empty series is an error", func(t *testing.T) {
+		if _, err := Run(nil, portfolio, index, Always); err != ErrEmptySeries {
+			t.Errorf("got %v want %v", err, ErrEmptySeries)
+		}
+	})
+}
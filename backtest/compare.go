@@ -0,0 +1,97 @@
+package backtest
+
+import (
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// BuyAndHold is the name of the baseline strategy of a Comparison.
+const BuyAndHold = "buy and hold"
+
+// A Strategy is a named way of rebalancing a portfolio: onto Index, at the
+// points chosen by Rule, with Options.
+type Strategy struct {
+	Name    string
+	Index   rebalancer.Index
+	Rule    Rule
+	Options []Option
+}
+
+// A Comparison holds the results of several strategies run over the same
+// series from the same portfolio.
+type Comparison struct {
+	Strategies []string
+	Results    []Result
+}
+
+// Compare runs every strategy over series from portfolio, along with a
+// buy-and-hold baseline which never trades, so their results can be compared
+// side by side. The baseline comes first, named BuyAndHold, followed by the
+// strategies in the order given.
+func Compare(series Series, portfolio map[rebalancer.Asset]decimal.Decimal, strategies ...Strategy) (Comparison, error) {
+	baseline := Strategy{Name: BuyAndHold, Rule: Never}
+	var comparison Comparison
+	for _, strategy := range append([]Strategy{baseline}, strategies...) {
+		result, err := Run(series, portfolio, strategy.Index, strategy.Rule, strategy.Options...)
+		if err != nil {
+			return Comparison{}, fmt.Errorf("%s: %s", strategy.Name, err)
+		}
+		result.Stats.Name = strategy.Name
+		comparison.Strategies = append(comparison.Strategies, strategy.Name)
+		comparison.Results = append(comparison.Results, result)
+	}
+	return comparison, nil
+}
+
+// Stats returns the stats of every strategy, in the order of Strategies.
+func (c Comparison) Stats() []Stats {
+	stats := make([]Stats, len(c.Results))
+	for i, result := range c.Results {
+		stats[i] = result.Stats
+	}
+	return stats
+}
+
+// WriteCSV writes the stats of every strategy to w as CSV, as WriteCSV does.
+func (c Comparison) WriteCSV(w io.Writer) error {
+	return WriteCSV(w, c.Stats()...)
+}
+
+// WriteTable writes the stats of the strategies to w as a table with a column
+// for each strategy and a row for each statistic. Returns and drawdowns are
+// written as percentages.
+func (c Comparison) WriteTable(w io.Writer) error {
+	rows := []struct {
+		name  string
+		value func(Stats) string
+	}{
+		{"CAGR", func(s Stats) string { return percent(s.CAGR) }},
+		{"Volatility", func(s Stats) string { return percent(s.Volatility) }},
+		{"Sharpe", func(s Stats) string { return s.Sharpe.StringFixed(2) }},
+		{"Sortino", func(s Stats) string { return s.Sortino.StringFixed(2) }},
+		{"Max drawdown", func(s Stats) string { return percent(s.MaxDrawdown) }},
+		{"Turnover", func(s Stats) string { return s.Turnover.StringFixed(2) }},
+		{"Fees", func(s Stats) string { return s.Fees.StringFixed(2) }},
+		{"Rebalances", func(s Stats) string { return fmt.Sprint(s.Rebalances) }},
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\t"+strings.Join(c.Strategies, "\t"))
+	for _, row := range rows {
+		cells := []string{row.name}
+		for _, result := range c.Results {
+			cells = append(cells, row.value(result.Stats))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// percent formats the fraction f as a percentage.
+func percent(f decimal.Decimal) string {
+	return f.Mul(decimal.New(100, 0)).StringFixed(2) + "%"
+}
@@ -0,0 +1,63 @@
+package backtest_test
+
+import (
+	"bytes"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/backtest"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	series := prices([]float64{200, 400, 200}, []float64{5000, 5000, 5000})
+	portfolio := rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(25),
+		"BTC": decimal.NewFromFloat(1),
+	}
+	index := rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	got, err := Compare(series, portfolio,
+		Strategy{Name: "monthly", Index: index, Rule: Calendar(Monthly)},
+		Strategy{Name: "annually", Index: index, Rule: Calendar(Annually)},
+	)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("strategies are compared to buy and hold", func(t *testing.T) {
+		if len(got.Results) != 3 || got.Strategies[0] != BuyAndHold || got.Strategies[2] != "annually" {
+			t.Fatalf("got %v want buy and hold, monthly and annually", got.Strategies)
+		}
+		if !got.Results[0].Values[2].Value.Equal(decimal.NewFromFloat(10000)) || !got.Results[1].Values[2].Value.Equal(decimal.NewFromFloat(11250)) {
+			t.Errorf("got %v and %v want 10000 and 11250", got.Results[0].Values[2].Value, got.Results[1].Values[2].Value)
+		}
+		if got.Stats()[1].Name != "monthly" {
+			t.Errorf("got %q want the stats named after their strategy", got.Stats()[1].Name)
+		}
+	})
+
+	t.Run("the stats are written side by side", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		if err := got.WriteTable(&buf); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		lines := strings.Split(buf.String(), "\n")
+		if !strings.Contains(lines[0], "buy and hold  monthly") || !strings.HasPrefix(lines[8], "Rebalances    0             3        1") {
+			t.Errorf("got %q want a column for each strategy", buf.String())
+		}
+	})
+
+	t.Run("a failing strategy is named", func(t *testing.T) {
+		_, err := Compare(series, portfolio, Strategy{Name: "broken", Index: rebalancer.Index{"XRP": decimal.NewFromFloat(1)}, Rule: Always})
+
+		if err == nil || !strings.HasPrefix(err.Error(), "broken: ") {
+			t.Errorf("got %v want an error naming the strategy", err)
+		}
+	})
+}
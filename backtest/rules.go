@@ -0,0 +1,88 @@
+package backtest
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// A Frequency is how often a Calendar rule rebalances.
+type Frequency int
+
+const (
+	// Monthly rebalances in every calendar month.
+	Monthly Frequency = iota
+	// Quarterly rebalances in every calendar quarter.
+	Quarterly
+	// Annually rebalances in every calendar year.
+	Annually
+)
+
+// String returns the name of the frequency.
+func (f Frequency) String() string {
+	switch f {
+	case Monthly:
+		return "monthly"
+	case Quarterly:
+		return "quarterly"
+	case Annually:
+		return "annually"
+	}
+	return "unknown"
+}
+
+// period returns the number of the period of t, counting months, quarters or
+// years from year zero.
+func (f Frequency) period(t time.Time) int {
+	switch f {
+	case Monthly:
+		return t.Year()*12 + int(t.Month()) - 1
+	case Quarterly:
+		return t.Year()*4 + (int(t.Month())-1)/3
+	}
+	return t.Year()
+}
+
+// Calendar rebalances at the first point of the series and then at the first
+// point of every new calendar period, in UTC. Points need not fall on period
+// boundaries, so a daily series rebalances monthly on the first day of each
+// month it has prices for.
+func Calendar(f Frequency) Rule {
+	return RuleFunc(func(s State) bool {
+		return s.LastRebalance.IsZero() || f.period(s.Time.UTC()) != f.period(s.LastRebalance.UTC())
+	})
+}
+
+// Interval rebalances at the first point of the series and then at the first
+// point at least d after the last rebalance.
+func Interval(d time.Duration) Rule {
+	return RuleFunc(func(s State) bool {
+		return s.LastRebalance.IsZero() || s.Time.Sub(s.LastRebalance) >= d
+	})
+}
+
+// Threshold rebalances whenever the weight of an asset has drifted from its
+// target weight by more than threshold, for example 0.05 for five percentage
+// points.
+func Threshold(threshold decimal.Decimal) Rule {
+	return RuleFunc(func(s State) bool {
+		for _, drift := range s.Account.Drift(s.Index) {
+			if drift.Absolute.Abs().GreaterThan(threshold) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Any rebalances whenever any of rules would, for instance to rebalance
+// annually and whenever a threshold is breached in between.
+func Any(rules ...Rule) Rule {
+	return RuleFunc(func(s State) bool {
+		for _, rule := range rules {
+			if rule.Rebalance(s) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,59 @@
+package backtest_test
+
+import (
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/backtest"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	eth := make([]float64, 13)
+	btc := make([]float64, 13)
+	for i := range eth {
+		eth[i], btc[i] = 200, 5000
+	}
+	eth[4] = 260
+	series := prices(eth, btc)
+	portfolio := rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(25),
+		"BTC": decimal.NewFromFloat(1),
+	}
+	index := rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+	tests := []struct {
+		name string
+		rule Rule
+		want int
+	}{
+		{"monthly rules rebalance every month", Calendar(Monthly), 13},
+		{"quarterly rules rebalance every quarter", Calendar(Quarterly), 5},
+		{"annual rules rebalance every year", Calendar(Annually), 2},
+		{"interval rules rebalance once the interval has passed", Interval(180 * 24 * time.Hour), 3},
+		{"threshold rules rebalance when drift exceeds the threshold", Threshold(decimal.NewFromFloat(0.05)), 2},
+		{"combined rules rebalance when any of them would", Any(Calendar(Annually), Threshold(decimal.NewFromFloat(0.05))), 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Run(series, portfolio, index, test.rule)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(got.Plans) != test.want {
+				t.Errorf("got %d rebalances want %d", len(got.Plans), test.want)
+			}
+		})
+	}
+}
+
+func TestFrequency_String(t *testing.T) {
+	t.Run("frequencies are named", func(t *testing.T) {
+		if got := Quarterly.String(); got != "quarterly" {
+			t.Errorf("got %s want quarterly", got)
+		}
+	})
+}
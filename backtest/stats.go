@@ -0,0 +1,178 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"github.com/shopspring/decimal"
+	"io"
+	"math"
+	"time"
+)
+
+// year is the length of a year used to annualise statistics.
+const year = 365.25 * 24 * time.Hour
+
+// statsPlaces is the number of decimal places statistics are rounded to.
+const statsPlaces = 8
+
+// Stats summarise the performance of a backtest. Returns are fractions, for
+// example 0.1 for 10%, and are annualised from the average spacing of the
+// series' points.
+type Stats struct {
+	// Name is the name of the strategy the stats are of, when they were
+	// produced by Compare.
+	Name       string `json:",omitempty"`
+	Start      time.Time
+	End        time.Time
+	StartValue decimal.Decimal
+	EndValue   decimal.Decimal
+	// CAGR is the compound annual growth rate of the portfolio's value.
+	CAGR decimal.Decimal
+	// Volatility is the annualised standard deviation of the returns between
+	// points.
+	Volatility decimal.Decimal
+	// Sharpe is the annualised return in excess of the risk-free rate per
+	// unit of volatility, and Sortino the same per unit of downside
+	// deviation.
+	Sharpe  decimal.Decimal
+	Sortino decimal.Decimal
+	// MaxDrawdown is the largest fall of the portfolio's value from a
+	// previous peak, as a fraction of the peak.
+	MaxDrawdown decimal.Decimal
+	// Turnover is the combined notional value of every trade.
+	Turnover decimal.Decimal
+	// Fees is the total estimated fee of every trade.
+	Fees decimal.Decimal
+	// Rebalances is the number of times the portfolio was rebalanced.
+	Rebalances int
+}
+
+// statsHeader is the header line written by WriteCSV.
+var statsHeader = []string{"name", "start", "end", "start_value", "end_value", "cagr", "volatility", "sharpe", "sortino", "max_drawdown", "turnover", "fees", "rebalances"}
+
+// WriteCSV writes stats to w as CSV, a line each, with a header line naming
+// the columns.
+func WriteCSV(w io.Writer, stats ...Stats) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(statsHeader); err != nil {
+		return err
+	}
+	for _, s := range stats {
+		err := writer.Write([]string{
+			s.Name,
+			s.Start.Format(time.RFC3339),
+			s.End.Format(time.RFC3339),
+			s.StartValue.String(),
+			s.EndValue.String(),
+			s.CAGR.String(),
+			s.Volatility.String(),
+			s.Sharpe.String(),
+			s.Sortino.String(),
+			s.MaxDrawdown.String(),
+			s.Turnover.String(),
+			s.Fees.String(),
+			decimal.New(int64(s.Rebalances), 0).String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// newStats returns the stats of result, measuring excess returns against an
+// annual riskFree rate.
+func newStats(result Result, riskFree decimal.Decimal) Stats {
+	first, last := result.Values[0], result.Values[len(result.Values)-1]
+	stats := Stats{
+		Start:       first.Time,
+		End:         last.Time,
+		StartValue:  first.Value,
+		EndValue:    last.Value,
+		CAGR:        decimal.Zero,
+		Volatility:  decimal.Zero,
+		Sharpe:      decimal.Zero,
+		Sortino:     decimal.Zero,
+		MaxDrawdown: decimal.Zero,
+		Turnover:    decimal.Zero,
+		Fees:        decimal.Zero,
+		Rebalances:  len(result.Plans),
+	}
+	for _, plan := range result.Plans {
+		stats.Turnover = stats.Turnover.Add(plan.Turnover)
+		stats.Fees = stats.Fees.Add(plan.Fees)
+	}
+
+	values := make([]float64, len(result.Values))
+	for i, value := range result.Values {
+		values[i], _ = value.Value.Float64()
+	}
+	stats.MaxDrawdown = round(maxDrawdown(values))
+
+	years := float64(last.Time.Sub(first.Time)) / float64(year)
+	if years <= 0 || values[0] <= 0 {
+		return stats
+	}
+	stats.CAGR = round(math.Pow(values[len(values)-1]/values[0], 1/years) - 1)
+
+	var returns []float64
+	for i := 1; i < len(values); i++ {
+		if values[i-1] > 0 {
+			returns = append(returns, values[i]/values[i-1]-1)
+		}
+	}
+	if len(returns) < 2 {
+		return stats
+	}
+	periods := float64(len(values)-1) / years
+	rf, _ := riskFree.Float64()
+	periodRF := rf / periods
+
+	var mean, variance, downside float64
+	for _, r := range returns {
+		mean += r
+		if r < periodRF {
+			downside += (r - periodRF) * (r - periodRF)
+		}
+	}
+	mean /= float64(len(returns))
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	downside /= float64(len(returns))
+
+	excess := (mean - periodRF) * periods
+	volatility := math.Sqrt(variance * periods)
+	stats.Volatility = round(volatility)
+	if volatility > 0 {
+		stats.Sharpe = round(excess / volatility)
+	}
+	if downside > 0 {
+		stats.Sortino = round(excess / math.Sqrt(downside*periods))
+	}
+	return stats
+}
+
+// maxDrawdown returns the largest fall of values from a previous peak, as a
+// fraction of the peak.
+func maxDrawdown(values []float64) float64 {
+	var peak, drawdown float64
+	for _, value := range values {
+		if value > peak {
+			peak = value
+		}
+		if peak > 0 && (peak-value)/peak > drawdown {
+			drawdown = (peak - value) / peak
+		}
+	}
+	return drawdown
+}
+
+// round returns f as a decimal rounded to statsPlaces.
+func round(f float64) decimal.Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return decimal.Zero
+	}
+	return decimal.NewFromFloat(f).Round(statsPlaces)
+}
@@ -0,0 +1,85 @@
+package backtest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/backtest"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	series := prices([]float64{100, 120, 90, 110, 130}, []float64{1, 1, 1, 1, 1})
+	portfolio := rebalancer.Portfolio{"ETH": decimal.NewFromFloat(100)}
+	index := rebalancer.Index{"ETH": decimal.NewFromFloat(1)}
+
+	result, err := Run(series, portfolio, index, Never)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("returns and risk are annualised", func(t *testing.T) {
+		got := result.Stats
+		want := map[string][2]decimal.Decimal{
+			"CAGR":       {got.CAGR, decimal.NewFromFloat(1.22236341)},
+			"Volatility": {got.Volatility, decimal.NewFromFloat(0.78954285)},
+			"Sharpe":     {got.Sharpe, decimal.NewFromFloat(1.36485369)},
+			"Sortino":    {got.Sortino, decimal.NewFromFloat(2.4706846)},
+		}
+		for name, values := range want {
+			if !values[0].Equal(values[1]) {
+				t.Errorf("got %s %v want %v", name, values[0], values[1])
+			}
+		}
+	})
+
+	t.Run("the max drawdown is the largest fall from a peak", func(t *testing.T) {
+		if got := result.Stats.MaxDrawdown; !got.Equal(decimal.NewFromFloat(0.25)) {
+			t.Errorf("got %v want %v", got, 0.25)
+		}
+	})
+
+	t.Run("turnover and fees add up over every rebalance", func(t *testing.T) {
+		got, err := Run(prices([]float64{200, 400}, []float64{5000, 5000}), rebalancer.Portfolio{
+			"ETH": decimal.NewFromFloat(25),
+			"BTC": decimal.NewFromFloat(1),
+		}, rebalancer.Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, Always)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got.Stats.Rebalances != 2 || !got.Stats.Turnover.Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %d rebalances turning over %v want 2 turning over 5000", got.Stats.Rebalances, got.Stats.Turnover)
+		}
+	})
+
+	t.Run("stats are exported as CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		if err := WriteCSV(&buf, result.Stats, result.Stats); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "name,start,end,start_value,end_value,cagr") || !strings.Contains(lines[1], ",0.25,") {
+			t.Errorf("got %q want a header and two rows", buf.String())
+		}
+	})
+
+	t.Run("stats are exported as JSON", func(t *testing.T) {
+		data, err := json.Marshal(result.Stats)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		var got Stats
+		if err := json.Unmarshal(data, &got); err != nil || !got.CAGR.Equal(result.Stats.CAGR) {
+			t.Errorf("got %v %v want the stats decoded", got, err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/pdbrito/rebalancer/executor/alpaca"
+	"github.com/pdbrito/rebalancer/executor/binance"
+	"github.com/pdbrito/rebalancer/executor/coinbase"
+	"github.com/pdbrito/rebalancer/executor/kraken"
+	"github.com/shopspring/decimal"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// exchanges returns the executor of each exchange -execute accepts, given the
+// account being rebalanced, the quote currency and the API credentials.
+var exchanges = map[string]func(account rebalancer.Account, quote rebalancer.Asset, key, secret string) (rebalancer.Executor, error){
+	"alpaca": func(_ rebalancer.Account, _ rebalancer.Asset, key, secret string) (rebalancer.Executor, error) {
+		return alpaca.New(key, secret), nil
+	},
+	"binance": func(_ rebalancer.Account, quote rebalancer.Asset, key, secret string) (rebalancer.Executor, error) {
+		return binance.New(key, secret, quote), nil
+	},
+	"coinbase": func(_ rebalancer.Account, quote rebalancer.Asset, key, secret string) (rebalancer.Executor, error) {
+		return coinbase.New(key, secret, quote), nil
+	},
+	"kraken": func(_ rebalancer.Account, quote rebalancer.Asset, key, secret string) (rebalancer.Executor, error) {
+		return kraken.New(key, secret, quote)
+	},
+	// sim fills every order in full at the account's prices, to rehearse an
+	// execution.
+	"sim": func(account rebalancer.Account, _ rebalancer.Asset, _, _ string) (rebalancer.Executor, error) {
+		return rebalancer.NewSimExecutor(account), nil
+	},
+}
+
+// exchangeNames returns the names of the exchanges in order.
+func exchangeNames() []string {
+	names := make([]string, 0, len(exchanges))
+	for name := range exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// executionOptions returns the options orders are placed with. Unless
+// -gate-timeout is 0, the buys are withheld until the sells funding them have
+// filled, and cancelled if they do not fill in time.
+func (c config) executionOptions() []rebalancer.ExecutionOption {
+	var opts []rebalancer.ExecutionOption
+	if c.gateTimeout > 0 {
+		opts = append(opts, rebalancer.GateBuysOnSells(decimal.New(1, 0), c.gateTimeout, rebalancer.CancelBuys))
+	}
+	return opts
+}
+
+// execute places the trades of plan with the exchange named by c, or lists
+// them when c is a dry run. Both go through rebalancer.ExecutePlan with the
+// same options, so a dry run lists exactly the orders a real run places.
+func execute(ctx context.Context, w io.Writer, c config, account rebalancer.Account, plan rebalancer.RebalancePlan) error {
+	fmt.Fprintln(w)
+	if c.dryRun {
+		_, err := rebalancer.ExecutePlan(ctx, &dryRun{w: w, exchange: c.execute}, plan.Trades(), c.executionOptions()...)
+		return err
+	}
+
+	executor, err := exchanges[c.execute](account, rebalancer.Asset(c.quote), os.Getenv("REBALANCE_API_KEY"), os.Getenv("REBALANCE_API_SECRET"))
+	if err != nil {
+		return err
+	}
+	report := rebalancer.NewExecutionReport()
+	opts := append(c.executionOptions(), rebalancer.RetryOrders(3, time.Second), rebalancer.WithExecutionReport(report))
+	fills, err := rebalancer.ExecutePlan(ctx, executor, plan.Trades(), opts...)
+	for _, fill := range fills {
+		fmt.Fprintf(w, "placed %s %s %s as order %s\n", fill.Action, fill.Ordered, fill.Asset, fill.OrderID)
+	}
+	if c.state != "" {
+		store, storeErr := c.store()
+		if storeErr == nil {
+			storeErr = store.SaveExecution(ctx, c.accountID, rebalancer.NewExecutionRecord(time.Now(), plan, report))
+		}
+		if err == nil {
+			err = storeErr
+		}
+	}
+	return err
+}
+
+// dryRun is an Executor which lists the orders it is given instead of placing
+// them, reporting each as filled in full.
+type dryRun struct {
+	w        io.Writer
+	exchange string
+	orders   []rebalancer.Order
+}
+
+func (d *dryRun) PlaceOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	d.orders = append(d.orders, order)
+	fmt.Fprintf(d.w, "would %s %s %s on %s\n", order.Trade.Action, order.Trade.Amount, order.Asset, d.exchange)
+	return fmt.Sprintf("dry-run-%d", len(d.orders)), nil
+}
+
+func (d *dryRun) OrderStatus(ctx context.Context, orderID string) (rebalancer.Fill, error) {
+	var n int
+	if _, err := fmt.Sscanf(orderID, "dry-run-%d", &n); err != nil || n < 1 || n > len(d.orders) {
+		return rebalancer.Fill{}, fmt.Errorf("unknown order %s", orderID)
+	}
+	order := d.orders[n-1]
+	return rebalancer.Fill{
+		OrderID:  orderID,
+		Asset:    order.Asset,
+		Action:   order.Trade.Action,
+		Ordered:  order.Trade.Amount,
+		Quantity: order.Trade.Amount,
+		Done:     true,
+	}, nil
+}
+
+func (d *dryRun) CancelOrder(ctx context.Context, orderID string) error {
+	return nil
+}
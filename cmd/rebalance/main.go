@@ -0,0 +1,280 @@
+// Command rebalance prints the trades which rebalance a portfolio onto a
+// target index, and optionally places them with an exchange.
+//
+// The portfolio is read from a CSV export, the target index from a YAML or
+// JSON file, and the prices either from a CSV or JSON pricelist or from
+// CoinGecko:
+//
+//	rebalance -portfolio positions.csv -index target.yaml -pricelist prices.csv
+//	rebalance -portfolio positions.csv -index target.yaml -prices coingecko -format json
+//
+// Orders are only placed when -execute names an exchange, whose API key and
+// secret are read from REBALANCE_API_KEY and REBALANCE_API_SECRET; with
+// -dry-run the orders are listed instead. The buys are only placed once the
+// sells funding them have filled, waiting up to -gate-timeout.
+//
+// With -state, the account, the plan and any orders placed are recorded in
+// JSON files in a directory, under the ID given by -account, building up a
+// history across runs.
+//
+// Running "rebalance tui" with the same flags reviews the plan interactively
+// before exporting it to -out or placing its orders.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/pdbrito/rebalancer/pricing/coingecko"
+	"github.com/pdbrito/rebalancer/store/jsonfile"
+	"github.com/shopspring/decimal"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// config holds the command line flags.
+type config struct {
+	portfolio    string
+	assetColumn  string
+	amountColumn string
+	index        string
+	pricelist    string
+	prices       string
+	quote        string
+	format       string
+	minTrade     string
+	driftBands   string
+	execute      string
+	dryRun       bool
+	gateTimeout  time.Duration
+	out          string
+	state        string
+	accountID    string
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with args, returning its exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "tui" {
+		return runTUI(ctx, args[1:], stdin, stdout, stderr)
+	}
+	c, err := parseFlags(args, stderr)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	account, plan, err := c.plan(ctx)
+	if err == nil {
+		err = writePlan(stdout, c.format, plan)
+	}
+	if err == nil && c.state != "" {
+		err = c.save(ctx, account, plan)
+	}
+	if err == nil && c.execute != "" {
+		err = execute(ctx, stdout, c, account, plan)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "rebalance:", err)
+		return 1
+	}
+	return 0
+}
+
+// parseFlags parses args into a config, reporting usage errors to stderr.
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+	var c config
+	flags := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&c.portfolio, "portfolio", "", "CSV `file` of the positions held")
+	flags.StringVar(&c.assetColumn, "asset-column", "asset", "header of the portfolio's symbol column")
+	flags.StringVar(&c.amountColumn, "amount-column", "amount", "header of the portfolio's amount column")
+	flags.StringVar(&c.index, "index", "", "YAML or JSON `file` of the target index")
+	flags.StringVar(&c.pricelist, "pricelist", "", "CSV or JSON `file` of prices")
+	flags.StringVar(&c.prices, "prices", "", "`source` to fetch prices from instead of -pricelist: coingecko")
+	flags.StringVar(&c.quote, "quote", "USD", "`currency` prices are fetched and orders placed in")
+	flags.StringVar(&c.format, "format", "table", "output `format`: table, json or csv")
+	flags.StringVar(&c.minTrade, "min-trade-value", "", "drop trades worth less than `value`")
+	flags.StringVar(&c.driftBands, "drift-bands", "", "only trade assets outside `absolute,relative` drift bands, such as 0.05,0.25")
+	flags.StringVar(&c.execute, "execute", "", "place the orders with `exchange`: "+strings.Join(exchangeNames(), ", "))
+	flags.BoolVar(&c.dryRun, "dry-run", false, "list the orders -execute would place without placing them")
+	flags.DurationVar(&c.gateTimeout, "gate-timeout", 5*time.Minute, "wait up to `duration` for the sells to fill before placing the buys they fund; 0 places every order at once")
+	flags.StringVar(&c.out, "out", "plan.csv", "CSV `file` the tui exports plans to")
+	flags.StringVar(&c.state, "state", "", "`directory` to record the account, plans and orders in")
+	flags.StringVar(&c.accountID, "account", "default", "`ID` the account is recorded under in -state")
+	if err := flags.Parse(args); err != nil {
+		return c, err
+	}
+
+	var err error
+	switch {
+	case c.portfolio == "" || c.index == "":
+		err = errors.New("-portfolio and -index are required")
+	case (c.pricelist == "") == (c.prices == ""):
+		err = errors.New("exactly one of -pricelist and -prices is required")
+	case c.prices != "" && c.prices != "coingecko":
+		err = fmt.Errorf("unknown price source %q", c.prices)
+	case c.format != "table" && c.format != "json" && c.format != "csv":
+		err = fmt.Errorf("unknown format %q", c.format)
+	case c.execute != "" && exchanges[c.execute] == nil:
+		err = fmt.Errorf("unknown exchange %q", c.execute)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		flags.Usage()
+	}
+	return c, err
+}
+
+// plan loads the account and target index and rebalances them.
+func (c config) plan(ctx context.Context) (rebalancer.Account, rebalancer.RebalancePlan, error) {
+	account, index, err := c.load(ctx)
+	if err != nil {
+		return rebalancer.Account{}, rebalancer.RebalancePlan{}, err
+	}
+	plan, err := c.rebalance(ctx, account, index)
+	return account, plan, err
+}
+
+// store opens the store in the -state directory.
+func (c config) store() (rebalancer.Store, error) {
+	return jsonfile.New(c.state)
+}
+
+// save records account and plan in the -state directory.
+func (c config) save(ctx context.Context, account rebalancer.Account, plan rebalancer.RebalancePlan) error {
+	store, err := c.store()
+	if err != nil {
+		return err
+	}
+	if err := store.SaveAccount(ctx, c.accountID, account); err != nil {
+		return err
+	}
+	return store.SavePlan(ctx, c.accountID, plan)
+}
+
+// load loads the account and the target index.
+func (c config) load(ctx context.Context) (rebalancer.Account, rebalancer.Index, error) {
+	index, err := loadIndex(c.index)
+	if err != nil {
+		return rebalancer.Account{}, nil, err
+	}
+	account, err := c.account(ctx, index)
+	return account, index, err
+}
+
+// rebalance rebalances account onto index with the options asked for by the
+// flags, followed by extra.
+func (c config) rebalance(ctx context.Context, account rebalancer.Account, index rebalancer.Index, extra ...rebalancer.RebalanceOption) (rebalancer.RebalancePlan, error) {
+	opts, err := c.options()
+	if err != nil {
+		return rebalancer.RebalancePlan{}, err
+	}
+	return account.RebalanceContext(ctx, index, append(opts, extra...)...)
+}
+
+// account loads the portfolio and prices it, along with the assets of index.
+func (c config) account(ctx context.Context, index rebalancer.Index) (rebalancer.Account, error) {
+	f, err := os.Open(c.portfolio)
+	if err != nil {
+		return rebalancer.Account{}, err
+	}
+	defer f.Close()
+	portfolio, err := rebalancer.LoadPortfolioCSV(f, rebalancer.PortfolioMapping{Asset: c.assetColumn, Amount: c.amountColumn})
+	if err != nil {
+		return rebalancer.Account{}, fmt.Errorf("%s: %s", c.portfolio, err)
+	}
+
+	if c.prices == "coingecko" {
+		client := coingecko.New(rebalancer.Asset(c.quote), coingecko.WithAPIKey(os.Getenv("COINGECKO_API_KEY")))
+		assets := make([]rebalancer.Asset, 0, len(portfolio)+len(index))
+		for asset := range portfolio {
+			assets = append(assets, asset)
+		}
+		for asset := range index {
+			if _, ok := portfolio[asset]; !ok {
+				assets = append(assets, asset)
+			}
+		}
+		pricelist, err := client.Prices(ctx, assets...)
+		if err != nil {
+			return rebalancer.Account{}, err
+		}
+		return rebalancer.NewAccountWithPricelist(portfolio, pricelist)
+	}
+
+	f, err = os.Open(c.pricelist)
+	if err != nil {
+		return rebalancer.Account{}, err
+	}
+	defer f.Close()
+	pricelist, err := rebalancer.LoadPricelist(f, formatOf(c.pricelist, rebalancer.CSV))
+	if err != nil {
+		return rebalancer.Account{}, fmt.Errorf("%s: %s", c.pricelist, err)
+	}
+	return rebalancer.NewAccountWithPricelist(portfolio, pricelist)
+}
+
+// options returns the RebalanceOptions asked for by the flags.
+func (c config) options() ([]rebalancer.RebalanceOption, error) {
+	var opts []rebalancer.RebalanceOption
+	if c.minTrade != "" {
+		min, err := decimal.NewFromString(c.minTrade)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -min-trade-value %q", c.minTrade)
+		}
+		opts = append(opts, rebalancer.WithMinTradeValue(min))
+	}
+	if c.driftBands != "" {
+		bands := strings.Split(c.driftBands, ",")
+		if len(bands) != 2 {
+			return nil, fmt.Errorf("invalid -drift-bands %q", c.driftBands)
+		}
+		absolute, err := decimal.NewFromString(strings.TrimSpace(bands[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid -drift-bands %q", c.driftBands)
+		}
+		relative, err := decimal.NewFromString(strings.TrimSpace(bands[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid -drift-bands %q", c.driftBands)
+		}
+		opts = append(opts, rebalancer.WithDriftBands(absolute, relative))
+	}
+	return opts, nil
+}
+
+// loadIndex reads the target index from path.
+func loadIndex(path string) (rebalancer.Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	index, err := rebalancer.LoadIndex(f, formatOf(path, rebalancer.YAML))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return index, nil
+}
+
+// formatOf returns the format of path from its extension, or fallback.
+func formatOf(path string, fallback rebalancer.Format) rebalancer.Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return rebalancer.JSON
+	case ".csv":
+		return rebalancer.CSV
+	case ".yaml", ".yml":
+		return rebalancer.YAML
+	}
+	return fallback
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/pdbrito/rebalancer"
+	"github.com/pdbrito/rebalancer/store/jsonfile"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFiles writes files, named relative to a temporary directory, and
+// returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "rebalance")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	dir := writeFiles(t, map[string]string{
+		"positions.csv": "Symbol,Quantity\neth,20\nbtc,0.5\n",
+		"target.yaml":   "ETH: 50%\nBTC: 50%\n",
+		"prices.json":   `{"ETH": 200, "BTC": 5000}`,
+	})
+	defer os.RemoveAll(dir)
+	args := []string{
+		"-portfolio", filepath.Join(dir, "positions.csv"),
+		"-asset-column", "Symbol",
+		"-amount-column", "Quantity",
+		"-index", filepath.Join(dir, "target.yaml"),
+		"-pricelist", filepath.Join(dir, "prices.json"),
+	}
+
+	t.Run("the trades are printed as a table", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(ctx, args, nil, &stdout, &stderr)
+
+		if code != 0 {
+			t.Fatalf("got exit code %d want 0: %s", code, stderr.String())
+		}
+		want := "ASSET  ACTION  QUANTITY  PRICE  NOTIONAL\n" +
+			"ETH    sell    3.75      200    750.00\n" +
+			"BTC    buy     0.15      5000   750.00\n" +
+			"\nValue 6500.00, turnover 1500.00\n"
+		if got := stdout.String(); got != want {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+	t.Run("the trades are printed as CSV", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		run(ctx, append(args, "-format", "csv"), nil, &stdout, &stderr)
+
+		if !strings.HasPrefix(stdout.String(), "asset,action,quantity,price,notional") {
+			t.Errorf("got %q want CSV", stdout.String())
+		}
+	})
+	t.Run("trades within the drift bands are left out", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		run(ctx, append(args, "-drift-bands", "0.2,0"), nil, &stdout, &stderr)
+
+		if strings.Contains(stdout.String(), "ETH") {
+			t.Errorf("got %q want no trades", stdout.String())
+		}
+	})
+	t.Run("a dry run lists the orders it would place", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		run(ctx, append(args, "-execute", "binance", "-dry-run"), nil, &stdout, &stderr)
+
+		if !strings.Contains(stdout.String(), "would sell 3.75 ETH on binance\nwould buy 0.15 BTC on binance\n") {
+			t.Errorf("got %q want the sell listed before the buy", stdout.String())
+		}
+	})
+	t.Run("a dry run lists the orders a real run places", func(t *testing.T) {
+		var dry, real, stderr bytes.Buffer
+		run(ctx, append(args, "-execute", "sim", "-dry-run"), nil, &dry, &stderr)
+		run(ctx, append(args, "-execute", "sim"), nil, &real, &stderr)
+
+		var listed, placed []string
+		for _, line := range strings.Split(dry.String(), "\n") {
+			if strings.HasPrefix(line, "would ") {
+				listed = append(listed, strings.TrimSuffix(strings.TrimPrefix(line, "would "), " on sim"))
+			}
+		}
+		for _, line := range strings.Split(real.String(), "\n") {
+			if i := strings.Index(line, " as order"); strings.HasPrefix(line, "placed ") && i > 0 {
+				placed = append(placed, strings.TrimPrefix(line[:i], "placed "))
+			}
+		}
+		if len(listed) == 0 || strings.Join(listed, ",") != strings.Join(placed, ",") {
+			t.Errorf("got %v listed want %v", listed, placed)
+		}
+	})
+	t.Run("orders are placed with the exchange", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(ctx, append(args, "-execute", "sim"), nil, &stdout, &stderr)
+
+		if code != 0 || !strings.Contains(stdout.String(), "placed sell 3.75 ETH as order") {
+			t.Errorf("got %d %q want the ETH sell placed", code, stdout.String())
+		}
+	})
+	t.Run("the account, plan and orders are recorded in the state directory", func(t *testing.T) {
+		state := filepath.Join(dir, "state")
+		var stdout, stderr bytes.Buffer
+		code := run(ctx, append(args, "-execute", "sim", "-state", state, "-account", "main"), nil, &stdout, &stderr)
+
+		if code != 0 {
+			t.Fatalf("got exit code %d want 0: %s", code, stderr.String())
+		}
+		store, err := jsonfile.New(state)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		plans, _ := store.LoadPlans(ctx, "main")
+		executions, _ := store.LoadExecutions(ctx, "main")
+		if len(plans) != 1 || len(executions) != 1 || len(executions[0].Orders) != 2 {
+			t.Errorf("got %d plans and %v want the plan and its 2 orders", len(plans), executions)
+		}
+	})
+	t.Run("missing flags are usage errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(ctx, args[:4], nil, &stdout, &stderr)
+
+		if code != 2 || !strings.Contains(stderr.String(), "-portfolio and -index are required") {
+			t.Errorf("got %d %q want a usage error", code, stderr.String())
+		}
+	})
+	t.Run("invalid inputs are reported", func(t *testing.T) {
+		bad := writeFiles(t, map[string]string{"target.yaml": "ETH: 60%\nBTC: 50%\n"})
+		defer os.RemoveAll(bad)
+
+		var stdout, stderr bytes.Buffer
+		code := run(ctx, append(args, "-index", filepath.Join(bad, "target.yaml")), nil, &stdout, &stderr)
+
+		if code != 1 || !strings.Contains(stderr.String(), "index values must sum to 1") {
+			t.Errorf("got %d %q want the invalid index reported", code, stderr.String())
+		}
+	})
+}
+
+// unfilled is an Executor which accepts orders but never fills them.
+type unfilled struct {
+	placed []rebalancer.Order
+}
+
+func (u *unfilled) PlaceOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	u.placed = append(u.placed, order)
+	return string(order.Asset), nil
+}
+
+func (u *unfilled) OrderStatus(ctx context.Context, orderID string) (rebalancer.Fill, error) {
+	return rebalancer.Fill{OrderID: orderID}, nil
+}
+
+func (u *unfilled) CancelOrder(ctx context.Context, orderID string) error {
+	return nil
+}
+
+func TestConfig_executionOptions(t *testing.T) {
+	trades := map[rebalancer.Asset]rebalancer.Trade{
+		"ETH": {Action: rebalancer.Sell, Amount: decimal.NewFromFloat(1)},
+		"BTC": {Action: rebalancer.Buy, Amount: decimal.NewFromFloat(0.1)},
+	}
+
+	t.Run("buys wait for the sells funding them to fill", func(t *testing.T) {
+		executor := &unfilled{}
+		c := config{gateTimeout: 10 * time.Millisecond}
+
+		_, err := rebalancer.ExecutePlan(context.Background(), executor, trades, append(c.executionOptions(), rebalancer.PollInterval(time.Millisecond))...)
+
+		if err != rebalancer.ErrFundingTimeout || len(executor.placed) != 1 {
+			t.Errorf("got %v and %v want only the sell placed", err, executor.placed)
+		}
+	})
+	t.Run("a zero gate timeout places every order at once", func(t *testing.T) {
+		executor := &unfilled{}
+
+		_, err := rebalancer.ExecutePlan(context.Background(), executor, trades, config{}.executionOptions()...)
+
+		if err != nil || len(executor.placed) != 2 {
+			t.Errorf("got %v and %v want both orders placed", err, executor.placed)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"io"
+	"text/tabwriter"
+)
+
+// writePlan writes the trades of plan to w in format.
+func writePlan(w io.Writer, format string, plan rebalancer.RebalancePlan) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "csv":
+		return plan.WriteCSV(w)
+	default:
+		return writeTable(w, plan)
+	}
+}
+
+// writeTable writes the trades of plan as an aligned table followed by the
+// plan's totals.
+func writeTable(w io.Writer, plan rebalancer.RebalancePlan) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ASSET\tACTION\tQUANTITY\tPRICE\tNOTIONAL")
+	for _, trade := range plan.SortedTrades() {
+		if trade.Amount.IsZero() {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			trade.Asset,
+			trade.Action,
+			trade.Amount,
+			plan.Pricelist[trade.Asset],
+			trade.Notional(plan.Pricelist).StringFixed(2),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nValue %s, turnover %s\n", plan.Value.StringFixed(2), plan.Turnover.StringFixed(2))
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// rawMode switches the terminal on stdin to raw mode with stty, so keys are
+// read as they are pressed, and returns a function restoring it. It fails
+// when stdin is not a terminal or stty is unavailable.
+func rawMode() (func(), error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(state))
+	}, nil
+}
+
+// stty runs stty on the terminal of stdin.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Keys which are not a single character.
+const (
+	keyUp   = "up"
+	keyDown = "down"
+)
+
+// A keyReader reads key presses, decoding the escape sequences of the arrow
+// keys.
+type keyReader struct {
+	r *bufio.Reader
+}
+
+func newKeyReader(r io.Reader) keyReader {
+	return keyReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next key pressed, skipping line endings so that keys can
+// also be entered a line at a time.
+func (k keyReader) next() (string, error) {
+	for {
+		b, err := k.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '\r', '\n':
+			continue
+		case 0x03:
+			// Ctrl-C is not turned into a signal in raw mode.
+			return "q", nil
+		case 0x1b:
+			seq := make([]byte, 2)
+			if _, err := io.ReadFull(k.r, seq); err != nil {
+				return "", err
+			}
+			switch string(seq) {
+			case "[A":
+				return keyUp, nil
+			case "[B":
+				return keyDown, nil
+			}
+			continue
+		}
+		return string(b), nil
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// barWidth is the number of characters a weight of 100% fills.
+const barWidth = 20
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// A review is the state of the interactive plan review: the loaded account
+// and index, the assets excluded from the index or locked, and the plan they
+// produce.
+type review struct {
+	c        config
+	account  rebalancer.Account
+	index    rebalancer.Index
+	assets   []rebalancer.Asset
+	excluded map[rebalancer.Asset]bool
+	locked   map[rebalancer.Asset]bool
+	cursor   int
+	plan     rebalancer.RebalancePlan
+	err      error
+	confirm  bool
+	message  string
+}
+
+// newReview loads the inputs of c and plans their rebalance.
+func newReview(ctx context.Context, c config) (*review, error) {
+	r := &review{
+		c:        c,
+		excluded: map[rebalancer.Asset]bool{},
+		locked:   map[rebalancer.Asset]bool{},
+	}
+	if err := r.reload(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the account and index again, picking up new prices, and
+// regenerates the plan.
+func (r *review) reload(ctx context.Context) error {
+	account, index, err := r.c.load(ctx)
+	if err != nil {
+		return err
+	}
+	r.account, r.index = account, index
+
+	seen := map[rebalancer.Asset]bool{}
+	r.assets = nil
+	for asset := range account.Holdings() {
+		seen[asset] = true
+		r.assets = append(r.assets, asset)
+	}
+	for asset := range index {
+		if !seen[asset] {
+			r.assets = append(r.assets, asset)
+		}
+	}
+	sort.Slice(r.assets, func(i, j int) bool {
+		return r.assets[i] < r.assets[j]
+	})
+	if r.cursor >= len(r.assets) {
+		r.cursor = len(r.assets) - 1
+	}
+	r.replan(ctx)
+	return nil
+}
+
+// replan regenerates the plan with the excluded and locked assets.
+func (r *review) replan(ctx context.Context) {
+	var opts []rebalancer.RebalanceOption
+	for _, asset := range r.assets {
+		if r.excluded[asset] {
+			opts = append(opts, rebalancer.WithBlockedAssets(asset))
+		}
+		if r.locked[asset] {
+			opts = append(opts, rebalancer.WithLockedAssets(asset))
+		}
+	}
+	r.plan, r.err = r.c.rebalance(ctx, r.account, r.index, opts...)
+}
+
+// handle applies key to the review, reporting whether to quit.
+func (r *review) handle(ctx context.Context, key string) bool {
+	if r.confirm {
+		r.confirm = false
+		r.message = "orders not placed"
+		if key == "y" {
+			var out bytes.Buffer
+			err := execute(ctx, &out, r.c, r.account, r.plan)
+			r.message = strings.TrimSpace(out.String())
+			if err != nil {
+				r.message = strings.TrimSpace(r.message + "\n" + err.Error())
+			}
+		}
+		return false
+	}
+
+	r.message = ""
+	switch key {
+	case "q":
+		return true
+	case keyUp, "k":
+		if r.cursor > 0 {
+			r.cursor--
+		}
+	case keyDown, "j":
+		if r.cursor < len(r.assets)-1 {
+			r.cursor++
+		}
+	case "x":
+		asset := r.assets[r.cursor]
+		r.excluded[asset] = !r.excluded[asset]
+		r.replan(ctx)
+	case "l":
+		asset := r.assets[r.cursor]
+		r.locked[asset] = !r.locked[asset]
+		r.replan(ctx)
+	case "r":
+		if err := r.reload(ctx); err != nil {
+			r.message = err.Error()
+		}
+	case "e":
+		r.message = r.export()
+	case "p":
+		switch {
+		case r.c.execute == "":
+			r.message = "run with -execute to place orders"
+		case r.err != nil:
+			r.message = "there is no plan to execute"
+		default:
+			r.confirm = true
+		}
+	}
+	return false
+}
+
+// export writes the plan to the -out file, returning a message describing
+// the outcome.
+func (r *review) export() string {
+	if r.err != nil {
+		return "there is no plan to export"
+	}
+	f, err := os.Create(r.c.out)
+	if err != nil {
+		return err.Error()
+	}
+	err = r.plan.WriteCSV(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "exported the plan to " + r.c.out
+}
+
+// render draws the review: every asset's current and target weight, the
+// trades of the plan and the keys available.
+func (r *review) render(w io.Writer) {
+	current := r.account.Allocation()
+	target := r.index
+	if r.err == nil {
+		target = r.plan.Index
+	}
+
+	fmt.Fprintf(w, "  %-8s%-*s  %s\n", "ASSET", barWidth+8, "CURRENT", "TARGET")
+	for i, asset := range r.assets {
+		cursor := " "
+		if i == r.cursor {
+			cursor = ">"
+		}
+		var flags []string
+		if r.excluded[asset] {
+			flags = append(flags, "excluded")
+		}
+		if r.locked[asset] {
+			flags = append(flags, "locked")
+		}
+		line := fmt.Sprintf("%s %-8s%s  %s  %s", cursor, asset, bar(current[asset]), bar(target[asset]), strings.Join(flags, " "))
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+
+	fmt.Fprintln(w)
+	if r.err != nil {
+		fmt.Fprintf(w, "no plan: %s\n", r.err)
+	} else {
+		trades := 0
+		for _, trade := range r.plan.SortedTrades() {
+			if trade.Amount.IsZero() {
+				continue
+			}
+			trades++
+			fmt.Fprintf(w, "%-4s %s %s (%s)\n", trade.Action, trade.Amount, trade.Asset, trade.Notional(r.plan.Pricelist).StringFixed(2))
+		}
+		if trades == 0 {
+			fmt.Fprintln(w, "no trades needed")
+		}
+	}
+
+	fmt.Fprintln(w)
+	if r.confirm {
+		fmt.Fprintf(w, "place these orders on %s? [y/N]\n", r.c.execute)
+		return
+	}
+	fmt.Fprintln(w, "↑/↓ select  x exclude  l lock  r reload  e export  p place orders  q quit")
+	if r.message != "" {
+		fmt.Fprintln(w, r.message)
+	}
+}
+
+// bar draws weight as a bar followed by its percentage.
+func bar(weight decimal.Decimal) string {
+	filled := int(weight.Mul(decimal.New(barWidth, 0)).Round(0).IntPart())
+	if filled > barWidth {
+		filled = barWidth
+	}
+	if filled < 0 {
+		filled = 0
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("·", barWidth-filled) + fmt.Sprintf(" %6s%%", weight.Mul(decimal.New(100, 0)).StringFixed(1))
+}
+
+// runTUI runs the interactive review with args, returning its exit code.
+func runTUI(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c, err := parseFlags(args, stderr)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	r, err := newReview(ctx, c)
+	if err != nil {
+		fmt.Fprintln(stderr, "rebalance:", err)
+		return 1
+	}
+
+	newline := "\n"
+	if stdin == os.Stdin {
+		if restore, err := rawMode(); err == nil {
+			defer restore()
+			newline = "\r\n"
+		}
+	}
+	keys := newKeyReader(stdin)
+	for {
+		var screen bytes.Buffer
+		r.render(&screen)
+		io.WriteString(stdout, clearScreen+strings.Replace(screen.String(), "\n", newline, -1))
+
+		key, err := keys.next()
+		if err != nil || r.handle(ctx, key) {
+			return 0
+		}
+	}
+}
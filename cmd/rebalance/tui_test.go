@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReview(t *testing.T) {
+	ctx := context.Background()
+	dir := writeFiles(t, map[string]string{
+		"positions.csv": "asset,amount\nETH,20\nBTC,0.5\n",
+		"target.yaml":   "ETH: 40%\nBTC: 40%\nXLM: 20%\n",
+		"prices.csv":    "ETH,200\nBTC,5000\nXLM,0.25\n",
+	})
+	defer os.RemoveAll(dir)
+	c, err := parseFlags([]string{
+		"-portfolio", filepath.Join(dir, "positions.csv"),
+		"-index", filepath.Join(dir, "target.yaml"),
+		"-pricelist", filepath.Join(dir, "prices.csv"),
+		"-execute", "sim",
+		"-out", filepath.Join(dir, "plan.csv"),
+	}, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("the weights and trades are drawn", func(t *testing.T) {
+		r, err := newReview(ctx, c)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var screen bytes.Buffer
+		r.render(&screen)
+
+		got := screen.String()
+		if !strings.Contains(got, "> BTC     ████████············   38.5%  ████████············   40.0%") {
+			t.Errorf("got %q want BTC selected at 38.5%% of 40%%", got)
+		}
+		if !strings.Contains(got, "buy  5200 XLM (1300.00)") {
+			t.Errorf("got %q want the XLM buy", got)
+		}
+	})
+	t.Run("excluding an asset regenerates the plan without it", func(t *testing.T) {
+		r, _ := newReview(ctx, c)
+
+		r.handle(ctx, keyDown)
+		r.handle(ctx, keyDown)
+		r.handle(ctx, "x")
+
+		if r.err != nil {
+			t.Fatalf("unexpected error: %s", r.err)
+		}
+		if _, ok := r.plan.Index["XLM"]; ok || !r.excluded["XLM"] {
+			t.Errorf("got %v want XLM excluded from the index", r.plan.Index)
+		}
+		r.handle(ctx, "x")
+		if _, ok := r.plan.Index["XLM"]; !ok {
+			t.Errorf("got %v want XLM back in the index", r.plan.Index)
+		}
+	})
+	t.Run("locked assets are not traded", func(t *testing.T) {
+		r, _ := newReview(ctx, c)
+
+		r.handle(ctx, keyDown)
+		r.handle(ctx, "l")
+
+		if trade := r.plan.Trades()["ETH"]; !trade.Amount.IsZero() {
+			t.Errorf("got %v want no ETH trade", trade)
+		}
+	})
+	t.Run("the plan is exported", func(t *testing.T) {
+		r, _ := newReview(ctx, c)
+
+		r.handle(ctx, "e")
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "plan.csv"))
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !strings.HasPrefix(string(data), "asset,action") || !strings.HasPrefix(r.message, "exported") {
+			t.Errorf("got %q and %q want the plan exported", data, r.message)
+		}
+	})
+	t.Run("orders are placed once confirmed", func(t *testing.T) {
+		r, _ := newReview(ctx, c)
+
+		r.handle(ctx, "p")
+		r.handle(ctx, "n")
+		if r.message != "orders not placed" {
+			t.Errorf("got %q want the orders withheld", r.message)
+		}
+
+		r.handle(ctx, "p")
+		r.handle(ctx, "y")
+		if !strings.Contains(r.message, "placed buy 5200 XLM") {
+			t.Errorf("got %q want the orders placed", r.message)
+		}
+	})
+	t.Run("keys are read until q is pressed", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runTUI(ctx, []string{
+			"-portfolio", filepath.Join(dir, "positions.csv"),
+			"-index", filepath.Join(dir, "target.yaml"),
+			"-pricelist", filepath.Join(dir, "prices.csv"),
+		}, strings.NewReader("\x1b[Bx\nq\nl"), &stdout, &stderr)
+
+		if code != 0 {
+			t.Errorf("got exit code %d want 0: %s", code, stderr.String())
+		}
+		if got := strings.Count(stdout.String(), clearScreen); got != 3 {
+			t.Errorf("got %d screens want 3", got)
+		}
+		if !strings.Contains(stdout.String(), "> ETH") || !strings.Contains(stdout.String(), "excluded") {
+			t.Errorf("got %q want ETH selected and excluded", stdout.String())
+		}
+	})
+}
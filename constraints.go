@@ -0,0 +1,160 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInfeasibleWeights is returned when the weight constraints given to
+// Rebalance cannot be satisfied by any allocation.
+var ErrInfeasibleWeights = errors.New("weight constraints cannot be satisfied")
+
+// maxWeight returns the cap on asset's target weight, if there is one.
+func (c rebalanceConfig) maxWeight(asset Asset) (decimal.Decimal, bool) {
+	max, ok := c.maxWeights[asset]
+	if c.weightCap.IsPositive() && (!ok || c.weightCap.LessThan(max)) {
+		return c.weightCap, true
+	}
+	return max, ok
+}
+
+// isBlocked reports whether asset may not be held by the account.
+func (c rebalanceConfig) isBlocked(asset Asset) bool {
+	return c.blocked[asset] || (c.allowed != nil && !c.allowed[asset])
+}
+
+// filterAssets returns targetIndex without its blocked assets, with the
+// remaining weights scaled to sum to 1.
+func filterAssets(targetIndex Index, config rebalanceConfig) (Index, error) {
+	if config.allowed == nil && config.blocked == nil {
+		return targetIndex, nil
+	}
+
+	weights := Index{}
+	total := decimal.Zero
+	for asset, weight := range targetIndex {
+		if !config.isBlocked(asset) {
+			weights[asset] = weight
+			total = total.Add(weight)
+		}
+	}
+	if !total.IsPositive() {
+		return nil, ErrEmptyIndex
+	}
+	for asset, weight := range weights {
+		weights[asset] = weight.Div(total)
+	}
+	return weights, nil
+}
+
+// minWeight returns the floor on asset's target weight, if there is one.
+func (c rebalanceConfig) minWeight(asset Asset) (decimal.Decimal, bool) {
+	min, ok := c.minWeights[asset]
+	if c.weightFloor.IsPositive() && (!ok || c.weightFloor.GreaterThan(min)) {
+		return c.weightFloor, true
+	}
+	return min, ok
+}
+
+// floorWeights returns targetIndex with every positive weight below its floor
+// either raised to it, funded proportionally by the assets above their
+// floors, or dropped and spread proportionally over the remaining assets when
+// DropBelowMinWeight is given. Funding a raise may push further assets below
+// their floors, so this repeats until none are. ErrInfeasibleWeights is
+// returned when no asset is left above its floor.
+func floorWeights(targetIndex Index, config rebalanceConfig) (Index, error) {
+	if config.minWeights == nil && !config.weightFloor.IsPositive() {
+		return targetIndex, nil
+	}
+
+	weights := Index{}
+	for asset, weight := range targetIndex {
+		weights[asset] = weight
+	}
+
+	fixed := map[Asset]bool{}
+	for {
+		change := decimal.Zero
+		for asset, weight := range weights {
+			min, ok := config.minWeight(asset)
+			if fixed[asset] || !ok || !weight.IsPositive() || weight.GreaterThanOrEqual(min) {
+				continue
+			}
+			fixed[asset] = true
+			if config.dropBelowMin {
+				change = change.Add(weight)
+				weights[asset] = decimal.Zero
+				continue
+			}
+			change = change.Sub(min.Sub(weight))
+			weights[asset] = min
+		}
+		if change.IsZero() {
+			return weights, nil
+		}
+
+		free := decimal.Zero
+		for asset, weight := range weights {
+			if !fixed[asset] {
+				free = free.Add(weight)
+			}
+		}
+		// Nothing is left to fund the raises or absorb the dropped weights,
+		// such as when every weight falls below its floor.
+		if !free.IsPositive() || !free.Add(change).IsPositive() {
+			return nil, ErrInfeasibleWeights
+		}
+		for asset, weight := range weights {
+			if !fixed[asset] {
+				weights[asset] = weight.Add(change.Mul(weight).Div(free))
+			}
+		}
+	}
+}
+
+// capWeights returns targetIndex with every weight above its cap lowered to
+// it, and the excess spread over the uncapped assets in proportion to their
+// weights. Spreading the excess may push further assets over their caps, so
+// this repeats until none are.
+func capWeights(targetIndex Index, config rebalanceConfig) (Index, error) {
+	if config.maxWeights == nil && !config.weightCap.IsPositive() {
+		return targetIndex, nil
+	}
+
+	weights := Index{}
+	for asset, weight := range targetIndex {
+		weights[asset] = weight
+	}
+
+	capped := map[Asset]bool{}
+	for {
+		excess := decimal.Zero
+		for asset, weight := range weights {
+			max, ok := config.maxWeight(asset)
+			if capped[asset] || !ok || weight.LessThanOrEqual(max) {
+				continue
+			}
+			excess = excess.Add(weight.Sub(max))
+			weights[asset] = max
+			capped[asset] = true
+		}
+		if excess.IsZero() {
+			return weights, nil
+		}
+
+		free := decimal.Zero
+		for asset, weight := range weights {
+			if !capped[asset] {
+				free = free.Add(weight)
+			}
+		}
+		if !free.IsPositive() {
+			return nil, ErrInfeasibleWeights
+		}
+		for asset, weight := range weights {
+			if !capped[asset] {
+				weights[asset] = weight.Add(excess.Mul(weight).Div(free))
+			}
+		}
+	}
+}
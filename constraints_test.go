@@ -0,0 +1,216 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestWithMaxWeight(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(100),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XLM": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.6),
+		"BTC": decimal.NewFromFloat(0.3),
+		"XLM": decimal.NewFromFloat(0.1),
+	}
+
+	t.Run("the excess over a cap is redistributed proportionally", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithMaxWeight("ETH", decimal.NewFromFloat(0.4)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(60)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(45)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(15)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("a global cap is applied until no asset exceeds it", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithWeightCap(decimal.NewFromFloat(0.35)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(65)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(35)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(30)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("caps summing to less than 1 are infeasible", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithWeightCap(decimal.NewFromFloat(0.3)))
+
+		if err != ErrInfeasibleWeights {
+			t.Errorf("got %v, want %s", err, ErrInfeasibleWeights)
+		}
+	})
+}
+
+func TestWithMinWeight(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(100),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XLM": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.64),
+		"BTC": decimal.NewFromFloat(0.32),
+		"XLM": decimal.NewFromFloat(0.04),
+	}
+
+	t.Run("weights below their minimum are raised to it", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithMinWeight("XLM", decimal.NewFromFloat(0.1)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(40)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(30)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(10)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("weights below the floor can be dropped instead", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.45),
+			"BTC": decimal.NewFromFloat(0.45),
+			"XLM": decimal.NewFromFloat(0.1),
+		}, WithWeightFloor(decimal.NewFromFloat(0.15)), DropBelowMinWeight())
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(50)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(50)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(0)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("floors summing to more than 1 are infeasible", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithWeightFloor(decimal.NewFromFloat(0.4)))
+
+		if err != ErrInfeasibleWeights {
+			t.Errorf("got %v, want %s", err, ErrInfeasibleWeights)
+		}
+	})
+	t.Run("dropping every weight below the floor is infeasible", func(t *testing.T) {
+		_, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, WithWeightFloor(decimal.NewFromFloat(0.6)), DropBelowMinWeight())
+
+		if err != ErrInfeasibleWeights {
+			t.Errorf("got %v, want %s", err, ErrInfeasibleWeights)
+		}
+	})
+}
+
+func TestWithBlockedAssets(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(50),
+		"XMR": decimal.NewFromFloat(50),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XMR": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.3),
+		"BTC": decimal.NewFromFloat(0.3),
+		"XMR": decimal.NewFromFloat(0.4),
+	}
+
+	want := map[Asset]Trade{
+		"ETH": {Action: Buy, Amount: decimal.NewFromFloat(0)},
+		"BTC": {Action: Buy, Amount: decimal.NewFromFloat(50)},
+		"XMR": {Action: Sell, Amount: decimal.NewFromFloat(50)},
+	}
+
+	t.Run("blocked assets are removed from the index and liquidated", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithBlockedAssets("XMR"))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("blocked holdings are liquidated even when unlisted holdings are kept", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, WithBlockedAssets("XMR"), KeepUnlisted())
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("assets which are not allowed are blocked", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithAllowedAssets("ETH", "BTC"))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("an index of only blocked assets is empty", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithAllowedAssets("DOGE"))
+
+		if err != ErrEmptyIndex {
+			t.Errorf("got %v, want %s", err, ErrEmptyIndex)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package rebalancer
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInsufficientValue is returned when more value is withdrawn from an
+// account than is available to rebalance.
+var ErrInsufficientValue = errors.New("account value is insufficient")
+
+// RebalanceWithContribution returns the plan whose trades balance the
+// account's portfolio, together with a cash contribution, to match the
+// supplied target index. The contribution is deployed towards underweight
+// assets first, and assets are only sold when they remain overweight once it
+// is invested, so the buys exceed the sells by exactly the contribution. A negative
+// contribution is a withdrawal, raised by selling overweight assets first.
+func (a Account) RebalanceWithContribution(targetIndex map[Asset]decimal.Decimal, contribution decimal.Decimal, opts ...RebalanceOption) (RebalancePlan, error) {
+	return a.rebalance(context.Background(), targetIndex, contribution, opts)
+}
+
+// RebalanceWithContributionContext is like RebalanceWithContribution, but
+// gives up with ctx's error once ctx is done.
+func (a Account) RebalanceWithContributionContext(ctx context.Context, targetIndex map[Asset]decimal.Decimal, contribution decimal.Decimal, opts ...RebalanceOption) (RebalancePlan, error) {
+	return a.rebalance(ctx, targetIndex, contribution, opts)
+}
@@ -0,0 +1,82 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestAccount_RebalanceWithContribution(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(60),
+		"BTC": decimal.NewFromFloat(40),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("a sufficient contribution is invested without selling", func(t *testing.T) {
+		plan, err := account.RebalanceWithContribution(index, decimal.NewFromFloat(30))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Buy, Amount: decimal.NewFromFloat(5)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(25)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("an insufficient contribution is topped up by selling", func(t *testing.T) {
+		plan, err := account.RebalanceWithContribution(index, decimal.NewFromFloat(10))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(5)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(15)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("a withdrawal is raised from overweight assets", func(t *testing.T) {
+		plan, err := account.RebalanceWithContribution(index, decimal.NewFromFloat(-20))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(20)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("a withdrawal cannot exceed the account's value", func(t *testing.T) {
+		_, err := account.RebalanceWithContribution(index, decimal.NewFromFloat(-101))
+
+		if err != ErrInsufficientValue {
+			t.Errorf("got %v, want %s", err, ErrInsufficientValue)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package rebalancer
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidMultiplier is returned by CPPI when its multiplier is negative.
+var ErrInvalidMultiplier = errors.New("CPPI multiplier must not be negative")
+
+// CPPI is a constant proportion portfolio insurance Strategy. It protects a
+// floor value by investing Multiplier times the cushion, the account's value
+// above Floor, in the assets of the target index and holding the rest in
+// Safe. The exposure is recomputed every time a plan is made, so risky assets
+// are sold as they fall and bought as they rise; it is never more than the
+// account's value.
+type CPPI struct {
+	Safe       Asset
+	Floor      decimal.Decimal
+	Multiplier decimal.Decimal
+}
+
+// Plan rebalances account onto the index returned by Index for its value,
+// together with any contribution, using Proportional.
+func (c CPPI) Plan(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error) {
+	value := account.Value().Add(newRebalanceConfig(opts).contribution)
+	index, err := c.Index(value, targetIndex)
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+	return Proportional.Plan(ctx, account, index, opts...)
+}
+
+// Index returns the target index of an account worth value, investing the
+// exposure in the assets of targetIndex in proportion to their weights and
+// the rest in Safe. Any weight targetIndex gives Safe is left out of the
+// exposure.
+func (c CPPI) Index(value decimal.Decimal, targetIndex Index) (Index, error) {
+	if c.Multiplier.IsNegative() {
+		return nil, ErrInvalidMultiplier
+	}
+	risky := Index{}
+	for asset, weight := range targetIndex {
+		if asset != c.Safe {
+			risky[asset] = weight
+		}
+	}
+
+	one := decimal.New(1, 0)
+	exposure := decimal.Zero
+	if value.IsPositive() && len(risky) > 0 {
+		cushion := decimal.Max(value.Sub(c.Floor), decimal.Zero)
+		exposure = decimal.Min(cushion.Mul(c.Multiplier).Div(value), one)
+	}
+	index := scaleWeights(risky, exposure)
+	if safe := one.Sub(exposure); safe.IsPositive() {
+		index[c.Safe] = safe
+	}
+	return index, nil
+}
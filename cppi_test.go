@@ -0,0 +1,91 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestCPPI_Index(t *testing.T) {
+	cppi := CPPI{Safe: "USD", Floor: decimal.NewFromFloat(800), Multiplier: decimal.NewFromFloat(3)}
+	risky := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	tests := []struct {
+		name  string
+		value float64
+		want  Index
+	}{
+		{"the multiple of the cushion is invested in the risky assets", 1000, Index{
+			"ETH": decimal.NewFromFloat(0.3),
+			"BTC": decimal.NewFromFloat(0.3),
+			"USD": decimal.NewFromFloat(0.4),
+		}},
+		{"the exposure falls faster than the value", 900, Index{
+			"ETH": decimal.RequireFromString("0.1666666666666667"),
+			"BTC": decimal.RequireFromString("0.1666666666666666"),
+			"USD": decimal.RequireFromString("0.6666666666666667"),
+		}},
+		{"everything is held in the safe asset at the floor", 800, Index{
+			"USD": decimal.NewFromFloat(1),
+		}},
+		{"the exposure never exceeds the value", 2000, Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cppi.Index(decimal.NewFromFloat(tt.value), risky)
+
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			assertSameIndex(t, got, tt.want)
+		})
+	}
+
+	t.Run("a negative multiplier is rejected", func(t *testing.T) {
+		_, err := CPPI{Safe: "USD", Multiplier: decimal.NewFromFloat(-1)}.Index(decimal.NewFromFloat(1000), risky)
+
+		if err != ErrInvalidMultiplier {
+			t.Errorf("got %v want %v", err, ErrInvalidMultiplier)
+		}
+	})
+}
+
+func TestCPPI_Plan(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(2),
+		"USD": decimal.NewFromFloat(600),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"USD": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	cppi := CPPI{Safe: "USD", Floor: decimal.NewFromFloat(800), Multiplier: decimal.NewFromFloat(3)}
+	plan, err := account.Rebalance(Index{"ETH": decimal.NewFromFloat(1)}, WithStrategy(cppi))
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if !plan.Index["ETH"].Equal(decimal.NewFromFloat(0.6)) || !plan.Index["USD"].Equal(decimal.NewFromFloat(0.4)) {
+		t.Errorf("got %v want 60%% ETH and 40%% USD", plan.Index)
+	}
+
+	want := map[Asset]Trade{
+		"ETH": {Action: Buy, Amount: decimal.NewFromFloat(1)},
+		"USD": {Action: Sell, Amount: decimal.NewFromFloat(200)},
+	}
+
+	assertSameTrades(t, plan.Trades(), want)
+}
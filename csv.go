@@ -0,0 +1,70 @@
+package rebalancer
+
+import (
+	"encoding/csv"
+	"github.com/shopspring/decimal"
+	"io"
+)
+
+// csvHeader is the header line written by RebalancePlan.WriteCSV.
+var csvHeader = []string{"asset", "action", "quantity", "price", "notional", "weight_before", "weight_after"}
+
+// weightPlaces is the number of decimal places weights are written with.
+const weightPlaces = 6
+
+// WriteCSV writes the trades of the plan to w as CSV, in the order of
+// SortedTrades, with a header line naming the columns: asset, action,
+// quantity, price, notional, weight_before and weight_after. The weights are
+// the asset's share of the holdings before and after the trades, valued at
+// the plan's prices, and are left blank when the plan has no holdings.
+func (p RebalancePlan) WriteCSV(w io.Writer) error {
+	before, after := p.weights()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, trade := range p.SortedTrades() {
+		record := []string{
+			string(trade.Asset),
+			string(trade.Action),
+			trade.Amount.String(),
+			p.Pricelist[trade.Asset].String(),
+			trade.Notional(p.Pricelist).String(),
+			"",
+			"",
+		}
+		if before != nil {
+			record[5] = before[trade.Asset].StringFixed(weightPlaces)
+			record[6] = after[trade.Asset].StringFixed(weightPlaces)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// weights returns the weight of each asset in the plan's holdings before and
+// after its trades, or nil if the plan has no holdings.
+func (p RebalancePlan) weights() (before, after map[Asset]decimal.Decimal) {
+	if p.Holdings == nil {
+		return nil, nil
+	}
+	return portfolioWeights(p.Holdings, p.Pricelist), portfolioWeights(applyTrades(p.Holdings, p.trades), p.Pricelist)
+}
+
+// portfolioWeights returns the weight of each asset of portfolio when valued
+// at pricelist.
+func portfolioWeights(portfolio Portfolio, pricelist Pricelist) map[Asset]decimal.Decimal {
+	total, _ := valuePortfolio(portfolio, pricelist)
+	weights := map[Asset]decimal.Decimal{}
+	if !total.IsPositive() {
+		return weights
+	}
+	for asset, amount := range portfolio {
+		weights[asset] = amount.Mul(pricelist[asset]).Div(total)
+	}
+	return weights
+}
@@ -0,0 +1,64 @@
+package rebalancer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestRebalancePlan_WriteCSV(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	plan, err := account.Rebalance(Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("trades are written with their weights before and after", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := plan.WriteCSV(&buf)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		want := "asset,action,quantity,price,notional,weight_before,weight_after\n" +
+			"ETH,sell,3.75,200,750,0.615385,0.500000\n" +
+			"BTC,buy,0.15,5000,750,0.384615,0.500000\n"
+		if got := buf.String(); got != want {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+	t.Run("weights are left blank without holdings", func(t *testing.T) {
+		data, _ := json.Marshal(plan)
+		var decoded RebalancePlan
+		json.Unmarshal(data, &decoded)
+		decoded.Holdings = nil
+
+		var buf bytes.Buffer
+		if err := decoded.WriteCSV(&buf); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		want := "asset,action,quantity,price,notional,weight_before,weight_after\n" +
+			"ETH,sell,3.75,200,750,,\n" +
+			"BTC,buy,0.15,5000,750,,\n"
+		if got := buf.String(); got != want {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+}
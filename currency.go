@@ -0,0 +1,116 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+// A QuotedPrice is the price of an asset in the currency it is quoted in.
+type QuotedPrice struct {
+	Price    decimal.Decimal
+	Currency Asset
+}
+
+// A CurrencyPricelist prices assets in their own quote currencies, such as
+// ETH in USD and DAX stocks in EUR, along with the exchange rates which
+// convert them into the base currency of an account.
+type CurrencyPricelist struct {
+	Base   Asset
+	Prices map[Asset]QuotedPrice
+	// Rates holds the price of each quote currency in Base.
+	Rates map[Asset]decimal.Decimal
+}
+
+// ErrMissingExchangeRate indicates a price quoted in a currency without an
+// exchange rate into the base currency.
+var ErrMissingExchangeRate = errors.New("quote currency missing an exchange rate")
+
+// NewCurrencyPricelist validates and returns a new CurrencyPricelist. Every
+// price must be quoted in base or in a currency with a rate.
+func NewCurrencyPricelist(base Asset, prices map[Asset]QuotedPrice, rates map[Asset]decimal.Decimal) (CurrencyPricelist, error) {
+	if string(base) != strings.ToUpper(string(base)) {
+		return CurrencyPricelist{}, ErrInvalidAsset
+	}
+	if len(prices) == 0 {
+		return CurrencyPricelist{}, ErrEmptyPricelist
+	}
+
+	result := CurrencyPricelist{Base: base, Prices: map[Asset]QuotedPrice{}, Rates: map[Asset]decimal.Decimal{}}
+	for currency, rate := range rates {
+		if string(currency) != strings.ToUpper(string(currency)) {
+			return CurrencyPricelist{}, ErrInvalidAsset
+		}
+		if !rate.IsPositive() {
+			return CurrencyPricelist{}, ErrInvalidAssetAmount{Asset: currency, Amount: rate}
+		}
+		result.Rates[currency] = rate
+	}
+	for asset, price := range prices {
+		if string(asset) != strings.ToUpper(string(asset)) {
+			return CurrencyPricelist{}, ErrInvalidAsset
+		}
+		if !price.Price.IsPositive() {
+			return CurrencyPricelist{}, ErrInvalidAssetAmount{Asset: asset, Amount: price.Price}
+		}
+		if _, ok := rates[price.Currency]; !ok && price.Currency != base {
+			return CurrencyPricelist{}, ErrMissingExchangeRate
+		}
+		result.Prices[asset] = price
+	}
+	return result, nil
+}
+
+// InBase returns a Pricelist of every asset converted into the base
+// currency. The base currency and the quote currencies are included too, so
+// an account can hold them as cash.
+func (c CurrencyPricelist) InBase() Pricelist {
+	pricelist := Pricelist{c.Base: decimal.New(1, 0)}
+	for currency, rate := range c.Rates {
+		pricelist[currency] = rate
+	}
+	for asset, price := range c.Prices {
+		rate, ok := c.Rates[price.Currency]
+		if !ok {
+			rate = decimal.New(1, 0)
+		}
+		pricelist[asset] = price.Price.Mul(rate)
+	}
+	return pricelist
+}
+
+// NewAccountWithCurrencyPricelist validates portfolio and pricelist and then
+// returns a new Account struct valued in pricelist's base currency.
+func NewAccountWithCurrencyPricelist(portfolio map[Asset]decimal.Decimal, pricelist CurrencyPricelist) (Account, error) {
+	return NewAccountWithPricelist(portfolio, pricelist.InBase())
+}
+
+// Convert returns the pricelist re-expressed in units of toQuote, where rate
+// is the price of toQuote in the pricelist's current currency. For example a
+// pricelist in USD is converted into BTC terms with the USD price of a BTC.
+func (p Pricelist) Convert(toQuote Asset, rate decimal.Decimal) (Pricelist, error) {
+	if !rate.IsPositive() {
+		return nil, ErrInvalidAssetAmount{Asset: toQuote, Amount: rate}
+	}
+	converted := Pricelist{toQuote: decimal.New(1, 0)}
+	for asset, price := range p {
+		if asset != toQuote {
+			converted[asset] = price.Div(rate)
+		}
+	}
+	return converted, nil
+}
+
+// CrossRate returns the price of a in units of b, derived from their prices
+// in the pricelist.
+func (p Pricelist) CrossRate(a, b Asset) (decimal.Decimal, error) {
+	priceA, ok := p[a]
+	if !ok {
+		return decimal.Zero, ErrAssetMissingFromPricelist
+	}
+	priceB, ok := p[b]
+	if !ok {
+		return decimal.Zero, ErrAssetMissingFromPricelist
+	}
+	return priceA.Div(priceB), nil
+}
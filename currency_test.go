@@ -0,0 +1,116 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestNewCurrencyPricelist(t *testing.T) {
+	prices := map[Asset]QuotedPrice{
+		"ETH": {Price: decimal.NewFromFloat(200), Currency: "USD"},
+		"SAP": {Price: decimal.NewFromFloat(100), Currency: "EUR"},
+	}
+
+	t.Run("prices are converted into the base currency", func(t *testing.T) {
+		pricelist, err := NewCurrencyPricelist("USD", prices, map[Asset]decimal.Decimal{
+			"EUR": decimal.NewFromFloat(1.1),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := pricelist.InBase()
+		want := Pricelist{
+			"USD": decimal.NewFromFloat(1),
+			"EUR": decimal.NewFromFloat(1.1),
+			"ETH": decimal.NewFromFloat(200),
+			"SAP": decimal.NewFromFloat(110),
+		}
+
+		if len(got) != len(want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+		for asset, price := range want {
+			if !got[asset].Equal(price) {
+				t.Errorf("got %v want %v for asset %s", got[asset], price, asset)
+			}
+		}
+	})
+	t.Run("every quote currency needs an exchange rate", func(t *testing.T) {
+		_, err := NewCurrencyPricelist("USD", prices, nil)
+
+		if err != ErrMissingExchangeRate {
+			t.Errorf("got %v, want %s", err, ErrMissingExchangeRate)
+		}
+	})
+	t.Run("accounts are valued in the base currency", func(t *testing.T) {
+		pricelist, _ := NewCurrencyPricelist("USD", prices, map[Asset]decimal.Decimal{
+			"EUR": decimal.NewFromFloat(1.1),
+		})
+
+		account, err := NewAccountWithCurrencyPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(1),
+			"SAP": decimal.NewFromFloat(1),
+		}, pricelist)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !account.Value().Equal(decimal.NewFromFloat(310)) {
+			t.Errorf("got %v want %v", account.Value(), 310)
+		}
+	})
+}
+
+func TestPricelist_Convert(t *testing.T) {
+	pricelist := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	}
+
+	t.Run("prices are re-expressed in the new quote", func(t *testing.T) {
+		got, err := pricelist.Convert("BTC", pricelist["BTC"])
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got["ETH"].Equal(decimal.NewFromFloat(0.04)) || !got["BTC"].Equal(decimal.NewFromFloat(1)) {
+			t.Errorf("got %v want ETH at 0.04 and BTC at 1", got)
+		}
+	})
+	t.Run("the rate must be positive", func(t *testing.T) {
+		_, err := pricelist.Convert("EUR", decimal.Zero)
+
+		want := ErrInvalidAssetAmount{Asset: "EUR", Amount: decimal.Zero}
+		if err != want {
+			t.Errorf("got %v, want %s", err, want)
+		}
+	})
+}
+
+func TestPricelist_CrossRate(t *testing.T) {
+	pricelist := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	}
+
+	t.Run("the cross rate is the ratio of the prices", func(t *testing.T) {
+		got, err := pricelist.CrossRate("BTC", "ETH")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Equal(decimal.NewFromFloat(25)) {
+			t.Errorf("got %v want %v", got, 25)
+		}
+	})
+	t.Run("both assets must be priced", func(t *testing.T) {
+		_, err := pricelist.CrossRate("BTC", "XLM")
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v, want %s", err, ErrAssetMissingFromPricelist)
+		}
+	})
+}
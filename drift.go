@@ -0,0 +1,108 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// weight returns the fraction of the account's value held in asset.
+func (a Account) weight(asset Asset) decimal.Decimal {
+	if !a.value.IsPositive() {
+		return decimal.Zero
+	}
+	return a.portfolio[asset].Mul(a.pricelist[asset]).Div(a.value)
+}
+
+// A Drift describes how far an asset's current weight has moved from its
+// target weight.
+type Drift struct {
+	Current decimal.Decimal
+	Target  decimal.Decimal
+	// Absolute is Current less Target, negative when the asset is
+	// underweight.
+	Absolute decimal.Decimal
+	// Relative is Absolute as a fraction of Target, or zero when the target
+	// is zero.
+	Relative decimal.Decimal
+}
+
+// Drift returns the drift of every asset in targetIndex or the account's
+// portfolio from its target weight, so callers can decide whether a rebalance
+// is warranted before generating trades.
+func (a Account) Drift(targetIndex map[Asset]decimal.Decimal) map[Asset]Drift {
+	drifts := map[Asset]Drift{}
+	add := func(asset Asset) {
+		drifts[asset] = newDrift(a.weight(asset), targetIndex[asset])
+	}
+	for asset := range targetIndex {
+		add(asset)
+	}
+	for asset := range a.portfolio {
+		add(asset)
+	}
+	return drifts
+}
+
+// newDrift returns the drift of an asset at weight current from target.
+func newDrift(current, target decimal.Decimal) Drift {
+	drift := Drift{Current: current, Target: target, Absolute: current.Sub(target), Relative: decimal.Zero}
+	if !target.IsZero() {
+		drift.Relative = drift.Absolute.Div(target)
+	}
+	return drift
+}
+
+// TotalDrift returns the fraction of an account's value which would have to
+// be traded to remove drifts: half the sum of their absolute drifts.
+func TotalDrift(drifts map[Asset]Drift) decimal.Decimal {
+	total := decimal.Zero
+	for _, drift := range drifts {
+		total = total.Add(drift.Absolute.Abs())
+	}
+	return total.Div(decimal.New(2, 0))
+}
+
+// outsideBands reports whether an asset with the given current and target
+// weights has drifted further than the absolute or relative band allows. A
+// zero band is disabled.
+func outsideBands(current, target, absolute, relative decimal.Decimal) bool {
+	drift := current.Sub(target).Abs()
+	if absolute.IsPositive() && drift.GreaterThan(absolute) {
+		return true
+	}
+	if !relative.IsPositive() || drift.IsZero() {
+		return false
+	}
+	return target.IsZero() || drift.Div(target).GreaterThan(relative)
+}
+
+// withinDriftBands reports for every asset in the target index or the
+// portfolio whether it lies within the configured drift bands. When only a
+// single asset has drifted there is nothing to trade it against, so every
+// asset is reported as outside its band and the account is fully rebalanced.
+func (a Account) withinDriftBands(targetIndex Index, config rebalanceConfig) map[Asset]bool {
+	inBand := map[Asset]bool{}
+	violators := 0
+	check := func(asset Asset) {
+		if _, seen := inBand[asset]; seen {
+			return
+		}
+		ok := !outsideBands(a.weight(asset), targetIndex[asset], config.absoluteBand, config.relativeBand)
+		if !ok {
+			violators++
+		}
+		inBand[asset] = ok
+	}
+	for asset := range targetIndex {
+		check(asset)
+	}
+	for asset := range a.portfolio {
+		check(asset)
+	}
+
+	if violators == 1 {
+		for asset := range inBand {
+			inBand[asset] = false
+		}
+	}
+	return inBand
+}
@@ -0,0 +1,53 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestAccount_Drift(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(60),
+		"BTC": decimal.NewFromFloat(30),
+		"XMR": decimal.NewFromFloat(10),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XMR": decimal.NewFromFloat(1),
+		"XLM": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	got := account.Drift(Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.4),
+		"XLM": decimal.NewFromFloat(0.1),
+	})
+
+	t.Run("each asset's drift is reported", func(t *testing.T) {
+		want := map[Asset]Drift{
+			"ETH": {Absolute: decimal.NewFromFloat(0.1), Relative: decimal.NewFromFloat(0.2)},
+			"BTC": {Absolute: decimal.NewFromFloat(-0.1), Relative: decimal.NewFromFloat(-0.25)},
+			"XLM": {Absolute: decimal.NewFromFloat(-0.1), Relative: decimal.NewFromFloat(-1)},
+			"XMR": {Absolute: decimal.NewFromFloat(0.1), Relative: decimal.Zero},
+		}
+
+		if len(got) != len(want) {
+			t.Errorf("got %d drifts want %d", len(got), len(want))
+		}
+		for asset, drift := range want {
+			if !got[asset].Absolute.Equal(drift.Absolute) || !got[asset].Relative.Equal(drift.Relative) {
+				t.Errorf("got %v want %v for asset %s", got[asset], drift, asset)
+			}
+		}
+	})
+	t.Run("the total drift is the value which must be traded", func(t *testing.T) {
+		if total := TotalDrift(got); !total.Equal(decimal.NewFromFloat(0.2)) {
+			t.Errorf("got %v want %v", total, 0.2)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+	"time"
+)
+
+// An Event is published to the subscribers of Events. It is one of
+// PricelistUpdated, PlanGenerated, TradeExecuted or DriftThresholdBreached.
+type Event interface {
+	event()
+}
+
+// PricelistUpdated is published to GlobalEvents when the global pricelist is
+// set or cleared.
+type PricelistUpdated struct {
+	Time      time.Time
+	Pricelist Pricelist
+}
+
+// PlanGenerated is published when Rebalance returns a plan, see WithEvents.
+type PlanGenerated struct {
+	Time time.Time
+	Plan RebalancePlan
+}
+
+// TradeExecuted is published when ExecutePlan places an order, see
+// PublishTrades.
+type TradeExecuted struct {
+	Time  time.Time
+	Order Order
+	Fill  Fill
+}
+
+// DriftThresholdBreached is published by PublishDrift when the drift of an
+// asset exceeds the threshold, along with the drift of every asset.
+type DriftThresholdBreached struct {
+	Time      time.Time
+	Threshold decimal.Decimal
+	Drift     map[Asset]Drift
+}
+
+func (PricelistUpdated) event()       {}
+func (PlanGenerated) event()          {}
+func (TradeExecuted) event()          {}
+func (DriftThresholdBreached) event() {}
+
+// Events delivers published events to its subscribers, so applications can
+// react to them without polling. The zero value has no subscribers and is
+// ready to use.
+type Events struct {
+	mu       sync.RWMutex
+	handlers map[int]func(Event)
+	next     int
+}
+
+// GlobalEvents receives PricelistUpdated events when the global pricelist
+// changes.
+var GlobalEvents = &Events{}
+
+// Subscribe calls handler with every event published from now on, until the
+// returned function is called. Handlers are called in the order they
+// subscribed, on the goroutine publishing the event, so they should return
+// quickly.
+func (e *Events) Subscribe(handler func(Event)) (unsubscribe func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.handlers == nil {
+		e.handlers = map[int]func(Event){}
+	}
+	id := e.next
+	e.next++
+	e.handlers[id] = handler
+	return func() {
+		e.mu.Lock()
+		delete(e.handlers, id)
+		e.mu.Unlock()
+	}
+}
+
+// Publish calls the subscribers of e with event.
+func (e *Events) Publish(event Event) {
+	if e == nil {
+		return
+	}
+	e.mu.RLock()
+	ids := make([]int, 0, len(e.handlers))
+	for id := range e.handlers {
+		ids = append(ids, id)
+	}
+	handlers := make([]func(Event), 0, len(ids))
+	sort.Ints(ids)
+	for _, id := range ids {
+		handlers = append(handlers, e.handlers[id])
+	}
+	e.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// WithEvents publishes a PlanGenerated event to events for every plan
+// Rebalance returns.
+func WithEvents(events *Events) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.events = events
+	}
+}
+
+// PublishTrades publishes a TradeExecuted event to events for every order
+// ExecutePlan places.
+func PublishTrades(events *Events) ExecutionOption {
+	return func(c *executionConfig) {
+		c.events = events
+	}
+}
+
+// PublishDrift publishes a DriftThresholdBreached event to events when the
+// absolute drift of any asset of the account from targetIndex exceeds
+// threshold, reporting whether it did.
+func (a Account) PublishDrift(events *Events, targetIndex map[Asset]decimal.Decimal, threshold decimal.Decimal) bool {
+	drifts := a.Drift(targetIndex)
+	for _, drift := range drifts {
+		if drift.Absolute.Abs().GreaterThan(threshold) {
+			events.Publish(DriftThresholdBreached{Time: time.Now(), Threshold: threshold, Drift: drifts})
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	t.Run("subscribers receive events until they unsubscribe", func(t *testing.T) {
+		events := &Events{}
+		var got []Event
+		unsubscribe := events.Subscribe(func(e Event) { got = append(got, e) })
+
+		events.Publish(PlanGenerated{})
+		unsubscribe()
+		events.Publish(PlanGenerated{})
+
+		if len(got) != 1 {
+			t.Errorf("got %d events want 1", len(got))
+		}
+	})
+
+	t.Run("setting the global pricelist publishes PricelistUpdated", func(t *testing.T) {
+		var got []Event
+		unsubscribe := GlobalEvents.Subscribe(func(e Event) { got = append(got, e) })
+		defer unsubscribe()
+		defer ClearGlobalPricelist()
+
+		err := SetPricelist(map[Asset]decimal.Decimal{"ETH": decimal.NewFromFloat(200)})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		updated, ok := got[0].(PricelistUpdated)
+		if len(got) != 1 || !ok || !updated.Pricelist["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want PricelistUpdated with ETH at 200", got)
+		}
+	})
+
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("rebalancing publishes PlanGenerated", func(t *testing.T) {
+		events := &Events{}
+		var got []Event
+		events.Subscribe(func(e Event) { got = append(got, e) })
+
+		plan, err := account.Rebalance(index, WithEvents(events))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		generated, ok := got[0].(PlanGenerated)
+		if len(got) != 1 || !ok || !generated.Plan.Turnover.Equal(plan.Turnover) {
+			t.Errorf("got %v want PlanGenerated with the plan", got)
+		}
+	})
+
+	t.Run("executing a plan publishes TradeExecuted for each order", func(t *testing.T) {
+		events := &Events{}
+		var got []Event
+		events.Subscribe(func(e Event) { got = append(got, e) })
+		plan, _ := account.Rebalance(index)
+
+		_, err := ExecutePlan(context.Background(), &fakeExecutor{sellFill: decimal.NewFromFloat(1)}, plan.Trades(), PublishTrades(events))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got %d events want 2", len(got))
+		}
+		executed, ok := got[0].(TradeExecuted)
+		if !ok || executed.Order.Asset != "ETH" || executed.Fill.OrderID != "order-1" {
+			t.Errorf("got %v want TradeExecuted for the ETH sell", got[0])
+		}
+	})
+
+	t.Run("drift beyond the threshold publishes DriftThresholdBreached", func(t *testing.T) {
+		events := &Events{}
+		var got []Event
+		events.Subscribe(func(e Event) { got = append(got, e) })
+
+		breached := account.PublishDrift(events, Index{
+			"ETH": decimal.NewFromFloat(0.2),
+			"BTC": decimal.NewFromFloat(0.8),
+		}, decimal.NewFromFloat(0.1))
+
+		if !breached || len(got) != 1 {
+			t.Errorf("got %v and %d events want a breach and 1 event", breached, len(got))
+		}
+		if _, ok := got[0].(DriftThresholdBreached); !ok {
+			t.Errorf("got %T want DriftThresholdBreached", got[0])
+		}
+	})
+
+	t.Run("drift within the threshold publishes nothing", func(t *testing.T) {
+		events := &Events{}
+		var got []Event
+		events.Subscribe(func(e Event) { got = append(got, e) })
+
+		if account.PublishDrift(events, index, decimal.NewFromFloat(0.5)) || len(got) != 0 {
+			t.Errorf("got %d events want none", len(got))
+		}
+	})
+}
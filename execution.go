@@ -0,0 +1,330 @@
+package rebalancer
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"net"
+	"sort"
+	"time"
+)
+
+// An Order is a single trade submitted to an Executor.
+type Order struct {
+	Asset Asset
+	Trade Trade
+}
+
+// A Fill reports how much of a submitted order has been executed so far.
+type Fill struct {
+	OrderID  string
+	Asset    Asset
+	Action   TradeAction
+	Ordered  decimal.Decimal
+	Quantity decimal.Decimal
+	// Price is the average price of the filled quantity, when the executor
+	// reports it.
+	Price decimal.Decimal
+	// Done is set once no further fills are expected for the order, either
+	// because it filled completely or because it was cancelled or rejected.
+	Done bool
+}
+
+// Ratio returns the fraction of the ordered quantity which has been filled.
+func (f Fill) Ratio() decimal.Decimal {
+	if f.Ordered.IsZero() {
+		return decimal.New(1, 0)
+	}
+	return f.Quantity.Div(f.Ordered)
+}
+
+// An Executor places orders on a trading venue and reports on their fills.
+type Executor interface {
+	PlaceOrder(ctx context.Context, order Order) (string, error)
+	OrderStatus(ctx context.Context, orderID string) (Fill, error)
+	CancelOrder(ctx context.Context, orderID string) error
+}
+
+// ErrOrderNotFound is returned by an OrderFinder which has no order for a
+// trade.
+var ErrOrderNotFound = errors.New("order not found")
+
+// An OrderFinder is an Executor which can look up the order it placed for a
+// trade by the trade's ID, which it sends to the venue as the client order ID.
+// FindOrder returns the ID of that order, or ErrOrderNotFound if there is
+// none.
+type OrderFinder interface {
+	FindOrder(ctx context.Context, order Order) (string, error)
+}
+
+// ErrFundingTimeout indicates that the sell orders funding a plan did not fill
+// before the gating timeout and the buy orders were withheld.
+var ErrFundingTimeout = errors.New("funding sells did not fill before timeout")
+
+// A GateFallback decides what happens to the buy orders of a gated execution
+// when the funding sells have not filled to the threshold in time.
+type GateFallback int
+
+const (
+	// CancelBuys withholds every buy order and returns ErrFundingTimeout.
+	CancelBuys GateFallback = iota
+	// ScaleBuys releases the buy orders scaled down to the fraction of the
+	// sells which did fill, so the buys never spend more than was raised.
+	ScaleBuys
+	// ReleaseBuys releases the buy orders in full regardless of the sells.
+	ReleaseBuys
+)
+
+type executionConfig struct {
+	gated        bool
+	threshold    decimal.Decimal
+	timeout      time.Duration
+	fallback     GateFallback
+	pollInterval time.Duration
+	report       *ExecutionReport
+	retries      int
+	backoff      time.Duration
+	events       *Events
+}
+
+// An ExecutionOption configures how ExecutePlan submits a plan's trades.
+type ExecutionOption func(*executionConfig)
+
+// GateBuysOnSells withholds buy orders until every sell order has filled at
+// least threshold of its quantity, a value between 0 and 1. Once released the
+// buys are scaled to the filled fraction of the sells, preventing the account
+// from spending cash it has not yet raised. If the threshold is not reached
+// within timeout, fallback decides what happens to the buys.
+func GateBuysOnSells(threshold decimal.Decimal, timeout time.Duration, fallback GateFallback) ExecutionOption {
+	return func(c *executionConfig) {
+		c.gated = true
+		c.threshold = threshold
+		c.timeout = timeout
+		c.fallback = fallback
+	}
+}
+
+// PollInterval sets how often order status is requested while waiting on
+// fills. It defaults to one second, which intervals that are not positive
+// leave in place.
+func PollInterval(interval time.Duration) ExecutionOption {
+	return func(c *executionConfig) {
+		if interval > 0 {
+			c.pollInterval = interval
+		}
+	}
+}
+
+// RetryOrders resubmits an order up to retries times, waiting backoff between
+// attempts, when placing it fails with a network error such as a timeout, even
+// one wrapped by another error. The order may have been accepted before the
+// connection dropped, so it is first looked up by its trade's ID and only
+// resubmitted if the venue has no such order. Only orders for trades with an
+// ID are retried, and only with executors which are OrderFinders. A
+// resubmitted order rejected as a duplicate is looked up again, in case the
+// venue accepted the first one after the lookup. Orders placed by an earlier
+// run are only found if it executed the same saved plan; see Trade.ID.
+func RetryOrders(retries int, backoff time.Duration) ExecutionOption {
+	return func(c *executionConfig) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// ExecutePlan submits trades to executor and returns the last known fill of
+// every order placed. Sells are always placed before buys. By default all
+// orders are placed at once; see GateBuysOnSells for conditional execution and
+// WithExecutionReport for tracking the orders placed.
+func ExecutePlan(ctx context.Context, executor Executor, trades map[Asset]Trade, opts ...ExecutionOption) ([]Fill, error) {
+	config := executionConfig{pollInterval: time.Second}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	sells, buys := splitOrders(trades)
+
+	if !config.gated || len(sells) == 0 {
+		return placeOrders(ctx, executor, append(sells, buys...), config)
+	}
+
+	sellFills, err := placeOrders(ctx, executor, sells, config)
+	if err != nil {
+		return sellFills, err
+	}
+
+	filled, err := awaitFills(ctx, executor, sellFills, config)
+	if err != nil {
+		return sellFills, err
+	}
+
+	ratio := minFillRatio(sellFills)
+	if !filled {
+		switch config.fallback {
+		case ReleaseBuys:
+			ratio = decimal.New(1, 0)
+		case CancelBuys:
+			return sellFills, ErrFundingTimeout
+		}
+	}
+	if ratio.IsZero() {
+		return sellFills, nil
+	}
+
+	scaled := make([]Order, len(buys))
+	for i, buy := range buys {
+		buy.Trade.Amount = buy.Trade.Amount.Mul(ratio)
+		scaled[i] = buy
+	}
+	buyFills, err := placeOrders(ctx, executor, scaled, config)
+	return append(sellFills, buyFills...), err
+}
+
+// splitOrders separates trades into sell and buy orders, each ordered by
+// asset so that execution is deterministic.
+func splitOrders(trades map[Asset]Trade) (sells, buys []Order) {
+	for asset, trade := range trades {
+		if trade.Amount.IsZero() {
+			continue
+		}
+		if trade.Action == Sell {
+			sells = append(sells, Order{Asset: asset, Trade: trade})
+			continue
+		}
+		buys = append(buys, Order{Asset: asset, Trade: trade})
+	}
+	sortOrders(sells)
+	sortOrders(buys)
+	return sells, buys
+}
+
+func sortOrders(orders []Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Asset < orders[j].Asset
+	})
+}
+
+func placeOrders(ctx context.Context, executor Executor, orders []Order, config executionConfig) ([]Fill, error) {
+	fills := make([]Fill, 0, len(orders))
+	for _, order := range orders {
+		id, err := placeOrder(ctx, executor, order, config)
+		if err != nil {
+			if config.report != nil {
+				config.report.Rejected(order, err)
+			}
+			return fills, err
+		}
+		if config.report != nil {
+			config.report.Submitted(order, id)
+		}
+		fill := Fill{
+			OrderID: id,
+			Asset:   order.Asset,
+			Action:  order.Trade.Action,
+			Ordered: order.Trade.Amount,
+		}
+		config.events.Publish(TradeExecuted{Time: time.Now(), Order: order, Fill: fill})
+		fills = append(fills, fill)
+	}
+	return fills, nil
+}
+
+// placeOrder places order with executor, retrying network errors as
+// configured by RetryOrders.
+func placeOrder(ctx context.Context, executor Executor, order Order, config executionConfig) (string, error) {
+	finder, _ := executor.(OrderFinder)
+	for attempt := 0; ; attempt++ {
+		id, err := executor.PlaceOrder(ctx, order)
+		if err == nil || finder == nil || order.Trade.ID == "" {
+			return id, err
+		}
+		if !isNetError(err) {
+			if attempt > 0 {
+				if found, findErr := finder.FindOrder(ctx, order); findErr == nil {
+					return found, nil
+				}
+			}
+			return id, err
+		}
+		if attempt >= config.retries {
+			return id, err
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(config.backoff):
+		}
+		found, findErr := finder.FindOrder(ctx, order)
+		if findErr == nil {
+			return found, nil
+		}
+		if findErr != ErrOrderNotFound {
+			return "", err
+		}
+	}
+}
+
+// isNetError reports whether err, or any error it wraps, is a network error.
+func isNetError(err error) bool {
+	for err != nil {
+		if _, ok := err.(net.Error); ok {
+			return true
+		}
+		wrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = wrapper.Unwrap()
+	}
+	return false
+}
+
+// awaitFills polls the status of fills in place until each has filled to the
+// configured threshold, reporting false if the timeout elapses first or every
+// order is done without reaching it.
+func awaitFills(ctx context.Context, executor Executor, fills []Fill, config executionConfig) (bool, error) {
+	deadline := time.NewTimer(config.timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(config.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		done := true
+		for i, fill := range fills {
+			status, err := executor.OrderStatus(ctx, fill.OrderID)
+			if err != nil {
+				return false, err
+			}
+			fills[i].Quantity = status.Quantity
+			fills[i].Price = status.Price
+			fills[i].Done = status.Done
+			if config.report != nil {
+				config.report.Update(fills[i])
+			}
+			done = done && status.Done
+		}
+		if minFillRatio(fills).GreaterThanOrEqual(config.threshold) {
+			return true, nil
+		}
+		if done {
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-deadline.C:
+			return false, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func minFillRatio(fills []Fill) decimal.Decimal {
+	ratio := decimal.New(1, 0)
+	for _, fill := range fills {
+		if fill.Ratio().LessThan(ratio) {
+			ratio = fill.Ratio()
+		}
+	}
+	return ratio
+}
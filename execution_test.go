@@ -0,0 +1,244 @@
+package rebalancer_test
+
+import (
+	"context"
+	"fmt"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+// fakeExecutor fills sell orders to sellFill of their quantity and records
+// every order placed.
+type fakeExecutor struct {
+	sellFill decimal.Decimal
+	placed   []Order
+}
+
+func (f *fakeExecutor) PlaceOrder(ctx context.Context, order Order) (string, error) {
+	f.placed = append(f.placed, order)
+	return fmt.Sprintf("order-%d", len(f.placed)), nil
+}
+
+func (f *fakeExecutor) OrderStatus(ctx context.Context, orderID string) (Fill, error) {
+	var n int
+	if _, err := fmt.Sscanf(orderID, "order-%d", &n); err != nil {
+		return Fill{}, err
+	}
+	order := f.placed[n-1]
+	quantity := order.Trade.Amount
+	if order.Trade.Action == Sell {
+		quantity = quantity.Mul(f.sellFill)
+	}
+	return Fill{OrderID: orderID, Quantity: quantity, Done: true}, nil
+}
+
+func (f *fakeExecutor) CancelOrder(ctx context.Context, orderID string) error {
+	return nil
+}
+
+func TestExecutePlan(t *testing.T) {
+	trades := map[Asset]Trade{
+		"ETH": {Action: Sell, Amount: decimal.NewFromFloat(10)},
+		"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.4)},
+	}
+
+	t.Run("sells are placed before buys", func(t *testing.T) {
+		executor := &fakeExecutor{sellFill: decimal.NewFromFloat(1)}
+
+		fills, err := ExecutePlan(context.Background(), executor, trades)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(fills) != 2 {
+			t.Fatalf("got %d fills want 2", len(fills))
+		}
+		if executor.placed[0].Asset != "ETH" || executor.placed[1].Asset != "BTC" {
+			t.Errorf("got orders %v, want ETH sell before BTC buy", executor.placed)
+		}
+	})
+	t.Run("gated buys are released once sells fill", func(t *testing.T) {
+		executor := &fakeExecutor{sellFill: decimal.NewFromFloat(1)}
+
+		_, err := ExecutePlan(
+			context.Background(),
+			executor,
+			trades,
+			GateBuysOnSells(decimal.NewFromFloat(1), time.Second, CancelBuys),
+			PollInterval(time.Millisecond),
+		)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := decimal.NewFromFloat(0.4)
+		if len(executor.placed) != 2 || !executor.placed[1].Trade.Amount.Equal(want) {
+			t.Errorf("got orders %v, want a buy of %s BTC", executor.placed, want)
+		}
+	})
+	t.Run("a zero poll interval falls back to the default", func(t *testing.T) {
+		executor := &fakeExecutor{sellFill: decimal.NewFromFloat(1)}
+
+		_, err := ExecutePlan(
+			context.Background(),
+			executor,
+			trades,
+			GateBuysOnSells(decimal.NewFromFloat(1), time.Second, CancelBuys),
+			PollInterval(0),
+		)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+	t.Run("gated buys are withheld when sells don't fill", func(t *testing.T) {
+		executor := &fakeExecutor{sellFill: decimal.NewFromFloat(0.5)}
+
+		_, err := ExecutePlan(
+			context.Background(),
+			executor,
+			trades,
+			GateBuysOnSells(decimal.NewFromFloat(1), time.Millisecond, CancelBuys),
+			PollInterval(time.Millisecond),
+		)
+
+		if err != ErrFundingTimeout {
+			t.Errorf("got %v, want %s", err, ErrFundingTimeout)
+		}
+		if len(executor.placed) != 1 {
+			t.Errorf("got %d orders want only the sell", len(executor.placed))
+		}
+	})
+	t.Run("gated buys can be scaled to the filled sells", func(t *testing.T) {
+		executor := &fakeExecutor{sellFill: decimal.NewFromFloat(0.5)}
+
+		_, err := ExecutePlan(
+			context.Background(),
+			executor,
+			trades,
+			GateBuysOnSells(decimal.NewFromFloat(1), time.Millisecond, ScaleBuys),
+			PollInterval(time.Millisecond),
+		)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := decimal.NewFromFloat(0.2)
+		if len(executor.placed) != 2 || !executor.placed[1].Trade.Amount.Equal(want) {
+			t.Errorf("got orders %v, want a buy of %s BTC", executor.placed, want)
+		}
+	})
+}
+
+// timeoutError is a network error, as returned by an HTTP client whose
+// request timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// wrappedError wraps another error, as errors annotated on their way up do.
+type wrappedError struct{ err error }
+
+func (w wrappedError) Error() string { return "placing order: " + w.err.Error() }
+func (w wrappedError) Unwrap() error { return w.err }
+
+// lossyExecutor places orders with a SimExecutor but fails the first lost
+// attempts with err: after placing the order when accepted is set, as if the
+// connection dropped before the response arrived, and before placing it
+// otherwise.
+type lossyExecutor struct {
+	*SimExecutor
+	lost     int
+	accepted bool
+	err      error
+	attempts int
+}
+
+func (l *lossyExecutor) PlaceOrder(ctx context.Context, order Order) (string, error) {
+	l.attempts++
+	if l.attempts <= l.lost && !l.accepted {
+		return "", l.err
+	}
+	id, err := l.SimExecutor.PlaceOrder(ctx, order)
+	if err == nil && l.attempts <= l.lost {
+		return "", l.err
+	}
+	return id, err
+}
+
+// blindExecutor is an Executor which cannot look up orders.
+type blindExecutor struct {
+	Executor
+}
+
+func TestExecutePlan_RetryOrders(t *testing.T) {
+	account := newSimAccount(t)
+	plan, err := account.Rebalance(map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("orders accepted before the connection dropped are found instead of placed again", func(t *testing.T) {
+		sim := NewSimExecutor(account)
+		executor := &lossyExecutor{SimExecutor: sim, lost: 2, accepted: true, err: timeoutError{}}
+
+		fills, err := ExecutePlan(context.Background(), executor, plan.Trades(), RetryOrders(2, time.Millisecond))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(fills) != 2 || executor.attempts != 2 {
+			t.Errorf("got %d fills after %d attempts want 2 after 2", len(fills), executor.attempts)
+		}
+		holdings := sim.Holdings()
+		if !holdings["BTC"].Equal(decimal.NewFromFloat(0.7)) || !holdings["ETH"].Equal(decimal.NewFromFloat(17.5)) {
+			t.Errorf("got %v want 0.7 BTC and 17.5 ETH", holdings)
+		}
+	})
+
+	t.Run("orders which never arrived are resubmitted", func(t *testing.T) {
+		sim := NewSimExecutor(account)
+		executor := &lossyExecutor{SimExecutor: sim, lost: 2, err: wrappedError{timeoutError{}}}
+
+		fills, err := ExecutePlan(context.Background(), executor, plan.Trades(), RetryOrders(2, time.Millisecond))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(fills) != 2 || executor.attempts != 4 {
+			t.Errorf("got %d fills after %d attempts want 2 after 4", len(fills), executor.attempts)
+		}
+		if holdings := sim.Holdings(); !holdings["BTC"].Equal(decimal.NewFromFloat(0.7)) {
+			t.Errorf("got %v want 0.7 BTC", holdings)
+		}
+	})
+
+	t.Run("orders are not retried beyond the limit", func(t *testing.T) {
+		executor := &lossyExecutor{SimExecutor: NewSimExecutor(account), lost: 2, err: timeoutError{}}
+
+		_, err := ExecutePlan(context.Background(), executor, plan.Trades(), RetryOrders(1, time.Millisecond))
+
+		if err != (timeoutError{}) {
+			t.Errorf("got %v want %v", err, timeoutError{})
+		}
+	})
+
+	t.Run("orders are not retried by executors which cannot find them", func(t *testing.T) {
+		lossy := &lossyExecutor{SimExecutor: NewSimExecutor(account), lost: 1, err: timeoutError{}}
+
+		_, err := ExecutePlan(context.Background(), blindExecutor{lossy}, plan.Trades(), RetryOrders(2, time.Millisecond))
+
+		if err != (timeoutError{}) || lossy.attempts != 1 {
+			t.Errorf("got %v after %d attempts want %v after 1", err, lossy.attempts, timeoutError{})
+		}
+	})
+}
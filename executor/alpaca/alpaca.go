@@ -0,0 +1,274 @@
+// Package alpaca provides a rebalancer.Executor which places orders for stocks
+// and ETFs with the Alpaca trading API.
+package alpaca
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// LiveURL is the address of the Alpaca trading API.
+	LiveURL = "https://api.alpaca.markets"
+	// PaperURL is the address of the Alpaca paper trading API, which trades
+	// with simulated funds.
+	PaperURL = "https://paper-api.alpaca.markets"
+)
+
+// ErrZeroQuantity is returned when an order for a symbol which cannot be
+// traded in fractions is for less than one share.
+var ErrZeroQuantity = errors.New("alpaca: order is for less than one share of a symbol which is not fractionable")
+
+// An APIError is an error reported by the Alpaca API.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error formats the error message for APIError.
+func (e APIError) Error() string {
+	return fmt.Sprintf("alpaca: %s (code %d)", e.Message, e.Code)
+}
+
+// A Client places orders with the Alpaca trading API. Assets are the symbols
+// being traded, such as SPY, and are bought and sold with the account's cash.
+type Client struct {
+	keyID      string
+	secret     string
+	baseURL    string
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	fractionable map[rebalancer.Asset]bool
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// Paper sends requests to the paper trading API.
+func Paper() Option {
+	return WithBaseURL(PaperURL)
+}
+
+// WithBaseURL sends requests to baseURL instead of LiveURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// New returns a Client which authenticates with keyID and secret.
+func New(keyID, secret string, opts ...Option) *Client {
+	c := &Client{
+		keyID:        keyID,
+		secret:       secret,
+		baseURL:      LiveURL,
+		httpClient:   http.DefaultClient,
+		fractionable: map[rebalancer.Asset]bool{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Fractionable reports whether asset can be traded in fractions of a share.
+// The answer is fetched once per asset and then cached.
+func (c *Client) Fractionable(ctx context.Context, asset rebalancer.Asset) (bool, error) {
+	c.mu.Lock()
+	fractionable, ok := c.fractionable[asset]
+	c.mu.Unlock()
+	if ok {
+		return fractionable, nil
+	}
+
+	var response struct {
+		Fractionable bool `json:"fractionable"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v2/assets/"+string(asset), nil, &response); err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.fractionable[asset] = response.Fractionable
+	c.mu.Unlock()
+	return response.Fractionable, nil
+}
+
+// ExchangeRules returns rules which round the amounts of assets that cannot be
+// traded in fractions to whole shares, for rebalancing with
+// rebalancer.WithExchangeRules.
+func (c *Client) ExchangeRules(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.ExchangeRules, error) {
+	rules := rebalancer.ExchangeRules{}
+	for _, asset := range assets {
+		fractionable, err := c.Fractionable(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		rules[asset] = rebalancer.AssetRules{Integral: !fractionable}
+	}
+	return rules, nil
+}
+
+type orderRequest struct {
+	ClientOrderID string           `json:"client_order_id,omitempty"`
+	Symbol        string           `json:"symbol"`
+	Qty           decimal.Decimal  `json:"qty"`
+	Side          string           `json:"side"`
+	Type          string           `json:"type"`
+	TimeInForce   string           `json:"time_in_force"`
+	LimitPrice    *decimal.Decimal `json:"limit_price,omitempty"`
+}
+
+type orderResponse struct {
+	ID        string          `json:"id"`
+	Symbol    string          `json:"symbol"`
+	Side      string          `json:"side"`
+	Status    string          `json:"status"`
+	Qty       decimal.Decimal `json:"qty"`
+	FilledQty decimal.Decimal `json:"filled_qty"`
+}
+
+// PlaceOrder places order as a day market order, or a day limit order if its
+// trade is a rebalancer.Limit order. Orders for symbols which are not
+// fractionable are rounded down to whole shares. The trade's ID is sent as the
+// client order ID, which FindOrder looks orders up by.
+func (c *Client) PlaceOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	fractionable, err := c.Fractionable(ctx, order.Asset)
+	if err != nil {
+		return "", err
+	}
+	quantity := order.Trade.Amount
+	if !fractionable {
+		quantity = quantity.Floor()
+		if quantity.IsZero() {
+			return "", ErrZeroQuantity
+		}
+	}
+
+	request := orderRequest{
+		ClientOrderID: order.Trade.ID,
+		Symbol:        string(order.Asset),
+		Qty:           quantity,
+		Side:          string(order.Trade.Action),
+		Type:          "market",
+		TimeInForce:   "day",
+	}
+	if order.Trade.OrderType == rebalancer.Limit {
+		price := order.Trade.LimitPrice.Round(2)
+		request.Type = "limit"
+		request.LimitPrice = &price
+	}
+
+	var response orderResponse
+	if err := c.do(ctx, http.MethodPost, "/v2/orders", request, &response); err != nil {
+		return "", err
+	}
+	return response.ID, nil
+}
+
+// FindOrder returns the ID of the order placed for the trade of order, looking
+// it up by the trade's ID, or rebalancer.ErrOrderNotFound.
+func (c *Client) FindOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	if order.Trade.ID == "" {
+		return "", rebalancer.ErrOrderNotFound
+	}
+	var response orderResponse
+	err := c.do(ctx, http.MethodGet, "/v2/orders:by_client_order_id?client_order_id="+url.QueryEscape(order.Trade.ID), nil, &response)
+	if apiErr, ok := err.(APIError); ok && (apiErr.Code == http.StatusNotFound || apiErr.Code == codeNotFound) {
+		return "", rebalancer.ErrOrderNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return response.ID, nil
+}
+
+// codeNotFound is the code of the APIError Alpaca returns for an order which
+// does not exist.
+const codeNotFound = 40410000
+
+// OrderStatus reports the fill of the order with orderID.
+func (c *Client) OrderStatus(ctx context.Context, orderID string) (rebalancer.Fill, error) {
+	var response orderResponse
+	if err := c.do(ctx, http.MethodGet, "/v2/orders/"+orderID, nil, &response); err != nil {
+		return rebalancer.Fill{}, err
+	}
+
+	done := false
+	switch response.Status {
+	case "filled", "canceled", "expired", "rejected", "done_for_day", "replaced", "stopped", "suspended":
+		done = true
+	}
+	return rebalancer.Fill{
+		OrderID:  orderID,
+		Asset:    rebalancer.Asset(response.Symbol),
+		Action:   rebalancer.TradeAction(response.Side),
+		Ordered:  response.Qty,
+		Quantity: response.FilledQty,
+		Done:     done,
+	}, nil
+}
+
+// CancelOrder cancels the order with orderID.
+func (c *Client) CancelOrder(ctx context.Context, orderID string) error {
+	return c.do(ctx, http.MethodDelete, "/v2/orders/"+orderID, nil, nil)
+}
+
+// do sends a request with body encoded as JSON to path and decodes the JSON
+// response into result.
+func (c *Client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("APCA-API-KEY-ID", c.keyID)
+	req.Header.Set("APCA-API-SECRET-KEY", c.secret)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := APIError{Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		_ = json.Unmarshal(data, &apiErr)
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
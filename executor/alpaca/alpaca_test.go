@@ -0,0 +1,169 @@
+package alpaca_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/executor/alpaca"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// OrderFinder asserts that Client implements rebalancer.OrderFinder.
+var _ rebalancer.OrderFinder = &Client{}
+
+func TestClient(t *testing.T) {
+	var orders []map[string]interface{}
+	assetLookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("APCA-API-KEY-ID") != "key" || r.Header.Get("APCA-API-SECRET-KEY") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"code": 40310000, "message": "access key verification failed"}`)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v2/assets/SPY":
+			assetLookups++
+			fmt.Fprint(w, `{"symbol": "SPY", "fractionable": true}`)
+		case "GET /v2/assets/BRK.A":
+			fmt.Fprint(w, `{"symbol": "BRK.A", "fractionable": false}`)
+		case "POST /v2/orders":
+			var order map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&order)
+			orders = append(orders, order)
+			fmt.Fprint(w, `{"id": "order-1", "status": "accepted"}`)
+		case "GET /v2/orders:by_client_order_id":
+			if r.URL.Query().Get("client_order_id") != "0123456789abcdef" {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code": 40410000, "message": "order not found"}`)
+				return
+			}
+			fmt.Fprint(w, `{"id": "order-1", "status": "filled"}`)
+		case "GET /v2/orders/order-1":
+			fmt.Fprint(w, `{"id": "order-1", "symbol": "SPY", "side": "buy", "status": "filled", "qty": "1.5", "filled_qty": "1.5"}`)
+		case "DELETE /v2/orders/order-1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code": 40410000, "message": "not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := New("key", "secret", WithBaseURL(server.URL))
+
+	t.Run("fractional orders are placed for fractionable symbols", func(t *testing.T) {
+		id, err := client.PlaceOrder(ctx, rebalancer.Order{
+			Asset: "SPY",
+			Trade: rebalancer.Trade{Action: rebalancer.Buy, Amount: decimal.NewFromFloat(1.5)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if id != "order-1" {
+			t.Errorf("got %s want order-1", id)
+		}
+		order := orders[len(orders)-1]
+		if order["symbol"] != "SPY" || order["qty"] != "1.5" || order["side"] != "buy" || order["type"] != "market" {
+			t.Errorf("got %v want a market buy of 1.5 SPY", order)
+		}
+	})
+
+	t.Run("fractionability is looked up once per symbol", func(t *testing.T) {
+		client.PlaceOrder(ctx, rebalancer.Order{
+			Asset: "SPY",
+			Trade: rebalancer.Trade{Action: rebalancer.Sell, Amount: decimal.NewFromFloat(1)},
+		})
+
+		if assetLookups != 1 {
+			t.Errorf("got %d lookups want 1", assetLookups)
+		}
+	})
+
+	t.Run("orders for other symbols are rounded down to whole shares", func(t *testing.T) {
+		_, err := client.PlaceOrder(ctx, rebalancer.Order{
+			Asset: "BRK.A",
+			Trade: rebalancer.Trade{Action: rebalancer.Sell, Amount: decimal.NewFromFloat(2.7), OrderType: rebalancer.Limit, LimitPrice: decimal.NewFromFloat(600000.123)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		order := orders[len(orders)-1]
+		if order["qty"] != "2" || order["type"] != "limit" || order["limit_price"] != "600000.12" {
+			t.Errorf("got %v want a limit sell of 2 BRK.A at 600000.12", order)
+		}
+	})
+
+	t.Run("orders for less than a whole share of other symbols are rejected", func(t *testing.T) {
+		_, err := client.PlaceOrder(ctx, rebalancer.Order{
+			Asset: "BRK.A",
+			Trade: rebalancer.Trade{Action: rebalancer.Buy, Amount: decimal.NewFromFloat(0.5)},
+		})
+
+		if err != ErrZeroQuantity {
+			t.Errorf("got %v want %v", err, ErrZeroQuantity)
+		}
+	})
+
+	t.Run("exchange rules round symbols which are not fractionable", func(t *testing.T) {
+		rules, err := client.ExchangeRules(ctx, "SPY", "BRK.A")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if rules["SPY"].Integral || !rules["BRK.A"].Integral {
+			t.Errorf("got %v want only BRK.A integral", rules)
+		}
+	})
+
+	t.Run("order status is reported as a fill", func(t *testing.T) {
+		fill, err := client.OrderStatus(ctx, "order-1")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if fill.Asset != "SPY" || fill.Action != rebalancer.Buy || !fill.Done || !fill.Quantity.Equal(decimal.NewFromFloat(1.5)) {
+			t.Errorf("got %v want a filled buy of 1.5 SPY", fill)
+		}
+	})
+
+	t.Run("orders are found by the trade's ID", func(t *testing.T) {
+		id, err := client.FindOrder(ctx, rebalancer.Order{Asset: "SPY", Trade: rebalancer.Trade{ID: "0123456789abcdef"}})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if id != "order-1" {
+			t.Errorf("got %s want order-1", id)
+		}
+
+		_, err = client.FindOrder(ctx, rebalancer.Order{Asset: "SPY", Trade: rebalancer.Trade{ID: "missing"}})
+
+		if err != rebalancer.ErrOrderNotFound {
+			t.Errorf("got %v want %v", err, rebalancer.ErrOrderNotFound)
+		}
+	})
+
+	t.Run("orders can be cancelled", func(t *testing.T) {
+		if err := client.CancelOrder(ctx, "order-1"); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("API errors are returned as APIError", func(t *testing.T) {
+		client := New("key", "wrong", WithBaseURL(server.URL))
+
+		err := client.CancelOrder(ctx, "order-1")
+
+		want := APIError{Code: 40310000, Message: "access key verification failed"}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+}
@@ -0,0 +1,363 @@
+// Package binance provides a rebalancer.Executor which places orders on the
+// Binance spot exchange.
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// BaseURL is the address of the Binance spot API.
+	BaseURL = "https://api.binance.com"
+	// TestnetURL is the address of the Binance spot test network, which
+	// trades with test funds.
+	TestnetURL = "https://testnet.binance.vision"
+)
+
+// ErrOrderTooSmall indicates an order was not placed because, once its
+// quantity is rounded down to the step size, it is below the minimum quantity
+// or value Binance accepts for the symbol.
+var ErrOrderTooSmall = errors.New("binance: order is below the symbol's minimum quantity or value")
+
+// An APIError is an error reported by the Binance API.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+// Error formats the error message for APIError.
+func (e APIError) Error() string {
+	return fmt.Sprintf("binance: %s (code %d)", e.Message, e.Code)
+}
+
+// A Client places orders with the Binance spot API. Every asset is traded
+// against a single quote asset, such as USDT.
+type Client struct {
+	apiKey     string
+	secret     string
+	baseURL    string
+	quote      rebalancer.Asset
+	httpClient *http.Client
+	symbols    map[rebalancer.Asset]string
+	rules      rebalancer.ExchangeRules
+	notionals  map[rebalancer.Asset]decimal.Decimal
+	now        func() time.Time
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// Testnet sends requests to the Binance spot test network.
+func Testnet() Option {
+	return WithBaseURL(TestnetURL)
+}
+
+// WithBaseURL sends requests to baseURL instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithSymbol trades asset on symbol instead of the asset followed by the
+// quote asset, for assets Binance lists under another name.
+func WithSymbol(asset rebalancer.Asset, symbol string) Option {
+	return func(c *Client) {
+		c.symbols[asset] = symbol
+	}
+}
+
+// New returns a Client which signs its requests with apiKey and secret, and
+// trades every asset against quote.
+func New(apiKey, secret string, quote rebalancer.Asset, opts ...Option) *Client {
+	c := &Client{
+		apiKey:     apiKey,
+		secret:     secret,
+		baseURL:    BaseURL,
+		quote:      quote,
+		httpClient: http.DefaultClient,
+		symbols:    map[rebalancer.Asset]string{},
+		rules:      rebalancer.ExchangeRules{},
+		notionals:  map[rebalancer.Asset]decimal.Decimal{},
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Symbol returns the symbol asset is traded on.
+func (c *Client) Symbol(asset rebalancer.Asset) string {
+	if symbol, ok := c.symbols[asset]; ok {
+		return symbol
+	}
+	return string(asset) + string(c.quote)
+}
+
+// asset returns the asset traded on symbol.
+func (c *Client) asset(symbol string) rebalancer.Asset {
+	for asset, s := range c.symbols {
+		if s == symbol {
+			return asset
+		}
+	}
+	return rebalancer.Asset(strings.TrimSuffix(symbol, string(c.quote)))
+}
+
+type exchangeInfo struct {
+	Symbols []struct {
+		Symbol     string `json:"symbol"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+		Filters    []struct {
+			FilterType  string          `json:"filterType"`
+			TickSize    decimal.Decimal `json:"tickSize"`
+			StepSize    decimal.Decimal `json:"stepSize"`
+			MinQty      decimal.Decimal `json:"minQty"`
+			MinNotional decimal.Decimal `json:"minNotional"`
+		} `json:"filters"`
+	} `json:"symbols"`
+}
+
+// LoadExchangeInfo fetches the price, quantity and notional filters of every
+// symbol traded against the quote asset, which are then applied to orders and
+// reported by ExchangeRules.
+func (c *Client) LoadExchangeInfo(ctx context.Context) error {
+	var info exchangeInfo
+	if err := c.do(ctx, http.MethodGet, "/api/v3/exchangeInfo", nil, false, &info); err != nil {
+		return err
+	}
+	rules := rebalancer.ExchangeRules{}
+	notionals := map[rebalancer.Asset]decimal.Decimal{}
+	for _, symbol := range info.Symbols {
+		if rebalancer.Asset(symbol.QuoteAsset) != c.quote {
+			continue
+		}
+		asset := c.asset(symbol.Symbol)
+		var rule rebalancer.AssetRules
+		for _, filter := range symbol.Filters {
+			switch filter.FilterType {
+			case "PRICE_FILTER":
+				rule.TickSize = filter.TickSize
+			case "LOT_SIZE":
+				rule.StepSize = filter.StepSize
+				rule.MinQuantity = filter.MinQty
+			case "MIN_NOTIONAL", "NOTIONAL":
+				notionals[asset] = filter.MinNotional
+			}
+		}
+		rules[asset] = rule
+	}
+	c.rules = rules
+	c.notionals = notionals
+	return nil
+}
+
+// ExchangeRules returns the rules loaded by LoadExchangeInfo, for rebalancing
+// with rebalancer.WithExchangeRules.
+func (c *Client) ExchangeRules() rebalancer.ExchangeRules {
+	rules := rebalancer.ExchangeRules{}
+	for asset, rule := range c.rules {
+		rules[asset] = rule
+	}
+	return rules
+}
+
+type orderResponse struct {
+	Symbol      string          `json:"symbol"`
+	OrderID     int64           `json:"orderId"`
+	Side        string          `json:"side"`
+	Status      string          `json:"status"`
+	OrigQty     decimal.Decimal `json:"origQty"`
+	ExecutedQty decimal.Decimal `json:"executedQty"`
+}
+
+// PlaceOrder places order as a market order, or a good-til-cancelled limit
+// order if its trade is a rebalancer.Limit order. The quantity is rounded
+// down, and the limit price to the nearest tick, using the filters loaded by
+// LoadExchangeInfo. Orders which are then below the symbol's minimum
+// quantity, or worth less than its minimum notional value at the limit price
+// or, for market orders, the trade's price if it has one, are not placed and
+// fail with ErrOrderTooSmall. The trade's ID is sent as the client order ID,
+// which FindOrder looks orders up by. Binance only rejects a repeated client
+// order ID while the first order is open, so a filled order is not protected
+// from being placed again.
+func (c *Client) PlaceOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	rule := c.rules[order.Asset]
+	quantity := order.Trade.Amount
+	if rule.StepSize.IsPositive() {
+		quantity = quantity.Div(rule.StepSize).Floor().Mul(rule.StepSize)
+	}
+	price := order.Trade.Price
+	if order.Trade.OrderType == rebalancer.Limit {
+		price = rule.RoundPrice(order.Trade.LimitPrice)
+	}
+	if !quantity.IsPositive() || quantity.LessThan(rule.MinQuantity) || price.IsPositive() && quantity.Mul(price).LessThan(c.notionals[order.Asset]) {
+		return "", ErrOrderTooSmall
+	}
+
+	params := url.Values{}
+	params.Set("symbol", c.Symbol(order.Asset))
+	params.Set("side", strings.ToUpper(string(order.Trade.Action)))
+	params.Set("quantity", quantity.String())
+	params.Set("type", "MARKET")
+	if order.Trade.ID != "" {
+		params.Set("newClientOrderId", order.Trade.ID)
+	}
+	if order.Trade.OrderType == rebalancer.Limit {
+		params.Set("type", "LIMIT")
+		params.Set("timeInForce", "GTC")
+		params.Set("price", price.String())
+	}
+
+	var response orderResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v3/order", params, true, &response); err != nil {
+		return "", err
+	}
+	return orderID(response.Symbol, response.OrderID), nil
+}
+
+// FindOrder returns the ID of the order placed for the trade of order, looking
+// it up by the trade's ID, or rebalancer.ErrOrderNotFound.
+func (c *Client) FindOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	if order.Trade.ID == "" {
+		return "", rebalancer.ErrOrderNotFound
+	}
+	params := url.Values{}
+	params.Set("symbol", c.Symbol(order.Asset))
+	params.Set("origClientOrderId", order.Trade.ID)
+
+	var response orderResponse
+	err := c.do(ctx, http.MethodGet, "/api/v3/order", params, true, &response)
+	if apiErr, ok := err.(APIError); ok && apiErr.Code == codeNoSuchOrder {
+		return "", rebalancer.ErrOrderNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return orderID(response.Symbol, response.OrderID), nil
+}
+
+// codeNoSuchOrder is the code of the APIError Binance returns for an order
+// which does not exist.
+const codeNoSuchOrder = -2013
+
+// OrderStatus reports the fill of the order with orderID.
+func (c *Client) OrderStatus(ctx context.Context, orderID string) (rebalancer.Fill, error) {
+	params, err := orderParams(orderID)
+	if err != nil {
+		return rebalancer.Fill{}, err
+	}
+
+	var response orderResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v3/order", params, true, &response); err != nil {
+		return rebalancer.Fill{}, err
+	}
+
+	done := true
+	switch response.Status {
+	case "NEW", "PARTIALLY_FILLED", "PENDING_NEW":
+		done = false
+	}
+	return rebalancer.Fill{
+		OrderID:  orderID,
+		Asset:    c.asset(response.Symbol),
+		Action:   rebalancer.TradeAction(strings.ToLower(response.Side)),
+		Ordered:  response.OrigQty,
+		Quantity: response.ExecutedQty,
+		Done:     done,
+	}, nil
+}
+
+// CancelOrder cancels the order with orderID.
+func (c *Client) CancelOrder(ctx context.Context, orderID string) error {
+	params, err := orderParams(orderID)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodDelete, "/api/v3/order", params, true, nil)
+}
+
+// orderID combines a symbol and Binance order ID, since Binance identifies
+// orders by both.
+func orderID(symbol string, id int64) string {
+	return symbol + ":" + strconv.FormatInt(id, 10)
+}
+
+// orderParams returns the request parameters identifying the order with id.
+func orderParams(id string) (url.Values, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("binance: invalid order ID %q", id)
+	}
+	params := url.Values{}
+	params.Set("symbol", parts[0])
+	params.Set("orderId", parts[1])
+	return params, nil
+}
+
+// do sends a request to path and decodes the JSON response into result.
+// Signed requests carry a timestamp and an HMAC-SHA256 signature of their
+// parameters.
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, signed bool, result interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	query := params.Encode()
+	if signed {
+		params.Set("timestamp", strconv.FormatInt(c.now().UnixNano()/int64(time.Millisecond), 10))
+		query = params.Encode()
+		mac := hmac.New(sha256.New, []byte(c.secret))
+		mac.Write([]byte(query))
+		query += "&signature=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-MBX-APIKEY", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := APIError{Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		_ = json.Unmarshal(body, &apiErr)
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
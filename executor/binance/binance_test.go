@@ -0,0 +1,209 @@
+package binance_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/executor/binance"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// OrderFinder asserts that Client implements rebalancer.OrderFinder.
+var _ rebalancer.OrderFinder = &Client{}
+
+const exchangeInfo = `{"symbols": [
+	{"symbol": "ETHUSDT", "baseAsset": "ETH", "quoteAsset": "USDT", "filters": [
+		{"filterType": "PRICE_FILTER", "tickSize": "0.01"},
+		{"filterType": "LOT_SIZE", "stepSize": "0.001", "minQty": "0.001"},
+		{"filterType": "NOTIONAL", "minNotional": "5"}
+	]},
+	{"symbol": "ETHBTC", "baseAsset": "ETH", "quoteAsset": "BTC", "filters": [
+		{"filterType": "LOT_SIZE", "stepSize": "1", "minQty": "1"}
+	]}
+]}`
+
+// verifySignature reports whether the request was signed with secret.
+func verifySignature(r *http.Request, secret string) bool {
+	query := r.URL.RawQuery
+	i := strings.LastIndex(query, "&signature=")
+	if i < 0 || r.Header.Get("X-MBX-APIKEY") != "key" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(query[:i]))
+	return hex.EncodeToString(mac.Sum(nil)) == query[i+len("&signature="):]
+}
+
+func TestClient(t *testing.T) {
+	var orders []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v3/exchangeInfo":
+			fmt.Fprint(w, exchangeInfo)
+		case !verifySignature(r, "secret"):
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code": -1022, "msg": "Signature for this request is not valid."}`)
+		case r.Method == http.MethodPost:
+			orders = append(orders, r.URL.Query())
+			fmt.Fprintf(w, `{"symbol": %q, "orderId": 42, "status": "NEW"}`, r.URL.Query().Get("symbol"))
+		case r.Method == http.MethodGet && r.URL.Query().Get("origClientOrderId") == "missing":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code": -2013, "msg": "Order does not exist."}`)
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"symbol": "ETHUSDT", "orderId": 42, "side": "SELL", "status": "PARTIALLY_FILLED", "origQty": "1.234", "executedQty": "0.5"}`)
+		case r.Method == http.MethodDelete:
+			fmt.Fprint(w, `{"symbol": "ETHUSDT", "orderId": 42, "status": "CANCELED"}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := New("key", "secret", "USDT", WithBaseURL(server.URL))
+	if err := client.LoadExchangeInfo(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("exchange rules are loaded for symbols of the quote asset", func(t *testing.T) {
+		rules := client.ExchangeRules()
+
+		if len(rules) != 1 {
+			t.Fatalf("got %d rules want 1", len(rules))
+		}
+		got, want := rules["ETH"], rebalancer.AssetRules{
+			StepSize:    decimal.NewFromFloat(0.001),
+			TickSize:    decimal.NewFromFloat(0.01),
+			MinQuantity: decimal.NewFromFloat(0.001),
+		}
+		if !got.StepSize.Equal(want.StepSize) || !got.TickSize.Equal(want.TickSize) || !got.MinQuantity.Equal(want.MinQuantity) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+
+	t.Run("market orders are placed with the quantity rounded down to the step size", func(t *testing.T) {
+		id, err := client.PlaceOrder(ctx, rebalancer.Order{
+			Asset: "ETH",
+			Trade: rebalancer.Trade{Action: rebalancer.Sell, Amount: decimal.NewFromFloat(1.23456), OrderType: rebalancer.Market, ID: "0123456789abcdef"},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if id != "ETHUSDT:42" {
+			t.Errorf("got %s want ETHUSDT:42", id)
+		}
+		order := orders[len(orders)-1]
+		if order.Get("symbol") != "ETHUSDT" || order.Get("side") != "SELL" || order.Get("type") != "MARKET" || order.Get("quantity") != "1.234" {
+			t.Errorf("got %v want a MARKET SELL of 1.234 ETHUSDT", order)
+		}
+		if order.Get("newClientOrderId") != "0123456789abcdef" {
+			t.Errorf("got client order ID %s want the trade's ID", order.Get("newClientOrderId"))
+		}
+	})
+
+	t.Run("limit orders are placed with the price rounded to the tick size", func(t *testing.T) {
+		_, err := client.PlaceOrder(ctx, rebalancer.Order{
+			Asset: "ETH",
+			Trade: rebalancer.Trade{Action: rebalancer.Buy, Amount: decimal.NewFromFloat(2), OrderType: rebalancer.Limit, LimitPrice: decimal.NewFromFloat(200.123)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		order := orders[len(orders)-1]
+		if order.Get("type") != "LIMIT" || order.Get("timeInForce") != "GTC" || order.Get("price") != "200.12" {
+			t.Errorf("got %v want a GTC LIMIT at 200.12", order)
+		}
+	})
+
+	t.Run("orders below the minimum quantity or value are not placed", func(t *testing.T) {
+		placed := len(orders)
+
+		for _, trade := range []rebalancer.Trade{
+			{Action: rebalancer.Sell, Amount: decimal.NewFromFloat(0.0009), OrderType: rebalancer.Market},
+			{Action: rebalancer.Sell, Amount: decimal.NewFromFloat(0.02), Price: decimal.NewFromFloat(200), OrderType: rebalancer.Market},
+			{Action: rebalancer.Buy, Amount: decimal.NewFromFloat(0.0249), OrderType: rebalancer.Limit, LimitPrice: decimal.NewFromFloat(200)},
+		} {
+			_, err := client.PlaceOrder(ctx, rebalancer.Order{Asset: "ETH", Trade: trade})
+
+			if err != ErrOrderTooSmall {
+				t.Errorf("got %v want %v for %v", err, ErrOrderTooSmall, trade)
+			}
+		}
+		if len(orders) != placed {
+			t.Errorf("got %v want no orders placed", orders[placed:])
+		}
+	})
+
+	t.Run("order status is reported as a fill", func(t *testing.T) {
+		fill, err := client.OrderStatus(ctx, "ETHUSDT:42")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if fill.Asset != "ETH" || fill.Action != rebalancer.Sell || fill.Done {
+			t.Errorf("got %v want an open ETH sell", fill)
+		}
+		if !fill.Quantity.Equal(decimal.NewFromFloat(0.5)) || !fill.Ordered.Equal(decimal.NewFromFloat(1.234)) {
+			t.Errorf("got %s of %s want 0.5 of 1.234", fill.Quantity, fill.Ordered)
+		}
+	})
+
+	t.Run("orders are found by the trade's ID", func(t *testing.T) {
+		id, err := client.FindOrder(ctx, rebalancer.Order{Asset: "ETH", Trade: rebalancer.Trade{ID: "0123456789abcdef"}})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if id != "ETHUSDT:42" {
+			t.Errorf("got %s want ETHUSDT:42", id)
+		}
+
+		_, err = client.FindOrder(ctx, rebalancer.Order{Asset: "ETH", Trade: rebalancer.Trade{ID: "missing"}})
+
+		if err != rebalancer.ErrOrderNotFound {
+			t.Errorf("got %v want %v", err, rebalancer.ErrOrderNotFound)
+		}
+	})
+
+	t.Run("orders can be cancelled", func(t *testing.T) {
+		if err := client.CancelOrder(ctx, "ETHUSDT:42"); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("invalid order IDs are rejected", func(t *testing.T) {
+		if err := client.CancelOrder(ctx, "42"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("API errors are returned as APIError", func(t *testing.T) {
+		client := New("key", "wrong", "USDT", WithBaseURL(server.URL))
+
+		err := client.CancelOrder(ctx, "ETHUSDT:42")
+
+		want := APIError{Code: -1022, Message: "Signature for this request is not valid."}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+}
+
+func TestClient_Symbol(t *testing.T) {
+	client := New("key", "secret", "USDT", WithSymbol("MIOTA", "IOTAUSDT"))
+
+	if got := client.Symbol("ETH"); got != "ETHUSDT" {
+		t.Errorf("got %s want ETHUSDT", got)
+	}
+	if got := client.Symbol("MIOTA"); got != "IOTAUSDT" {
+		t.Errorf("got %s want IOTAUSDT", got)
+	}
+}
@@ -0,0 +1,438 @@
+// Package coinbase provides a rebalancer.Executor which places orders with the
+// Coinbase Advanced Trade API. A plan is executed with
+//
+//	rebalancer.ExecutePlan(ctx, client, plan.Trades())
+//
+// Coinbase Developer Platform API keys, whose secret is a PEM encoded EC
+// private key, authenticate each request with a signed JWT; legacy API keys
+// sign them with an HMAC of the secret instead.
+package coinbase
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaseURL is the address of the Coinbase Advanced Trade API.
+const BaseURL = "https://api.coinbase.com"
+
+// ErrInvalidKey indicates the secret of a Coinbase Developer Platform API key
+// is not a PEM encoded EC private key.
+var ErrInvalidKey = errors.New("coinbase: secret is not a PEM encoded EC private key")
+
+// An APIError is an error reported by the Coinbase API.
+type APIError struct {
+	Code    string
+	Message string
+}
+
+// Error formats the error message for APIError.
+func (e APIError) Error() string {
+	return fmt.Sprintf("coinbase: %s (%s)", e.Message, e.Code)
+}
+
+// A Client places orders with the Coinbase Advanced Trade API. Every asset is
+// traded against a single quote currency, such as USD.
+type Client struct {
+	apiKey     string
+	secret     string
+	key        *ecdsa.PrivateKey
+	keyErr     error
+	baseURL    string
+	quote      rebalancer.Asset
+	httpClient *http.Client
+	products   map[rebalancer.Asset]string
+	now        func() time.Time
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sends requests to baseURL instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithProductID trades asset on productID instead of the asset and the quote
+// currency joined by a hyphen.
+func WithProductID(asset rebalancer.Asset, productID string) Option {
+	return func(c *Client) {
+		c.products[asset] = productID
+	}
+}
+
+// New returns a Client which signs its requests with apiKey and secret, and
+// trades every asset against quote. For a Coinbase Developer Platform key,
+// apiKey is the key's name, such as organizations/{org}/apiKeys/{id}, and
+// secret its private key, whose line breaks may be escaped as \n.
+func New(apiKey, secret string, quote rebalancer.Asset, opts ...Option) *Client {
+	c := &Client{
+		apiKey:     apiKey,
+		secret:     secret,
+		baseURL:    BaseURL,
+		quote:      quote,
+		httpClient: http.DefaultClient,
+		products:   map[rebalancer.Asset]string{},
+		now:        time.Now,
+	}
+	if strings.Contains(secret, "PRIVATE KEY") {
+		c.key, c.keyErr = parseKey(secret)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// parseKey parses the private key of a Coinbase Developer Platform API key,
+// in SEC 1 or PKCS #8 form.
+func parseKey(secret string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.Replace(secret, `\n`, "\n", -1)))
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// ProductID returns the product asset is traded on, such as ETH-USD.
+func (c *Client) ProductID(asset rebalancer.Asset) string {
+	if product, ok := c.products[asset]; ok {
+		return product
+	}
+	return string(asset) + "-" + string(c.quote)
+}
+
+// asset returns the asset traded on productID.
+func (c *Client) asset(productID string) rebalancer.Asset {
+	for asset, product := range c.products {
+		if product == productID {
+			return asset
+		}
+	}
+	return rebalancer.Asset(strings.TrimSuffix(productID, "-"+string(c.quote)))
+}
+
+type orderConfiguration struct {
+	Market *marketOrder `json:"market_market_ioc,omitempty"`
+	Limit  *limitOrder  `json:"limit_limit_gtc,omitempty"`
+}
+
+type marketOrder struct {
+	BaseSize decimal.Decimal `json:"base_size"`
+}
+
+type limitOrder struct {
+	BaseSize   decimal.Decimal `json:"base_size"`
+	LimitPrice decimal.Decimal `json:"limit_price"`
+}
+
+type createOrderRequest struct {
+	ClientOrderID      string             `json:"client_order_id"`
+	ProductID          string             `json:"product_id"`
+	Side               string             `json:"side"`
+	OrderConfiguration orderConfiguration `json:"order_configuration"`
+}
+
+type createOrderResponse struct {
+	Success         bool `json:"success"`
+	SuccessResponse struct {
+		OrderID string `json:"order_id"`
+	} `json:"success_response"`
+	ErrorResponse struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	} `json:"error_response"`
+}
+
+// PlaceOrder places order as an immediate-or-cancel market order, or a
+// good-til-cancelled limit order if its trade is a rebalancer.Limit order.
+// The trade's ID is sent as the client order ID, which FindOrder looks orders
+// up by.
+func (c *Client) PlaceOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	clientOrderID := order.Trade.ID
+	if clientOrderID == "" {
+		var err error
+		if clientOrderID, err = newClientOrderID(); err != nil {
+			return "", err
+		}
+	}
+	request := createOrderRequest{
+		ClientOrderID: clientOrderID,
+		ProductID:     c.ProductID(order.Asset),
+		Side:          strings.ToUpper(string(order.Trade.Action)),
+	}
+	if order.Trade.OrderType == rebalancer.Limit {
+		request.OrderConfiguration.Limit = &limitOrder{BaseSize: order.Trade.Amount, LimitPrice: order.Trade.LimitPrice}
+	} else {
+		request.OrderConfiguration.Market = &marketOrder{BaseSize: order.Trade.Amount}
+	}
+
+	var response createOrderResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v3/brokerage/orders", request, &response); err != nil {
+		return "", err
+	}
+	if !response.Success {
+		return "", APIError{Code: response.ErrorResponse.Error, Message: response.ErrorResponse.Message}
+	}
+	return response.SuccessResponse.OrderID, nil
+}
+
+type listOrdersResponse struct {
+	Orders []struct {
+		OrderID       string `json:"order_id"`
+		ClientOrderID string `json:"client_order_id"`
+	} `json:"orders"`
+	HasNext bool   `json:"has_next"`
+	Cursor  string `json:"cursor"`
+}
+
+// FindOrder returns the ID of the order placed for the trade of order,
+// looking it up by the trade's ID amongst the orders of its product, page by
+// page, or rebalancer.ErrOrderNotFound.
+func (c *Client) FindOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	if order.Trade.ID == "" {
+		return "", rebalancer.ErrOrderNotFound
+	}
+	query := url.Values{}
+	query.Set("product_ids", c.ProductID(order.Asset))
+	for {
+		var response listOrdersResponse
+		if err := c.do(ctx, http.MethodGet, "/api/v3/brokerage/orders/historical/batch?"+query.Encode(), nil, &response); err != nil {
+			return "", err
+		}
+		for _, o := range response.Orders {
+			if o.ClientOrderID == order.Trade.ID {
+				return o.OrderID, nil
+			}
+		}
+		if !response.HasNext || response.Cursor == "" {
+			return "", rebalancer.ErrOrderNotFound
+		}
+		query.Set("cursor", response.Cursor)
+	}
+}
+
+type orderResponse struct {
+	Order struct {
+		OrderID            string             `json:"order_id"`
+		ProductID          string             `json:"product_id"`
+		Side               string             `json:"side"`
+		Status             string             `json:"status"`
+		FilledSize         decimal.Decimal    `json:"filled_size"`
+		OrderConfiguration orderConfiguration `json:"order_configuration"`
+	} `json:"order"`
+}
+
+// OrderStatus reports the fill of the order with orderID.
+func (c *Client) OrderStatus(ctx context.Context, orderID string) (rebalancer.Fill, error) {
+	var response orderResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v3/brokerage/orders/historical/"+orderID, nil, &response); err != nil {
+		return rebalancer.Fill{}, err
+	}
+
+	order := response.Order
+	ordered := decimal.Zero
+	switch {
+	case order.OrderConfiguration.Market != nil:
+		ordered = order.OrderConfiguration.Market.BaseSize
+	case order.OrderConfiguration.Limit != nil:
+		ordered = order.OrderConfiguration.Limit.BaseSize
+	}
+	done := true
+	switch order.Status {
+	case "PENDING", "OPEN", "QUEUED":
+		done = false
+	}
+	return rebalancer.Fill{
+		OrderID:  orderID,
+		Asset:    c.asset(order.ProductID),
+		Action:   rebalancer.TradeAction(strings.ToLower(order.Side)),
+		Ordered:  ordered,
+		Quantity: order.FilledSize,
+		Done:     done,
+	}, nil
+}
+
+type cancelOrdersRequest struct {
+	OrderIDs []string `json:"order_ids"`
+}
+
+type cancelOrdersResponse struct {
+	Results []struct {
+		Success       bool   `json:"success"`
+		FailureReason string `json:"failure_reason"`
+	} `json:"results"`
+}
+
+// CancelOrder cancels the order with orderID.
+func (c *Client) CancelOrder(ctx context.Context, orderID string) error {
+	var response cancelOrdersResponse
+	request := cancelOrdersRequest{OrderIDs: []string{orderID}}
+	if err := c.do(ctx, http.MethodPost, "/api/v3/brokerage/orders/batch_cancel", request, &response); err != nil {
+		return err
+	}
+	for _, result := range response.Results {
+		if !result.Success {
+			return APIError{Code: result.FailureReason, Message: "order " + orderID + " was not cancelled"}
+		}
+	}
+	return nil
+}
+
+// newClientOrderID returns a random ID for orders of trades without an ID,
+// since Coinbase requires one.
+func newClientOrderID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss"`
+	NotBefore int64  `json:"nbf"`
+	Expires   int64  `json:"exp"`
+	URI       string `json:"uri"`
+}
+
+// token returns a JWT authorising a request of method to u for two minutes,
+// signed with ES256 by the client's key.
+func (c *Client) token(method string, u *url.URL) (string, error) {
+	if c.keyErr != nil {
+		return "", c.keyErr
+	}
+	nonce, err := newClientOrderID()
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": c.apiKey, "nonce": nonce, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	now := c.now().Unix()
+	claims, err := json.Marshal(jwtClaims{
+		Subject:   c.apiKey,
+		Issuer:    "cdp",
+		NotBefore: now,
+		Expires:   now + 120,
+		URI:       method + " " + u.Host + u.Path,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// ES256 signatures are r and s as 32 byte big-endian integers.
+	signature := make([]byte, 64)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(signature[32-len(rb):32], rb)
+	copy(signature[64-len(sb):], sb)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// do sends a request with body encoded as JSON to path and decodes the JSON
+// response into result. Requests carry a JWT from token when the client has
+// a Coinbase Developer Platform key, and are otherwise signed with an
+// HMAC-SHA256 of their timestamp, method, path without its query and body.
+func (c *Client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	if c.key != nil || c.keyErr != nil {
+		token, err := c.token(method, req.URL)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		timestamp := strconv.FormatInt(c.now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(c.secret))
+		mac.Write([]byte(timestamp + method + strings.SplitN(path, "?", 2)[0] + string(payload)))
+		req.Header.Set("CB-ACCESS-KEY", c.apiKey)
+		req.Header.Set("CB-ACCESS-SIGN", hex.EncodeToString(mac.Sum(nil)))
+		req.Header.Set("CB-ACCESS-TIMESTAMP", timestamp)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		if apiErr.Error == "" {
+			apiErr.Error = strconv.Itoa(resp.StatusCode)
+		}
+		return APIError{Code: apiErr.Error, Message: apiErr.Message}
+	}
+	return json.Unmarshal(data, result)
+}
@@ -0,0 +1,314 @@
+// Package kraken provides a rebalancer.Executor which places orders on the
+// Kraken spot exchange.
+//
+// Kraken names some assets differently from other exchanges, such as XBT for
+// BTC, and prefixes its legacy asset codes with X or Z in balances and pair
+// names, such as XXBT and ZUSD. The client translates between these names and
+// the rebalancer's assets, so plans can use the usual names.
+package kraken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaseURL is the address of the Kraken REST API.
+const BaseURL = "https://api.kraken.com"
+
+// Aliases maps assets to the names Kraken gives them, where they differ.
+var Aliases = map[rebalancer.Asset]string{
+	"BTC":  "XBT",
+	"DOGE": "XDG",
+}
+
+// legacy is the set of Kraken asset names which carry an X or Z prefix in
+// balances and pair names.
+var legacy = map[string]bool{
+	"XBT": true, "XDG": true, "ETH": true, "ETC": true, "LTC": true, "XLM": true,
+	"XMR": true, "XRP": true, "ZEC": true, "REP": true, "MLN": true,
+	"USD": true, "EUR": true, "GBP": true, "CAD": true, "JPY": true,
+}
+
+// An APIError is an error reported by the Kraken API, such as
+// "EOrder:Insufficient funds".
+type APIError []string
+
+// Error formats the error message for APIError.
+func (e APIError) Error() string {
+	return "kraken: " + strings.Join(e, ", ")
+}
+
+// A Client places orders with the Kraken REST API. Every asset is traded
+// against a single quote currency, such as USD.
+type Client struct {
+	apiKey     string
+	secret     []byte
+	baseURL    string
+	quote      rebalancer.Asset
+	httpClient *http.Client
+	aliases    map[rebalancer.Asset]string
+	nonce      func() int64
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sends requests to baseURL instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithAlias trades asset under name on Kraken, in addition to the Aliases.
+func WithAlias(asset rebalancer.Asset, name string) Option {
+	return func(c *Client) {
+		c.aliases[asset] = name
+	}
+}
+
+// New returns a Client which signs its requests with apiKey and the base64
+// encoded secret, and trades every asset against quote.
+func New(apiKey, secret string, quote rebalancer.Asset, opts ...Option) (*Client, error) {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("kraken: invalid secret: %s", err)
+	}
+	c := &Client{
+		apiKey:     apiKey,
+		secret:     key,
+		baseURL:    BaseURL,
+		quote:      quote,
+		httpClient: http.DefaultClient,
+		aliases:    map[rebalancer.Asset]string{},
+		nonce: func() int64 {
+			return time.Now().UnixNano()
+		},
+	}
+	for asset, name := range Aliases {
+		c.aliases[asset] = name
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Name returns the name Kraken gives asset, such as XBT for BTC.
+func (c *Client) Name(asset rebalancer.Asset) string {
+	if name, ok := c.aliases[asset]; ok {
+		return name
+	}
+	return string(asset)
+}
+
+// Asset returns the asset Kraken calls name, accepting both the short and the
+// prefixed forms, such as XBT and XXBT for BTC.
+func (c *Client) Asset(name string) rebalancer.Asset {
+	if len(name) == 4 && (name[0] == 'X' || name[0] == 'Z') && legacy[name[1:]] {
+		name = name[1:]
+	}
+	for asset, alias := range c.aliases {
+		if alias == name {
+			return asset
+		}
+	}
+	return rebalancer.Asset(name)
+}
+
+// Pair returns the pair asset is traded on, such as XBTUSD.
+func (c *Client) Pair(asset rebalancer.Asset) string {
+	return c.Name(asset) + c.Name(c.quote)
+}
+
+// pairAsset returns the asset traded on pair, in either its short or its
+// prefixed form, such as XBTUSD or XXBTZUSD.
+func (c *Client) pairAsset(pair string) rebalancer.Asset {
+	quote := c.Name(c.quote)
+	if legacy[quote] && strings.HasSuffix(pair, "Z"+quote) && len(pair) == 8 {
+		return c.Asset(pair[:4])
+	}
+	return c.Asset(strings.TrimSuffix(pair, quote))
+}
+
+// Balance returns the account's balances, keyed by asset.
+func (c *Client) Balance(ctx context.Context) (rebalancer.Portfolio, error) {
+	var result map[string]decimal.Decimal
+	if err := c.private(ctx, "Balance", url.Values{}, &result); err != nil {
+		return nil, err
+	}
+	portfolio := rebalancer.Portfolio{}
+	for name, amount := range result {
+		asset := c.Asset(name)
+		portfolio[asset] = portfolio[asset].Add(amount)
+	}
+	return portfolio, nil
+}
+
+// PlaceOrder places order as a market order, or a limit order if its trade is
+// a rebalancer.Limit order. The trade's ID is sent as the client order ID,
+// which FindOrder looks orders up by.
+func (c *Client) PlaceOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	params := url.Values{}
+	params.Set("pair", c.Pair(order.Asset))
+	params.Set("type", string(order.Trade.Action))
+	params.Set("volume", order.Trade.Amount.String())
+	params.Set("ordertype", "market")
+	if order.Trade.ID != "" {
+		params.Set("cl_ord_id", order.Trade.ID)
+	}
+	if order.Trade.OrderType == rebalancer.Limit {
+		params.Set("ordertype", "limit")
+		params.Set("price", order.Trade.LimitPrice.String())
+	}
+
+	var result struct {
+		TxID []string `json:"txid"`
+	}
+	if err := c.private(ctx, "AddOrder", params, &result); err != nil {
+		return "", err
+	}
+	if len(result.TxID) == 0 {
+		return "", APIError{"EOrder:No transaction ID returned"}
+	}
+	return result.TxID[0], nil
+}
+
+type orderInfo struct {
+	Status string `json:"status"`
+	Descr  struct {
+		Pair string `json:"pair"`
+		Type string `json:"type"`
+	} `json:"descr"`
+	Vol     decimal.Decimal `json:"vol"`
+	VolExec decimal.Decimal `json:"vol_exec"`
+}
+
+// FindOrder returns the ID of the order placed for the trade of order, looking
+// it up by the trade's ID amongst the open and then the closed orders, or
+// rebalancer.ErrOrderNotFound.
+func (c *Client) FindOrder(ctx context.Context, order rebalancer.Order) (string, error) {
+	if order.Trade.ID == "" {
+		return "", rebalancer.ErrOrderNotFound
+	}
+	for _, method := range []string{"OpenOrders", "ClosedOrders"} {
+		params := url.Values{}
+		params.Set("cl_ord_id", order.Trade.ID)
+		var result struct {
+			Open   map[string]orderInfo `json:"open"`
+			Closed map[string]orderInfo `json:"closed"`
+		}
+		if err := c.private(ctx, method, params, &result); err != nil {
+			return "", err
+		}
+		for txid := range result.Open {
+			return txid, nil
+		}
+		for txid := range result.Closed {
+			return txid, nil
+		}
+	}
+	return "", rebalancer.ErrOrderNotFound
+}
+
+// OrderStatus reports the fill of the order with orderID.
+func (c *Client) OrderStatus(ctx context.Context, orderID string) (rebalancer.Fill, error) {
+	params := url.Values{}
+	params.Set("txid", orderID)
+
+	var result map[string]orderInfo
+	if err := c.private(ctx, "QueryOrders", params, &result); err != nil {
+		return rebalancer.Fill{}, err
+	}
+	order, ok := result[orderID]
+	if !ok {
+		return rebalancer.Fill{}, APIError{"EOrder:Unknown order"}
+	}
+	return rebalancer.Fill{
+		OrderID:  orderID,
+		Asset:    c.pairAsset(order.Descr.Pair),
+		Action:   rebalancer.TradeAction(order.Descr.Type),
+		Ordered:  order.Vol,
+		Quantity: order.VolExec,
+		Done:     order.Status != "pending" && order.Status != "open",
+	}, nil
+}
+
+// CancelOrder cancels the order with orderID.
+func (c *Client) CancelOrder(ctx context.Context, orderID string) error {
+	params := url.Values{}
+	params.Set("txid", orderID)
+	return c.private(ctx, "CancelOrder", params, nil)
+}
+
+// private calls the private API method with params and decodes its result
+// into result. Requests are signed with an HMAC-SHA512 of the path and a
+// SHA-256 of the nonce and the encoded parameters.
+func (c *Client) private(ctx context.Context, method string, params url.Values, result interface{}) error {
+	path := "/0/private/" + method
+	nonce := strconv.FormatInt(c.nonce(), 10)
+	params.Set("nonce", nonce)
+	body := params.Encode()
+
+	digest := sha256.Sum256([]byte(nonce + body))
+	mac := hmac.New(sha512.New, c.secret)
+	mac.Write([]byte(path))
+	mac.Write(digest[:])
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("API-Key", c.apiKey)
+	req.Header.Set("API-Sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Error  []string        `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return APIError{http.StatusText(resp.StatusCode)}
+		}
+		return err
+	}
+	if len(response.Error) > 0 {
+		return APIError(response.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
@@ -0,0 +1,195 @@
+package kraken_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/executor/kraken"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// OrderFinder asserts that Client implements rebalancer.OrderFinder.
+var _ rebalancer.OrderFinder = &Client{}
+
+var secret = base64.StdEncoding.EncodeToString([]byte("secret"))
+
+// verifySignature reports whether the request with body was signed with
+// secret.
+func verifySignature(r *http.Request, body []byte) bool {
+	params, _ := url.ParseQuery(string(body))
+	digest := sha256.Sum256([]byte(params.Get("nonce") + string(body)))
+	mac := hmac.New(sha512.New, []byte("secret"))
+	mac.Write([]byte(r.URL.Path))
+	mac.Write(digest[:])
+	return r.Header.Get("API-Key") == "key" &&
+		r.Header.Get("API-Sign") == base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestClient(t *testing.T) {
+	var orders []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !verifySignature(r, body) {
+			fmt.Fprint(w, `{"error": ["EAPI:Invalid signature"]}`)
+			return
+		}
+		params, _ := url.ParseQuery(string(body))
+		switch r.URL.Path {
+		case "/0/private/Balance":
+			fmt.Fprint(w, `{"error": [], "result": {"XXBT": "0.5", "XETH": "10", "ZUSD": "100", "DOT": "3"}}`)
+		case "/0/private/AddOrder":
+			orders = append(orders, params)
+			fmt.Fprint(w, `{"error": [], "result": {"txid": ["OABC-123"]}}`)
+		case "/0/private/QueryOrders":
+			fmt.Fprint(w, `{"error": [], "result": {"OABC-123": {"status": "open",
+				"descr": {"pair": "XBTUSD", "type": "buy"}, "vol": "0.2", "vol_exec": "0.05"}}}`)
+		case "/0/private/OpenOrders":
+			fmt.Fprint(w, `{"error": [], "result": {"open": {}}}`)
+		case "/0/private/ClosedOrders":
+			if params.Get("cl_ord_id") != "0123456789abcdef" {
+				fmt.Fprint(w, `{"error": [], "result": {"closed": {}}}`)
+				return
+			}
+			fmt.Fprint(w, `{"error": [], "result": {"closed": {"OABC-123": {"status": "closed"}}}}`)
+		case "/0/private/CancelOrder":
+			fmt.Fprint(w, `{"error": [], "result": {"count": 1}}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := New("key", secret, "USD", WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("balances are keyed by asset", func(t *testing.T) {
+		got, err := client.Balance(ctx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		want := rebalancer.Portfolio{
+			"BTC": decimal.NewFromFloat(0.5),
+			"ETH": decimal.NewFromFloat(10),
+			"USD": decimal.NewFromFloat(100),
+			"DOT": decimal.NewFromFloat(3),
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %v want %v", got, want)
+		}
+		for asset, amount := range want {
+			if !got[asset].Equal(amount) {
+				t.Errorf("got %v want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("orders are placed on the aliased pair", func(t *testing.T) {
+		id, err := client.PlaceOrder(ctx, rebalancer.Order{
+			Asset: "BTC",
+			Trade: rebalancer.Trade{Action: rebalancer.Buy, Amount: decimal.NewFromFloat(0.2), OrderType: rebalancer.Limit, LimitPrice: decimal.NewFromFloat(5000)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if id != "OABC-123" {
+			t.Errorf("got %s want OABC-123", id)
+		}
+		order := orders[len(orders)-1]
+		if order.Get("pair") != "XBTUSD" || order.Get("type") != "buy" || order.Get("ordertype") != "limit" ||
+			order.Get("volume") != "0.2" || order.Get("price") != "5000" {
+			t.Errorf("got %v want a limit buy of 0.2 XBTUSD at 5000", order)
+		}
+	})
+
+	t.Run("order status is reported as a fill", func(t *testing.T) {
+		fill, err := client.OrderStatus(ctx, "OABC-123")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if fill.Asset != "BTC" || fill.Action != rebalancer.Buy || fill.Done {
+			t.Errorf("got %v want an open BTC buy", fill)
+		}
+		if !fill.Quantity.Equal(decimal.NewFromFloat(0.05)) || !fill.Ordered.Equal(decimal.NewFromFloat(0.2)) {
+			t.Errorf("got %s of %s want 0.05 of 0.2", fill.Quantity, fill.Ordered)
+		}
+	})
+
+	t.Run("orders are found by the trade's ID", func(t *testing.T) {
+		id, err := client.FindOrder(ctx, rebalancer.Order{Asset: "BTC", Trade: rebalancer.Trade{ID: "0123456789abcdef"}})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if id != "OABC-123" {
+			t.Errorf("got %s want OABC-123", id)
+		}
+
+		_, err = client.FindOrder(ctx, rebalancer.Order{Asset: "BTC", Trade: rebalancer.Trade{ID: "missing"}})
+
+		if err != rebalancer.ErrOrderNotFound {
+			t.Errorf("got %v want %v", err, rebalancer.ErrOrderNotFound)
+		}
+	})
+
+	t.Run("orders can be cancelled", func(t *testing.T) {
+		if err := client.CancelOrder(ctx, "OABC-123"); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("API errors are returned as APIError", func(t *testing.T) {
+		client, _ := New("key", base64.StdEncoding.EncodeToString([]byte("wrong")), "USD", WithBaseURL(server.URL))
+
+		err := client.CancelOrder(ctx, "OABC-123")
+
+		want := APIError{"EAPI:Invalid signature"}
+		if !reflect.DeepEqual(err, want) {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("key", "not base64!", "USD"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestClient_Asset(t *testing.T) {
+	client, _ := New("key", secret, "USD", WithAlias("MATIC", "POL"))
+
+	tests := map[string]rebalancer.Asset{
+		"XBT":  "BTC",
+		"XXBT": "BTC",
+		"XETH": "ETH",
+		"ZUSD": "USD",
+		"XXDG": "DOGE",
+		"POL":  "MATIC",
+		"DOT":  "DOT",
+	}
+	for name, want := range tests {
+		if got := client.Asset(name); got != want {
+			t.Errorf("got %s want %s for %s", got, want, name)
+		}
+	}
+	if got := client.Pair("BTC"); got != "XBTUSD" {
+		t.Errorf("got %s want XBTUSD", got)
+	}
+	if got := client.Name("MATIC"); got != "POL" {
+		t.Errorf("got %s want POL", got)
+	}
+}
@@ -0,0 +1,83 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// A FeeModel estimates the fee charged for executing a trade at price. Fees
+// are expressed in the same currency as the pricelist.
+type FeeModel interface {
+	Fee(trade Trade, price decimal.Decimal) decimal.Decimal
+}
+
+// PercentageFee charges Rate, a fraction such as 0.001 for 0.1%, of the
+// notional value of every trade.
+type PercentageFee struct {
+	Rate decimal.Decimal
+}
+
+// Fee returns the fee for trade.
+func (f PercentageFee) Fee(trade Trade, price decimal.Decimal) decimal.Decimal {
+	return trade.Amount.Mul(price).Mul(f.Rate)
+}
+
+// FixedFee charges Amount for every trade regardless of its size.
+type FixedFee struct {
+	Amount decimal.Decimal
+}
+
+// Fee returns the fee for trade, which is zero if the trade is empty.
+func (f FixedFee) Fee(trade Trade, price decimal.Decimal) decimal.Decimal {
+	if trade.Amount.IsZero() {
+		return decimal.Zero
+	}
+	return f.Amount
+}
+
+// A FeeTier holds the maker and taker rates which apply once the trading
+// volume of an account reaches Volume.
+type FeeTier struct {
+	Volume decimal.Decimal
+	Maker  decimal.Decimal
+	Taker  decimal.Decimal
+}
+
+// TieredFee charges a percentage of notional value taken from the highest of
+// Tiers whose Volume does not exceed the account's trading Volume, using the
+// maker rate if Maker is set and the taker rate otherwise.
+type TieredFee struct {
+	Tiers  []FeeTier
+	Volume decimal.Decimal
+	Maker  bool
+}
+
+// Fee returns the fee for trade.
+func (f TieredFee) Fee(trade Trade, price decimal.Decimal) decimal.Decimal {
+	tiers := make([]FeeTier, len(f.Tiers))
+	copy(tiers, f.Tiers)
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].Volume.LessThan(tiers[j].Volume)
+	})
+
+	rate := decimal.Zero
+	for _, tier := range tiers {
+		if tier.Volume.GreaterThan(f.Volume) {
+			break
+		}
+		rate = tier.Taker
+		if f.Maker {
+			rate = tier.Maker
+		}
+	}
+	return trade.Amount.Mul(price).Mul(rate)
+}
+
+// TotalFees returns the sum of the estimated fees of trades.
+func TotalFees(trades map[Asset]Trade) decimal.Decimal {
+	total := decimal.Zero
+	for _, trade := range trades {
+		total = total.Add(trade.Fee)
+	}
+	return total
+}
@@ -0,0 +1,94 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestFeeModels(t *testing.T) {
+	trade := Trade{Action: Buy, Amount: decimal.NewFromFloat(2), Asset: "ETH"}
+	price := decimal.NewFromFloat(200)
+
+	t.Run("percentage fees are a fraction of notional value", func(t *testing.T) {
+		got := PercentageFee{Rate: decimal.NewFromFloat(0.001)}.Fee(trade, price)
+		want := decimal.NewFromFloat(0.4)
+
+		if !got.Equal(want) {
+			t.Errorf("got %s want %s", got, want)
+		}
+	})
+	t.Run("fixed fees are charged per trade", func(t *testing.T) {
+		model := FixedFee{Amount: decimal.NewFromFloat(1.5)}
+
+		if got := model.Fee(trade, price); !got.Equal(decimal.NewFromFloat(1.5)) {
+			t.Errorf("got %s want 1.5", got)
+		}
+		if got := model.Fee(Trade{Action: Buy}, price); !got.IsZero() {
+			t.Errorf("got %s want 0 for an empty trade", got)
+		}
+	})
+	t.Run("tiered fees use the rate of the account's volume tier", func(t *testing.T) {
+		model := TieredFee{
+			Tiers: []FeeTier{
+				{Volume: decimal.Zero, Maker: decimal.NewFromFloat(0.002), Taker: decimal.NewFromFloat(0.003)},
+				{Volume: decimal.NewFromFloat(50000), Maker: decimal.NewFromFloat(0.001), Taker: decimal.NewFromFloat(0.002)},
+				{Volume: decimal.NewFromFloat(1000000), Maker: decimal.Zero, Taker: decimal.NewFromFloat(0.001)},
+			},
+			Volume: decimal.NewFromFloat(75000),
+		}
+
+		if got := model.Fee(trade, price); !got.Equal(decimal.NewFromFloat(0.8)) {
+			t.Errorf("got %s want 0.8 as a taker", got)
+		}
+
+		model.Maker = true
+		if got := model.Fee(trade, price); !got.Equal(decimal.NewFromFloat(0.4)) {
+			t.Errorf("got %s want 0.4 as a maker", got)
+		}
+	})
+}
+
+func TestWithFees(t *testing.T) {
+	t.Run("trades land on the target index after fees", func(t *testing.T) {
+		account, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		index := Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}
+
+		plan, err := account.Rebalance(index, WithFees(PercentageFee{Rate: decimal.NewFromFloat(0.01)}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		trades := plan.Trades()
+
+		fees := TotalFees(trades)
+		if !fees.IsPositive() {
+			t.Errorf("got total fees of %s want them to be positive", fees)
+		}
+
+		proceeds := trades["ETH"].Amount.Mul(decimal.NewFromFloat(200))
+		cost := trades["BTC"].Amount.Mul(decimal.NewFromFloat(5000))
+		if !proceeds.Sub(cost).Sub(fees).Abs().LessThan(decimal.NewFromFloat(0.000001)) {
+			t.Errorf("got proceeds %s and cost %s, want them to differ by the fees %s", proceeds, cost, fees)
+		}
+
+		if !AchievesIndex(account, trades, index, decimal.NewFromFloat(0.000001)) {
+			t.Errorf("got trades %v which do not achieve the target index", trades)
+		}
+	})
+}
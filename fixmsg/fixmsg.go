@@ -0,0 +1,260 @@
+// Package fixmsg converts rebalance plans into FIX 4.4 NewOrderSingle
+// messages, for brokers which only accept orders over FIX.
+package fixmsg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"strconv"
+	"time"
+)
+
+// Tags of the fields written by NewOrderSingles.
+const (
+	TagAccount      = 1
+	TagBeginString  = 8
+	TagBodyLength   = 9
+	TagCheckSum     = 10
+	TagClOrdID      = 11
+	TagMsgSeqNum    = 34
+	TagMsgType      = 35
+	TagOrderQty     = 38
+	TagOrdType      = 40
+	TagPrice        = 44
+	TagSenderCompID = 49
+	TagSendingTime  = 52
+	TagSide         = 54
+	TagSymbol       = 55
+	TagTargetCompID = 56
+	TagTimeInForce  = 59
+	TagTransactTime = 60
+)
+
+// SOH separates the fields of a message.
+const SOH = '\x01'
+
+// BeginString identifies the FIX version of the messages.
+const BeginString = "FIX.4.4"
+
+// timeFormat is the UTCTimestamp format of SendingTime and TransactTime.
+const timeFormat = "20060102-15:04:05.000"
+
+// An OrdType is the value of the OrdType field.
+type OrdType string
+
+// The order types NewOrderSingles can send.
+const (
+	MarketOrder OrdType = "1"
+	LimitOrder  OrdType = "2"
+)
+
+// A TimeInForce is the value of the TimeInForce field.
+type TimeInForce string
+
+// The common values of TimeInForce.
+const (
+	Day               TimeInForce = "0"
+	GoodTillCancel    TimeInForce = "1"
+	ImmediateOrCancel TimeInForce = "3"
+	FillOrKill        TimeInForce = "4"
+)
+
+// ErrNoLimitPrice indicates a limit order was requested for a trade without a
+// LimitPrice.
+var ErrNoLimitPrice = errors.New("limit orders need a trade with a limit price")
+
+// A Field is a tag and its value.
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// A Message is the fields of a FIX message, in order, without the
+// BeginString, BodyLength and CheckSum fields which Bytes adds.
+type Message []Field
+
+// Get returns the value of the first field with tag, if any.
+func (m Message) Get(tag int) (string, bool) {
+	for _, field := range m {
+		if field.Tag == tag {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Bytes encodes the message for the wire, framed by its BeginString,
+// BodyLength and CheckSum.
+func (m Message) Bytes() []byte {
+	var body bytes.Buffer
+	for _, field := range m {
+		writeField(&body, field.Tag, field.Value)
+	}
+
+	var msg bytes.Buffer
+	writeField(&msg, TagBeginString, BeginString)
+	writeField(&msg, TagBodyLength, strconv.Itoa(body.Len()))
+	msg.Write(body.Bytes())
+	sum := 0
+	for _, b := range msg.Bytes() {
+		sum += int(b)
+	}
+	writeField(&msg, TagCheckSum, fmt.Sprintf("%03d", sum%256))
+	return msg.Bytes()
+}
+
+// String returns the encoded message with | in place of SOH, for logging.
+func (m Message) String() string {
+	return string(bytes.Replace(m.Bytes(), []byte{SOH}, []byte{'|'}, -1))
+}
+
+// writeField writes tag=value followed by SOH to buf.
+func writeField(buf *bytes.Buffer, tag int, value string) {
+	buf.WriteString(strconv.Itoa(tag))
+	buf.WriteByte('=')
+	buf.WriteString(value)
+	buf.WriteByte(SOH)
+}
+
+// config holds the settings applied by Options.
+type config struct {
+	sender  string
+	target  string
+	seqNum  int
+	account string
+	tif     TimeInForce
+	ordType OrdType
+	symbol  func(rebalancer.Asset) string
+	extra   []Field
+	now     func() time.Time
+}
+
+// An Option configures the messages of NewOrderSingles.
+type Option func(*config)
+
+// WithCompIDs sets the SenderCompID and TargetCompID of the messages.
+func WithCompIDs(sender, target string) Option {
+	return func(c *config) {
+		c.sender = sender
+		c.target = target
+	}
+}
+
+// WithSeqNum numbers the messages from seqNum, which defaults to 1.
+func WithSeqNum(seqNum int) Option {
+	return func(c *config) {
+		c.seqNum = seqNum
+	}
+}
+
+// WithAccount sets the Account of the orders.
+func WithAccount(account string) Option {
+	return func(c *config) {
+		c.account = account
+	}
+}
+
+// WithTimeInForce sets the TimeInForce of the orders, which brokers default
+// to Day when it is left out.
+func WithTimeInForce(tif TimeInForce) Option {
+	return func(c *config) {
+		c.tif = tif
+	}
+}
+
+// WithOrdType sends every order as ordType, instead of the OrderType of its
+// trade.
+func WithOrdType(ordType OrdType) Option {
+	return func(c *config) {
+		c.ordType = ordType
+	}
+}
+
+// WithSymbol maps assets to the broker's symbols. Assets are sent as they are
+// by default.
+func WithSymbol(symbol func(rebalancer.Asset) string) Option {
+	return func(c *config) {
+		c.symbol = symbol
+	}
+}
+
+// WithTag adds a field to every order, such as ExDestination or HandlInst.
+func WithTag(tag int, value string) Option {
+	return func(c *config) {
+		c.extra = append(c.extra, Field{Tag: tag, Value: value})
+	}
+}
+
+// WithClock sets the function the SendingTime and TransactTime are read from.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// NewOrderSingles returns a NewOrderSingle message for each trade of plan in
+// its execution order, skipping trades with nothing to trade. Each order's
+// ClOrdID is its trade's ID, so resent orders are recognised as duplicates.
+// Trades are sent as market orders unless their OrderType is Limit or
+// WithOrdType says otherwise.
+func NewOrderSingles(plan rebalancer.RebalancePlan, opts ...Option) ([]Message, error) {
+	c := config{
+		seqNum: 1,
+		symbol: func(asset rebalancer.Asset) string { return string(asset) },
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var messages []Message
+	for _, trade := range plan.ExecutionOrder() {
+		if !trade.Amount.IsPositive() {
+			continue
+		}
+		ordType := c.ordType
+		if ordType == "" {
+			ordType = MarketOrder
+			if trade.OrderType == rebalancer.Limit {
+				ordType = LimitOrder
+			}
+		}
+		if ordType == LimitOrder && !trade.LimitPrice.IsPositive() {
+			return nil, ErrNoLimitPrice
+		}
+
+		now := c.now().UTC().Format(timeFormat)
+		side := "1"
+		if trade.Action == rebalancer.Sell {
+			side = "2"
+		}
+		msg := Message{
+			{TagMsgType, "D"},
+			{TagSenderCompID, c.sender},
+			{TagTargetCompID, c.target},
+			{TagMsgSeqNum, strconv.Itoa(c.seqNum + len(messages))},
+			{TagSendingTime, now},
+			{TagClOrdID, trade.ID},
+		}
+		if c.account != "" {
+			msg = append(msg, Field{TagAccount, c.account})
+		}
+		msg = append(msg,
+			Field{TagSymbol, c.symbol(trade.Asset)},
+			Field{TagSide, side},
+			Field{TagTransactTime, now},
+			Field{TagOrderQty, trade.Amount.String()},
+			Field{TagOrdType, string(ordType)},
+		)
+		if ordType == LimitOrder {
+			msg = append(msg, Field{TagPrice, trade.LimitPrice.String()})
+		}
+		if c.tif != "" {
+			msg = append(msg, Field{TagTimeInForce, string(c.tif)})
+		}
+		messages = append(messages, append(msg, c.extra...))
+	}
+	return messages, nil
+}
@@ -0,0 +1,90 @@
+package fixmsg_test
+
+import (
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/fixmsg"
+	"testing"
+	"time"
+)
+
+func newPlan(t *testing.T, trades string) rebalancer.RebalancePlan {
+	var plan rebalancer.RebalancePlan
+	if err := json.Unmarshal([]byte(`{"Pricelist": {"ETH": "200", "BTC": "5000"}, "Trades": `+trades+`}`), &plan); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return plan
+}
+
+func TestNewOrderSingles(t *testing.T) {
+	clock := WithClock(func() time.Time {
+		return time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	})
+	plan := newPlan(t, `{
+		"ETH": {"Action": "sell", "Asset": "ETH", "ID": "a1", "Amount": "3.75", "Value": "750"},
+		"BTC": {"Action": "buy", "Asset": "BTC", "ID": "b2", "Amount": "0.15", "Value": "750"}
+	}`)
+
+	t.Run("orders are encoded in execution order", func(t *testing.T) {
+		got, err := NewOrderSingles(plan, clock, WithCompIDs("CLIENT", "BROKER"), WithAccount("ACC1"), WithTimeInForce(Day))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got %d messages want 2", len(got))
+		}
+		want := "8=FIX.4.4|9=123|35=D|49=CLIENT|56=BROKER|34=1|52=20190102-03:04:05.000|11=a1|1=ACC1|55=ETH|54=2|60=20190102-03:04:05.000|38=3.75|40=1|59=0|10=099|"
+		if got[0].String() != want {
+			t.Errorf("got %s want %s", got[0], want)
+		}
+		if side, _ := got[1].Get(TagSide); side != "1" {
+			t.Errorf("got side %s want 1", side)
+		}
+		if seq, _ := got[1].Get(TagMsgSeqNum); seq != "2" {
+			t.Errorf("got MsgSeqNum %s want 2", seq)
+		}
+	})
+	t.Run("limit orders carry their price", func(t *testing.T) {
+		plan := newPlan(t, `{
+			"ETH": {"Action": "buy", "Asset": "ETH", "ID": "c3", "Amount": "1", "OrderType": "limit", "LimitPrice": "199.5"}
+		}`)
+
+		got, err := NewOrderSingles(plan, clock, WithSymbol(func(asset rebalancer.Asset) string {
+			return string(asset) + "/USD"
+		}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if ordType, _ := got[0].Get(TagOrdType); ordType != string(LimitOrder) {
+			t.Errorf("got OrdType %s want %s", ordType, LimitOrder)
+		}
+		if price, _ := got[0].Get(TagPrice); price != "199.5" {
+			t.Errorf("got Price %s want 199.5", price)
+		}
+		if symbol, _ := got[0].Get(TagSymbol); symbol != "ETH/USD" {
+			t.Errorf("got Symbol %s want ETH/USD", symbol)
+		}
+	})
+	t.Run("extra tags are added to every order", func(t *testing.T) {
+		got, _ := NewOrderSingles(plan, clock, WithTag(100, "XNAS"), WithSeqNum(7))
+
+		if dest, _ := got[1].Get(100); dest != "XNAS" {
+			t.Errorf("got ExDestination %s want XNAS", dest)
+		}
+		if seq, _ := got[1].Get(TagMsgSeqNum); seq != "8" {
+			t.Errorf("got MsgSeqNum %s want 8", seq)
+		}
+		if _, ok := got[0].Get(TagTimeInForce); ok {
+			t.Error("got a TimeInForce want none")
+		}
+	})
+	t.Run("limit orders need a limit price", func(t *testing.T) {
+		_, err := NewOrderSingles(plan, clock, WithOrdType(LimitOrder))
+
+		if err != ErrNoLimitPrice {
+			t.Errorf("got %v want %v", err, ErrNoLimitPrice)
+		}
+	})
+}
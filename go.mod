@@ -1,6 +1,18 @@
 module github.com/pdbrito/rebalancer
 
+go 1.21
+
 require (
+	github.com/mattn/go-sqlite3 v1.14.0
 	github.com/pdbrito/randomSum v0.0.0-20181209213857-cf25ad0ce9f1
 	github.com/shopspring/decimal v0.0.0-20180709203117-cd690d0c9e24
+	google.golang.org/grpc v1.64.0
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
 )
@@ -0,0 +1,131 @@
+package rebalancer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"sort"
+)
+
+// A FundingNode is an asset traded by a plan along with the value traded.
+type FundingNode struct {
+	Asset  Asset           `json:"asset"`
+	Action TradeAction     `json:"action"`
+	Value  decimal.Decimal `json:"value"`
+}
+
+// A FundingEdge records that the proceeds of selling From pay for Value of
+// the buy of To, settled through the Via cash asset.
+type FundingEdge struct {
+	From  Asset           `json:"from"`
+	To    Asset           `json:"to"`
+	Via   Asset           `json:"via"`
+	Value decimal.Decimal `json:"value"`
+}
+
+// A FundingGraph describes which sells fund which buys in a set of trades.
+type FundingGraph struct {
+	Cash  Asset         `json:"cash"`
+	Nodes []FundingNode `json:"nodes"`
+	Edges []FundingEdge `json:"edges"`
+}
+
+// NewFundingGraph values trades using pricelist and matches the proceeds of
+// each sell against the buys they pay for, settled through cash. Sells and
+// buys are matched in asset order so the same trades always produce the same
+// graph.
+func NewFundingGraph(trades map[Asset]Trade, pricelist Pricelist, cash Asset) (FundingGraph, error) {
+	graph := FundingGraph{Cash: cash}
+
+	var sells, buys []FundingNode
+	for asset, trade := range trades {
+		price, ok := pricelist[asset]
+		if !ok {
+			return FundingGraph{}, ErrAssetMissingFromPricelist
+		}
+		if trade.Amount.IsZero() {
+			continue
+		}
+		node := FundingNode{Asset: asset, Action: trade.Action, Value: trade.Amount.Mul(price)}
+		if trade.Action == Sell {
+			sells = append(sells, node)
+			continue
+		}
+		buys = append(buys, node)
+	}
+	sortFundingNodes(sells)
+	sortFundingNodes(buys)
+	graph.Nodes = append(sells, buys...)
+
+	var sellRemaining, buyRemaining decimal.Decimal
+	for s, b := 0, 0; s < len(sells) && b < len(buys); {
+		if sellRemaining.IsZero() {
+			sellRemaining = sells[s].Value
+		}
+		if buyRemaining.IsZero() {
+			buyRemaining = buys[b].Value
+		}
+
+		value := sellRemaining
+		if buyRemaining.LessThan(value) {
+			value = buyRemaining
+		}
+		graph.Edges = append(graph.Edges, FundingEdge{
+			From:  sells[s].Asset,
+			To:    buys[b].Asset,
+			Via:   cash,
+			Value: value,
+		})
+
+		sellRemaining = sellRemaining.Sub(value)
+		buyRemaining = buyRemaining.Sub(value)
+		if sellRemaining.IsZero() {
+			s++
+		}
+		if buyRemaining.IsZero() {
+			b++
+		}
+	}
+
+	return graph, nil
+}
+
+func sortFundingNodes(nodes []FundingNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Asset < nodes[j].Asset
+	})
+}
+
+// WriteJSON writes the graph to w as JSON.
+func (g FundingGraph) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(g)
+}
+
+// WriteDOT writes the graph to w in the Graphviz DOT language, with sells
+// drawn as boxes, buys as ellipses and each edge labelled with its value.
+func (g FundingGraph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph funding {"); err != nil {
+		return err
+	}
+	for _, node := range g.Nodes {
+		shape := "ellipse"
+		if node.Action == Sell {
+			shape = "box"
+		}
+		_, err := fmt.Fprintf(w, "\t%q [shape=%s, label=\"%s %s\\n%s\"];\n",
+			node.Asset, shape, node.Action, node.Asset, node.Value)
+		if err != nil {
+			return err
+		}
+	}
+	for _, edge := range g.Edges {
+		_, err := fmt.Fprintf(w, "\t%q -> %q [label=\"%s via %s\"];\n",
+			edge.From, edge.To, edge.Value, edge.Via)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -0,0 +1,86 @@
+package rebalancer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+func TestNewFundingGraph(t *testing.T) {
+	pricelist := Pricelist{
+		"ETH":  decimal.NewFromFloat(200),
+		"BTC":  decimal.NewFromFloat(2000),
+		"IOTA": decimal.NewFromFloat(0.3),
+		"XLM":  decimal.NewFromFloat(0.2),
+	}
+
+	trades := map[Asset]Trade{
+		"ETH":  {Action: Sell, Amount: decimal.NewFromFloat(10)},
+		"IOTA": {Action: Sell, Amount: decimal.NewFromFloat(5000)},
+		"BTC":  {Action: Buy, Amount: decimal.NewFromFloat(1)},
+		"XLM":  {Action: Buy, Amount: decimal.NewFromFloat(7500)},
+	}
+
+	t.Run("sells are matched to the buys they fund", func(t *testing.T) {
+		graph, err := NewFundingGraph(trades, pricelist, "USD")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := []FundingEdge{
+			{From: "ETH", To: "BTC", Via: "USD", Value: decimal.NewFromFloat(2000)},
+			{From: "IOTA", To: "XLM", Via: "USD", Value: decimal.NewFromFloat(1500)},
+		}
+
+		if len(graph.Edges) != len(want) {
+			t.Fatalf("got %d edges want %d", len(graph.Edges), len(want))
+		}
+		for i, edge := range graph.Edges {
+			if edge.From != want[i].From || edge.To != want[i].To || !edge.Value.Equal(want[i].Value) {
+				t.Errorf("got %v want %v", edge, want[i])
+			}
+		}
+	})
+	t.Run("trades must be priced by the pricelist", func(t *testing.T) {
+		_, err := NewFundingGraph(map[Asset]Trade{
+			"BAT": {Action: Buy, Amount: decimal.NewFromFloat(1)},
+		}, pricelist, "USD")
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v, want %s", err, ErrAssetMissingFromPricelist)
+		}
+	})
+	t.Run("the graph can be written as DOT", func(t *testing.T) {
+		graph, _ := NewFundingGraph(trades, pricelist, "USD")
+
+		var buf bytes.Buffer
+		if err := graph.WriteDOT(&buf); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := `"ETH" -> "BTC" [label="2000 via USD"];`
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("got %s, want it to contain %s", buf.String(), want)
+		}
+	})
+	t.Run("the graph can be written as JSON", func(t *testing.T) {
+		graph, _ := NewFundingGraph(trades, pricelist, "USD")
+
+		var buf bytes.Buffer
+		if err := graph.WriteJSON(&buf); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		var got FundingGraph
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if got.Cash != "USD" || len(got.Edges) != 2 {
+			t.Errorf("got %v want a graph settled through USD with 2 edges", got)
+		}
+	})
+}
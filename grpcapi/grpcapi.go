@@ -0,0 +1,152 @@
+// Package grpcapi serves the RebalanceService defined in rebalancer.proto, so
+// the rebalancer can be deployed as a service for systems not written in Go.
+//
+// The messages and the service stubs are generated from rebalancer.proto by
+// protoc with protoc-gen-go and protoc-gen-go-grpc; run go generate after
+// changing it. A Server is registered with a grpc.Server to serve calls:
+//
+//	s := grpc.NewServer()
+//	grpcapi.RegisterRebalanceServiceServer(s, grpcapi.NewServer())
+//	err := s.Serve(listener)
+//
+// and the generated RebalanceServiceClient makes them.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative rebalancer.proto
+
+import (
+	"context"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// A Server implements the RebalanceService.
+type Server struct {
+	UnimplementedRebalanceServiceServer
+}
+
+// NewServer returns a Server.
+func NewServer() *Server {
+	return &Server{}
+}
+
+// Rebalance plans the rebalance asked for by req, failing with
+// InvalidArgument if it cannot be planned.
+func (s *Server) Rebalance(ctx context.Context, req *RebalanceRequest) (*Plan, error) {
+	portfolio, err := parseDecimals("portfolio", req.GetPortfolio().GetHoldings())
+	if err != nil {
+		return nil, err
+	}
+	pricelist, err := parseDecimals("pricelist", req.GetPricelist().GetPrices())
+	if err != nil {
+		return nil, err
+	}
+	index, err := parseDecimals("index", req.GetIndex().GetWeights())
+	if err != nil {
+		return nil, err
+	}
+	var opts []rebalancer.RebalanceOption
+	if req.GetAsOf() != "" {
+		asOf, err := time.Parse(time.RFC3339Nano, req.GetAsOf())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts = append(opts, rebalancer.AsOf(asOf))
+	}
+
+	account, err := rebalancer.NewAccountWithPricelist(portfolio, pricelist)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	plan, err := account.RebalanceContext(ctx, index, opts...)
+	switch err {
+	case nil:
+	case context.Canceled:
+		return nil, status.Error(codes.Canceled, err.Error())
+	case context.DeadlineExceeded:
+		return nil, status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return NewPlan(plan), nil
+}
+
+// NewRebalanceRequest returns the request for the plan rebalancing
+// portfolio, valued with pricelist, onto index. The plan is made at asOf, or
+// at the time the request is served if asOf is zero.
+func NewRebalanceRequest(portfolio rebalancer.Portfolio, pricelist rebalancer.Pricelist, index rebalancer.Index, asOf time.Time) *RebalanceRequest {
+	req := &RebalanceRequest{
+		Portfolio: &Portfolio{Holdings: formatDecimals(portfolio)},
+		Pricelist: &Pricelist{Prices: formatDecimals(pricelist)},
+		Index:     &Index{Weights: formatDecimals(index)},
+	}
+	if !asOf.IsZero() {
+		req.AsOf = asOf.Format(time.RFC3339Nano)
+	}
+	return req
+}
+
+// NewPlan returns the message describing plan, with its trades in the order
+// of SortedTrades.
+func NewPlan(plan rebalancer.RebalancePlan) *Plan {
+	p := &Plan{
+		Time:      plan.Time.Format(time.RFC3339Nano),
+		Index:     &Index{Weights: formatDecimals(plan.Index)},
+		Pricelist: &Pricelist{Prices: formatDecimals(plan.Pricelist)},
+		Value:     formatDecimal(plan.Value),
+		Turnover:  formatDecimal(plan.Turnover),
+		Fees:      formatDecimal(plan.Fees),
+		Residual:  formatDecimal(plan.Residual),
+		Hash:      plan.Hash(),
+	}
+	for _, trade := range plan.SortedTrades() {
+		p.Trades = append(p.Trades, &Trade{
+			Asset:      string(trade.Asset),
+			Action:     string(trade.Action),
+			Amount:     formatDecimal(trade.Amount),
+			Id:         trade.ID,
+			Price:      formatDecimal(trade.Price),
+			Value:      formatDecimal(trade.Value),
+			OrderType:  string(trade.OrderType),
+			LimitPrice: formatDecimal(trade.LimitPrice),
+			Fee:        formatDecimal(trade.Fee),
+			Residual:   formatDecimal(trade.Residual),
+		})
+	}
+	return p
+}
+
+// formatDecimal formats d, leaving it out when zero as proto3 does.
+func formatDecimal(d decimal.Decimal) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.String()
+}
+
+// formatDecimals formats the decimals of m by asset.
+func formatDecimals(m map[rebalancer.Asset]decimal.Decimal) map[string]string {
+	formatted := make(map[string]string, len(m))
+	for asset, d := range m {
+		formatted[string(asset)] = d.String()
+	}
+	return formatted
+}
+
+// parseDecimals parses the decimals of the field called name by asset,
+// failing with InvalidArgument if any are malformed.
+func parseDecimals(name string, m map[string]string) (map[rebalancer.Asset]decimal.Decimal, error) {
+	parsed := make(map[rebalancer.Asset]decimal.Decimal, len(m))
+	for asset, s := range m {
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s: %s: %s", name, asset, err))
+		}
+		parsed[rebalancer.Asset(asset)] = d
+	}
+	return parsed, nil
+}
@@ -0,0 +1,85 @@
+package grpcapi_test
+
+import (
+	"context"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/grpcapi"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRebalanceService(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	server := grpc.NewServer()
+	RegisterRebalanceServiceServer(server, NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer conn.Close()
+	client := NewRebalanceServiceClient(conn)
+
+	asOf := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	portfolio := rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+	pricelist := rebalancer.Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	}
+	index := rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("the service returns the plan of the rebalance", func(t *testing.T) {
+		got, err := client.Rebalance(ctx, NewRebalanceRequest(portfolio, pricelist, index, asOf))
+
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		account, _ := rebalancer.NewAccountWithPricelist(portfolio, pricelist)
+		plan, _ := account.Rebalance(index, rebalancer.AsOf(asOf))
+		want := NewPlan(plan)
+
+		if !proto.Equal(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+		if len(got.Trades) != 2 || got.Trades[0].Action != string(rebalancer.Sell) {
+			t.Errorf("got %v want the sell before the buy", got.Trades)
+		}
+	})
+	t.Run("invalid requests fail with InvalidArgument", func(t *testing.T) {
+		invalid := rebalancer.Index{"ETH": decimal.NewFromFloat(0.4)}
+
+		_, err := client.Rebalance(ctx, NewRebalanceRequest(portfolio, pricelist, invalid, asOf))
+
+		if status.Code(err) != codes.InvalidArgument || status.Convert(err).Message() != rebalancer.ErrIndexSumIncorrect.Error() {
+			t.Errorf("got %v want %s with %q", err, codes.InvalidArgument, rebalancer.ErrIndexSumIncorrect)
+		}
+	})
+	t.Run("malformed decimals fail with InvalidArgument", func(t *testing.T) {
+		req := NewRebalanceRequest(portfolio, pricelist, index, asOf)
+		req.Pricelist.Prices["ETH"] = "two hundred"
+
+		_, err := client.Rebalance(ctx, req)
+
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("got %v want %s", err, codes.InvalidArgument)
+		}
+	})
+}
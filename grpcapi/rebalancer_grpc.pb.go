@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: rebalancer.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	RebalanceService_Rebalance_FullMethodName = "/rebalancer.v1.RebalanceService/Rebalance"
+)
+
+// RebalanceServiceClient is the client API for RebalanceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RebalanceServiceClient interface {
+	Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*Plan, error)
+}
+
+type rebalanceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRebalanceServiceClient(cc grpc.ClientConnInterface) RebalanceServiceClient {
+	return &rebalanceServiceClient{cc}
+}
+
+func (c *rebalanceServiceClient) Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*Plan, error) {
+	out := new(Plan)
+	err := c.cc.Invoke(ctx, RebalanceService_Rebalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RebalanceServiceServer is the server API for RebalanceService service.
+// All implementations must embed UnimplementedRebalanceServiceServer
+// for forward compatibility
+type RebalanceServiceServer interface {
+	Rebalance(context.Context, *RebalanceRequest) (*Plan, error)
+	mustEmbedUnimplementedRebalanceServiceServer()
+}
+
+// UnimplementedRebalanceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRebalanceServiceServer struct {
+}
+
+func (UnimplementedRebalanceServiceServer) Rebalance(context.Context, *RebalanceRequest) (*Plan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rebalance not implemented")
+}
+func (UnimplementedRebalanceServiceServer) mustEmbedUnimplementedRebalanceServiceServer() {}
+
+// UnsafeRebalanceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RebalanceServiceServer will
+// result in compilation errors.
+type UnsafeRebalanceServiceServer interface {
+	mustEmbedUnimplementedRebalanceServiceServer()
+}
+
+func RegisterRebalanceServiceServer(s grpc.ServiceRegistrar, srv RebalanceServiceServer) {
+	s.RegisterService(&RebalanceService_ServiceDesc, srv)
+}
+
+func _RebalanceService_Rebalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RebalanceServiceServer).Rebalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RebalanceService_Rebalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RebalanceServiceServer).Rebalance(ctx, req.(*RebalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RebalanceService_ServiceDesc is the grpc.ServiceDesc for RebalanceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RebalanceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rebalancer.v1.RebalanceService",
+	HandlerType: (*RebalanceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Rebalance",
+			Handler:    _RebalanceService_Rebalance_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rebalancer.proto",
+}
@@ -0,0 +1,141 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrInsufficientHistory indicates a History with fewer than two snapshots,
+// which has no returns.
+var ErrInsufficientHistory = errors.New("history needs at least two snapshots")
+
+// ErrUndefinedReturn indicates a History whose money-weighted return cannot
+// be solved for, for instance because its first snapshot has no value.
+var ErrUndefinedReturn = errors.New("money-weighted return is undefined")
+
+// Snapshot returns a snapshot of the account taken now.
+func (a Account) Snapshot() Snapshot {
+	return Snapshot{
+		Time:      time.Now(),
+		Portfolio: a.Holdings(),
+		Pricelist: a.Pricelist(),
+		Flow:      decimal.Zero,
+	}
+}
+
+// A History is a series of snapshots of an account, ordered by time.
+type History []Snapshot
+
+// NewHistory returns the snapshots as a History, ordered by time.
+func NewHistory(snapshots ...Snapshot) History {
+	history := append(History(nil), snapshots...)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	return history
+}
+
+// Between returns the snapshots taken at or after from and at or before to.
+func (h History) Between(from, to time.Time) History {
+	var between History
+	for _, snapshot := range h {
+		if !snapshot.Time.Before(from) && !snapshot.Time.After(to) {
+			between = append(between, snapshot)
+		}
+	}
+	return between
+}
+
+// Returns is the performance of an account between two snapshots.
+type Returns struct {
+	From time.Time
+	To   time.Time
+	// TimeWeighted is the return of the holdings over the period regardless
+	// of the timing and size of the flows, for example 0.1 for 10%. It
+	// measures the performance of the allocation.
+	TimeWeighted decimal.Decimal
+	// MoneyWeighted is the internal rate of return over the period, weighting
+	// each flow by how long it was invested. It measures the performance of
+	// the money actually put in.
+	MoneyWeighted decimal.Decimal
+}
+
+// Returns returns the time-weighted and money-weighted returns from the first
+// snapshot of the history to the last. The flow of the first snapshot is
+// ignored, since it is part of the starting value.
+func (h History) Returns() (Returns, error) {
+	if len(h) < 2 {
+		return Returns{}, ErrInsufficientHistory
+	}
+	values := make([]decimal.Decimal, len(h))
+	for i, snapshot := range h {
+		value, err := snapshot.Value()
+		if err != nil {
+			return Returns{}, err
+		}
+		values[i] = value
+	}
+	returns := Returns{From: h[0].Time, To: h[len(h)-1].Time}
+
+	growth := decimal.New(1, 0)
+	for i := 1; i < len(h); i++ {
+		if !values[i-1].IsPositive() {
+			continue
+		}
+		growth = growth.Mul(values[i].Sub(h[i].Flow).DivRound(values[i-1], 16))
+	}
+	returns.TimeWeighted = growth.Sub(decimal.New(1, 0)).Round(8)
+
+	moneyWeighted, err := h.moneyWeightedGrowth(values[0], values[len(values)-1])
+	if err != nil {
+		return Returns{}, err
+	}
+	returns.MoneyWeighted = decimal.NewFromFloat(moneyWeighted - 1).Round(8)
+	return returns, nil
+}
+
+// moneyWeightedGrowth solves for the growth g over the period for which the
+// value of the first snapshot, startValue, and every flow, each grown for the
+// fraction of the period it was invested, add up to the value of the last
+// snapshot, endValue.
+func (h History) moneyWeightedGrowth(startValue, endValue decimal.Decimal) (float64, error) {
+	first, last := h[0], h[len(h)-1]
+	period := last.Time.Sub(first.Time).Seconds()
+	start, _ := startValue.Float64()
+	end, _ := endValue.Float64()
+	if start <= 0 || period <= 0 {
+		return 0, ErrUndefinedReturn
+	}
+	type flow struct {
+		amount, invested float64
+	}
+	flows := make([]flow, 0, len(h)-1)
+	for _, snapshot := range h[1:] {
+		amount, _ := snapshot.Flow.Float64()
+		flows = append(flows, flow{amount, last.Time.Sub(snapshot.Time).Seconds() / period})
+	}
+	value := func(g float64) float64 {
+		v := start * g
+		for _, f := range flows {
+			v += f.amount * math.Pow(g, f.invested)
+		}
+		return v - end
+	}
+
+	lo, hi := 1e-9, 1e9
+	if value(lo) > 0 || value(hi) < 0 {
+		return 0, ErrUndefinedReturn
+	}
+	for i := 0; i < 200; i++ {
+		mid := math.Sqrt(lo * hi)
+		if value(mid) < 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
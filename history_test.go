@@ -0,0 +1,104 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestHistory_Returns(t *testing.T) {
+	usd := Pricelist{"USD": decimal.NewFromFloat(1)}
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	history := NewHistory(
+		Snapshot{Time: end, Portfolio: Portfolio{"USD": decimal.NewFromFloat(2200)}, Pricelist: usd},
+		Snapshot{Time: start, Portfolio: Portfolio{"USD": decimal.NewFromFloat(1000)}, Pricelist: usd},
+		Snapshot{Time: start.Add(end.Sub(start) / 2), Portfolio: Portfolio{"USD": decimal.NewFromFloat(2000)}, Pricelist: usd, Flow: decimal.NewFromFloat(1000)},
+	)
+
+	t.Run("time-weighted returns ignore the timing of flows", func(t *testing.T) {
+		got, err := history.Returns()
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.TimeWeighted.Equal(decimal.NewFromFloat(0.1)) {
+			t.Errorf("got %v want %v", got.TimeWeighted, 0.1)
+		}
+		if !got.From.Equal(start) {
+			t.Errorf("got %s want the returns to start at the first snapshot", got.From)
+		}
+	})
+
+	t.Run("money-weighted returns weight flows by how long they were invested", func(t *testing.T) {
+		got, err := history.Returns()
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.MoneyWeighted.Equal(decimal.NewFromFloat(0.13475242)) {
+			t.Errorf("got %v want %v", got.MoneyWeighted, 0.13475242)
+		}
+	})
+
+	t.Run("without flows both returns agree", func(t *testing.T) {
+		got, err := NewHistory(
+			Snapshot{Time: start, Portfolio: Portfolio{"USD": decimal.NewFromFloat(1000)}, Pricelist: usd},
+			Snapshot{Time: start.AddDate(0, 6, 0), Portfolio: Portfolio{"USD": decimal.NewFromFloat(1100)}, Pricelist: usd},
+			Snapshot{Time: start.AddDate(1, 0, 0), Portfolio: Portfolio{"USD": decimal.NewFromFloat(1210)}, Pricelist: usd},
+		).Returns()
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.TimeWeighted.Equal(decimal.NewFromFloat(0.21)) || !got.MoneyWeighted.Equal(decimal.NewFromFloat(0.21)) {
+			t.Errorf("got %v and %v want both %v", got.TimeWeighted, got.MoneyWeighted, 0.21)
+		}
+	})
+
+	t.Run("returns are limited to the snapshots between two times", func(t *testing.T) {
+		got, err := history.Between(start, start.AddDate(0, 7, 0)).Returns()
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.TimeWeighted.IsZero() {
+			t.Errorf("got %v want 0", got.TimeWeighted)
+		}
+	})
+
+	t.Run("a single snapshot has no returns", func(t *testing.T) {
+		if _, err := history[:1].Returns(); err != ErrInsufficientHistory {
+			t.Errorf("got %v want %v", err, ErrInsufficientHistory)
+		}
+	})
+}
+
+func TestAccount_Snapshot(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("snapshots are saved to the account's history", func(t *testing.T) {
+		ctx := context.Background()
+		store := &MemoryStore{}
+
+		store.SaveSnapshot(ctx, "main", account.Snapshot())
+		got, err := store.LoadHistory(ctx, "main")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 1 || !got[0].Portfolio["ETH"].Equal(decimal.NewFromFloat(20)) {
+			t.Errorf("got %v want a snapshot of the account", got)
+		}
+	})
+}
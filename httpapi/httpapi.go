@@ -0,0 +1,149 @@
+// Package httpapi exposes the rebalancer as a JSON HTTP API which can be
+// mounted in an existing service, for instance under a prefix with
+// http.StripPrefix.
+package httpapi
+
+import (
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strings"
+)
+
+// A RebalanceRequest is the body of POST /rebalance. The portfolio is valued
+// with Pricelist, or with the pricelist registered as PricelistName, or with
+// the global pricelist when neither is given.
+type RebalanceRequest struct {
+	Portfolio     rebalancer.Portfolio `json:"portfolio"`
+	Index         rebalancer.Index     `json:"index"`
+	Pricelist     rebalancer.Pricelist `json:"pricelist,omitempty"`
+	PricelistName string               `json:"pricelistName,omitempty"`
+	Options       Options              `json:"options"`
+}
+
+// Options are the RebalanceOptions a request may ask for.
+type Options struct {
+	MinTradeValue decimal.Decimal `json:"minTradeValue"`
+	// IgnoreUnlisted is a deprecated synonym of KeepUnlisted.
+	IgnoreUnlisted bool               `json:"ignoreUnlisted"`
+	KeepUnlisted   bool               `json:"keepUnlisted"`
+	LockedAssets   []rebalancer.Asset `json:"lockedAssets"`
+}
+
+// rebalanceOptions returns the RebalanceOptions o asks for.
+func (o Options) rebalanceOptions() []rebalancer.RebalanceOption {
+	var opts []rebalancer.RebalanceOption
+	if o.MinTradeValue.IsPositive() {
+		opts = append(opts, rebalancer.WithMinTradeValue(o.MinTradeValue))
+	}
+	if o.KeepUnlisted || o.IgnoreUnlisted {
+		opts = append(opts, rebalancer.KeepUnlisted())
+	}
+	if len(o.LockedAssets) > 0 {
+		opts = append(opts, rebalancer.WithLockedAssets(o.LockedAssets...))
+	}
+	return opts
+}
+
+// An Allocation is the response of GET /allocation.
+type Allocation struct {
+	Value      decimal.Decimal  `json:"value"`
+	Allocation rebalancer.Index `json:"allocation"`
+}
+
+// An Error is the body of every response which is not a success.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Handler returns an http.Handler serving the API:
+//
+//	POST /rebalance   takes a RebalanceRequest and returns a RebalancePlan.
+//	GET  /allocation  returns the Allocation of the portfolio given by the
+//	                  query, such as ?ETH=20&BTC=0.5, valued with the global
+//	                  pricelist or the pricelist named by ?pricelist=.
+//
+// Invalid requests are answered with 400 Bad Request and an Error.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rebalance", serveRebalance)
+	mux.HandleFunc("/allocation", serveAllocation)
+	return mux
+}
+
+// serveRebalance handles POST /rebalance.
+func serveRebalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req RebalanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	account, err := newAccount(req.Portfolio, req.Pricelist, req.PricelistName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	plan, err := account.RebalanceContext(r.Context(), req.Index, req.Options.rebalanceOptions()...)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// serveAllocation handles GET /allocation.
+func serveAllocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	portfolio := rebalancer.Portfolio{}
+	for key, values := range query {
+		if key == "pricelist" {
+			continue
+		}
+		amount, err := decimal.NewFromString(values[0])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid amount of "+key)
+			return
+		}
+		portfolio[rebalancer.Asset(strings.ToUpper(key))] = amount
+	}
+	account, err := newAccount(portfolio, nil, query.Get("pricelist"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, Allocation{Value: account.Value(), Allocation: account.Allocation()})
+}
+
+// newAccount returns an account holding portfolio, valued with pricelist,
+// the pricelist registered as name, or the global pricelist.
+func newAccount(portfolio rebalancer.Portfolio, pricelist rebalancer.Pricelist, name string) (rebalancer.Account, error) {
+	switch {
+	case pricelist != nil:
+		return rebalancer.NewAccountWithPricelist(portfolio, pricelist)
+	case name != "":
+		return rebalancer.NewAccountWithNamedPricelist(portfolio, name)
+	default:
+		return rebalancer.NewAccount(portfolio)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, Error{Error: message})
+}
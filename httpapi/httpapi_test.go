@@ -0,0 +1,110 @@
+package httpapi_test
+
+import (
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/httpapi"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+
+	t.Run("a plan is returned for a rebalance request", func(t *testing.T) {
+		resp, err := http.Post(server.URL+"/rebalance", "application/json", strings.NewReader(`{
+			"portfolio": {"ETH": "20", "BTC": "0.5"},
+			"pricelist": {"ETH": "200", "BTC": "5000"},
+			"index": {"ETH": "0.5", "BTC": "0.5"}
+		}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("got %d want %d", resp.StatusCode, http.StatusOK)
+		}
+		var plan rebalancer.RebalancePlan
+		if err := json.NewDecoder(resp.Body).Decode(&plan); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		trades := plan.Trades()
+		if trades["ETH"].Action != rebalancer.Sell || !trades["ETH"].Amount.Equal(decimal.NewFromFloat(3.75)) {
+			t.Errorf("got %v want a sell of 3.75 ETH", trades)
+		}
+	})
+	t.Run("rebalance options are applied", func(t *testing.T) {
+		resp, err := http.Post(server.URL+"/rebalance", "application/json", strings.NewReader(`{
+			"portfolio": {"ETH": "20", "BTC": "0.5"},
+			"pricelist": {"ETH": "200", "BTC": "5000"},
+			"index": {"ETH": "0.5", "BTC": "0.5"},
+			"options": {"minTradeValue": "1000"}
+		}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+
+		var plan rebalancer.RebalancePlan
+		json.NewDecoder(resp.Body).Decode(&plan)
+		for asset, trade := range plan.Trades() {
+			if !trade.Amount.IsZero() {
+				t.Errorf("got a trade of %s %s want trades below 1000 dropped", trade.Amount, asset)
+			}
+		}
+	})
+	t.Run("invalid requests are answered with an error", func(t *testing.T) {
+		resp, err := http.Post(server.URL+"/rebalance", "application/json", strings.NewReader(`{
+			"portfolio": {"ETH": "20"},
+			"pricelist": {"ETH": "200"},
+			"index": {"ETH": "0.5"}
+		}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+
+		var got Error
+		json.NewDecoder(resp.Body).Decode(&got)
+		if resp.StatusCode != http.StatusBadRequest || got.Error != rebalancer.ErrIndexSumIncorrect.Error() {
+			t.Errorf("got %d %q want %d %q", resp.StatusCode, got.Error, http.StatusBadRequest, rebalancer.ErrIndexSumIncorrect)
+		}
+	})
+	t.Run("the allocation of a portfolio is returned", func(t *testing.T) {
+		rebalancer.SetNamedPricelist("usd", rebalancer.Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		})
+		defer rebalancer.RemoveNamedPricelist("usd")
+
+		resp, err := http.Get(server.URL + "/allocation?ETH=20&BTC=0.5&pricelist=usd")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+
+		var got Allocation
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Value.Equal(decimal.NewFromFloat(6500)) || !got.Allocation["BTC"].Equal(decimal.New(2500, 0).Div(decimal.New(6500, 0))) {
+			t.Errorf("got %v want 6500 with BTC at 2500/6500", got)
+		}
+	})
+	t.Run("endpoints only accept their method", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/rebalance")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("got %d want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package websocket_test
+
+import (
+	"bytes"
+	"context"
+	. "github.com/pdbrito/rebalancer/internal/websocket"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(bytes.ToUpper(message))
+		}
+	}))
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("messages are exchanged with the server", func(t *testing.T) {
+		for _, message := range []string{"hello", strings.Repeat("x", 200), strings.Repeat("y", 70000)} {
+			if err := conn.WriteMessage([]byte(message)); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			got, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got) != strings.ToUpper(message) {
+				t.Errorf("got %d bytes want %d", len(got), len(message))
+			}
+		}
+	})
+
+	t.Run("closing ends the stream", func(t *testing.T) {
+		conn.Close()
+
+		if _, err := conn.ReadMessage(); err == nil || err == io.ErrUnexpectedEOF {
+			t.Errorf("got %v want an error from the closed connection", err)
+		}
+	})
+}
+
+func TestDial(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"))
+
+	if err != ErrHandshake {
+		t.Errorf("got %v want %v", err, ErrHandshake)
+	}
+}
@@ -0,0 +1,102 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// valueTolerance is the relative difference in value below which two
+// valuations are considered equal, absorbing the rounding of decimal division.
+var valueTolerance = decimal.New(1, -12)
+
+// ConservesValue reports whether executing trades leaves the value of account
+// unchanged when valued with pricelist, that is whether the proceeds of the
+// sells exactly pay for the buys.
+func ConservesValue(account Account, trades map[Asset]Trade, pricelist Pricelist) bool {
+	before, ok := valuePortfolio(account.portfolio, pricelist)
+	if !ok {
+		return false
+	}
+	after, ok := valuePortfolio(applyTrades(account.portfolio, trades), pricelist)
+	if !ok {
+		return false
+	}
+	return after.Sub(before).Abs().LessThanOrEqual(before.Mul(valueTolerance))
+}
+
+// AchievesIndex reports whether executing trades leaves account allocated
+// according to index, with every asset's weight within epsilon of its target.
+// Assets held after the trades but missing from index must have a weight of 0.
+func AchievesIndex(account Account, trades map[Asset]Trade, index map[Asset]decimal.Decimal, epsilon decimal.Decimal) bool {
+	portfolio := applyTrades(account.portfolio, trades)
+	total, ok := valuePortfolio(portfolio, account.pricelist)
+	if !ok || !total.IsPositive() {
+		return false
+	}
+
+	for asset, amount := range portfolio {
+		weight := amount.Mul(account.pricelist[asset]).Div(total)
+		if weight.Sub(index[asset]).Abs().GreaterThan(epsilon) {
+			return false
+		}
+	}
+	for asset, target := range index {
+		if _, ok := portfolio[asset]; !ok && target.GreaterThan(epsilon) {
+			return false
+		}
+	}
+	return true
+}
+
+// TrackingError returns how far executing trades leaves account from index,
+// as the fraction of its value which would still have to be traded to reach
+// it: half the sum of the absolute differences between each asset's weight
+// and its target.
+func TrackingError(account Account, trades map[Asset]Trade, index map[Asset]decimal.Decimal) decimal.Decimal {
+	portfolio := applyTrades(account.portfolio, trades)
+	total, ok := valuePortfolio(portfolio, account.pricelist)
+	if !ok || !total.IsPositive() {
+		return decimal.New(1, 0)
+	}
+
+	difference := decimal.Zero
+	for asset, amount := range portfolio {
+		weight := amount.Mul(account.pricelist[asset]).Div(total)
+		difference = difference.Add(weight.Sub(index[asset]).Abs())
+	}
+	for asset, target := range index {
+		if _, ok := portfolio[asset]; !ok {
+			difference = difference.Add(target)
+		}
+	}
+	return difference.Div(decimal.New(2, 0))
+}
+
+// applyTrades returns a copy of portfolio with trades executed against it.
+func applyTrades(portfolio Portfolio, trades map[Asset]Trade) Portfolio {
+	result := Portfolio{}
+	for asset, amount := range portfolio {
+		result[asset] = amount
+	}
+	for asset, trade := range trades {
+		if trade.Action == Sell {
+			result[asset] = result[asset].Sub(trade.Amount)
+			continue
+		}
+		result[asset] = result[asset].Add(trade.Amount)
+	}
+	return result
+}
+
+// valuePortfolio sums the value of portfolio at pricelist, reporting false if
+// any asset is missing a price.
+func valuePortfolio(portfolio Portfolio, pricelist Pricelist) (decimal.Decimal, bool) {
+	total := decimal.Zero
+	for asset, amount := range portfolio {
+		price, ok := pricelist[asset]
+		if !ok {
+			return decimal.Zero, false
+		}
+		total = total.Add(amount.Mul(price))
+	}
+	return total, true
+}
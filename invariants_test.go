@@ -0,0 +1,129 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestConservesValue(t *testing.T) {
+	pricelist := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	}
+	_ = SetPricelist(pricelist)
+
+	account, err := NewAccount(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("self-financing trades conserve value", func(t *testing.T) {
+		trades := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(10)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.4)},
+		}
+
+		if !ConservesValue(account, trades, pricelist) {
+			t.Error("got false want true")
+		}
+	})
+	t.Run("unfunded trades do not conserve value", func(t *testing.T) {
+		trades := map[Asset]Trade{
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.4)},
+		}
+
+		if ConservesValue(account, trades, pricelist) {
+			t.Error("got true want false")
+		}
+	})
+}
+
+func TestAchievesIndex(t *testing.T) {
+	_ = SetPricelist(Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	account, err := NewAccount(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("trades reaching the index achieve it", func(t *testing.T) {
+		trades := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(3.75)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.15)},
+		}
+
+		if !AchievesIndex(account, trades, index, decimal.Zero) {
+			t.Error("got false want true")
+		}
+	})
+	t.Run("trades close to the index achieve it within epsilon", func(t *testing.T) {
+		trades := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(3.7)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.148)},
+		}
+
+		if AchievesIndex(account, trades, index, decimal.Zero) {
+			t.Error("got true want false without an epsilon")
+		}
+		if !AchievesIndex(account, trades, index, decimal.NewFromFloat(0.01)) {
+			t.Error("got false want true with an epsilon of 0.01")
+		}
+	})
+}
+
+func TestTrackingError(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(60),
+		"BTC": decimal.NewFromFloat(40),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("the untraded fraction of value is reported", func(t *testing.T) {
+		got := TrackingError(account, map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(4)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(4)},
+		}, index)
+
+		if !got.Equal(decimal.NewFromFloat(0.06)) {
+			t.Errorf("got %v want %v", got, 0.06)
+		}
+	})
+	t.Run("trades reaching the index have no tracking error", func(t *testing.T) {
+		got := TrackingError(account, map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(10)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(10)},
+		}, index)
+
+		if !got.IsZero() {
+			t.Errorf("got %v want 0", got)
+		}
+	})
+}
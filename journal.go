@@ -0,0 +1,198 @@
+package rebalancer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// A JournalEntryKind says what a JournalEntry records.
+type JournalEntryKind string
+
+const (
+	// PlanEntry records a generated plan.
+	PlanEntry JournalEntryKind = "plan"
+	// TradeEntry records an order accepted by an executor.
+	TradeEntry JournalEntryKind = "trade"
+	// FailedTradeEntry records an order an executor rejected.
+	FailedTradeEntry JournalEntryKind = "failed trade"
+)
+
+// A JournalEntry records a plan or a trade along with the prices it was made
+// with and the reason given for it.
+type JournalEntry struct {
+	Kind JournalEntryKind
+	Time time.Time
+	// PlanHash is the Hash of the plan, or of the plan the trade executed.
+	PlanHash string
+	// PricelistHash is the Hash of the plan's pricelist.
+	PricelistHash string
+	Reason        string         `json:",omitempty"`
+	Plan          *RebalancePlan `json:",omitempty"`
+	Asset         Asset          `json:",omitempty"`
+	Order         *Order         `json:",omitempty"`
+	Fill          *Fill          `json:",omitempty"`
+	Error         string         `json:",omitempty"`
+}
+
+// A JournalQuery selects journal entries. Zero fields match every entry.
+type JournalQuery struct {
+	// From and To select entries recorded at or after From and before To.
+	From time.Time
+	To   time.Time
+	// Asset selects the trades of Asset, and the plans which trade it.
+	Asset Asset
+	Kind  JournalEntryKind
+}
+
+// matches reports whether entry is selected by q.
+func (q JournalQuery) matches(entry JournalEntry) bool {
+	if !q.From.IsZero() && entry.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !entry.Time.Before(q.To) {
+		return false
+	}
+	if q.Kind != "" && entry.Kind != q.Kind {
+		return false
+	}
+	if q.Asset != "" {
+		if entry.Plan != nil {
+			_, ok := entry.Plan.trades[q.Asset]
+			return ok
+		}
+		return entry.Asset == q.Asset
+	}
+	return true
+}
+
+// A Journal is an append-only audit log of the plans generated and the trades
+// executed, for compliance and debugging. Each entry is written to the
+// journal's writer as a line of JSON as it is recorded. It is safe for
+// concurrent use.
+type Journal struct {
+	mu      sync.Mutex
+	w       io.Writer
+	entries []JournalEntry
+}
+
+// NewJournal returns an empty Journal writing its entries to w, which may be
+// nil to keep them in memory only.
+func NewJournal(w io.Writer) *Journal {
+	return &Journal{w: w}
+}
+
+// OpenJournal returns a Journal holding the entries read from r, as written
+// by a previous Journal, which writes further entries to w.
+func OpenJournal(r io.Reader, w io.Writer) (*Journal, error) {
+	j := NewJournal(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		j.entries = append(j.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// RecordPlan records plan, generated for reason.
+func (j *Journal) RecordPlan(plan RebalancePlan, reason string) error {
+	return j.record(JournalEntry{
+		Kind:          PlanEntry,
+		Time:          time.Now(),
+		PlanHash:      plan.Hash(),
+		PricelistHash: plan.Pricelist.Hash(),
+		Reason:        reason,
+		Plan:          &plan,
+	})
+}
+
+// RecordExecution records every order in report, placed to execute plan for
+// reason, as a trade or a failed trade.
+func (j *Journal) RecordExecution(plan RebalancePlan, report *ExecutionReport, reason string) error {
+	planHash, pricelistHash := plan.Hash(), plan.Pricelist.Hash()
+	for _, order := range report.Orders() {
+		order := order
+		entry := JournalEntry{
+			Kind:          TradeEntry,
+			Time:          time.Now(),
+			PlanHash:      planHash,
+			PricelistHash: pricelistHash,
+			Reason:        reason,
+			Asset:         order.Order.Asset,
+			Order:         &order.Order,
+			Fill:          &order.Fill,
+		}
+		if order.State == Rejected {
+			entry.Kind = FailedTradeEntry
+			entry.Fill = nil
+			if order.Err != nil {
+				entry.Error = order.Err.Error()
+			}
+		}
+		if err := j.record(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record appends entry to the journal, writing it out first.
+func (j *Journal) record(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.w != nil {
+		if err := json.NewEncoder(j.w).Encode(entry); err != nil {
+			return err
+		}
+	}
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+// Entries returns the entries selected by query in the order they were
+// recorded.
+func (j *Journal) Entries(query JournalQuery) []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var entries []JournalEntry
+	for _, entry := range j.entries {
+		if query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// WithJournal records every plan Rebalance returns in journal, giving reason
+// as the reason it was generated. Rebalance fails if the plan cannot be
+// recorded.
+func WithJournal(journal *Journal, reason string) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.journal = journal
+		c.journalReason = reason
+	}
+}
+
+// Hash returns a hex encoded SHA-256 digest of the pricelist, which identifies
+// the prices a plan was made with.
+func (p Pricelist) Hash() string {
+	h := sha256.New()
+	for _, asset := range sortedAssets(p) {
+		h.Write([]byte(string(asset) + "=" + p[asset].String() + "|"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
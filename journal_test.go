@@ -0,0 +1,90 @@
+package rebalancer_test
+
+import (
+	"bytes"
+	"errors"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	journal := NewJournal(&buf)
+	start := time.Now()
+
+	plan, err := account.Rebalance(Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, WithJournal(journal, "monthly rebalance"))
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	report := NewExecutionReport()
+	report.Submitted(Order{Asset: "ETH", Trade: plan.Trades()["ETH"]}, "order-1")
+	report.Rejected(Order{Asset: "BTC", Trade: plan.Trades()["BTC"]}, errors.New("insufficient funds"))
+	if err := journal.RecordExecution(plan, report, "monthly rebalance"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("plans and trades are recorded with their pricelist hash and reason", func(t *testing.T) {
+		got := journal.Entries(JournalQuery{})
+
+		if len(got) != 3 {
+			t.Fatalf("got %d entries want 3", len(got))
+		}
+		if got[0].Kind != PlanEntry || got[0].PlanHash != plan.Hash() || got[0].Reason != "monthly rebalance" {
+			t.Errorf("got %v want the plan recorded first", got[0])
+		}
+		if got[1].PricelistHash != plan.Pricelist.Hash() {
+			t.Errorf("got pricelist hash %s want %s", got[1].PricelistHash, plan.Pricelist.Hash())
+		}
+		if got[2].Kind != FailedTradeEntry || got[2].Error != "insufficient funds" {
+			t.Errorf("got %v want the failed BTC trade", got[2])
+		}
+	})
+
+	t.Run("entries are selected by asset", func(t *testing.T) {
+		got := journal.Entries(JournalQuery{Asset: "ETH", Kind: TradeEntry})
+
+		if len(got) != 1 || got[0].Order.Asset != "ETH" {
+			t.Errorf("got %v want the ETH trade", got)
+		}
+	})
+
+	t.Run("entries are selected by date range", func(t *testing.T) {
+		if got := journal.Entries(JournalQuery{From: start}); len(got) != 3 {
+			t.Errorf("got %d entries want 3", len(got))
+		}
+		if got := journal.Entries(JournalQuery{To: start}); len(got) != 0 {
+			t.Errorf("got %d entries want none", len(got))
+		}
+	})
+
+	t.Run("a journal is reopened from what it wrote", func(t *testing.T) {
+		reopened, err := OpenJournal(bytes.NewReader(buf.Bytes()), nil)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		got := reopened.Entries(JournalQuery{Asset: "BTC"})
+		if len(got) != 2 || got[0].Plan.Hash() != plan.Hash() {
+			t.Errorf("got %v want the plan and the BTC trade", got)
+		}
+	})
+}
@@ -0,0 +1,373 @@
+package rebalancer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// A Format is a file format data can be loaded from.
+type Format string
+
+// The formats supported by the Load functions.
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// ErrUnsupportedFormat indicates data was loaded from a format the Load
+// function does not support.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// ErrMalformedRecord indicates a line of loaded data could not be parsed.
+type ErrMalformedRecord struct {
+	Line   int
+	Reason string
+}
+
+// Error formats the error message for ErrMalformedRecord.
+func (e ErrMalformedRecord) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// ErrDuplicateAsset indicates loaded data lists an asset more than once.
+type ErrDuplicateAsset struct {
+	Asset Asset
+}
+
+// Error formats the error message for ErrDuplicateAsset.
+func (e ErrDuplicateAsset) Error() string {
+	return fmt.Sprintf("%s is listed more than once", e.Asset)
+}
+
+// LoadPricelist reads a pricelist from r and validates it as NewPricelist
+// does. CSV data has an asset and a price on each line, optionally preceded
+// by a header line; JSON data is an object of assets to prices, which may be
+// numbers or strings.
+func LoadPricelist(r io.Reader, format Format) (Pricelist, error) {
+	switch format {
+	case CSV:
+		return loadPricelistCSV(r)
+	case JSON:
+		var pricelist map[Asset]decimal.Decimal
+		if err := json.NewDecoder(r).Decode(&pricelist); err != nil {
+			return nil, err
+		}
+		return NewPricelist(pricelist)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// loadPricelistCSV reads asset,price lines from r.
+func loadPricelistCSV(r io.Reader) (Pricelist, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	pricelist := map[Asset]decimal.Decimal{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		price, err := decimal.NewFromString(strings.TrimSpace(record[1]))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, ErrMalformedRecord{Line: line, Reason: fmt.Sprintf("invalid price %q", record[1])}
+		}
+		asset := Asset(strings.TrimSpace(record[0]))
+		if _, ok := pricelist[asset]; ok {
+			return nil, ErrDuplicateAsset{Asset: asset}
+		}
+		pricelist[asset] = price
+	}
+	return NewPricelist(pricelist)
+}
+
+// ErrMissingColumn indicates loaded CSV data has no column with the header a
+// mapping expects.
+type ErrMissingColumn struct {
+	Column string
+}
+
+// Error formats the error message for ErrMissingColumn.
+func (e ErrMissingColumn) Error() string {
+	return fmt.Sprintf("no %q column", e.Column)
+}
+
+// A PortfolioMapping describes how the columns of a CSV export map onto a
+// Portfolio.
+type PortfolioMapping struct {
+	// Asset and Amount are the headers of the columns holding each
+	// position's symbol and amount, matched ignoring case. They default to
+	// "asset" and "amount".
+	Asset  string
+	Amount string
+	// Normalize turns a symbol into an asset, for example by stripping an
+	// exchange suffix. Symbols are trimmed and uppercased by default.
+	Normalize func(symbol string) Asset
+	// Aliases renames normalized assets, such as XBT to BTC.
+	Aliases map[Asset]Asset
+}
+
+// normalize returns the asset symbol refers to.
+func (m PortfolioMapping) normalize(symbol string) Asset {
+	var asset Asset
+	if m.Normalize != nil {
+		asset = m.Normalize(symbol)
+	} else {
+		asset = Asset(strings.ToUpper(strings.TrimSpace(symbol)))
+	}
+	if alias, ok := m.Aliases[asset]; ok {
+		return alias
+	}
+	return asset
+}
+
+// LoadPortfolioCSV reads a portfolio from CSV data with a header line, such as
+// a broker's positions export, using mapping to find and normalize its
+// columns. Amounts may contain thousands separators. Positions in the same
+// asset are summed, and rows without a symbol or with a zero amount are
+// skipped. The assets are not checked against a pricelist until the portfolio
+// is given to an Account.
+func LoadPortfolioCSV(r io.Reader, mapping PortfolioMapping) (Portfolio, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyPortfolio
+	}
+	if err != nil {
+		return nil, err
+	}
+	assetColumn, err := column(header, mapping.Asset, "asset")
+	if err != nil {
+		return nil, err
+	}
+	amountColumn, err := column(header, mapping.Amount, "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio := Portfolio{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if assetColumn >= len(record) || strings.TrimSpace(record[assetColumn]) == "" {
+			continue
+		}
+		if amountColumn >= len(record) {
+			return nil, ErrMalformedRecord{Line: line, Reason: "missing amount"}
+		}
+		amount, err := decimal.NewFromString(strings.NewReplacer(",", "", " ", "").Replace(record[amountColumn]))
+		if err != nil {
+			return nil, ErrMalformedRecord{Line: line, Reason: fmt.Sprintf("invalid amount %q", record[amountColumn])}
+		}
+		if amount.IsZero() {
+			continue
+		}
+		asset := mapping.normalize(record[assetColumn])
+		portfolio[asset] = portfolio[asset].Add(amount)
+	}
+
+	if len(portfolio) == 0 {
+		return nil, ErrEmptyPortfolio
+	}
+	for asset, amount := range portfolio {
+		if string(asset) != strings.ToUpper(string(asset)) {
+			return nil, ErrInvalidAsset
+		}
+		if !amount.IsPositive() {
+			return nil, ErrInvalidAssetAmount{Asset: asset, Amount: amount}
+		}
+	}
+	return portfolio, nil
+}
+
+// column returns the index of the header matching name, or fallback when name
+// is empty.
+func column(header []string, name, fallback string) (int, error) {
+	if name == "" {
+		name = fallback
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i, nil
+		}
+	}
+	return 0, ErrMissingColumn{Column: name}
+}
+
+// LoadIndex reads a target index from r and validates that its weights are
+// positive and sum to 1. The data maps each asset to its weight, given as a
+// fraction such as 0.6, a percentage such as "60%" or basis points such as
+// "6000bps". YAML data is read as a flat mapping of one asset per line;
+// anchors, flow style and nesting are not supported. Whether the assets are
+// priced is checked when the index is used.
+func LoadIndex(r io.Reader, format Format) (Index, error) {
+	var weights map[Asset]string
+	var err error
+	switch format {
+	case JSON:
+		weights, err = loadWeightsJSON(r)
+	case YAML:
+		weights, err = loadWeightsYAML(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(weights) == 0 {
+		return nil, ErrEmptyIndex
+	}
+	index := Index{}
+	total := decimal.Zero
+	for asset, value := range weights {
+		weight, err := parseWeight(value)
+		if err != nil {
+			return nil, ErrInvalidWeight{Asset: asset, Value: value}
+		}
+		if string(asset) != strings.ToUpper(string(asset)) {
+			return nil, ErrInvalidAsset
+		}
+		if !weight.IsPositive() {
+			return nil, ErrInvalidAssetAmount{Asset: asset, Amount: weight}
+		}
+		index[asset] = weight
+		total = total.Add(weight)
+	}
+	if !total.Equal(decimal.New(1, 0)) {
+		return nil, ErrIndexSumIncorrect
+	}
+	return index, nil
+}
+
+// ErrInvalidWeight indicates a loaded index weight is not a number, percentage
+// or number of basis points.
+type ErrInvalidWeight struct {
+	Asset Asset
+	Value string
+}
+
+// Error formats the error message for ErrInvalidWeight.
+func (e ErrInvalidWeight) Error() string {
+	return fmt.Sprintf("invalid weight %q for %s", e.Value, e.Asset)
+}
+
+// parseWeight parses a fraction, a percentage or a number of basis points.
+func parseWeight(value string) (decimal.Decimal, error) {
+	value = strings.TrimSpace(value)
+	scale := decimal.New(1, 0)
+	switch {
+	case strings.HasSuffix(value, "%"):
+		value, scale = strings.TrimSuffix(value, "%"), decimal.New(1, -2)
+	case strings.HasSuffix(value, "bps"):
+		value, scale = strings.TrimSuffix(value, "bps"), decimal.New(1, -4)
+	case strings.HasSuffix(value, "bp"):
+		value, scale = strings.TrimSuffix(value, "bp"), decimal.New(1, -4)
+	}
+	weight, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return weight.Mul(scale), nil
+}
+
+// loadWeightsJSON reads an object of assets to numbers or strings.
+func loadWeightsJSON(r io.Reader) (map[Asset]string, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var raw map[Asset]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	weights := map[Asset]string{}
+	for asset, value := range raw {
+		switch v := value.(type) {
+		case json.Number:
+			weights[asset] = v.String()
+		case string:
+			weights[asset] = v
+		default:
+			return nil, ErrInvalidWeight{Asset: asset, Value: fmt.Sprint(value)}
+		}
+	}
+	return weights, nil
+}
+
+// loadWeightsYAML reads a flat YAML mapping of assets to scalars.
+func loadWeightsYAML(r io.Reader) (map[Asset]string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	weights := map[Asset]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \t\r")
+		if strings.TrimSpace(line) == "" || line == "---" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, ErrMalformedRecord{Line: i + 1, Reason: "nested values are not supported"}
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, ErrMalformedRecord{Line: i + 1, Reason: "expected asset: weight"}
+		}
+		asset := Asset(unquoteYAML(line[:colon]))
+		if _, ok := weights[asset]; ok {
+			return nil, ErrDuplicateAsset{Asset: asset}
+		}
+		weights[asset] = unquoteYAML(line[colon+1:])
+	}
+	return weights, nil
+}
+
+// stripYAMLComment removes a trailing comment from line, ignoring any # within
+// quotes.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquoteYAML trims s and removes the quotes around it, if any.
+func unquoteYAML(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
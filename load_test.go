@@ -0,0 +1,206 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"strings"
+	"testing"
+)
+
+func TestLoadPricelist(t *testing.T) {
+	t.Run("prices are loaded from CSV", func(t *testing.T) {
+		got, err := LoadPricelist(strings.NewReader("asset,price\nETH, 200\nBTC,5000.5\n"), CSV)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["BTC"].Equal(decimal.NewFromFloat(5000.5)) || !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want the ETH and BTC prices", got)
+		}
+	})
+	t.Run("the CSV header is optional", func(t *testing.T) {
+		got, err := LoadPricelist(strings.NewReader("ETH,200\n"), CSV)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 1 {
+			t.Errorf("got %v want the ETH price", got)
+		}
+	})
+	t.Run("prices are loaded from JSON", func(t *testing.T) {
+		got, err := LoadPricelist(strings.NewReader(`{"ETH": 200, "BTC": "5000"}`), JSON)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["BTC"].Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %v want the ETH and BTC prices", got)
+		}
+	})
+	t.Run("malformed prices are reported by line", func(t *testing.T) {
+		_, err := LoadPricelist(strings.NewReader("asset,price\nETH,200\nBTC,lots\n"), CSV)
+
+		want := ErrMalformedRecord{Line: 3, Reason: `invalid price "lots"`}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+	t.Run("assets listed twice are rejected", func(t *testing.T) {
+		_, err := LoadPricelist(strings.NewReader("ETH,200\nETH,201\n"), CSV)
+
+		if err != (ErrDuplicateAsset{Asset: "ETH"}) {
+			t.Errorf("got %v want %v", err, ErrDuplicateAsset{Asset: "ETH"})
+		}
+	})
+	t.Run("prices are validated like SetPricelist", func(t *testing.T) {
+		_, err := LoadPricelist(strings.NewReader("eth,200\n"), CSV)
+		if err != ErrInvalidAsset {
+			t.Errorf("got %v want %v", err, ErrInvalidAsset)
+		}
+
+		_, err = LoadPricelist(strings.NewReader(`{}`), JSON)
+		if err != ErrEmptyPricelist {
+			t.Errorf("got %v want %v", err, ErrEmptyPricelist)
+		}
+
+		_, err = LoadPricelist(strings.NewReader("ETH,0\n"), CSV)
+		if _, ok := err.(ErrInvalidAssetAmount); !ok {
+			t.Errorf("got %v want an ErrInvalidAssetAmount", err)
+		}
+	})
+	t.Run("unknown formats are rejected", func(t *testing.T) {
+		_, err := LoadPricelist(strings.NewReader(""), Format("xml"))
+
+		if err != ErrUnsupportedFormat {
+			t.Errorf("got %v want %v", err, ErrUnsupportedFormat)
+		}
+	})
+}
+
+func TestLoadPortfolioCSV(t *testing.T) {
+	export := `Account,Symbol,Description,Quantity
+main,xbt,Bitcoin,"1,000.5"
+main,ETH,Ether,20
+savings,ETH,Ether,5
+main,DOGE,Dogecoin,0
+,,Total,
+`
+	mapping := PortfolioMapping{
+		Asset:   "symbol",
+		Amount:  "Quantity",
+		Aliases: map[Asset]Asset{"XBT": "BTC"},
+	}
+
+	t.Run("positions are mapped onto a portfolio", func(t *testing.T) {
+		got, err := LoadPortfolioCSV(strings.NewReader(export), mapping)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["BTC"].Equal(decimal.NewFromFloat(1000.5)) || !got["ETH"].Equal(decimal.NewFromFloat(25)) {
+			t.Errorf("got %v want 1000.5 BTC and 25 ETH", got)
+		}
+	})
+	t.Run("symbols can be normalized", func(t *testing.T) {
+		mapping := PortfolioMapping{Normalize: func(symbol string) Asset {
+			return Asset(strings.TrimSuffix(symbol, ".US"))
+		}}
+
+		got, err := LoadPortfolioCSV(strings.NewReader("asset,amount\nVTI.US,10\n"), mapping)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got["VTI"].Equal(decimal.NewFromFloat(10)) {
+			t.Errorf("got %v want 10 VTI", got)
+		}
+	})
+	t.Run("missing columns are reported", func(t *testing.T) {
+		_, err := LoadPortfolioCSV(strings.NewReader(export), PortfolioMapping{Asset: "Symbol"})
+
+		if err != (ErrMissingColumn{Column: "amount"}) {
+			t.Errorf("got %v want %v", err, ErrMissingColumn{Column: "amount"})
+		}
+	})
+	t.Run("malformed amounts are reported by line", func(t *testing.T) {
+		_, err := LoadPortfolioCSV(strings.NewReader("asset,amount\nETH,20\nBTC,n/a\n"), PortfolioMapping{})
+
+		want := ErrMalformedRecord{Line: 3, Reason: `invalid amount "n/a"`}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+	t.Run("short positions are rejected", func(t *testing.T) {
+		_, err := LoadPortfolioCSV(strings.NewReader("asset,amount\nETH,-2\n"), PortfolioMapping{})
+
+		if _, ok := err.(ErrInvalidAssetAmount); !ok {
+			t.Errorf("got %v want an ErrInvalidAssetAmount", err)
+		}
+	})
+	t.Run("exports without positions are rejected", func(t *testing.T) {
+		_, err := LoadPortfolioCSV(strings.NewReader("asset,amount\n"), PortfolioMapping{})
+
+		if err != ErrEmptyPortfolio {
+			t.Errorf("got %v want %v", err, ErrEmptyPortfolio)
+		}
+	})
+}
+
+func TestLoadIndex(t *testing.T) {
+	t.Run("weights are loaded from YAML", func(t *testing.T) {
+		got, err := LoadIndex(strings.NewReader(`---
+# core holdings
+ETH: 60%   # growth
+"BTC": '2500bps'
+XLM: 0.15
+`), YAML)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 3 || !got["ETH"].Equal(decimal.NewFromFloat(0.6)) || !got["BTC"].Equal(decimal.NewFromFloat(0.25)) || !got["XLM"].Equal(decimal.NewFromFloat(0.15)) {
+			t.Errorf("got %v want ETH 0.6, BTC 0.25 and XLM 0.15", got)
+		}
+	})
+	t.Run("weights are loaded from JSON", func(t *testing.T) {
+		got, err := LoadIndex(strings.NewReader(`{"ETH": "60%", "BTC": 0.4}`), JSON)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["ETH"].Equal(decimal.NewFromFloat(0.6)) || !got["BTC"].Equal(decimal.NewFromFloat(0.4)) {
+			t.Errorf("got %v want ETH 0.6 and BTC 0.4", got)
+		}
+	})
+	t.Run("weights must sum to 1", func(t *testing.T) {
+		_, err := LoadIndex(strings.NewReader("ETH: 60%\nBTC: 30%\n"), YAML)
+
+		if err != ErrIndexSumIncorrect {
+			t.Errorf("got %v want %v", err, ErrIndexSumIncorrect)
+		}
+	})
+	t.Run("invalid weights are reported", func(t *testing.T) {
+		_, err := LoadIndex(strings.NewReader("ETH: most\n"), YAML)
+
+		want := ErrInvalidWeight{Asset: "ETH", Value: "most"}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+	t.Run("nested YAML is rejected", func(t *testing.T) {
+		_, err := LoadIndex(strings.NewReader("index:\n  ETH: 100%\n"), YAML)
+
+		want := ErrMalformedRecord{Line: 2, Reason: "nested values are not supported"}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+	t.Run("empty indexes are rejected", func(t *testing.T) {
+		_, err := LoadIndex(strings.NewReader("# nothing yet\n"), YAML)
+
+		if err != ErrEmptyIndex {
+			t.Errorf("got %v want %v", err, ErrEmptyIndex)
+		}
+	})
+}
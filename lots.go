@@ -0,0 +1,356 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+// A Lot is a quantity of an asset acquired in a single purchase, used to
+// estimate the gains realised by selling it.
+type Lot struct {
+	// Quantity is the amount of the asset remaining in the lot.
+	Quantity decimal.Decimal
+	// CostBasis is the price paid per unit of the asset.
+	CostBasis decimal.Decimal
+	// Acquired is when the lot was bought.
+	Acquired time.Time
+}
+
+// Lots contains a map of Assets and the lots which make up their holding.
+type Lots map[Asset][]Lot
+
+// ErrInvalidCostBasis indicates a lot with a negative cost basis.
+var ErrInvalidCostBasis = errors.New("lot cost basis must not be negative")
+
+// NewPortfolioWithLots validates lots and returns the Portfolio holding their
+// combined quantities, whose assets are all priced by the global pricelist.
+func NewPortfolioWithLots(lots map[Asset][]Lot) (Portfolio, error) {
+	return newPortfolioWithLots(lots, GlobalPricelist())
+}
+
+func newPortfolioWithLots(lots map[Asset][]Lot, pricelist Pricelist) (Portfolio, error) {
+	portfolio := Portfolio{}
+	for asset, assetLots := range lots {
+		total := decimal.Zero
+		for _, lot := range assetLots {
+			if !lot.Quantity.IsPositive() {
+				return nil, ErrInvalidAssetAmount{Asset: asset, Amount: lot.Quantity}
+			}
+			if lot.CostBasis.IsNegative() {
+				return nil, ErrInvalidCostBasis
+			}
+			total = total.Add(lot.Quantity)
+		}
+		portfolio[asset] = total
+	}
+	return newPortfolio(portfolio, pricelist)
+}
+
+// NewAccountWithLots validates lots and pricelist and then returns a new
+// Account struct holding the combined quantities of lots, which keeps track
+// of them for lot selection and gain estimation.
+func NewAccountWithLots(lots map[Asset][]Lot, pricelist map[Asset]decimal.Decimal) (Account, error) {
+	prices, err := NewPricelist(pricelist)
+	if err != nil {
+		return Account{}, err
+	}
+	portfolio, err := newPortfolioWithLots(lots, prices)
+	if err != nil {
+		return Account{}, err
+	}
+	account, err := NewAccountWithPricelist(portfolio, prices)
+	if err != nil {
+		return Account{}, err
+	}
+	account.lots = Lots{}
+	for asset, assetLots := range lots {
+		account.lots[asset] = append([]Lot(nil), assetLots...)
+	}
+	return account, nil
+}
+
+// Lots returns a copy of the lots held by the account, or nil if it does not
+// track lots.
+func (a Account) Lots() Lots {
+	if a.lots == nil {
+		return nil
+	}
+	lots := Lots{}
+	for asset, assetLots := range a.lots {
+		lots[asset] = append([]Lot(nil), assetLots...)
+	}
+	return lots
+}
+
+// A LotSelector chooses which of an asset's lots a sell of quantity units at
+// price is taken from. It returns the lots sold, with their quantities reduced
+// to the amount sold from each.
+type LotSelector interface {
+	SelectLots(lots []Lot, quantity, price decimal.Decimal) []Lot
+}
+
+// FIFO sells the lots acquired first.
+type FIFO struct{}
+
+// SelectLots implements LotSelector.
+func (FIFO) SelectLots(lots []Lot, quantity, price decimal.Decimal) []Lot {
+	return takeLots(lots, quantity, func(a, b Lot) bool {
+		return a.Acquired.Before(b.Acquired)
+	})
+}
+
+// LIFO sells the lots acquired last.
+type LIFO struct{}
+
+// SelectLots implements LotSelector.
+func (LIFO) SelectLots(lots []Lot, quantity, price decimal.Decimal) []Lot {
+	return takeLots(lots, quantity, func(a, b Lot) bool {
+		return a.Acquired.After(b.Acquired)
+	})
+}
+
+// HIFO sells the lots with the highest cost basis, realising the smallest
+// gains.
+type HIFO struct{}
+
+// SelectLots implements LotSelector.
+func (HIFO) SelectLots(lots []Lot, quantity, price decimal.Decimal) []Lot {
+	return takeLots(lots, quantity, func(a, b Lot) bool {
+		return a.CostBasis.GreaterThan(b.CostBasis)
+	})
+}
+
+// MinTax sells lots in the order which least increases tax where long-term
+// gains are taxed more lightly than short-term ones: short-term losses first,
+// then long-term losses, long-term gains and finally short-term gains, each
+// largest loss or smallest gain first. A lot is long-term once it has been
+// held for longer than LongTerm at Now, which defaults to the current time.
+type MinTax struct {
+	LongTerm time.Duration
+	Now      time.Time
+}
+
+// SelectLots implements LotSelector.
+func (m MinTax) SelectLots(lots []Lot, quantity, price decimal.Decimal) []Lot {
+	now := m.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	rank := func(lot Lot) int {
+		gain := !lot.CostBasis.GreaterThan(price)
+		longTerm := now.Sub(lot.Acquired) > m.LongTerm
+		switch {
+		case !gain && !longTerm:
+			return 0
+		case !gain:
+			return 1
+		case longTerm:
+			return 2
+		}
+		return 3
+	}
+	return takeLots(lots, quantity, func(a, b Lot) bool {
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return a.CostBasis.GreaterThan(b.CostBasis)
+	})
+}
+
+// RealizedGains returns the total short-term and long-term gains realised by
+// trades. Losses are negative.
+func RealizedGains(trades map[Asset]Trade) (shortTerm, longTerm decimal.Decimal) {
+	shortTerm, longTerm = decimal.Zero, decimal.Zero
+	for _, trade := range trades {
+		shortTerm = shortTerm.Add(trade.ShortTermGain)
+		longTerm = longTerm.Add(trade.LongTermGain)
+	}
+	return shortTerm, longTerm
+}
+
+// lotGains returns the short-term and long-term gains realised by selling lots
+// at price. Lots held for longer than threshold at now are long-term; if
+// threshold is not positive every gain is short-term.
+func lotGains(lots []Lot, price decimal.Decimal, now time.Time, threshold time.Duration) (shortTerm, longTerm decimal.Decimal) {
+	shortTerm, longTerm = decimal.Zero, decimal.Zero
+	for _, lot := range lots {
+		gain := price.Sub(lot.CostBasis).Mul(lot.Quantity)
+		if threshold > 0 && now.Sub(lot.Acquired) > threshold {
+			longTerm = longTerm.Add(gain)
+			continue
+		}
+		shortTerm = shortTerm.Add(gain)
+	}
+	return shortTerm, longTerm
+}
+
+// asOf returns the time set by AsOf, or the current time.
+func (c rebalanceConfig) asOf() time.Time {
+	if c.now.IsZero() {
+		return time.Now()
+	}
+	return c.now
+}
+
+// selector returns the LotSelector configured by WithLotSelector. Otherwise it
+// is HIFO when gains are budgeted, selling losses before gains, or FIFO.
+func (c rebalanceConfig) selector() LotSelector {
+	if c.lotSelector != nil {
+		return c.lotSelector
+	}
+	if c.gainsBudget {
+		return HIFO{}
+	}
+	return FIFO{}
+}
+
+// ErrGainsBudget is returned when the sells cannot be cut back far enough to
+// keep their realised gains within WithMaxRealizedGains, for instance when the
+// budget is negative and the sells realise too few losses to meet it.
+var ErrGainsBudget = errors.New("realised gains cannot be kept within the budget")
+
+// limitGains shrinks the sells of trades until the gains they realise are no
+// more than config's budget. Each sell is cut back through its selected lots
+// from the last, so the lots still selected are the ones the selector picks
+// for the smaller sell, always cutting the lot with the highest gain relative
+// to its value. When every last lot is a loss, the whole loss lot of a sell
+// with gains selected before it is cut to reach them. The buys are shrunk
+// proportionally by the value no longer sold, so only buys funded by a
+// contribution are kept whole. The value each trade falls short by is added to
+// Trade.Residual.
+func (a Account) limitGains(trades map[Asset]Trade, config rebalanceConfig) error {
+	selected := map[Asset][]Lot{}
+	prices := map[Asset]decimal.Decimal{}
+	excess := config.maxGains.Neg()
+	for asset, trade := range trades {
+		if trade.Action != Sell || a.lots == nil {
+			continue
+		}
+		prices[asset] = a.executionPrice(trade, config)
+		selected[asset] = config.selector().SelectLots(a.lots[asset], trade.Amount, prices[asset])
+		shortTerm, longTerm := lotGains(selected[asset], prices[asset], config.asOf(), config.longTerm)
+		excess = excess.Add(shortTerm).Add(longTerm)
+	}
+
+	cut := map[Asset]decimal.Decimal{}
+	for excess.IsPositive() {
+		var asset Asset
+		rate := decimal.Zero
+		for candidate, lots := range selected {
+			if len(lots) == 0 {
+				continue
+			}
+			lot := lots[len(lots)-1]
+			r := prices[candidate].Sub(lot.CostBasis).Div(prices[candidate])
+			if r.GreaterThan(rate) || (r.Equal(rate) && asset != "" && candidate < asset) {
+				asset, rate = candidate, r
+			}
+		}
+		if asset == "" {
+			asset = lossBeforeGains(selected, prices)
+		}
+		if asset == "" {
+			break
+		}
+
+		lots := selected[asset]
+		lot := lots[len(lots)-1]
+		gain := prices[asset].Sub(lot.CostBasis)
+		quantity := lot.Quantity
+		if gain.IsPositive() && gain.Mul(quantity).GreaterThan(excess) {
+			quantity = excess.Div(gain)
+			lots[len(lots)-1].Quantity = lot.Quantity.Sub(quantity)
+		} else {
+			selected[asset] = lots[:len(lots)-1]
+		}
+		cut[asset] = cut[asset].Add(quantity)
+		excess = excess.Sub(gain.Mul(quantity))
+	}
+	if excess.IsPositive() {
+		return ErrGainsBudget
+	}
+
+	released := decimal.Zero
+	for asset, quantity := range cut {
+		trade := trades[asset]
+		trade.Amount = trade.Amount.Sub(quantity)
+		trade.Residual = trade.Residual.Add(quantity.Mul(a.pricelist[asset]))
+		trades[asset] = trade
+		released = released.Add(quantity.Mul(a.pricelist[asset]))
+	}
+
+	buys := map[Asset]decimal.Decimal{}
+	for asset, trade := range trades {
+		if trade.Action == Buy {
+			buys[asset] = trade.Amount
+		}
+	}
+	shrinkSide(trades, a.pricelist, Buy, released)
+	for asset, amount := range buys {
+		trade := trades[asset]
+		trade.Residual = trade.Residual.Add(amount.Sub(trade.Amount).Mul(a.pricelist[asset]))
+		trades[asset] = trade
+	}
+
+	// Selectors which do not take the lots of a smaller sell from those of a
+	// larger one may still pick lots realising more than the budget.
+	gains := decimal.Zero
+	for asset, price := range prices {
+		shortTerm, longTerm := lotGains(config.selector().SelectLots(a.lots[asset], trades[asset].Amount, price), price, config.asOf(), config.longTerm)
+		gains = gains.Add(shortTerm).Add(longTerm)
+	}
+	if gains.Sub(config.maxGains).GreaterThan(a.value.Mul(valueTolerance)) {
+		return ErrGainsBudget
+	}
+	return nil
+}
+
+// lossBeforeGains returns the asset whose last selected lot is the smallest
+// loss among the sells which have lots realising gains selected before it, or
+// "" if no sell has gains left.
+func lossBeforeGains(selected map[Asset][]Lot, prices map[Asset]decimal.Decimal) Asset {
+	var asset Asset
+	var smallest decimal.Decimal
+	for candidate, lots := range selected {
+		if len(lots) == 0 {
+			continue
+		}
+		gains := false
+		for _, lot := range lots[:len(lots)-1] {
+			gains = gains || prices[candidate].GreaterThan(lot.CostBasis)
+		}
+		if !gains {
+			continue
+		}
+		lot := lots[len(lots)-1]
+		loss := lot.CostBasis.Sub(prices[candidate]).Mul(lot.Quantity)
+		if asset == "" || loss.LessThan(smallest) || (loss.Equal(smallest) && candidate < asset) {
+			asset, smallest = candidate, loss
+		}
+	}
+	return asset
+}
+
+// takeLots returns the lots making up quantity, taken in the order given by
+// less. The last lot taken is cut down to the quantity still needed.
+func takeLots(lots []Lot, quantity decimal.Decimal, less func(a, b Lot) bool) []Lot {
+	ordered := append([]Lot(nil), lots...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return less(ordered[i], ordered[j])
+	})
+
+	var taken []Lot
+	for _, lot := range ordered {
+		if !quantity.IsPositive() {
+			break
+		}
+		if lot.Quantity.GreaterThan(quantity) {
+			lot.Quantity = quantity
+		}
+		taken = append(taken, lot)
+		quantity = quantity.Sub(lot.Quantity)
+	}
+	return taken
+}
@@ -0,0 +1,359 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestNewPortfolioWithLots(t *testing.T) {
+	_ = SetPricelist(Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	t.Run("the portfolio holds the combined quantity of each asset's lots", func(t *testing.T) {
+		got, err := NewPortfolioWithLots(map[Asset][]Lot{
+			"ETH": {
+				{Quantity: decimal.NewFromFloat(5), CostBasis: decimal.NewFromFloat(100)},
+				{Quantity: decimal.NewFromFloat(15), CostBasis: decimal.NewFromFloat(300)},
+			},
+			"BTC": {
+				{Quantity: decimal.NewFromFloat(0.5), CostBasis: decimal.NewFromFloat(4000)},
+			},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if !got["ETH"].Equal(decimal.NewFromFloat(20)) || !got["BTC"].Equal(decimal.NewFromFloat(0.5)) {
+			t.Errorf("got %v want 20 ETH and 0.5 BTC", got)
+		}
+	})
+	t.Run("lots must have a positive quantity", func(t *testing.T) {
+		_, err := NewPortfolioWithLots(map[Asset][]Lot{
+			"ETH": {{Quantity: decimal.Zero, CostBasis: decimal.NewFromFloat(100)}},
+		})
+
+		want := ErrInvalidAssetAmount{Asset: "ETH", Amount: decimal.Zero}
+		if err != want {
+			t.Errorf("got %v, want %s", err, want)
+		}
+	})
+	t.Run("lots must not have a negative cost basis", func(t *testing.T) {
+		_, err := NewPortfolioWithLots(map[Asset][]Lot{
+			"ETH": {{Quantity: decimal.NewFromFloat(1), CostBasis: decimal.NewFromFloat(-1)}},
+		})
+
+		if err != ErrInvalidCostBasis {
+			t.Errorf("got %v, want %s", err, ErrInvalidCostBasis)
+		}
+	})
+}
+
+func TestNewAccountWithLots(t *testing.T) {
+	acquired := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	lots := map[Asset][]Lot{
+		"ETH": {{Quantity: decimal.NewFromFloat(20), CostBasis: decimal.NewFromFloat(100), Acquired: acquired}},
+	}
+
+	account, err := NewAccountWithLots(lots, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("the account keeps a copy of its lots", func(t *testing.T) {
+		lots["ETH"][0].Quantity = decimal.NewFromFloat(1)
+
+		got := account.Lots()["ETH"]
+		if len(got) != 1 || !got[0].Quantity.Equal(decimal.NewFromFloat(20)) || !got[0].Acquired.Equal(acquired) {
+			t.Errorf("got %v want a single lot of 20 acquired on %s", got, acquired)
+		}
+	})
+	t.Run("accounts created without lots do not track them", func(t *testing.T) {
+		account, _ := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+		})
+
+		if account.Lots() != nil {
+			t.Errorf("got %v want nil", account.Lots())
+		}
+	})
+}
+
+func TestLotSelector(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	year := 365 * 24 * time.Hour
+	lots := []Lot{
+		{Quantity: decimal.NewFromFloat(10), CostBasis: decimal.NewFromFloat(100), Acquired: now.Add(-3 * year)},
+		{Quantity: decimal.NewFromFloat(10), CostBasis: decimal.NewFromFloat(300), Acquired: now.Add(-2 * year)},
+		{Quantity: decimal.NewFromFloat(10), CostBasis: decimal.NewFromFloat(150), Acquired: now.Add(-year / 2)},
+	}
+	price := decimal.NewFromFloat(200)
+
+	cases := []struct {
+		name     string
+		selector LotSelector
+		want     []decimal.Decimal
+	}{
+		{"FIFO sells the oldest lots first", FIFO{}, []decimal.Decimal{decimal.NewFromFloat(100), decimal.NewFromFloat(300)}},
+		{"LIFO sells the newest lots first", LIFO{}, []decimal.Decimal{decimal.NewFromFloat(150), decimal.NewFromFloat(300)}},
+		{"HIFO sells the costliest lots first", HIFO{}, []decimal.Decimal{decimal.NewFromFloat(300), decimal.NewFromFloat(150)}},
+		{"MinTax sells losses then long-term gains first", MinTax{LongTerm: year, Now: now}, []decimal.Decimal{decimal.NewFromFloat(300), decimal.NewFromFloat(100)}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.selector.SelectLots(lots, decimal.NewFromFloat(15), price)
+
+			if len(got) != len(c.want) {
+				t.Fatalf("got %d lots want %d", len(got), len(c.want))
+			}
+			for i, lot := range got {
+				if !lot.CostBasis.Equal(c.want[i]) {
+					t.Errorf("got a cost basis of %v want %v for lot %d", lot.CostBasis, c.want[i], i)
+				}
+			}
+			if !got[1].Quantity.Equal(decimal.NewFromFloat(5)) {
+				t.Errorf("got %v want the last lot cut down to 5", got[1].Quantity)
+			}
+		})
+	}
+}
+
+func TestRebalance_Lots(t *testing.T) {
+	account, err := NewAccountWithLots(map[Asset][]Lot{
+		"ETH": {
+			{Quantity: decimal.NewFromFloat(5), CostBasis: decimal.NewFromFloat(100), Acquired: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Quantity: decimal.NewFromFloat(15), CostBasis: decimal.NewFromFloat(300), Acquired: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("sells are taken from the oldest lots by default", func(t *testing.T) {
+		plan, err := account.Rebalance(index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		lots := got["ETH"].Lots
+		if len(lots) != 2 || !lots[0].Quantity.Equal(decimal.NewFromFloat(5)) || !lots[1].Quantity.Equal(decimal.NewFromFloat(5)) {
+			t.Errorf("got %v want 5 from each lot", lots)
+		}
+		if got["BTC"].Lots != nil {
+			t.Errorf("got %v want no lots for a buy", got["BTC"].Lots)
+		}
+	})
+	t.Run("sells are taken from the lots chosen by the selector", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithLotSelector(HIFO{}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		lots := got["ETH"].Lots
+		if len(lots) != 1 || !lots[0].CostBasis.Equal(decimal.NewFromFloat(300)) {
+			t.Errorf("got %v want 10 from the lot costing 300", lots)
+		}
+	})
+}
+
+func TestRealizedGains(t *testing.T) {
+	account, err := NewAccountWithLots(map[Asset][]Lot{
+		"ETH": {
+			{Quantity: decimal.NewFromFloat(5), CostBasis: decimal.NewFromFloat(100), Acquired: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Quantity: decimal.NewFromFloat(15), CostBasis: decimal.NewFromFloat(300), Acquired: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+	asOf := AsOf(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("gains are split by how long their lots were held", func(t *testing.T) {
+		plan, err := account.Rebalance(index, asOf, WithLongTermThreshold(365*24*time.Hour))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		trades := plan.Trades()
+
+		shortTerm, longTerm := RealizedGains(trades)
+		if !shortTerm.Equal(decimal.NewFromFloat(-500)) {
+			t.Errorf("got %v want %v short-term", shortTerm, -500)
+		}
+		if !longTerm.Equal(decimal.NewFromFloat(500)) {
+			t.Errorf("got %v want %v long-term", longTerm, 500)
+		}
+	})
+	t.Run("gains are short-term without a threshold", func(t *testing.T) {
+		plan, err := account.Rebalance(index, asOf)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		trades := plan.Trades()
+
+		shortTerm, longTerm := RealizedGains(trades)
+		if !shortTerm.Equal(decimal.Zero) || !longTerm.Equal(decimal.Zero) {
+			t.Errorf("got %v short-term and %v long-term want 0 and 0", shortTerm, longTerm)
+		}
+		if !trades["ETH"].ShortTermGain.Equal(decimal.Zero) {
+			t.Errorf("got %v want %v", trades["ETH"].ShortTermGain, 0)
+		}
+	})
+}
+
+func TestWithMaxRealizedGains(t *testing.T) {
+	account, err := NewAccountWithLots(map[Asset][]Lot{
+		"ETH": {
+			{Quantity: decimal.NewFromFloat(10), CostBasis: decimal.NewFromFloat(100)},
+			{Quantity: decimal.NewFromFloat(10), CostBasis: decimal.NewFromFloat(250)},
+		},
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.125),
+		"BTC": decimal.NewFromFloat(0.875),
+	}
+
+	t.Run("sells are cut back to the gains budget after realising losses", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithMaxRealizedGains(decimal.NewFromFloat(100)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(16)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(3200)},
+		}
+
+		assertSameTrades(t, got, want)
+
+		shortTerm, longTerm := RealizedGains(got)
+		if !shortTerm.Add(longTerm).Equal(decimal.NewFromFloat(100)) {
+			t.Errorf("got %v want %v", shortTerm.Add(longTerm), 100)
+		}
+		if !TotalResidual(got).Equal(decimal.NewFromFloat(600)) {
+			t.Errorf("got %v want %v", TotalResidual(got), 600)
+		}
+		if !TrackingError(account, got, index).Equal(decimal.NewFromFloat(0.075)) {
+			t.Errorf("got %v want %v", TrackingError(account, got, index), 0.075)
+		}
+	})
+	t.Run("trades within the budget are untouched", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithMaxRealizedGains(decimal.NewFromFloat(1000)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(17.5)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(3500)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+
+	t.Run("gains selected before losses are cut back too", func(t *testing.T) {
+		older, newer := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		cases := []struct {
+			name     string
+			selector LotSelector
+			lots     []Lot
+		}{
+			{"FIFO", FIFO{}, []Lot{
+				{Quantity: decimal.NewFromFloat(5), CostBasis: decimal.NewFromFloat(10), Acquired: older},
+				{Quantity: decimal.NewFromFloat(15), CostBasis: decimal.NewFromFloat(150), Acquired: newer},
+			}},
+			{"LIFO", LIFO{}, []Lot{
+				{Quantity: decimal.NewFromFloat(15), CostBasis: decimal.NewFromFloat(150), Acquired: older},
+				{Quantity: decimal.NewFromFloat(5), CostBasis: decimal.NewFromFloat(10), Acquired: newer},
+			}},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				account, err := NewAccountWithLots(map[Asset][]Lot{"ETH": c.lots}, Pricelist{
+					"ETH": decimal.NewFromFloat(100),
+					"BTC": decimal.NewFromFloat(1),
+				})
+
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+
+				plan, err := account.Rebalance(Index{
+					"ETH": decimal.NewFromFloat(0.5),
+					"BTC": decimal.NewFromFloat(0.5),
+				}, WithMaxRealizedGains(decimal.Zero), WithLotSelector(c.selector))
+
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+
+				shortTerm, longTerm := RealizedGains(plan.Trades())
+				if shortTerm.Add(longTerm).IsPositive() {
+					t.Errorf("got gains of %v want none", shortTerm.Add(longTerm))
+				}
+				if got := plan.Trades()["ETH"]; !got.Amount.IsZero() {
+					t.Errorf("got %v want the ETH sell cut back entirely", got)
+				}
+			})
+		}
+	})
+	t.Run("budgets the sells cannot meet are an error", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithMaxRealizedGains(decimal.NewFromFloat(-1000)))
+
+		if err != ErrGainsBudget {
+			t.Errorf("got %v want %v", err, ErrGainsBudget)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// A MergePolicy decides which price Pricelist.Merge keeps for an asset priced
+// by both pricelists.
+type MergePolicy int
+
+const (
+	// KeepExisting keeps the price of the pricelist being merged into.
+	KeepExisting MergePolicy = iota
+	// OverwriteExisting takes the price of the pricelist being merged in.
+	OverwriteExisting
+)
+
+// A PriceConflict is an asset priced differently by two merged pricelists.
+type PriceConflict struct {
+	Asset    Asset
+	Existing decimal.Decimal
+	Other    decimal.Decimal
+}
+
+// Merge returns a new pricelist with the prices of p and other, for instance
+// to layer manually maintained prices of illiquid assets over a provider's
+// feed. Assets priced differently by both are resolved by policy and reported
+// as conflicts, in asset order.
+func (p Pricelist) Merge(other Pricelist, policy MergePolicy) (Pricelist, []PriceConflict) {
+	merged := Pricelist{}
+	for asset, price := range p {
+		merged[asset] = price
+	}
+
+	var conflicts []PriceConflict
+	for asset, price := range other {
+		existing, ok := merged[asset]
+		if ok && !existing.Equal(price) {
+			conflicts = append(conflicts, PriceConflict{Asset: asset, Existing: existing, Other: price})
+		}
+		if !ok || policy == OverwriteExisting {
+			merged[asset] = price
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Asset < conflicts[j].Asset
+	})
+	return merged, conflicts
+}
@@ -0,0 +1,51 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestPricelist_Merge(t *testing.T) {
+	feed := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+		"XYZ": decimal.NewFromFloat(1),
+	}
+	manual := Pricelist{
+		"XYZ": decimal.NewFromFloat(1.5),
+		"BTC": decimal.NewFromFloat(5000),
+		"ABC": decimal.NewFromFloat(3),
+	}
+
+	t.Run("existing prices are kept", func(t *testing.T) {
+		got, conflicts := feed.Merge(manual, KeepExisting)
+
+		if len(got) != 4 || !got["XYZ"].Equal(decimal.NewFromFloat(1)) || !got["ABC"].Equal(decimal.NewFromFloat(3)) {
+			t.Errorf("got %v want the feed's prices plus ABC", got)
+		}
+		if len(conflicts) != 1 || conflicts[0].Asset != "XYZ" {
+			t.Errorf("got %v want a conflict for XYZ only", conflicts)
+		}
+	})
+
+	t.Run("existing prices are overwritten", func(t *testing.T) {
+		got, conflicts := feed.Merge(manual, OverwriteExisting)
+
+		if !got["XYZ"].Equal(decimal.NewFromFloat(1.5)) || !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want the manual XYZ price over the feed", got)
+		}
+		want := PriceConflict{Asset: "XYZ", Existing: decimal.NewFromFloat(1), Other: decimal.NewFromFloat(1.5)}
+		if len(conflicts) != 1 || !conflicts[0].Existing.Equal(want.Existing) || !conflicts[0].Other.Equal(want.Other) {
+			t.Errorf("got %v want %v", conflicts, want)
+		}
+	})
+
+	t.Run("the pricelists are not modified", func(t *testing.T) {
+		feed.Merge(manual, OverwriteExisting)
+
+		if len(feed) != 3 || !feed["XYZ"].Equal(decimal.NewFromFloat(1)) {
+			t.Errorf("got %v want the feed unchanged", feed)
+		}
+	})
+}
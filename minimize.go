@@ -0,0 +1,115 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// minimizeTrades removes every trade which is not needed to bring its asset
+// within tolerance, a value, of its target. The value those trades would have
+// moved is shared between the remaining trades so that the plan still funds
+// itself, evenly except that no trade is made to sell more than holdings
+// holds; what a trade cannot absorb is shared among the others. When a share
+// would push an asset outside the tolerance, or the remaining trades cannot
+// absorb the value, the largest of the removed trades is restored and the
+// value is shared again.
+func minimizeTrades(trades map[Asset]Trade, pricelist Pricelist, holdings Portfolio, tolerance decimal.Decimal) {
+	signed := map[Asset]decimal.Decimal{}
+	for asset, trade := range trades {
+		value := trade.Notional(pricelist)
+		if trade.Action == Sell {
+			value = value.Neg()
+		}
+		signed[asset] = value
+	}
+
+	kept := map[Asset]bool{}
+	for asset, value := range signed {
+		if value.Abs().GreaterThan(tolerance) {
+			kept[asset] = true
+		}
+	}
+
+	var shares map[Asset]decimal.Decimal
+	for {
+		unmoved := decimal.Zero
+		var largest Asset
+		for asset, value := range signed {
+			if kept[asset] {
+				continue
+			}
+			unmoved = unmoved.Add(value)
+			if largest == "" || value.Abs().GreaterThan(signed[largest].Abs()) ||
+				(value.Abs().Equal(signed[largest].Abs()) && asset < largest) {
+				largest = asset
+			}
+		}
+
+		var left decimal.Decimal
+		shares, left = shareOut(unmoved, kept, signed, pricelist, holdings)
+		// Without trades to absorb it, the value left unmoved must be nothing
+		// more than rounding error.
+		absorbed := left.Abs().LessThanOrEqual(tolerance.Mul(valueTolerance))
+		for _, share := range shares {
+			absorbed = absorbed && share.Abs().LessThanOrEqual(tolerance)
+		}
+		if largest == "" || absorbed {
+			break
+		}
+		kept[largest] = true
+	}
+
+	for asset, value := range signed {
+		if !kept[asset] {
+			delete(trades, asset)
+			continue
+		}
+		trade := trades[asset]
+		value = value.Add(shares[asset])
+		trade.Action = Buy
+		if value.IsNegative() {
+			trade.Action = Sell
+		}
+		trade.Amount = value.Abs().Div(pricelist[asset])
+		trades[asset] = trade
+	}
+}
+
+// shareOut shares unmoved between the kept assets, returning the share of
+// each and what is left over. Shares are even, except that a negative share
+// never takes an asset's trade beyond selling its whole holding; the rest of
+// such a share is spread over the assets with room left.
+func shareOut(unmoved decimal.Decimal, kept map[Asset]bool, signed map[Asset]decimal.Decimal, pricelist Pricelist, holdings Portfolio) (map[Asset]decimal.Decimal, decimal.Decimal) {
+	shares := map[Asset]decimal.Decimal{}
+	var open []Asset
+	for asset := range kept {
+		open = append(open, asset)
+	}
+	sort.Slice(open, func(i, j int) bool {
+		return open[i] < open[j]
+	})
+
+	left := unmoved
+	for len(open) > 0 && !left.IsZero() {
+		each := left.Div(decimal.New(int64(len(open)), 0))
+		var room []Asset
+		for _, asset := range open {
+			if each.IsPositive() || signed[asset].Add(shares[asset]).Add(each).Add(holdings[asset].Mul(pricelist[asset])).IsPositive() {
+				room = append(room, asset)
+				continue
+			}
+			// Sell the whole holding and leave the rest to the others.
+			share := signed[asset].Add(holdings[asset].Mul(pricelist[asset])).Neg()
+			left = left.Sub(share.Sub(shares[asset]))
+			shares[asset] = share
+		}
+		if len(room) == len(open) {
+			for _, asset := range open {
+				shares[asset] = shares[asset].Add(each)
+			}
+			left = decimal.Zero
+		}
+		open = room
+	}
+	return shares, left
+}
@@ -0,0 +1,108 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestMinimizeTrades(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(40),
+		"BTC": decimal.NewFromFloat(31),
+		"XLM": decimal.NewFromFloat(29),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XLM": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.33),
+		"BTC": decimal.NewFromFloat(0.33),
+		"XLM": decimal.NewFromFloat(0.34),
+	}
+
+	t.Run("assets within tolerance are not traded", func(t *testing.T) {
+		plan, err := account.Rebalance(index, MinimizeTrades(decimal.NewFromFloat(0.02)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(6)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(6)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("an untraded asset is traded when the rest cannot absorb its drift", func(t *testing.T) {
+		plan, err := account.Rebalance(index, MinimizeTrades(decimal.NewFromFloat(0.05)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(6)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(6)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("no trades are needed when every asset is within tolerance", func(t *testing.T) {
+		plan, err := account.Rebalance(index, MinimizeTrades(decimal.NewFromFloat(0.1)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		if len(got) != 0 {
+			t.Errorf("got %v want no trades", got)
+		}
+	})
+	t.Run("sells are never grown beyond the holding", func(t *testing.T) {
+		account, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(56),
+			"BTC": decimal.NewFromFloat(15),
+			"XLM": decimal.NewFromFloat(29),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(1),
+			"BTC": decimal.NewFromFloat(1),
+			"XLM": decimal.NewFromFloat(1),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, MinimizeTrades(decimal.NewFromFloat(0.07)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(29)},
+			"XLM": {Action: Sell, Amount: decimal.NewFromFloat(29)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+}
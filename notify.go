@@ -0,0 +1,79 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// A NotificationKind says what a Notification reports.
+type NotificationKind string
+
+const (
+	// PlanNotification reports a plan returned by Rebalance.
+	PlanNotification NotificationKind = "plan"
+	// DriftNotification reports assets which drifted beyond a threshold.
+	DriftNotification NotificationKind = "drift"
+)
+
+// A Notification reports a generated plan, or drift beyond a threshold along
+// with the drift of every asset.
+type Notification struct {
+	Kind  NotificationKind
+	Time  time.Time
+	Plan  *RebalancePlan  `json:",omitempty"`
+	Drift map[Asset]Drift `json:",omitempty"`
+}
+
+// A Notifier is told about plans and drift, for instance to post them to a
+// webhook or a chat channel.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// notifier pairs a Notifier with the handler of its errors.
+type notifier struct {
+	notifier Notifier
+	onError  func(error)
+}
+
+// WithNotifier sends every plan Rebalance returns to n. The plan is returned
+// even if n fails, in which case the error is passed to onError, if given.
+// Rebalance waits for n, so notifiers which make network calls should bound
+// how long they take, as those of the notify package do.
+func WithNotifier(n Notifier, onError func(error)) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.notifiers = append(c.notifiers, notifier{notifier: n, onError: onError})
+	}
+}
+
+// notify sends plan to the notifiers of the config.
+func (c rebalanceConfig) notify(ctx context.Context, plan RebalancePlan) {
+	for _, n := range c.notifiers {
+		err := n.notifier.Notify(ctx, Notification{Kind: PlanNotification, Time: plan.Time, Plan: &plan})
+		if err != nil && n.onError != nil {
+			n.onError(err)
+		}
+	}
+}
+
+// NotifyDrift sends a DriftNotification to n when the absolute drift of any
+// asset of the account from targetIndex exceeds threshold, reporting whether
+// it did.
+func (a Account) NotifyDrift(ctx context.Context, n Notifier, targetIndex map[Asset]decimal.Decimal, threshold decimal.Decimal) (bool, error) {
+	drifts := a.Drift(targetIndex)
+	for _, drift := range drifts {
+		if drift.Absolute.Abs().GreaterThan(threshold) {
+			return true, n.Notify(ctx, Notification{Kind: DriftNotification, Time: time.Now(), Drift: drifts})
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A Slack posts a summary of each notification to a Slack incoming webhook.
+type Slack struct {
+	config
+	url string
+}
+
+// NewSlack returns a Slack posting to the incoming webhook at url.
+func NewSlack(url string, opts ...Option) *Slack {
+	return &Slack{config: newConfig(opts), url: url}
+}
+
+// Notify posts the Summary of n.
+func (s *Slack) Notify(ctx context.Context, n rebalancer.Notification) error {
+	return s.postJSON(ctx, s.url, map[string]string{"text": Summary(n, s.currency)})
+}
+
+// A Discord posts a summary of each notification to a Discord webhook.
+type Discord struct {
+	config
+	url string
+}
+
+// NewDiscord returns a Discord posting to the webhook at url.
+func NewDiscord(url string, opts ...Option) *Discord {
+	return &Discord{config: newConfig(opts), url: url}
+}
+
+// Notify posts the Summary of n.
+func (d *Discord) Notify(ctx context.Context, n rebalancer.Notification) error {
+	return d.postJSON(ctx, d.url, map[string]string{"content": Summary(n, d.currency)})
+}
+
+// postJSON posts v to url as JSON.
+func (c config) postJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(ctx, req)
+}
+
+// Summary describes n in a line of text, such as "Rebalance plan: Sell 3.75
+// ETH, Buy 0.15 BTC, turnover $1,500", with values prefixed by currency.
+func Summary(n rebalancer.Notification, currency string) string {
+	switch n.Kind {
+	case rebalancer.PlanNotification:
+		if n.Plan == nil {
+			break
+		}
+		var parts []string
+		for _, trade := range n.Plan.SortedTrades() {
+			if trade.Amount.IsPositive() {
+				action := string(trade.Action)
+				parts = append(parts, fmt.Sprintf("%s%s %s %s", strings.ToUpper(action[:1]), action[1:], trade.Amount, trade.Asset))
+			}
+		}
+		if len(parts) == 0 {
+			return "Rebalance plan: no trades needed"
+		}
+		return fmt.Sprintf("Rebalance plan: %s, turnover %s%s", strings.Join(parts, ", "), currency, formatValue(n.Plan.Turnover))
+	case rebalancer.DriftNotification:
+		assets := make([]rebalancer.Asset, 0, len(n.Drift))
+		for asset, drift := range n.Drift {
+			if !drift.Absolute.IsZero() {
+				assets = append(assets, asset)
+			}
+		}
+		sort.Slice(assets, func(i, j int) bool {
+			a, b := n.Drift[assets[i]].Absolute.Abs(), n.Drift[assets[j]].Absolute.Abs()
+			if !a.Equal(b) {
+				return a.GreaterThan(b)
+			}
+			return assets[i] < assets[j]
+		})
+		parts := make([]string, len(assets))
+		for i, asset := range assets {
+			drift := n.Drift[asset].Absolute.Mul(decimal.New(100, 0))
+			sign := "+"
+			if drift.IsNegative() {
+				sign = ""
+			}
+			parts[i] = fmt.Sprintf("%s %s%s%%", asset, sign, drift.StringFixed(1))
+		}
+		return "Drift threshold breached: " + strings.Join(parts, ", ")
+	}
+	return fmt.Sprintf("Rebalancer notification: %s", n.Kind)
+}
+
+// formatValue formats d to two decimal places with thousands separators,
+// leaving out the decimals of whole values: 1500 is 1,500 and 1500.5 is
+// 1,500.50.
+func formatValue(d decimal.Decimal) string {
+	s := d.StringFixed(2)
+	s = strings.TrimSuffix(s, ".00")
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		whole, fraction = s[:i], s[i:]
+	}
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + whole + fraction
+}
@@ -0,0 +1,87 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/notify"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	account, err := rebalancer.NewAccountWithPricelist(rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, rebalancer.Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	index := rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+	plan, err := account.Rebalance(index)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("plans are summarised by their trades and turnover", func(t *testing.T) {
+		got := Summary(rebalancer.Notification{Kind: rebalancer.PlanNotification, Plan: &plan}, "$")
+
+		want := "Rebalance plan: Sell 3.75 ETH, Buy 0.15 BTC, turnover $1,500"
+		if got != want {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+	t.Run("drift is summarised largest first", func(t *testing.T) {
+		got := Summary(rebalancer.Notification{Kind: rebalancer.DriftNotification, Drift: map[rebalancer.Asset]rebalancer.Drift{
+			"ETH": {Absolute: decimal.NewFromFloat(0.05)},
+			"BTC": {Absolute: decimal.NewFromFloat(-0.115)},
+			"XLM": {Absolute: decimal.Zero},
+		}}, "")
+
+		want := "Drift threshold breached: BTC -11.5%, ETH +5.0%"
+		if got != want {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+}
+
+func TestChatNotifiers(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		if r.URL.Path == "/discord" {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	n := rebalancer.Notification{Kind: rebalancer.DriftNotification, Drift: map[rebalancer.Asset]rebalancer.Drift{
+		"ETH": {Absolute: decimal.NewFromFloat(0.05)},
+	}}
+
+	t.Run("Slack messages are sent as text", func(t *testing.T) {
+		if err := NewSlack(server.URL+"/slack").Notify(ctx, n); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if got["text"] != "Drift threshold breached: ETH +5.0%" {
+			t.Errorf("got %v want the summary as text", got)
+		}
+	})
+	t.Run("Discord messages are sent as content", func(t *testing.T) {
+		if err := NewDiscord(server.URL+"/discord").Notify(ctx, n); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if got["content"] != "Drift threshold breached: ETH +5.0%" {
+			t.Errorf("got %v want the summary as content", got)
+		}
+	})
+}
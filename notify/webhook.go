@@ -0,0 +1,151 @@
+// Package notify provides rebalancer.Notifiers which deliver notifications
+// over HTTP.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers set on the requests of a Webhook.
+const (
+	SignatureHeader = "X-Rebalancer-Signature"
+	TimestampHeader = "X-Rebalancer-Timestamp"
+)
+
+// DefaultTimeout bounds each notification unless WithTimeout says otherwise,
+// so a slow receiver cannot hold up the Rebalance sending it.
+const DefaultTimeout = 10 * time.Second
+
+// ErrUnexpectedStatus indicates a notification was answered with a status
+// other than 2xx.
+type ErrUnexpectedStatus struct {
+	StatusCode int
+}
+
+// Error formats the error message for ErrUnexpectedStatus.
+func (e ErrUnexpectedStatus) Error() string {
+	return fmt.Sprintf("notify: unexpected status %d", e.StatusCode)
+}
+
+// config holds the settings applied by Options.
+type config struct {
+	httpClient *http.Client
+	currency   string
+	timeout    time.Duration
+}
+
+// An Option configures a notifier.
+type Option func(*config)
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.httpClient = client
+	}
+}
+
+// WithTimeout gives up on each notification after timeout, which defaults to
+// DefaultTimeout. A timeout of zero waits for as long as the context allows.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+// WithCurrency prefixes the values in summaries with symbol, such as $.
+func WithCurrency(symbol string) Option {
+	return func(c *config) {
+		c.currency = symbol
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{httpClient: http.DefaultClient, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// A Webhook posts notifications as JSON to a URL, signed with HMAC-SHA256
+// so the receiver can check they were sent by the holder of the secret.
+type Webhook struct {
+	config
+	url    string
+	secret []byte
+	now    func() time.Time
+}
+
+// NewWebhook returns a Webhook posting to url and signing with secret.
+func NewWebhook(url string, secret []byte, opts ...Option) *Webhook {
+	return &Webhook{config: newConfig(opts), url: url, secret: secret, now: time.Now}
+}
+
+// Notify posts n as JSON. The request carries the Unix time it was sent at
+// in TimestampHeader and, in SignatureHeader, "sha256=" followed by the hex
+// encoded HMAC-SHA256 of the timestamp, a full stop and the body; see
+// VerifySignature.
+func (w *Webhook) Notify(ctx context.Context, n rebalancer.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+	return w.send(ctx, req)
+}
+
+// send sends req within the timeout, failing unless it is answered with a 2xx
+// status.
+func (c config) send(ctx context.Context, req *http.Request) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return ErrUnexpectedStatus{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the signature of a webhook body sent at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature was made with secret for body
+// sent at timestamp, comparing in constant time. Receivers should also reject
+// timestamps too far in the past, so that captured requests cannot be
+// replayed.
+func VerifySignature(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
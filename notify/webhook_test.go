@@ -0,0 +1,116 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/notify"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Notifier asserts that Webhook implements rebalancer.Notifier.
+var _ rebalancer.Notifier = &Webhook{}
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("secret")
+	var received []rebalancer.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !VerifySignature(secret, r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var n rebalancer.Notification
+		if err := json.Unmarshal(body, &n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, n)
+	}))
+	defer server.Close()
+
+	account, err := rebalancer.NewAccountWithPricelist(rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, rebalancer.Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	index := rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("generated plans are posted to the webhook", func(t *testing.T) {
+		received = nil
+		var failed error
+
+		plan, err := account.Rebalance(index, rebalancer.WithNotifier(NewWebhook(server.URL, secret), func(err error) {
+			failed = err
+		}))
+
+		if err != nil || failed != nil {
+			t.Errorf("unexpected error: %v %v", err, failed)
+		}
+		if len(received) != 1 || received[0].Kind != rebalancer.PlanNotification || received[0].Plan == nil {
+			t.Fatalf("got %v want a plan notification", received)
+		}
+		if got := received[0].Plan.Trades()["ETH"]; got.ID != plan.Trades()["ETH"].ID {
+			t.Errorf("got %v want %v", got, plan.Trades()["ETH"])
+		}
+	})
+	t.Run("drift beyond the threshold is posted to the webhook", func(t *testing.T) {
+		received = nil
+
+		breached, err := account.NotifyDrift(context.Background(), NewWebhook(server.URL, secret), index, decimal.NewFromFloat(0.1))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !breached || len(received) != 1 || received[0].Kind != rebalancer.DriftNotification || len(received[0].Drift) != 2 {
+			t.Errorf("got %v want a drift notification", received)
+		}
+	})
+	t.Run("unsigned notifications are refused", func(t *testing.T) {
+		var failed error
+
+		_, err := account.Rebalance(index, rebalancer.WithNotifier(NewWebhook(server.URL, []byte("wrong")), func(err error) {
+			failed = err
+		}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if failed != (ErrUnexpectedStatus{StatusCode: http.StatusUnauthorized}) {
+			t.Errorf("got %v want %v", failed, ErrUnexpectedStatus{StatusCode: http.StatusUnauthorized})
+		}
+	})
+	t.Run("slow receivers are given up on after the timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer slow.Close()
+		defer close(release)
+		var failed error
+
+		_, err := account.Rebalance(index, rebalancer.WithNotifier(NewWebhook(slow.URL, secret, WithTimeout(10*time.Millisecond)), func(err error) {
+			failed = err
+		}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if failed == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestAccount_NotifyDrift(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	var got []Notification
+	notifier := NotifierFunc(func(ctx context.Context, n Notification) error {
+		got = append(got, n)
+		return nil
+	})
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("drift within the threshold is not notified", func(t *testing.T) {
+		breached, err := account.NotifyDrift(context.Background(), notifier, index, decimal.NewFromFloat(0.2))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if breached || len(got) != 0 {
+			t.Errorf("got %v want no notification", got)
+		}
+	})
+	t.Run("drift beyond the threshold is notified", func(t *testing.T) {
+		breached, err := account.NotifyDrift(context.Background(), notifier, index, decimal.NewFromFloat(0.1))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !breached || len(got) != 1 || !got[0].Drift["ETH"].Absolute.GreaterThan(decimal.NewFromFloat(0.1)) {
+			t.Errorf("got %v want ETH reported overweight", got)
+		}
+	})
+	t.Run("plans are notified", func(t *testing.T) {
+		got = nil
+
+		plan, _ := account.Rebalance(index, WithNotifier(notifier, nil))
+
+		if len(got) != 1 || got[0].Kind != PlanNotification || got[0].Plan.Hash() != plan.Hash() {
+			t.Errorf("got %v want the plan notified", got)
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// rebalanceConfig holds the settings applied by RebalanceOptions.
+type rebalanceConfig struct {
+	keepUnlisted   bool
+	minTradeValue  decimal.Decimal
+	driftBands     bool
+	absoluteBand   decimal.Decimal
+	relativeBand   decimal.Decimal
+	fees           FeeModel
+	slippage       SlippageModel
+	exchangeRules  ExchangeRules
+	cashReserve    decimal.Decimal
+	reserveRatio   decimal.Decimal
+	locked         map[Asset]bool
+	maxWeights     map[Asset]decimal.Decimal
+	weightCap      decimal.Decimal
+	minWeights     map[Asset]decimal.Decimal
+	weightFloor    decimal.Decimal
+	dropBelowMin   bool
+	allowed        map[Asset]bool
+	blocked        map[Asset]bool
+	maxTurnover    decimal.Decimal
+	turnoverRatio  decimal.Decimal
+	minimizeTrades bool
+	tolerance      decimal.Decimal
+	lotSelector    LotSelector
+	longTerm       time.Duration
+	now            time.Time
+	gainsBudget    bool
+	maxGains       decimal.Decimal
+	limitOrders    bool
+	limitOffset    decimal.Decimal
+	maxPriceAge    bool
+	priceAge       time.Duration
+	notifiers      []notifier
+	tracer         Tracer
+	events         *Events
+	journal        *Journal
+	journalReason  string
+	strategy       Strategy
+	contribution   decimal.Decimal
+}
+
+// A RebalanceOption configures how Rebalance calculates trades.
+type RebalanceOption func(*rebalanceConfig)
+
+func newRebalanceConfig(opts []RebalanceOption) rebalanceConfig {
+	config := rebalanceConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// IgnoreUnlisted stops Rebalance from selling holdings which are absent from
+// the target index, exactly as KeepUnlisted does.
+//
+// Deprecated: use KeepUnlisted. IgnoreUnlisted used to leave the value of
+// those holdings in the total being rebalanced, which made plans spend cash
+// the sells did not raise.
+func IgnoreUnlisted() RebalanceOption {
+	return KeepUnlisted()
+}
+
+// KeepUnlisted freezes holdings which are absent from the target index: no
+// trades are generated for them and their value is excluded from the total
+// being rebalanced, so the remaining assets are rebalanced around them. This
+// suits positions which cannot be traded, such as locked staking balances.
+func KeepUnlisted() RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.keepUnlisted = true
+	}
+}
+
+// WithLockedAssets freezes the given holdings, such as vested or staked
+// balances: they count towards the account's value but are never traded. Their
+// value is excluded from the total being rebalanced, so the remaining target
+// weights are scaled to allocate what is left around them.
+func WithLockedAssets(assets ...Asset) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		if c.locked == nil {
+			c.locked = map[Asset]bool{}
+		}
+		for _, asset := range assets {
+			c.locked[asset] = true
+		}
+	}
+}
+
+// WithAllowedAssets restricts the account to the given assets, as if every
+// other asset had been given to WithBlockedAssets.
+func WithAllowedAssets(assets ...Asset) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		if c.allowed == nil {
+			c.allowed = map[Asset]bool{}
+		}
+		for _, asset := range assets {
+			c.allowed[asset] = true
+		}
+	}
+}
+
+// WithBlockedAssets removes the given assets from the target index, scaling
+// the remaining weights up to sum to 1, and sells any holdings of them in
+// full, even when KeepUnlisted is given.
+func WithBlockedAssets(assets ...Asset) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		if c.blocked == nil {
+			c.blocked = map[Asset]bool{}
+		}
+		for _, asset := range assets {
+			c.blocked[asset] = true
+		}
+	}
+}
+
+// WithMinTradeValue drops trades whose notional value, their amount multiplied
+// by the asset's price, is below min. Such dust trades are usually rejected by
+// exchanges. The value they would have moved is redistributed by shrinking the
+// remaining trades on the opposite side, so the plan still funds itself.
+func WithMinTradeValue(min decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.minTradeValue = min
+	}
+}
+
+// WithDriftBands only trades assets whose current weight has drifted from its
+// target weight by more than absolute, or by more than relative as a fraction
+// of the target weight; passing zero disables either band. For example the
+// 5/25 rule is WithDriftBands(0.05, 0.25). Assets within their bands are left
+// untouched and the assets outside them are rebalanced amongst themselves.
+func WithDriftBands(absolute, relative decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.driftBands = true
+		c.absoluteBand = absolute
+		c.relativeBand = relative
+	}
+}
+
+// WithFees sizes trades so that the portfolio lands on the target index after
+// the fees estimated by model have been paid, and records each trade's
+// estimated fee in Trade.Fee.
+func WithFees(model FeeModel) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.fees = model
+	}
+}
+
+// WithSlippage sizes trades so that they still fund each other once their
+// execution prices have moved by the slippage estimated by model.
+func WithSlippage(model SlippageModel) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.slippage = model
+	}
+}
+
+// WithExchangeRules rounds every trade's amount to a quantity rules allow,
+// reporting the value which could not be allocated in Trade.Residual.
+func WithExchangeRules(rules ExchangeRules) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.exchangeRules = rules
+	}
+}
+
+// WithCashReserve leaves amount of the account's value uninvested, as a
+// buffer for fees, margin or withdrawals. The trades raise the reserve by
+// selling more than they buy, and the target weights are applied to the rest.
+func WithCashReserve(amount decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.cashReserve = amount
+	}
+}
+
+// WithCashReserveRatio is like WithCashReserve, but leaves ratio of the value
+// being rebalanced uninvested, for example 0.05 keeps a 5% cash buffer.
+func WithCashReserveRatio(ratio decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.reserveRatio = ratio
+	}
+}
+
+// WithMaxWeight caps the target weight of asset at max. Any weight above the
+// cap is redistributed amongst the other assets of the index in proportion to
+// their weights.
+func WithMaxWeight(asset Asset, max decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		if c.maxWeights == nil {
+			c.maxWeights = map[Asset]decimal.Decimal{}
+		}
+		c.maxWeights[asset] = max
+	}
+}
+
+// WithWeightCap caps the target weight of every asset at max, as if
+// WithMaxWeight had been given for each of them.
+func WithWeightCap(max decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.weightCap = max
+	}
+}
+
+// WithMinWeight raises the target weight of asset to min when it is below it,
+// funding the difference from the other assets of the index in proportion to
+// their weights. Assets with a target weight of zero are left out.
+func WithMinWeight(asset Asset, min decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		if c.minWeights == nil {
+			c.minWeights = map[Asset]decimal.Decimal{}
+		}
+		c.minWeights[asset] = min
+	}
+}
+
+// WithWeightFloor sets a minimum target weight for every asset, as if
+// WithMinWeight had been given for each of them.
+func WithWeightFloor(min decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.weightFloor = min
+	}
+}
+
+// DropBelowMinWeight removes assets whose target weight is below their
+// minimum from the index instead of raising them to it, redistributing their
+// weight amongst the remaining assets in proportion to their weights.
+func DropBelowMinWeight() RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.dropBelowMin = true
+	}
+}
+
+// WithMaxTurnover limits the combined notional value of the buys and sells of
+// a rebalance to max. The budget is spent on the largest drifts first and the
+// value each trade falls short of its target by is reported in Trade.Residual.
+func WithMaxTurnover(max decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.maxTurnover = max
+	}
+}
+
+// WithMaxTurnoverRatio is like WithMaxTurnover, but limits turnover to ratio of
+// the account's value.
+func WithMaxTurnoverRatio(ratio decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.turnoverRatio = ratio
+	}
+}
+
+// MinimizeTrades plans the fewest trades which bring every asset's weight
+// within tolerance of its target weight, rather than trading every asset
+// exactly onto its target. Assets already within tolerance are left alone.
+func MinimizeTrades(tolerance decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.minimizeTrades = true
+		c.tolerance = tolerance
+	}
+}
+
+// WithLotSelector chooses the lots each sell is taken from using selector,
+// recording them in Trade.Lots, when the account tracks lots. FIFO is used if
+// no selector is given.
+func WithLotSelector(selector LotSelector) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.lotSelector = selector
+	}
+}
+
+// WithLongTermThreshold treats gains on lots held for longer than threshold
+// as long-term when estimating the gains realised by sells. Without it every
+// gain is reported as short-term.
+func WithLongTermThreshold(threshold time.Duration) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.longTerm = threshold
+	}
+}
+
+// AsOf sets the time at which the rebalance is planned, used to decide how
+// long lots have been held. It defaults to the current time.
+func AsOf(now time.Time) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.now = now
+	}
+}
+
+// WithMaxRealizedGains caps the gains realised by sells, short-term and
+// long-term together, at max when the account tracks lots. Sells which would
+// realise more are cut back, along with the buys they fund, and the value left
+// untraded is reported in Trade.Residual, or ErrGainsBudget is returned if
+// they cannot be cut back far enough. Unless WithLotSelector is given, lots
+// are sold with HIFO so that losses are realised first.
+func WithMaxRealizedGains(max decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.gainsBudget = true
+		c.maxGains = max
+	}
+}
+
+// WithLimitOrders marks every trade as a Limit order, with a LimitPrice offset
+// from the asset's price by offsetBps basis points: above it for buys and
+// below it for sells. The price is rounded to the asset's tick size when
+// rebalancing WithExchangeRules. Without this option trades are Market orders.
+func WithLimitOrders(offsetBps decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.limitOrders = true
+		c.limitOffset = offsetBps
+	}
+}
+
+// WithMaxPriceAge refuses to plan with prices observed more than maxAge before
+// the plan's time, returning an ErrStalePrice, when any asset of the target
+// index or the portfolio has one. The account must have been created with
+// NewAccountWithTimedPricelist; ErrMissingPriceTime is returned otherwise.
+func WithMaxPriceAge(maxAge time.Duration) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.maxPriceAge = true
+		c.priceAge = maxAge
+	}
+}
@@ -0,0 +1,337 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestWithMinTradeValue(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+		"XLM": decimal.NewFromFloat(0.2),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("dust trades are dropped and their value redistributed", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.499),
+			"XLM": decimal.NewFromFloat(0.001),
+		}, WithMinTradeValue(decimal.NewFromFloat(10)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(3.7175)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.1487)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("trades shrunk below the minimum are dropped too", func(t *testing.T) {
+		account, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(60),
+			"BTC": decimal.NewFromFloat(40),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(1),
+			"BTC": decimal.NewFromFloat(1),
+			"XLM": decimal.NewFromFloat(1),
+			"ADA": decimal.NewFromFloat(1),
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.45),
+			"BTC": decimal.NewFromFloat(0.28),
+			"XLM": decimal.NewFromFloat(0.18),
+			"ADA": decimal.NewFromFloat(0.09),
+		}, WithMinTradeValue(decimal.NewFromFloat(10)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(10)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(10)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+}
+
+func TestWithDriftBands(t *testing.T) {
+	pricelist := Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XLM": decimal.NewFromFloat(1),
+	}
+
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(40),
+		"BTC": decimal.NewFromFloat(30),
+		"XLM": decimal.NewFromFloat(30),
+	}, pricelist)
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	bands := WithDriftBands(decimal.NewFromFloat(0.05), decimal.NewFromFloat(0.25))
+
+	t.Run("assets within their bands are not traded", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.3),
+			"BTC": decimal.NewFromFloat(0.3),
+			"XLM": decimal.NewFromFloat(0.4),
+		}, bands)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(10)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(10)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("a single drifted asset triggers a full rebalance", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.34),
+			"BTC": decimal.NewFromFloat(0.33),
+			"XLM": decimal.NewFromFloat(0.33),
+		}, bands)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(6)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(3)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(3)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("no trades are generated when every asset is within its band", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.38),
+			"BTC": decimal.NewFromFloat(0.31),
+			"XLM": decimal.NewFromFloat(0.31),
+		}, bands)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		if len(got) != 0 {
+			t.Errorf("got %v want no trades", got)
+		}
+	})
+}
+
+func TestWithCashReserve(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(60),
+		"BTC": decimal.NewFromFloat(40),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("a fixed amount is left uninvested", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithCashReserve(decimal.NewFromFloat(10)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(15)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(5)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("a ratio of the value is left uninvested", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithCashReserveRatio(decimal.NewFromFloat(0.2)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(20)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("the reserve cannot exceed the account's value", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithCashReserve(decimal.NewFromFloat(101)))
+
+		if err != ErrInsufficientValue {
+			t.Errorf("got %v, want %s", err, ErrInsufficientValue)
+		}
+	})
+	t.Run("the reserve ratio cannot be above 1", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithCashReserveRatio(decimal.NewFromFloat(1.5)))
+
+		if err != ErrInvalidCashReserve {
+			t.Errorf("got %v, want %s", err, ErrInvalidCashReserve)
+		}
+	})
+}
+
+func TestWithLockedAssets(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"STAKED": decimal.NewFromFloat(20),
+		"ETH":    decimal.NewFromFloat(60),
+		"BTC":    decimal.NewFromFloat(20),
+	}, Pricelist{
+		"STAKED": decimal.NewFromFloat(1),
+		"ETH":    decimal.NewFromFloat(1),
+		"BTC":    decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("locked assets are not traded and the rest is rebalanced around them", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"STAKED": decimal.NewFromFloat(0.5),
+			"ETH":    decimal.NewFromFloat(0.25),
+			"BTC":    decimal.NewFromFloat(0.25),
+		}, WithLockedAssets("STAKED"))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(20)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(20)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("locked assets absent from the index are not sold", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, WithLockedAssets("STAKED"))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(20)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(20)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+}
+
+func TestWithLimitOrders(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("trades are market orders by default", func(t *testing.T) {
+		plan, err := account.Rebalance(index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if got := plan.Trades()["ETH"].OrderType; got != Market {
+			t.Errorf("got %s want %s", got, Market)
+		}
+	})
+	t.Run("limit prices are offset in the direction of the trade", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithLimitOrders(decimal.NewFromFloat(50)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		trades := plan.Trades()
+		if trades["ETH"].OrderType != Limit || !trades["ETH"].LimitPrice.Equal(decimal.NewFromFloat(199)) {
+			t.Errorf("got %v want a limit sell at 199", trades["ETH"])
+		}
+		if !trades["BTC"].LimitPrice.Equal(decimal.NewFromFloat(5025)) {
+			t.Errorf("got %v want a limit buy at 5025", trades["BTC"])
+		}
+	})
+	t.Run("limit prices are rounded to the tick size", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithLimitOrders(decimal.NewFromFloat(33)), WithExchangeRules(ExchangeRules{
+			"ETH": {TickSize: decimal.NewFromFloat(0.5)},
+		}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if got := plan.Trades()["ETH"].LimitPrice; !got.Equal(decimal.NewFromFloat(199.5)) {
+			t.Errorf("got %v want %v", got, 199.5)
+		}
+	})
+}
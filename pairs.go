@@ -0,0 +1,256 @@
+package rebalancer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strings"
+)
+
+// A Pair is an exchange market trading Base against Quote, where Price is the
+// amount of Quote one unit of Base costs.
+type Pair struct {
+	Base  Asset
+	Quote Asset
+	Price decimal.Decimal
+}
+
+// String returns the pair's symbol, such as ETH/BTC.
+func (p Pair) String() string {
+	return fmt.Sprintf("%s/%s", p.Base, p.Quote)
+}
+
+// rate returns how much of the other side of the pair one unit of from is
+// worth.
+func (p Pair) rate(from Asset) decimal.Decimal {
+	if from == p.Base {
+		return p.Price
+	}
+	return decimal.New(1, 0).Div(p.Price)
+}
+
+// other returns the side of the pair which is not asset.
+func (p Pair) other(asset Asset) Asset {
+	if asset == p.Base {
+		return p.Quote
+	}
+	return p.Base
+}
+
+// ErrInvalidPair indicates a pair trading an asset against itself.
+var ErrInvalidPair = errors.New("pair must trade two different assets")
+
+// ErrUnreachableAsset indicates there is no path between two assets through
+// the pairs of a PairBook.
+type ErrUnreachableAsset struct {
+	From Asset
+	To   Asset
+}
+
+// Error formats the error message for ErrUnreachableAsset.
+func (e ErrUnreachableAsset) Error() string {
+	return fmt.Sprintf("no pairs lead from %s to %s", e.From, e.To)
+}
+
+// A PairBook holds the pairs available on an exchange, and prices assets by
+// converting them through those pairs.
+type PairBook struct {
+	pairs map[Asset][]Pair
+}
+
+// NewPairBook validates pairs and returns a new PairBook. Paths through the
+// book prefer the fewest hops, and otherwise the pairs given first.
+func NewPairBook(pairs []Pair) (PairBook, error) {
+	book := PairBook{pairs: map[Asset][]Pair{}}
+	for _, pair := range pairs {
+		for _, asset := range []Asset{pair.Base, pair.Quote} {
+			if string(asset) != strings.ToUpper(string(asset)) {
+				return PairBook{}, ErrInvalidAsset
+			}
+		}
+		if pair.Base == pair.Quote {
+			return PairBook{}, ErrInvalidPair
+		}
+		if !pair.Price.IsPositive() {
+			return PairBook{}, ErrInvalidAssetAmount{Asset: pair.Base, Amount: pair.Price}
+		}
+		book.pairs[pair.Base] = append(book.pairs[pair.Base], pair)
+		book.pairs[pair.Quote] = append(book.pairs[pair.Quote], pair)
+	}
+	return book, nil
+}
+
+// Path returns the pairs to trade through, in order, to convert from into
+// to, using as few pairs as possible.
+func (b PairBook) Path(from, to Asset) ([]Pair, error) {
+	if from == to {
+		return nil, nil
+	}
+
+	via := map[Asset]Pair{}
+	visited := map[Asset]bool{from: true}
+	queue := []Asset{from}
+	for len(queue) > 0 && !visited[to] {
+		asset := queue[0]
+		queue = queue[1:]
+		for _, pair := range b.pairs[asset] {
+			next := pair.other(asset)
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			via[next] = pair
+			queue = append(queue, next)
+		}
+	}
+	if !visited[to] {
+		return nil, ErrUnreachableAsset{From: from, To: to}
+	}
+
+	var path []Pair
+	for asset := to; asset != from; asset = via[asset].other(asset) {
+		path = append([]Pair{via[asset]}, path...)
+	}
+	return path, nil
+}
+
+// Price returns the price of asset in units of quote, converting it through
+// the pairs on the path between them.
+func (b PairBook) Price(asset, quote Asset) (decimal.Decimal, error) {
+	path, err := b.Path(asset, quote)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	price := decimal.New(1, 0)
+	for _, pair := range path {
+		price = price.Mul(pair.rate(asset))
+		asset = pair.other(asset)
+	}
+	return price, nil
+}
+
+// Pricelist returns the price in quote of every asset reachable from it,
+// including quote itself, for use with NewAccountWithPricelist.
+func (b PairBook) Pricelist(quote Asset) Pricelist {
+	pricelist := Pricelist{quote: decimal.New(1, 0)}
+	for asset := range b.pairs {
+		if price, err := b.Price(asset, quote); err == nil {
+			pricelist[asset] = price
+		}
+	}
+	return pricelist
+}
+
+// ValidateIndex returns an ErrUnreachableAsset for the first asset of index,
+// in asset order, which cannot be reached from quote.
+func (b PairBook) ValidateIndex(index map[Asset]decimal.Decimal, quote Asset) error {
+	for _, asset := range sortedAssets(index) {
+		if _, err := b.Path(quote, asset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedAssets returns the assets of m in order.
+func sortedAssets(m map[Asset]decimal.Decimal) []Asset {
+	assets := make([]Asset, 0, len(m))
+	for asset := range m {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i] < assets[j]
+	})
+	return assets
+}
+
+// A PairTrade is a trade on an exchange pair: a buy of Quantity units of the
+// pair's base asset paid for with QuoteAmount of its quote asset, or a sell of
+// them for it.
+type PairTrade struct {
+	Pair        Pair
+	Side        TradeAction
+	Quantity    decimal.Decimal
+	QuoteAmount decimal.Decimal
+}
+
+// String returns the trade as it would be written to an exchange, such as
+// SELL 10 ETH/BTC.
+func (t PairTrade) String() string {
+	return fmt.Sprintf("%s %s %s", strings.ToUpper(string(t.Side)), t.Quantity, t.Pair)
+}
+
+// Route returns the pair trades which convert amount of from into to, in the
+// order they must be executed, trading through the path between them. Each
+// trade spends everything the one before it received.
+func (b PairBook) Route(from, to Asset, amount decimal.Decimal) ([]PairTrade, error) {
+	path, err := b.Path(from, to)
+	if err != nil {
+		return nil, err
+	}
+	trades := make([]PairTrade, 0, len(path))
+	for _, pair := range path {
+		if pair.Base == from {
+			trade := PairTrade{Pair: pair, Side: Sell, Quantity: amount, QuoteAmount: amount.Mul(pair.Price)}
+			trades = append(trades, trade)
+			amount = trade.QuoteAmount
+		} else {
+			trade := PairTrade{Pair: pair, Side: Buy, Quantity: amount.Div(pair.Price), QuoteAmount: amount}
+			trades = append(trades, trade)
+			amount = trade.Quantity
+		}
+		from = pair.other(from)
+	}
+	return trades, nil
+}
+
+// PairTrades expresses the trades of the plan as an ordered list of trades on
+// the pairs of book. The proceeds of each sell are converted into the buys
+// they fund through the fewest pairs, directly where a pair trades them
+// against each other and otherwise through intermediate hops, such as selling
+// IOTA for BTC and buying XLM with the BTC. Any proceeds or costs left over
+// once sells and buys are matched are converted from or into quote.
+func (p RebalancePlan) PairTrades(book PairBook, quote Asset) ([]PairTrade, error) {
+	graph, err := NewFundingGraph(p.trades, p.Pricelist, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []PairTrade
+	convert := func(from, to Asset, value decimal.Decimal) error {
+		price, ok := p.Pricelist[from]
+		if !ok {
+			return ErrAssetMissingFromPricelist
+		}
+		route, err := book.Route(from, to, value.Div(price))
+		if err != nil {
+			return err
+		}
+		trades = append(trades, route...)
+		return nil
+	}
+
+	matched := map[Asset]decimal.Decimal{}
+	for _, edge := range graph.Edges {
+		if err := convert(edge.From, edge.To, edge.Value); err != nil {
+			return nil, err
+		}
+		matched[edge.From] = matched[edge.From].Add(edge.Value)
+		matched[edge.To] = matched[edge.To].Add(edge.Value)
+	}
+	for _, node := range graph.Nodes {
+		remaining := node.Value.Sub(matched[node.Asset])
+		if !remaining.IsPositive() || node.Asset == quote {
+			continue
+		}
+		from, to := node.Asset, quote
+		if node.Action == Buy {
+			from, to = quote, node.Asset
+		}
+		if err := convert(from, to, remaining); err != nil {
+			return nil, err
+		}
+	}
+	return trades, nil
+}
@@ -0,0 +1,216 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestPairBook(t *testing.T) {
+	book, err := NewPairBook([]Pair{
+		{Base: "ETH", Quote: "BTC", Price: decimal.NewFromFloat(0.04)},
+		{Base: "BTC", Quote: "USDT", Price: decimal.NewFromFloat(5000)},
+		{Base: "XLM", Quote: "ETH", Price: decimal.NewFromFloat(0.001)},
+		{Base: "DOGE", Quote: "BNB", Price: decimal.NewFromFloat(0.0001)},
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("prices are derived through a path of pairs", func(t *testing.T) {
+		got, err := book.Price("XLM", "USDT")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Equal(decimal.NewFromFloat(0.2)) {
+			t.Errorf("got %v want %v", got, 0.2)
+		}
+	})
+	t.Run("pairs can be traded in either direction", func(t *testing.T) {
+		got, err := book.Price("USDT", "ETH")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Equal(decimal.NewFromFloat(0.005)) {
+			t.Errorf("got %v want %v", got, 0.005)
+		}
+	})
+	t.Run("the path lists the pairs in order", func(t *testing.T) {
+		path, err := book.Path("USDT", "XLM")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		var got []string
+		for _, pair := range path {
+			got = append(got, pair.String())
+		}
+		want := []string{"BTC/USDT", "ETH/BTC", "XLM/ETH"}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("a pricelist is derived for every reachable asset", func(t *testing.T) {
+		got := book.Pricelist("USDT")
+
+		if len(got) != 4 || !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want 4 prices with ETH at 200", got)
+		}
+	})
+	t.Run("index assets must be reachable from the quote asset", func(t *testing.T) {
+		err := book.ValidateIndex(Index{
+			"ETH":  decimal.NewFromFloat(0.5),
+			"DOGE": decimal.NewFromFloat(0.5),
+		}, "USDT")
+
+		want := ErrUnreachableAsset{From: "USDT", To: "DOGE"}
+		if err != want {
+			t.Errorf("got %v, want %s", err, want)
+		}
+	})
+	t.Run("pairs must trade different assets", func(t *testing.T) {
+		_, err := NewPairBook([]Pair{{Base: "ETH", Quote: "ETH", Price: decimal.NewFromFloat(1)}})
+
+		if err != ErrInvalidPair {
+			t.Errorf("got %v, want %s", err, ErrInvalidPair)
+		}
+	})
+}
+
+func TestRebalancePlan_PairTrades(t *testing.T) {
+	book, err := NewPairBook([]Pair{
+		{Base: "ETH", Quote: "BTC", Price: decimal.NewFromFloat(0.04)},
+		{Base: "BTC", Quote: "USDT", Price: decimal.NewFromFloat(5000)},
+		{Base: "ETH", Quote: "USDT", Price: decimal.NewFromFloat(200)},
+		{Base: "XLM", Quote: "USDT", Price: decimal.NewFromFloat(0.2)},
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, book.Pricelist("USDT"))
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("sells are converted directly into the buys they fund", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got, err := plan.PairTrades(book, "USDT")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if len(got) != 1 || got[0].String() != "SELL 3.75 ETH/BTC" || !got[0].QuoteAmount.Equal(decimal.NewFromFloat(0.15)) {
+			t.Errorf("got %v want SELL 3.75 ETH/BTC for 0.15 BTC", got)
+		}
+	})
+	t.Run("assets without a direct pair are converted through the quote asset", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"BTC": decimal.NewFromFloat(0.5),
+			"XLM": decimal.NewFromFloat(0.5),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got, err := plan.PairTrades(book, "USDT")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		var symbols []string
+		for _, trade := range got {
+			symbols = append(symbols, trade.String())
+		}
+		want := []string{"SELL 3.75 ETH/BTC", "SELL 16.25 ETH/USDT", "BUY 16250 XLM/USDT"}
+		if len(symbols) != len(want) {
+			t.Fatalf("got %v want %v", symbols, want)
+		}
+		for i := range want {
+			if symbols[i] != want[i] {
+				t.Errorf("got %v want %v", symbols, want)
+			}
+		}
+	})
+}
+
+func TestPairBook_Route(t *testing.T) {
+	book, err := NewPairBook([]Pair{
+		{Base: "IOTA", Quote: "BTC", Price: decimal.NewFromFloat(0.0001)},
+		{Base: "XLM", Quote: "BTC", Price: decimal.NewFromFloat(0.00004)},
+		{Base: "BTC", Quote: "USDT", Price: decimal.NewFromFloat(5000)},
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	account, err := NewAccountWithPricelist(Portfolio{
+		"IOTA": decimal.NewFromFloat(10000),
+	}, book.Pricelist("USDT"))
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("plans include the intermediate hop between assets without a pair", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"IOTA": decimal.NewFromFloat(0.5),
+			"XLM":  decimal.NewFromFloat(0.5),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got, err := plan.PairTrades(book, "USDT")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := []string{"SELL 5000 IOTA/BTC", "BUY 12500 XLM/BTC"}
+		if len(got) != len(want) {
+			t.Fatalf("got %v want %v", got, want)
+		}
+		for i := range want {
+			if got[i].String() != want[i] {
+				t.Errorf("got %v want %v", got[i], want[i])
+			}
+		}
+	})
+	t.Run("each hop spends what the one before it received", func(t *testing.T) {
+		got, err := book.Route("USDT", "IOTA", decimal.NewFromFloat(1000))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if len(got) != 2 || !got[0].Quantity.Equal(decimal.NewFromFloat(0.2)) || !got[1].QuoteAmount.Equal(decimal.NewFromFloat(0.2)) {
+			t.Errorf("got %v want 0.2 BTC bought then spent on IOTA", got)
+		}
+		if !got[1].Quantity.Equal(decimal.NewFromFloat(2000)) {
+			t.Errorf("got %v want %v", got[1].Quantity, 2000)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package rebalancer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A RebalancePlan is the result of rebalancing an account: the trades which
+// balance it along with the inputs and totals which produced them, so that a
+// plan can be audited after the fact.
+type RebalancePlan struct {
+	// Time is when the plan was made, or the time given to AsOf.
+	Time time.Time
+	// Index is the target index the trades balance the account to, once any
+	// weight constraints have been applied.
+	Index Index
+	// Pricelist is a snapshot of the prices the account was valued with.
+	Pricelist Pricelist
+	// Holdings is a snapshot of the account's portfolio before the trades.
+	Holdings Portfolio
+	// Value is the value of the account before the trades.
+	Value decimal.Decimal
+	// Contribution is the cash deposited by the trades, or withdrawn when it
+	// is negative.
+	Contribution decimal.Decimal
+	// Turnover is the combined notional value of the buys and sells.
+	Turnover decimal.Decimal
+	// Fees is the total estimated fee of the trades.
+	Fees decimal.Decimal
+	// Residual is the total value the trades could not allocate.
+	Residual decimal.Decimal
+	// ShortTermGain and LongTermGain are the total estimated gains realised
+	// by the sells when the account tracks lots.
+	ShortTermGain decimal.Decimal
+	LongTermGain  decimal.Decimal
+
+	trades map[Asset]Trade
+}
+
+// newRebalancePlan returns the plan for trades, totalling them up.
+func newRebalancePlan(trades map[Asset]Trade, targetIndex Index, a Account, contribution decimal.Decimal, now time.Time) RebalancePlan {
+	plan := RebalancePlan{
+		Time:         now,
+		Index:        targetIndex,
+		Pricelist:    a.Pricelist(),
+		Holdings:     a.Holdings(),
+		Value:        a.value,
+		Contribution: contribution,
+		Turnover:     decimal.Zero,
+		Fees:         TotalFees(trades),
+		Residual:     TotalResidual(trades),
+		trades:       trades,
+	}
+	for _, trade := range trades {
+		plan.Turnover = plan.Turnover.Add(trade.Notional(a.pricelist))
+	}
+	plan.ShortTermGain, plan.LongTermGain = RealizedGains(trades)
+	return plan
+}
+
+// Trades returns a copy of the trades of the plan.
+func (p RebalancePlan) Trades() map[Asset]Trade {
+	trades := map[Asset]Trade{}
+	for asset, trade := range p.trades {
+		trades[asset] = trade
+	}
+	return trades
+}
+
+// Hash returns a hex encoded SHA-256 digest of the plan's time, index and
+// trades, which identifies the plan across retries and restarts.
+func (p RebalancePlan) Hash() string {
+	h := sha256.New()
+	h.Write([]byte(p.Time.UTC().Format(time.RFC3339Nano)))
+	for _, asset := range sortedAssets(p.Index) {
+		h.Write([]byte("|" + string(asset) + "=" + p.Index[asset].String()))
+	}
+	assets := make([]Asset, 0, len(p.trades))
+	for asset := range p.trades {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i] < assets[j]
+	})
+	for _, asset := range assets {
+		trade := p.trades[asset]
+		h.Write([]byte("|" + string(asset) + ":" + string(trade.Action) + ":" + trade.Amount.String() +
+			":" + string(trade.OrderType) + ":" + trade.LimitPrice.String()))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// withTradeIDs returns the plan with the ID of every trade derived from the
+// plan's hash and the trade's asset. Deriving them from the trades alone would
+// give a trade the same ID as an identical one of an earlier plan, which
+// venues deduplicating client order IDs would then refuse to place.
+func (p RebalancePlan) withTradeIDs() RebalancePlan {
+	hash := p.Hash()
+	trades := map[Asset]Trade{}
+	for asset, trade := range p.trades {
+		trade.ID = tradeID(hash, string(asset))
+		trades[asset] = trade
+	}
+	p.trades = trades
+	return p
+}
+
+// tradeID returns a short stable ID derived from parts, which fits the client
+// order IDs accepted by exchanges.
+func tradeID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SortedTrades returns the trades of the plan in the order given by
+// SortTrades.
+func (p RebalancePlan) SortedTrades() []Trade {
+	return SortTrades(p.trades, p.Pricelist)
+}
+
+// MarshalJSON encodes the plan, including its trades, as JSON.
+func (p RebalancePlan) MarshalJSON() ([]byte, error) {
+	type fields RebalancePlan
+	return json.Marshal(struct {
+		fields
+		Trades map[Asset]Trade
+	}{fields(p), p.trades})
+}
+
+// UnmarshalJSON decodes a plan encoded by MarshalJSON.
+func (p *RebalancePlan) UnmarshalJSON(data []byte) error {
+	type fields RebalancePlan
+	var decoded struct {
+		fields
+		Trades map[Asset]Trade
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*p = RebalancePlan(decoded.fields)
+	p.trades = decoded.Trades
+	return nil
+}
+
+// ExecutionOrder returns the trades of the plan in an order they can be
+// executed in without running out of cash. Cash starts at the plan's
+// contribution, if any, and moves by each trade's Value and Fee. Sells come
+// first, largest first, and each is followed by the buys, largest first, which
+// the cash raised so far can pay for. A buy the cash can only pay for part of
+// is split: the part which can be paid for is placed straight away and the
+// rest after the next sells, so every buy is executed in full as the sells
+// funding it settle. Split parts are given IDs of their own, derived from the
+// trade's ID. Only the value which no sell can fund, when the plan is not self
+// funding, is left unbought and added to the Residual of the buy it belongs
+// to, so the balance never goes negative.
+func (p RebalancePlan) ExecutionOrder() []Trade {
+	cash := decimal.Zero
+	if p.Contribution.IsPositive() {
+		cash = p.Contribution
+	}
+
+	var sells, pending, ordered []Trade
+	for _, trade := range SortTrades(p.trades, p.Pricelist) {
+		if trade.Action == Sell {
+			sells = append(sells, trade)
+		} else {
+			pending = append(pending, trade)
+		}
+	}
+	// part numbers the parts of a split buy.
+	parts := map[Asset]int{}
+	part := func(trade Trade) Trade {
+		parts[trade.Asset]++
+		if trade.ID != "" {
+			trade.ID = tradeID(trade.ID, strconv.Itoa(parts[trade.Asset]))
+		}
+		return trade
+	}
+	// release appends the pending buys the cash pays for, and the part of the
+	// next one it pays for, if any.
+	release := func() {
+		for len(pending) > 0 {
+			trade := pending[0]
+			cost := trade.Value.Add(trade.Fee)
+			if cost.LessThanOrEqual(cash) {
+				if parts[trade.Asset] > 0 {
+					trade = part(trade)
+				}
+				ordered = append(ordered, trade)
+				cash = cash.Sub(cost)
+				pending = pending[1:]
+				continue
+			}
+			if cash.IsPositive() {
+				scale := cash.Div(cost)
+				paid := trade
+				paid.Amount = trade.Amount.Mul(scale)
+				paid.Value = trade.Value.Mul(scale)
+				paid.Fee = trade.Fee.Mul(scale)
+				paid.Residual = decimal.Zero
+				ordered = append(ordered, part(paid))
+				trade.Amount = trade.Amount.Sub(paid.Amount)
+				trade.Value = trade.Value.Sub(paid.Value)
+				trade.Fee = trade.Fee.Sub(paid.Fee)
+				pending[0] = trade
+				cash = decimal.Zero
+			}
+			return
+		}
+	}
+
+	release()
+	for _, sell := range sells {
+		ordered = append(ordered, sell)
+		cash = cash.Add(sell.Value).Sub(sell.Fee)
+		release()
+	}
+	for _, trade := range pending {
+		trade.Residual = trade.Residual.Add(trade.Amount.Mul(p.Pricelist[trade.Asset]))
+		trade.Amount = decimal.Zero
+		trade.Value = decimal.Zero
+		trade.Fee = decimal.Zero
+		if parts[trade.Asset] > 0 {
+			trade = part(trade)
+		}
+		ordered = append(ordered, trade)
+	}
+	return ordered
+}
@@ -0,0 +1,211 @@
+package rebalancer_test
+
+import (
+	"encoding/json"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestRebalancePlan(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	asOf := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	plan, err := account.Rebalance(Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, AsOf(asOf))
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("the plan describes how it was calculated", func(t *testing.T) {
+		if !plan.Time.Equal(asOf) {
+			t.Errorf("got %s want %s", plan.Time, asOf)
+		}
+		if !plan.Value.Equal(decimal.NewFromFloat(6500)) {
+			t.Errorf("got %v want %v", plan.Value, 6500)
+		}
+		if !plan.Turnover.Equal(decimal.NewFromFloat(1500)) {
+			t.Errorf("got %v want %v", plan.Turnover, 1500)
+		}
+		if !plan.Pricelist["BTC"].Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %v want %v", plan.Pricelist["BTC"], 5000)
+		}
+		if !plan.Index["ETH"].Equal(decimal.NewFromFloat(0.5)) {
+			t.Errorf("got %v want %v", plan.Index["ETH"], 0.5)
+		}
+	})
+	t.Run("the trades are returned as a copy", func(t *testing.T) {
+		trades := plan.Trades()
+		delete(trades, "ETH")
+
+		if _, ok := plan.Trades()["ETH"]; !ok {
+			t.Error("got no ETH trade want the plan to be unchanged")
+		}
+	})
+	t.Run("the trades can be sorted", func(t *testing.T) {
+		sorted := plan.SortedTrades()
+
+		if len(sorted) != 2 || sorted[0].Asset != "ETH" || sorted[1].Asset != "BTC" {
+			t.Errorf("got %v want the ETH sell before the BTC buy", sorted)
+		}
+	})
+	t.Run("plans round trip through JSON", func(t *testing.T) {
+		data, err := json.Marshal(plan)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		var got RebalancePlan
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		assertSameTrades(t, got.Trades(), plan.Trades())
+		if !got.Value.Equal(plan.Value) || !got.Time.Equal(plan.Time) {
+			t.Errorf("got %v want %v", got, plan)
+		}
+		if got.Hash() != plan.Hash() || got.Trades()["ETH"].ID != plan.Trades()["ETH"].ID {
+			t.Errorf("got hash %s want %s", got.Hash(), plan.Hash())
+		}
+	})
+	t.Run("trades have IDs which are stable across identical plans", func(t *testing.T) {
+		again, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, AsOf(asOf))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		trades := plan.Trades()
+		if trades["ETH"].ID == "" || trades["ETH"].ID == trades["BTC"].ID {
+			t.Errorf("got IDs %s and %s want distinct IDs", trades["ETH"].ID, trades["BTC"].ID)
+		}
+		for asset, trade := range again.Trades() {
+			if trade.ID != trades[asset].ID {
+				t.Errorf("got ID %s for %s want %s", trade.ID, asset, trades[asset].ID)
+			}
+		}
+	})
+	t.Run("trades planned again later have new IDs unless the plan is saved", func(t *testing.T) {
+		later, _ := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, AsOf(asOf.Add(time.Minute)))
+		data, _ := json.Marshal(plan)
+		var saved RebalancePlan
+		json.Unmarshal(data, &saved)
+
+		if later.Trades()["ETH"].ID == plan.Trades()["ETH"].ID {
+			t.Errorf("got ID %s for both plans want a new one", plan.Trades()["ETH"].ID)
+		}
+		for asset, trade := range saved.Trades() {
+			if trade.ID != plan.Trades()[asset].ID {
+				t.Errorf("got ID %s for %s want the saved %s", trade.ID, asset, plan.Trades()[asset].ID)
+			}
+		}
+	})
+	t.Run("trades of different plans have different IDs", func(t *testing.T) {
+		other, _ := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.4),
+			"BTC": decimal.NewFromFloat(0.6),
+		}, AsOf(asOf))
+
+		if other.Hash() == plan.Hash() || other.Trades()["ETH"].ID == plan.Trades()["ETH"].ID {
+			t.Errorf("got the same hash %s for different plans", plan.Hash())
+		}
+	})
+}
+
+func TestRebalancePlan_ExecutionOrder(t *testing.T) {
+	t.Run("sells are executed before the buys they fund", func(t *testing.T) {
+		account, _ := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+			"XLM": decimal.NewFromFloat(0.2),
+		})
+
+		plan, err := account.Rebalance(Index{
+			"BTC": decimal.NewFromFloat(0.5),
+			"XLM": decimal.NewFromFloat(0.5),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.ExecutionOrder()
+		if len(got) != 3 || got[0].Asset != "ETH" || got[1].Asset != "XLM" || got[2].Asset != "BTC" {
+			t.Errorf("got %v want ETH sold before the XLM and BTC buys", got)
+		}
+		if !got[2].Amount.Equal(decimal.NewFromFloat(0.15)) {
+			t.Errorf("got %v want %v", got[2].Amount, 0.15)
+		}
+	})
+	t.Run("buys the cash cannot pay for are cut down", func(t *testing.T) {
+		var plan RebalancePlan
+		err := json.Unmarshal([]byte(`{
+			"Pricelist": {"ETH": "200", "BTC": "5000"},
+			"Trades": {
+				"ETH": {"Action": "sell", "Asset": "ETH", "Amount": "5", "Value": "1000", "Fee": "10"},
+				"BTC": {"Action": "buy", "Asset": "BTC", "Amount": "0.2", "Value": "1000"}
+			}
+		}`), &plan)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.ExecutionOrder()
+		if len(got) != 3 || got[1].Asset != "BTC" || !got[1].Amount.Equal(decimal.NewFromFloat(0.198)) {
+			t.Errorf("got %v want the BTC buy cut down to 0.198", got)
+		}
+		if !got[2].Amount.IsZero() || !got[2].Residual.Equal(decimal.NewFromFloat(10)) {
+			t.Errorf("got %v want the unfunded 10 left as residual", got[2])
+		}
+	})
+	t.Run("buys funded by several sells are split between them", func(t *testing.T) {
+		var plan RebalancePlan
+		err := json.Unmarshal([]byte(`{
+			"Pricelist": {"ETH": "200", "XLM": "0.2", "BTC": "5000"},
+			"Trades": {
+				"ETH": {"Action": "sell", "Asset": "ETH", "Amount": "3", "Value": "600"},
+				"XLM": {"Action": "sell", "Asset": "XLM", "Amount": "2000", "Value": "400"},
+				"BTC": {"Action": "buy", "Asset": "BTC", "Amount": "0.2", "Value": "1000", "ID": "0123456789abcdef"}
+			}
+		}`), &plan)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.ExecutionOrder()
+		if len(got) != 4 || got[0].Asset != "ETH" || got[1].Asset != "BTC" || got[2].Asset != "XLM" || got[3].Asset != "BTC" {
+			t.Fatalf("got %v want the BTC buy split after each sell", got)
+		}
+		if !got[1].Amount.Equal(decimal.NewFromFloat(0.12)) || !got[3].Amount.Equal(decimal.NewFromFloat(0.08)) {
+			t.Errorf("got %v and %v want 0.12 and 0.08", got[1].Amount, got[3].Amount)
+		}
+		if got[1].ID == got[3].ID || got[1].ID == "0123456789abcdef" {
+			t.Errorf("got IDs %s and %s want distinct IDs for each part", got[1].ID, got[3].ID)
+		}
+	})
+}
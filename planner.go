@@ -0,0 +1,170 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+)
+
+// maxCostIterations bounds the refinement of trades when execution costs are
+// taken into account.
+const maxCostIterations = 50
+
+// ErrInvalidCashReserve is returned when a cash reserve is negative, or its
+// ratio is above 1.
+var ErrInvalidCashReserve = errors.New("cash reserve must not be negative or above 100%")
+
+// frozenAssets returns the assets which must not be traded, whose value is
+// excluded from the total being rebalanced.
+func (a Account) frozenAssets(targetIndex Index, config rebalanceConfig) map[Asset]bool {
+	frozen := map[Asset]bool{}
+	for asset := range config.locked {
+		frozen[asset] = true
+	}
+	if config.keepUnlisted {
+		for asset := range a.portfolio {
+			if _, ok := targetIndex[asset]; !ok && !config.isBlocked(asset) {
+				frozen[asset] = true
+			}
+		}
+	}
+	if config.driftBands {
+		for asset, inBand := range a.withinDriftBands(targetIndex, config) {
+			if inBand && !config.isBlocked(asset) {
+				frozen[asset] = true
+			}
+		}
+	}
+	return frozen
+}
+
+// investable returns the value of the account available to rebalance, that
+// is the value of every asset which is not frozen.
+func (a Account) investable(frozen map[Asset]bool) decimal.Decimal {
+	investable := a.value
+	for asset, amount := range a.portfolio {
+		if frozen[asset] {
+			investable = investable.Sub(amount.Mul(a.pricelist[asset]))
+		}
+	}
+	return investable
+}
+
+// withoutCashReserve returns what remains of investable once the cash reserve
+// configured by config has been set aside.
+func withoutCashReserve(investable decimal.Decimal, config rebalanceConfig) (decimal.Decimal, error) {
+	if config.cashReserve.IsNegative() || config.reserveRatio.IsNegative() || config.reserveRatio.GreaterThan(decimal.New(1, 0)) {
+		return decimal.Zero, ErrInvalidCashReserve
+	}
+	investable = investable.Sub(investable.Mul(config.reserveRatio)).Sub(config.cashReserve)
+	if investable.IsNegative() {
+		return decimal.Zero, ErrInsufficientValue
+	}
+	return investable, nil
+}
+
+// proportionalTrades returns the trades which allocate investable across the
+// assets of targetIndex which are not frozen, in proportion to their weights,
+// and sell any holdings absent from targetIndex which are not frozen.
+func (a Account) proportionalTrades(targetIndex Index, frozen map[Asset]bool, investable decimal.Decimal) map[Asset]Trade {
+	weights := decimal.Zero
+	for asset, percentage := range targetIndex {
+		if !frozen[asset] {
+			weights = weights.Add(percentage)
+		}
+	}
+
+	trades := map[Asset]Trade{}
+	amountRequired := decimal.Zero
+
+	for asset, percentage := range targetIndex {
+		if frozen[asset] {
+			continue
+		}
+
+		amountRequired = investable.Mul(percentage).Div(weights).Div(a.pricelist[asset])
+
+		if portfolioAmount, ok := a.portfolio[asset]; ok {
+			amountRequired = amountRequired.Sub(portfolioAmount)
+		}
+
+		if amountRequired.IsNegative() {
+			trades[asset] = Trade{Action: Sell, Amount: amountRequired.Abs(), Asset: asset}
+			continue
+		}
+		trades[asset] = Trade{Action: Buy, Amount: amountRequired.Abs(), Asset: asset}
+	}
+
+	for asset, amount := range a.portfolio {
+		if _, ok := targetIndex[asset]; ok || frozen[asset] {
+			continue
+		}
+		trades[asset] = Trade{Action: Sell, Amount: amount, Asset: asset}
+	}
+
+	return trades
+}
+
+// costAwareTrades refines the proportional trades so that, once the costs of
+// executing them are paid out of their proceeds, the remaining value is
+// allocated according to targetIndex. The costs are the fees estimated by
+// config's FeeModel, plus the difference between the account's prices and
+// the execution prices caused by spread and slippage. Since the costs depend
+// on the trades, their total is found by fixed-point iteration.
+func (a Account) costAwareTrades(targetIndex Index, frozen map[Asset]bool, investable decimal.Decimal, config rebalanceConfig) map[Asset]Trade {
+	costs := decimal.Zero
+	trades := map[Asset]Trade{}
+	for i := 0; i < maxCostIterations; i++ {
+		trades = a.proportionalTrades(targetIndex, frozen, investable.Sub(costs))
+		next := decimal.Zero
+		for asset, trade := range trades {
+			price := a.executionPrice(trade, config)
+			if config.fees != nil {
+				trade.Fee = config.fees.Fee(trade, price)
+				trades[asset] = trade
+			}
+			spread := price.Sub(a.pricelist[asset]).Abs().Mul(trade.Amount)
+			next = next.Add(trade.Fee).Add(spread)
+		}
+		if next.Sub(costs).Abs().LessThanOrEqual(investable.Mul(valueTolerance)) {
+			break
+		}
+		costs = next
+	}
+	return trades
+}
+
+// executionPrice returns the price trade is expected to execute at: the bid
+// for sells and the ask for buys when the account has quotes, otherwise its
+// price, moved against the trade by any slippage estimated by config.
+func (a Account) executionPrice(trade Trade, config rebalanceConfig) decimal.Decimal {
+	price := a.pricelist[trade.Asset]
+	if quote, ok := a.quotes[trade.Asset]; ok {
+		price = quote.Ask
+		if trade.Action == Sell {
+			price = quote.Bid
+		}
+	}
+	if config.slippage == nil {
+		return price
+	}
+	slippage := config.slippage.Slippage(trade, price)
+	if trade.Action == Sell {
+		return price.Mul(decimal.New(1, 0).Sub(slippage))
+	}
+	return price.Mul(decimal.New(1, 0).Add(slippage))
+}
+
+// limitPrice returns the suggested limit price of trade: its price in the
+// account's pricelist moved by config's offset in the trade's favour of being
+// filled, up for buys and down for sells, then rounded to any tick size.
+func (a Account) limitPrice(trade Trade, config rebalanceConfig) decimal.Decimal {
+	offset := config.limitOffset.Div(decimal.New(10000, 0))
+	price := a.pricelist[trade.Asset].Mul(decimal.New(1, 0).Add(offset))
+	if trade.Action == Sell {
+		price = a.pricelist[trade.Asset].Mul(decimal.New(1, 0).Sub(offset))
+	}
+	if rule, ok := config.exchangeRules[trade.Asset]; ok {
+		price = rule.RoundPrice(price)
+	}
+	return price
+}
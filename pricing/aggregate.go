@@ -0,0 +1,110 @@
+package pricing
+
+import (
+	"context"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+)
+
+// A Method combines the prices of an asset from several providers into one.
+// It is never given an empty slice.
+type Method func(prices []decimal.Decimal) decimal.Decimal
+
+// MedianOf takes the median price, which a single bad feed amongst three or
+// more cannot move far.
+var MedianOf Method = median
+
+// MeanWithoutOutliers takes the mean of the prices within maxDeviation of
+// their median, as a fraction of it, for example 0.05 to discard prices more
+// than 5% away. When no price is that close, as with two prices far apart, it
+// takes the median instead.
+func MeanWithoutOutliers(maxDeviation decimal.Decimal) Method {
+	return func(prices []decimal.Decimal) decimal.Decimal {
+		mid := median(prices)
+		sum, n := decimal.Zero, 0
+		for _, price := range prices {
+			if price.Sub(mid).Abs().LessThanOrEqual(mid.Mul(maxDeviation)) {
+				sum = sum.Add(price)
+				n++
+			}
+		}
+		if n == 0 {
+			return mid
+		}
+		return sum.Div(decimal.New(int64(n), 0))
+	}
+}
+
+func median(prices []decimal.Decimal) decimal.Decimal {
+	sorted := append([]decimal.Decimal(nil), prices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].LessThan(sorted[j])
+	})
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return sorted[mid-1].Add(sorted[mid]).Div(decimal.New(2, 0))
+}
+
+type aggregate struct {
+	method    Method
+	providers []rebalancer.PriceProvider
+}
+
+// Aggregate returns a provider which asks every one of providers at once and
+// combines the prices they return for each asset with method, protecting
+// rebalances from a single bad feed. Providers which fail are ignored unless
+// all of them do. Assets no provider prices are left out, or listed in an
+// ErrUnpriced when they were requested.
+func Aggregate(method Method, providers ...rebalancer.PriceProvider) rebalancer.PriceProvider {
+	return aggregate{method: method, providers: providers}
+}
+
+// Prices returns the combined prices of assets.
+func (a aggregate) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	results := make([]rebalancer.Pricelist, len(a.providers))
+	errs := make([]error, len(a.providers))
+	var wg sync.WaitGroup
+	for i, provider := range a.providers {
+		wg.Add(1)
+		go func(i int, provider rebalancer.PriceProvider) {
+			defer wg.Done()
+			results[i], errs[i] = provider.Prices(ctx, assets...)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	var failures []error
+	prices := map[rebalancer.Asset][]decimal.Decimal{}
+	for i, result := range results {
+		if errs[i] != nil {
+			failures = append(failures, errs[i])
+			continue
+		}
+		for asset, price := range result {
+			prices[asset] = append(prices[asset], price)
+		}
+	}
+
+	pricelist := rebalancer.Pricelist{}
+	for asset, quoted := range prices {
+		pricelist[asset] = a.method(quoted)
+	}
+
+	var missing []rebalancer.Asset
+	for _, asset := range assets {
+		if _, ok := pricelist[asset]; !ok {
+			missing = append(missing, asset)
+		}
+	}
+	if len(missing) > 0 || (len(failures) > 0 && len(failures) == len(a.providers)) {
+		sort.Slice(missing, func(i, j int) bool {
+			return missing[i] < missing[j]
+		})
+		return pricelist, ErrUnpriced{Assets: missing, Errors: failures}
+	}
+	return pricelist, nil
+}
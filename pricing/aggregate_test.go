@@ -0,0 +1,81 @@
+package pricing_test
+
+import (
+	"context"
+	"errors"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	feeds := []rebalancer.PriceProvider{
+		rebalancer.Pricelist{"BTC": decimal.NewFromFloat(5000), "ETH": decimal.NewFromFloat(200)},
+		rebalancer.Pricelist{"BTC": decimal.NewFromFloat(5100), "ETH": decimal.NewFromFloat(210)},
+		rebalancer.Pricelist{"BTC": decimal.NewFromFloat(50), "ETH": decimal.NewFromFloat(205)},
+		failing(errors.New("down")),
+	}
+
+	t.Run("the median price is taken", func(t *testing.T) {
+		got, err := Aggregate(MedianOf, feeds...).Prices(ctx, "BTC", "ETH")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got["BTC"].Equal(decimal.NewFromFloat(5000)) || !got["ETH"].Equal(decimal.NewFromFloat(205)) {
+			t.Errorf("got %v want BTC 5000 and ETH 205", got)
+		}
+	})
+
+	t.Run("the median of an even number of prices is their middle", func(t *testing.T) {
+		got, _ := Aggregate(MedianOf, feeds[:2]...).Prices(ctx, "BTC")
+
+		if !got["BTC"].Equal(decimal.NewFromFloat(5050)) {
+			t.Errorf("got %s want 5050", got["BTC"])
+		}
+	})
+
+	t.Run("outliers are excluded from the mean", func(t *testing.T) {
+		got, err := Aggregate(MeanWithoutOutliers(decimal.NewFromFloat(0.05)), feeds...).Prices(ctx, "BTC")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got["BTC"].Equal(decimal.NewFromFloat(5050)) {
+			t.Errorf("got %s want 5050", got["BTC"])
+		}
+	})
+
+	t.Run("the median is taken when every price is an outlier", func(t *testing.T) {
+		got, err := Aggregate(MeanWithoutOutliers(decimal.NewFromFloat(0.01)),
+			rebalancer.Pricelist{"BTC": decimal.NewFromFloat(100)},
+			rebalancer.Pricelist{"BTC": decimal.NewFromFloat(110)},
+		).Prices(ctx, "BTC")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got["BTC"].Equal(decimal.NewFromFloat(105)) {
+			t.Errorf("got %s want 105", got["BTC"])
+		}
+	})
+
+	t.Run("assets no provider prices are listed", func(t *testing.T) {
+		_, err := Aggregate(MedianOf, feeds...).Prices(ctx, "BTC", "XRP")
+
+		unpriced, ok := err.(ErrUnpriced)
+		if !ok || len(unpriced.Assets) != 1 || unpriced.Assets[0] != "XRP" {
+			t.Errorf("got %v want XRP unpriced", err)
+		}
+	})
+
+	t.Run("an error is returned when every provider fails", func(t *testing.T) {
+		_, err := Aggregate(MedianOf, failing(errors.New("down"))).Prices(ctx)
+
+		if _, ok := err.(ErrUnpriced); !ok {
+			t.Errorf("got %v want ErrUnpriced", err)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+// Package binance provides a rebalancer.PriceProvider backed by the public
+// ticker endpoints of the Binance spot API, and a rebalancer.PriceStream
+// backed by its market data websocket.
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// BaseURL is the address of the Binance spot API.
+const BaseURL = "https://api.binance.com"
+
+// An APIError is an error reported by the Binance API.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+// Error formats the error message for APIError.
+func (e APIError) Error() string {
+	return fmt.Sprintf("binance: %s (code %d)", e.Message, e.Code)
+}
+
+// A Client prices assets in a single quote asset, such as USDT, by converting
+// the prices of the pairs Binance trades. Assets without a pair against the
+// quote asset are priced through intermediate assets, for instance an
+// altcoin only traded against BTC is priced through BTC/USDT.
+type Client struct {
+	baseURL    string
+	streamURL  string
+	quote      rebalancer.Asset
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	symbols map[string]symbol
+}
+
+type symbol struct {
+	Symbol     string           `json:"symbol"`
+	Status     string           `json:"status"`
+	BaseAsset  rebalancer.Asset `json:"baseAsset"`
+	QuoteAsset rebalancer.Asset `json:"quoteAsset"`
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sends requests to baseURL instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// New returns a Client which prices assets in quote.
+func New(quote rebalancer.Asset, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    BaseURL,
+		streamURL:  StreamURL,
+		quote:      quote,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Prices returns the prices of assets in the quote asset, or of every asset
+// which can be converted into it when no assets are given.
+func (c *Client) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	book, err := c.PairBook(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all := book.Pricelist(c.quote)
+	if len(assets) == 0 {
+		return all, nil
+	}
+	return all.Prices(ctx, assets...)
+}
+
+// PairBook returns the book of every pair Binance is trading, at their latest
+// prices.
+func (c *Client) PairBook(ctx context.Context) (rebalancer.PairBook, error) {
+	symbols, err := c.loadSymbols(ctx)
+	if err != nil {
+		return rebalancer.PairBook{}, err
+	}
+	var tickers []struct {
+		Symbol string          `json:"symbol"`
+		Price  decimal.Decimal `json:"price"`
+	}
+	if err := c.get(ctx, "/api/v3/ticker/price", &tickers); err != nil {
+		return rebalancer.PairBook{}, err
+	}
+
+	var pairs []rebalancer.Pair
+	for _, ticker := range tickers {
+		s, ok := symbols[ticker.Symbol]
+		if !ok || !ticker.Price.IsPositive() {
+			continue
+		}
+		pairs = append(pairs, rebalancer.Pair{Base: s.BaseAsset, Quote: s.QuoteAsset, Price: ticker.Price})
+	}
+	return rebalancer.NewPairBook(pairs)
+}
+
+// Quotes returns the best bid and ask of assets in the quote asset, or of
+// every asset traded against it when no assets are given. Only assets with a
+// pair against the quote asset, in either direction, are quoted.
+func (c *Client) Quotes(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Quotelist, error) {
+	symbols, err := c.loadSymbols(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tickers []struct {
+		Symbol   string          `json:"symbol"`
+		BidPrice decimal.Decimal `json:"bidPrice"`
+		AskPrice decimal.Decimal `json:"askPrice"`
+	}
+	if err := c.get(ctx, "/api/v3/ticker/bookTicker", &tickers); err != nil {
+		return nil, err
+	}
+
+	wanted := map[rebalancer.Asset]bool{}
+	for _, asset := range assets {
+		wanted[asset] = true
+	}
+	quotes := rebalancer.Quotelist{}
+	for _, ticker := range tickers {
+		s, ok := symbols[ticker.Symbol]
+		if !ok || !ticker.BidPrice.IsPositive() || !ticker.AskPrice.IsPositive() {
+			continue
+		}
+		switch {
+		case s.QuoteAsset == c.quote && (len(wanted) == 0 || wanted[s.BaseAsset]):
+			quotes[s.BaseAsset] = rebalancer.Quote{Bid: ticker.BidPrice, Ask: ticker.AskPrice}
+		case s.BaseAsset == c.quote && (len(wanted) == 0 || wanted[s.QuoteAsset]):
+			// Selling the asset buys the quote asset at its ask, and
+			// buying it sells the quote asset at its bid.
+			if _, ok := quotes[s.QuoteAsset]; ok {
+				continue
+			}
+			one := decimal.New(1, 0)
+			quotes[s.QuoteAsset] = rebalancer.Quote{Bid: one.Div(ticker.AskPrice), Ask: one.Div(ticker.BidPrice)}
+		}
+	}
+	return quotes, nil
+}
+
+// loadSymbols returns every trading symbol, fetching them from exchangeInfo
+// on first use.
+func (c *Client) loadSymbols(ctx context.Context) (map[string]symbol, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.symbols != nil {
+		return c.symbols, nil
+	}
+
+	var info struct {
+		Symbols []symbol `json:"symbols"`
+	}
+	if err := c.get(ctx, "/api/v3/exchangeInfo", &info); err != nil {
+		return nil, err
+	}
+	symbols := map[string]symbol{}
+	for _, s := range info.Symbols {
+		if s.Status == "TRADING" {
+			symbols[s.Symbol] = s
+		}
+	}
+	c.symbols = symbols
+	return symbols, nil
+}
+
+// get requests path and decodes the JSON response into result.
+func (c *Client) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := APIError{Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		_ = json.Unmarshal(body, &apiErr)
+		return apiErr
+	}
+	return json.Unmarshal(body, result)
+}
@@ -0,0 +1,98 @@
+package binance_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing/binance"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// PriceProvider asserts that Client implements rebalancer.PriceProvider.
+var _ rebalancer.PriceProvider = &Client{}
+
+func newServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/exchangeInfo":
+			fmt.Fprint(w, `{"symbols": [
+				{"symbol": "BTCUSDT", "status": "TRADING", "baseAsset": "BTC", "quoteAsset": "USDT"},
+				{"symbol": "ETHBTC", "status": "TRADING", "baseAsset": "ETH", "quoteAsset": "BTC"},
+				{"symbol": "USDTTRY", "status": "TRADING", "baseAsset": "USDT", "quoteAsset": "TRY"},
+				{"symbol": "OLDUSDT", "status": "BREAK", "baseAsset": "OLD", "quoteAsset": "USDT"}
+			]}`)
+		case "/api/v3/ticker/price":
+			fmt.Fprint(w, `[
+				{"symbol": "BTCUSDT", "price": "5000"},
+				{"symbol": "ETHBTC", "price": "0.04"},
+				{"symbol": "USDTTRY", "price": "20"},
+				{"symbol": "OLDUSDT", "price": "1"}
+			]`)
+		case "/api/v3/ticker/bookTicker":
+			fmt.Fprint(w, `[
+				{"symbol": "BTCUSDT", "bidPrice": "4990", "askPrice": "5010"},
+				{"symbol": "ETHBTC", "bidPrice": "0.0399", "askPrice": "0.0401"},
+				{"symbol": "USDTTRY", "bidPrice": "20", "askPrice": "25"}
+			]`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code": -1121, "msg": "Invalid symbol."}`)
+		}
+	}))
+}
+
+func TestClient(t *testing.T) {
+	server := newServer()
+	defer server.Close()
+	ctx := context.Background()
+	client := New("USDT", WithBaseURL(server.URL))
+
+	t.Run("assets are priced in the quote asset through intermediate pairs", func(t *testing.T) {
+		got, err := client.Prices(ctx, "BTC", "ETH", "TRY")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		want := rebalancer.Pricelist{
+			"BTC": decimal.NewFromFloat(5000),
+			"ETH": decimal.NewFromFloat(200),
+			"TRY": decimal.NewFromFloat(0.05),
+		}
+		for asset, price := range want {
+			if !got[asset].Equal(price) {
+				t.Errorf("got %s for %s want %s", got[asset], asset, price)
+			}
+		}
+	})
+
+	t.Run("symbols which are not trading are ignored", func(t *testing.T) {
+		got, err := client.Prices(ctx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if _, ok := got["OLD"]; ok || len(got) != 4 {
+			t.Errorf("got %v want prices for USDT, BTC, ETH and TRY", got)
+		}
+	})
+
+	t.Run("bid and ask are quoted for pairs against the quote asset", func(t *testing.T) {
+		got, err := client.Quotes(ctx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got %v want quotes for BTC and TRY", got)
+		}
+		if !got["BTC"].Bid.Equal(decimal.NewFromFloat(4990)) || !got["BTC"].Ask.Equal(decimal.NewFromFloat(5010)) {
+			t.Errorf("got %v want 4990/5010", got["BTC"])
+		}
+		if !got["TRY"].Bid.Equal(decimal.NewFromFloat(0.04)) || !got["TRY"].Ask.Equal(decimal.NewFromFloat(0.05)) {
+			t.Errorf("got %v want 0.04/0.05", got["TRY"])
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	"github.com/pdbrito/rebalancer/internal/websocket"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+// StreamURL is the address of the Binance market data websocket.
+const StreamURL = "wss://stream.binance.com:9443"
+
+// WithStreamURL streams prices from streamURL instead of StreamURL.
+func WithStreamURL(streamURL string) Option {
+	return func(c *Client) {
+		c.streamURL = strings.TrimRight(streamURL, "/")
+	}
+}
+
+type miniTicker struct {
+	Symbol string          `json:"s"`
+	Close  decimal.Decimal `json:"c"`
+}
+
+// Stream streams the last traded prices of assets in the quote asset from
+// Binance's mini ticker stream, or of every asset traded against the quote
+// asset when no assets are given. Only assets with a pair against the quote
+// asset are streamed.
+func (c *Client) Stream(ctx context.Context, assets ...rebalancer.Asset) (<-chan rebalancer.Pricelist, error) {
+	symbols := map[string]rebalancer.Asset{}
+	path := "/ws/!miniTicker@arr"
+	if len(assets) > 0 {
+		streams := make([]string, len(assets))
+		for i, asset := range assets {
+			symbol := string(asset) + string(c.quote)
+			symbols[symbol] = asset
+			streams[i] = strings.ToLower(symbol) + "@miniTicker"
+		}
+		path = "/stream?streams=" + strings.Join(streams, "/")
+	} else {
+		all, err := c.loadSymbols(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for symbol, s := range all {
+			if s.QuoteAsset == c.quote {
+				symbols[symbol] = s.BaseAsset
+			}
+		}
+	}
+
+	conn, err := websocket.Dial(ctx, c.streamURL+path)
+	if err != nil {
+		return nil, err
+	}
+	updates := make(chan rebalancer.Pricelist)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer close(updates)
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var tickers []miniTicker
+			var combined struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if json.Unmarshal(message, &combined) == nil && combined.Data != nil {
+				message = combined.Data
+			}
+			if err := json.Unmarshal(message, &tickers); err != nil {
+				var ticker miniTicker
+				if json.Unmarshal(message, &ticker) != nil {
+					continue
+				}
+				tickers = []miniTicker{ticker}
+			}
+
+			pricelist := rebalancer.Pricelist{}
+			for _, ticker := range tickers {
+				if asset, ok := symbols[ticker.Symbol]; ok && ticker.Close.IsPositive() {
+					pricelist[asset] = ticker.Close
+				}
+			}
+			if len(pricelist) == 0 {
+				continue
+			}
+			select {
+			case updates <- pricelist:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
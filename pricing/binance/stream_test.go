@@ -0,0 +1,53 @@
+package binance_test
+
+import (
+	"context"
+	"github.com/pdbrito/rebalancer"
+	"github.com/pdbrito/rebalancer/internal/websocket"
+	. "github.com/pdbrito/rebalancer/pricing/binance"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// PriceStream asserts that Client implements rebalancer.PriceStream.
+var _ rebalancer.PriceStream = &Client{}
+
+func TestClient_Stream(t *testing.T) {
+	var streams string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streams = r.URL.Query().Get("streams")
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage([]byte(`{"stream": "btcusdt@miniTicker", "data": {"e": "24hrMiniTicker", "s": "BTCUSDT", "c": "5000.5"}}`))
+		conn.WriteMessage([]byte(`{"stream": "ethusdt@miniTicker", "data": {"e": "24hrMiniTicker", "s": "ETHUSDT", "c": "201"}}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := New("USDT", WithStreamURL("ws"+strings.TrimPrefix(server.URL, "http")))
+
+	updates, err := client.Stream(ctx, "BTC", "ETH")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if streams != "btcusdt@miniTicker/ethusdt@miniTicker" {
+		t.Errorf("got streams %s want the BTCUSDT and ETHUSDT mini tickers", streams)
+	}
+	first, second := <-updates, <-updates
+	if !first["BTC"].Equal(decimal.NewFromFloat(5000.5)) || !second["ETH"].Equal(decimal.NewFromFloat(201)) {
+		t.Errorf("got %v then %v want BTC 5000.5 then ETH 201", first, second)
+	}
+
+	cancel()
+	for range updates {
+	}
+}
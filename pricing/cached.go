@@ -0,0 +1,157 @@
+package pricing
+
+import (
+	"context"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"sync"
+	"time"
+)
+
+// A CacheOption configures a provider returned by Cached.
+type CacheOption func(*cached)
+
+// MaxAge is the hard limit on the age of cached prices. A price older than
+// maxAge is refreshed before it is served, and if that fails a
+// rebalancer.ErrStalePrice is returned instead of it.
+func MaxAge(maxAge time.Duration) CacheOption {
+	return func(c *cached) {
+		c.maxAge = maxAge
+	}
+}
+
+type cachedPrice struct {
+	price   decimal.Decimal
+	fetched time.Time
+}
+
+type cached struct {
+	provider rebalancer.PriceProvider
+	ttl      time.Duration
+	maxAge   time.Duration
+
+	mu         sync.Mutex
+	prices     map[rebalancer.Asset]cachedPrice
+	refreshing map[rebalancer.Asset]bool
+	all        time.Time
+}
+
+// Cached returns a provider which serves prices from provider for ttl after
+// fetching them. Once a price is older than ttl it is still served, but
+// refreshed in the background; see MaxAge to bound how old it may get. Prices
+// which were never fetched are fetched before being served.
+func Cached(provider rebalancer.PriceProvider, ttl time.Duration, opts ...CacheOption) rebalancer.PriceProvider {
+	c := &cached{
+		provider:   provider,
+		ttl:        ttl,
+		prices:     map[rebalancer.Asset]cachedPrice{},
+		refreshing: map[rebalancer.Asset]bool{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Prices returns the prices of assets, from the cache where possible. When no
+// assets are given every cached price is returned, fetching them all first if
+// that was not done within the ttl.
+func (c *cached) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	if len(assets) == 0 {
+		return c.allPrices(ctx)
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	var fetch, refresh []rebalancer.Asset
+	for _, asset := range assets {
+		cached, ok := c.prices[asset]
+		age := now.Sub(cached.fetched)
+		switch {
+		case !ok || (c.maxAge > 0 && age >= c.maxAge):
+			fetch = append(fetch, asset)
+		case age >= c.ttl && !c.refreshing[asset]:
+			c.refreshing[asset] = true
+			refresh = append(refresh, asset)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(refresh) > 0 {
+		go c.refresh(refresh)
+	}
+	var fetchErr error
+	if len(fetch) > 0 {
+		fetchErr = c.fetch(ctx, fetch)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now = time.Now()
+	pricelist := rebalancer.Pricelist{}
+	for _, asset := range assets {
+		cached, ok := c.prices[asset]
+		if !ok {
+			continue
+		}
+		if age := now.Sub(cached.fetched); c.maxAge > 0 && age >= c.maxAge {
+			return nil, rebalancer.ErrStalePrice{Asset: asset, Age: age}
+		}
+		pricelist[asset] = cached.price
+	}
+	if fetchErr != nil {
+		for _, asset := range fetch {
+			if _, ok := pricelist[asset]; !ok {
+				return pricelist, fetchErr
+			}
+		}
+	}
+	return pricelist, nil
+}
+
+// allPrices returns every cached price, fetching them all if that was not
+// done within the ttl.
+func (c *cached) allPrices(ctx context.Context) (rebalancer.Pricelist, error) {
+	c.mu.Lock()
+	stale := time.Since(c.all) >= c.ttl
+	c.mu.Unlock()
+	if stale {
+		if err := c.fetch(ctx, nil); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.all = time.Now()
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pricelist := rebalancer.Pricelist{}
+	for asset, cached := range c.prices {
+		pricelist[asset] = cached.price
+	}
+	return pricelist, nil
+}
+
+// fetch fetches the prices of assets from the provider into the cache.
+func (c *cached) fetch(ctx context.Context, assets []rebalancer.Asset) error {
+	prices, err := c.provider.Prices(ctx, assets...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for asset, price := range prices {
+		c.prices[asset] = cachedPrice{price: price, fetched: now}
+	}
+	return err
+}
+
+// refresh fetches the prices of assets in the background. Failures are left
+// for the next request to notice through the prices' age.
+func (c *cached) refresh(assets []rebalancer.Asset) {
+	_ = c.fetch(context.Background(), assets)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, asset := range assets {
+		delete(c.refreshing, asset)
+	}
+}
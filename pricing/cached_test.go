@@ -0,0 +1,108 @@
+package pricing_test
+
+import (
+	"context"
+	"errors"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing"
+	"github.com/shopspring/decimal"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingProvider prices every asset at its number of calls so far, or
+// fails once failing is set.
+type countingProvider struct {
+	mu      sync.Mutex
+	calls   int
+	failing bool
+}
+
+func (p *countingProvider) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing {
+		return nil, errors.New("down")
+	}
+	p.calls++
+	pricelist := rebalancer.Pricelist{}
+	for _, asset := range assets {
+		pricelist[asset] = decimal.New(int64(p.calls), 0)
+	}
+	return pricelist, nil
+}
+
+func (p *countingProvider) fail() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failing = true
+}
+
+func (p *countingProvider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func TestCached(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("prices are served from the cache within the ttl", func(t *testing.T) {
+		provider := &countingProvider{}
+		cache := Cached(provider, time.Hour)
+
+		cache.Prices(ctx, "BTC")
+		got, err := cache.Prices(ctx, "BTC")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if provider.count() != 1 || !got["BTC"].Equal(decimal.New(1, 0)) {
+			t.Errorf("got %v after %d calls want the first price", got, provider.count())
+		}
+	})
+
+	t.Run("expired prices are served while they refresh in the background", func(t *testing.T) {
+		provider := &countingProvider{}
+		cache := Cached(provider, 10*time.Millisecond)
+
+		cache.Prices(ctx, "BTC")
+		time.Sleep(20 * time.Millisecond)
+		stale, _ := cache.Prices(ctx, "BTC")
+		time.Sleep(20 * time.Millisecond)
+		fresh, _ := cache.Prices(ctx, "BTC")
+
+		if !stale["BTC"].Equal(decimal.New(1, 0)) {
+			t.Errorf("got %s want the cached price 1", stale["BTC"])
+		}
+		if !fresh["BTC"].Equal(decimal.New(2, 0)) {
+			t.Errorf("got %s want the refreshed price 2", fresh["BTC"])
+		}
+	})
+
+	t.Run("prices older than the maximum age which cannot be refreshed are stale", func(t *testing.T) {
+		provider := &countingProvider{}
+		cache := Cached(provider, time.Millisecond, MaxAge(20*time.Millisecond))
+
+		cache.Prices(ctx, "BTC")
+		provider.fail()
+		time.Sleep(30 * time.Millisecond)
+		_, err := cache.Prices(ctx, "BTC")
+
+		stale, ok := err.(rebalancer.ErrStalePrice)
+		if !ok || stale.Asset != "BTC" || stale.Age < 20*time.Millisecond {
+			t.Errorf("got %v want a stale BTC price", err)
+		}
+	})
+
+	t.Run("errors fetching uncached prices are returned", func(t *testing.T) {
+		provider := &countingProvider{failing: true}
+
+		_, err := Cached(provider, time.Hour).Prices(ctx, "BTC")
+
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,267 @@
+// Package cmc provides a rebalancer.PriceProvider backed by the CoinMarketCap
+// API, which also reports market capitalisations for building cap-weighted
+// indexes.
+package cmc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// BaseURL is the address of the CoinMarketCap API.
+const BaseURL = "https://pro-api.coinmarketcap.com"
+
+// DefaultBatchSize is the number of symbols requested at once by default.
+const DefaultBatchSize = 100
+
+// ErrNoMarketCap is returned by CapWeightedIndex when none of the assets has a
+// market capitalisation.
+var ErrNoMarketCap = errors.New("cmc: no asset has a market cap")
+
+// An APIError is an error reported by the CoinMarketCap API.
+type APIError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"error_message"`
+}
+
+// Error formats the error message for APIError.
+func (e APIError) Error() string {
+	return fmt.Sprintf("cmc: %s (code %d)", e.Message, e.Code)
+}
+
+// A Listing is the latest price and market capitalisation of an asset, both
+// in the client's quote currency.
+type Listing struct {
+	Price     decimal.Decimal
+	MarketCap decimal.Decimal
+}
+
+// A Client fetches prices from the CoinMarketCap API in a single quote
+// currency, such as USD.
+type Client struct {
+	apiKey     string
+	baseURL    string
+	convert    string
+	batchSize  int
+	limit      int
+	httpClient *http.Client
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sends requests to baseURL instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithBatchSize requests the listings of at most size symbols at once.
+func WithBatchSize(size int) Option {
+	return func(c *Client) {
+		c.batchSize = size
+	}
+}
+
+// WithLimit sets how many of the largest assets are listed when no assets are
+// requested. It defaults to 100.
+func WithLimit(limit int) Option {
+	return func(c *Client) {
+		c.limit = limit
+	}
+}
+
+// New returns a Client which authenticates with apiKey and prices assets in
+// quote.
+func New(apiKey string, quote rebalancer.Asset, opts ...Option) *Client {
+	c := &Client{
+		apiKey:     apiKey,
+		baseURL:    BaseURL,
+		convert:    string(quote),
+		batchSize:  DefaultBatchSize,
+		limit:      100,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Prices returns the prices of assets, or of the largest assets by market
+// capitalisation when no assets are given.
+func (c *Client) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	listings, err := c.Listings(ctx, assets...)
+	if err != nil {
+		return nil, err
+	}
+	pricelist := rebalancer.Pricelist{}
+	for asset, listing := range listings {
+		if listing.Price.IsPositive() {
+			pricelist[asset] = listing.Price
+		}
+	}
+	return pricelist, nil
+}
+
+// CapWeightedIndex returns an index weighting assets by their market
+// capitalisation, or the largest assets when no assets are given. Assets
+// without a market capitalisation are left out.
+func (c *Client) CapWeightedIndex(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Index, error) {
+	listings, err := c.Listings(ctx, assets...)
+	if err != nil {
+		return nil, err
+	}
+	total := decimal.Zero
+	for _, listing := range listings {
+		if listing.MarketCap.IsPositive() {
+			total = total.Add(listing.MarketCap)
+		}
+	}
+	if !total.IsPositive() {
+		return nil, ErrNoMarketCap
+	}
+	index := rebalancer.Index{}
+	for asset, listing := range listings {
+		if listing.MarketCap.IsPositive() {
+			index[asset] = listing.MarketCap.Div(total)
+		}
+	}
+	return index, nil
+}
+
+type quote struct {
+	Symbol string                     `json:"symbol"`
+	Quote  map[string]json.RawMessage `json:"quote"`
+}
+
+type quotePrice struct {
+	Price     *decimal.Decimal `json:"price"`
+	MarketCap *decimal.Decimal `json:"market_cap"`
+}
+
+// Listings returns the price and market capitalisation of assets, or of the
+// largest assets by market capitalisation when no assets are given. Symbols
+// are requested in batches. Assets CoinMarketCap does not list are left out.
+func (c *Client) Listings(ctx context.Context, assets ...rebalancer.Asset) (map[rebalancer.Asset]Listing, error) {
+	listings := map[rebalancer.Asset]Listing{}
+	if len(assets) == 0 {
+		params := url.Values{}
+		params.Set("convert", c.convert)
+		params.Set("limit", strconv.Itoa(c.limit))
+		var data []quote
+		if err := c.get(ctx, "/v1/cryptocurrency/listings/latest", params, &data); err != nil {
+			return nil, err
+		}
+		for _, q := range data {
+			c.add(listings, q)
+		}
+		return listings, nil
+	}
+
+	size := c.batchSize
+	if size < 1 {
+		size = DefaultBatchSize
+	}
+	for start := 0; start < len(assets); start += size {
+		end := start + size
+		if end > len(assets) {
+			end = len(assets)
+		}
+		symbols := make([]string, 0, end-start)
+		for _, asset := range assets[start:end] {
+			symbols = append(symbols, string(asset))
+		}
+
+		params := url.Values{}
+		params.Set("convert", c.convert)
+		params.Set("symbol", strings.Join(symbols, ","))
+		params.Set("skip_invalid", "true")
+		var data map[string][]quote
+		if err := c.get(ctx, "/v2/cryptocurrency/quotes/latest", params, &data); err != nil {
+			return nil, err
+		}
+		for _, quotes := range data {
+			// Several assets can share a symbol; CoinMarketCap lists the
+			// largest first.
+			if len(quotes) > 0 {
+				c.add(listings, quotes[0])
+			}
+		}
+	}
+	return listings, nil
+}
+
+// add adds the listing of q in the client's quote currency to listings.
+func (c *Client) add(listings map[rebalancer.Asset]Listing, q quote) {
+	raw, ok := q.Quote[c.convert]
+	if !ok {
+		return
+	}
+	var price quotePrice
+	if err := json.Unmarshal(raw, &price); err != nil || price.Price == nil {
+		return
+	}
+	listing := Listing{Price: *price.Price, MarketCap: decimal.Zero}
+	if price.MarketCap != nil {
+		listing.MarketCap = *price.MarketCap
+	}
+	listings[rebalancer.Asset(q.Symbol)] = listing
+}
+
+// get requests path with params and decodes the data of the response into
+// result.
+func (c *Client) get(ctx context.Context, path string, params url.Values, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-CMC_PRO_API_KEY", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Status APIError        `json:"status"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return APIError{Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+		return err
+	}
+	if response.Status.Code != 0 || resp.StatusCode != http.StatusOK {
+		if response.Status.Code == 0 {
+			response.Status.Code = resp.StatusCode
+		}
+		return response.Status
+	}
+	return json.Unmarshal(response.Data, result)
+}
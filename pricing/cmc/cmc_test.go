@@ -0,0 +1,106 @@
+package cmc_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing/cmc"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// PriceProvider asserts that Client implements rebalancer.PriceProvider.
+var _ rebalancer.PriceProvider = &Client{}
+
+var listings = map[string]string{
+	"BTC": `{"symbol": "BTC", "quote": {"USD": {"price": 5000, "market_cap": 90000}}}`,
+	"ETH": `{"symbol": "ETH", "quote": {"USD": {"price": 200, "market_cap": 10000}}}`,
+	"XYZ": `{"symbol": "XYZ", "quote": {"USD": {"price": 1, "market_cap": null}}}`,
+}
+
+func TestClient(t *testing.T) {
+	var batches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-CMC_PRO_API_KEY") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"status": {"error_code": 1001, "error_message": "This API Key is invalid."}}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/cryptocurrency/listings/latest":
+			fmt.Fprintf(w, `{"status": {"error_code": 0}, "data": [%s, %s]}`, listings["BTC"], listings["ETH"])
+		case "/v2/cryptocurrency/quotes/latest":
+			symbols := r.URL.Query().Get("symbol")
+			batches = append(batches, symbols)
+			var data []string
+			for _, symbol := range strings.Split(symbols, ",") {
+				if listing, ok := listings[symbol]; ok {
+					data = append(data, fmt.Sprintf("%q: [%s]", symbol, listing))
+				}
+			}
+			fmt.Fprintf(w, `{"status": {"error_code": 0}, "data": {%s}}`, strings.Join(data, ","))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := New("key", "USD", WithBaseURL(server.URL), WithBatchSize(2))
+
+	t.Run("prices are requested in batches", func(t *testing.T) {
+		batches = nil
+
+		got, err := client.Prices(ctx, "BTC", "ETH", "XYZ", "NOPE")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(batches) != 2 || batches[0] != "BTC,ETH" || batches[1] != "XYZ,NOPE" {
+			t.Errorf("got batches %v want BTC,ETH then XYZ,NOPE", batches)
+		}
+		if len(got) != 3 || !got["BTC"].Equal(decimal.NewFromFloat(5000)) || !got["XYZ"].Equal(decimal.NewFromFloat(1)) {
+			t.Errorf("got %v want prices for BTC, ETH and XYZ", got)
+		}
+	})
+
+	t.Run("the largest assets are listed when none are given", func(t *testing.T) {
+		got, err := client.Prices(ctx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want prices for BTC and ETH", got)
+		}
+	})
+
+	t.Run("market caps weight a cap-weighted index", func(t *testing.T) {
+		got, err := client.CapWeightedIndex(ctx, "BTC", "ETH", "XYZ")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["BTC"].Equal(decimal.NewFromFloat(0.9)) || !got["ETH"].Equal(decimal.NewFromFloat(0.1)) {
+			t.Errorf("got %v want BTC 0.9 and ETH 0.1", got)
+		}
+	})
+
+	t.Run("an index of assets without market caps is an error", func(t *testing.T) {
+		if _, err := client.CapWeightedIndex(ctx, "XYZ"); err != ErrNoMarketCap {
+			t.Errorf("got %v want %v", err, ErrNoMarketCap)
+		}
+	})
+
+	t.Run("API errors are returned as APIError", func(t *testing.T) {
+		client := New("wrong", "USD", WithBaseURL(server.URL))
+
+		_, err := client.Prices(ctx, "BTC")
+
+		want := APIError{Code: 1001, Message: "This API Key is invalid."}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+// Package coinbase provides a rebalancer.PriceStream backed by the Coinbase
+// Exchange websocket feed.
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	"github.com/pdbrito/rebalancer/internal/websocket"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+// FeedURL is the address of the Coinbase Exchange websocket feed.
+const FeedURL = "wss://ws-feed.exchange.coinbase.com"
+
+// A Client streams prices of assets traded against a single quote currency,
+// such as USD.
+type Client struct {
+	feedURL string
+	quote   rebalancer.Asset
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithFeedURL streams prices from feedURL instead of FeedURL.
+func WithFeedURL(feedURL string) Option {
+	return func(c *Client) {
+		c.feedURL = feedURL
+	}
+}
+
+// New returns a Client which streams prices in quote.
+func New(quote rebalancer.Asset, opts ...Option) *Client {
+	c := &Client{feedURL: FeedURL, quote: quote}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type subscribe struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"product_ids"`
+	Channels   []string `json:"channels"`
+}
+
+type ticker struct {
+	Type      string          `json:"type"`
+	ProductID string          `json:"product_id"`
+	Price     decimal.Decimal `json:"price"`
+	Message   string          `json:"message"`
+}
+
+// Stream streams the last traded prices of assets from the ticker channel.
+// Coinbase requires the products to be named, so assets must be given.
+func (c *Client) Stream(ctx context.Context, assets ...rebalancer.Asset) (<-chan rebalancer.Pricelist, error) {
+	products := make([]string, len(assets))
+	for i, asset := range assets {
+		products[i] = string(asset) + "-" + string(c.quote)
+	}
+
+	conn, err := websocket.Dial(ctx, c.feedURL)
+	if err != nil {
+		return nil, err
+	}
+	request, _ := json.Marshal(subscribe{Type: "subscribe", ProductIDs: products, Channels: []string{"ticker"}})
+	if err := conn.WriteMessage(request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	updates := make(chan rebalancer.Pricelist)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer close(updates)
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var t ticker
+			if json.Unmarshal(message, &t) != nil {
+				continue
+			}
+			if t.Type == "error" {
+				return
+			}
+			if t.Type != "ticker" || !t.Price.IsPositive() {
+				continue
+			}
+			asset := rebalancer.Asset(strings.TrimSuffix(t.ProductID, "-"+string(c.quote)))
+			select {
+			case updates <- rebalancer.Pricelist{asset: t.Price}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
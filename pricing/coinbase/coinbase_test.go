@@ -0,0 +1,58 @@
+package coinbase_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	"github.com/pdbrito/rebalancer/internal/websocket"
+	. "github.com/pdbrito/rebalancer/pricing/coinbase"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// PriceStream asserts that Client implements rebalancer.PriceStream.
+var _ rebalancer.PriceStream = &Client{}
+
+func TestClient_Stream(t *testing.T) {
+	subscribed := make(chan []string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		message, _ := conn.ReadMessage()
+		var request struct {
+			ProductIDs []string `json:"product_ids"`
+		}
+		json.Unmarshal(message, &request)
+		subscribed <- request.ProductIDs
+		conn.WriteMessage([]byte(`{"type": "subscriptions"}`))
+		conn.WriteMessage([]byte(`{"type": "ticker", "product_id": "ETH-USD", "price": "201.5"}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := New("USD", WithFeedURL("ws"+strings.TrimPrefix(server.URL, "http")))
+
+	updates, err := client.Stream(ctx, "BTC", "ETH")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := <-subscribed; len(got) != 2 || got[0] != "BTC-USD" || got[1] != "ETH-USD" {
+		t.Errorf("got %v want BTC-USD and ETH-USD", got)
+	}
+	if got := <-updates; !got["ETH"].Equal(decimal.NewFromFloat(201.5)) {
+		t.Errorf("got %v want ETH 201.5", got)
+	}
+
+	cancel()
+	for range updates {
+	}
+}
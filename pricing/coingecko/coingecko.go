@@ -0,0 +1,140 @@
+// Package coingecko provides a rebalancer.PriceProvider backed by the
+// CoinGecko API.
+package coingecko
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BaseURL is the address of the public CoinGecko API.
+const BaseURL = "https://api.coingecko.com/api/v3"
+
+// An APIError is an error reported by the CoinGecko API.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+// Error formats the error message for APIError.
+func (e APIError) Error() string {
+	return fmt.Sprintf("coingecko: %s (status %d)", e.Message, e.Status)
+}
+
+// A Client fetches prices from the CoinGecko API in a single quote currency,
+// such as USD.
+type Client struct {
+	apiKey     string
+	baseURL    string
+	quote      string
+	httpClient *http.Client
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with a demo API key.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithBaseURL sends requests to baseURL instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// New returns a Client which prices assets in quote.
+func New(quote rebalancer.Asset, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    BaseURL,
+		quote:      strings.ToLower(string(quote)),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Prices returns the prices of assets, looked up by their symbols. Assets
+// CoinGecko does not list are left out. CoinGecko cannot list every asset, so
+// at least one must be given.
+func (c *Client) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	pricelist := rebalancer.Pricelist{}
+	if len(assets) == 0 {
+		return pricelist, nil
+	}
+	symbols := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		symbols = append(symbols, strings.ToLower(string(asset)))
+	}
+	params := url.Values{}
+	params.Set("symbols", strings.Join(symbols, ","))
+	params.Set("vs_currencies", c.quote)
+	params.Set("precision", "full")
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/simple/price?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("x-cg-demo-api-key", c.apiKey)
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error  string `json:"error"`
+			Status struct {
+				Message string `json:"error_message"`
+			} `json:"status"`
+		}
+		json.Unmarshal(body, &failure)
+		message := failure.Error
+		if message == "" {
+			message = failure.Status.Message
+		}
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return nil, APIError{Status: resp.StatusCode, Message: message}
+	}
+
+	var prices map[string]map[string]decimal.Decimal
+	if err := json.Unmarshal(body, &prices); err != nil {
+		return nil, err
+	}
+	for symbol, quotes := range prices {
+		if price, ok := quotes[c.quote]; ok && price.IsPositive() {
+			pricelist[rebalancer.Asset(strings.ToUpper(symbol))] = price
+		}
+	}
+	return pricelist, nil
+}
@@ -0,0 +1,56 @@
+package coingecko_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing/coingecko"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// PriceProvider asserts that Client implements rebalancer.PriceProvider.
+var _ rebalancer.PriceProvider = &Client{}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("vs_currencies") != "usd" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid vs_currencies"}`)
+			return
+		}
+		if r.URL.Query().Get("symbols") != "btc,eth,nope" || r.Header.Get("x-cg-demo-api-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"btc": {"usd": 5000.5}, "eth": {"usd": 200}}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("prices are looked up by symbol", func(t *testing.T) {
+		client := New("USD", WithAPIKey("key"), WithBaseURL(server.URL))
+
+		got, err := client.Prices(ctx, "BTC", "ETH", "NOPE")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["BTC"].Equal(decimal.NewFromFloat(5000.5)) || !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want the BTC and ETH prices", got)
+		}
+	})
+	t.Run("API errors are reported", func(t *testing.T) {
+		client := New("XXX", WithBaseURL(server.URL))
+
+		_, err := client.Prices(ctx, "BTC")
+
+		want := APIError{Status: http.StatusBadRequest, Message: "invalid vs_currencies"}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+}
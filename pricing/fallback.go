@@ -0,0 +1,95 @@
+// Package pricing composes rebalancer.PriceProviders into more robust ones.
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"sort"
+	"strings"
+)
+
+// ErrUnpriced lists the assets which no provider could price, along with the
+// errors the providers returned, if any.
+type ErrUnpriced struct {
+	Assets []rebalancer.Asset
+	Errors []error
+}
+
+// Error formats the error message for ErrUnpriced.
+func (e ErrUnpriced) Error() string {
+	assets := make([]string, len(e.Assets))
+	for i, asset := range e.Assets {
+		assets[i] = string(asset)
+	}
+	msg := "no price for " + strings.Join(assets, ", ")
+	if len(e.Errors) > 0 {
+		msg += fmt.Sprintf(" (%d provider errors, first: %s)", len(e.Errors), e.Errors[0])
+	}
+	return msg
+}
+
+type fallback []rebalancer.PriceProvider
+
+// Fallback returns a provider which asks providers in order for the assets
+// the previous ones could not price, merging their results. Providers which
+// fail are skipped. If any asset is left unpriced the prices found are
+// returned along with an ErrUnpriced. When no assets are requested every
+// provider is asked for everything it prices, earlier providers taking
+// precedence.
+func Fallback(providers ...rebalancer.PriceProvider) rebalancer.PriceProvider {
+	return fallback(providers)
+}
+
+// Prices returns the prices of assets from the first provider which has them.
+func (f fallback) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	pricelist := rebalancer.Pricelist{}
+	var errs []error
+
+	if len(assets) == 0 {
+		for _, provider := range f {
+			prices, err := provider.Prices(ctx)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for asset, price := range prices {
+				if _, ok := pricelist[asset]; !ok {
+					pricelist[asset] = price
+				}
+			}
+		}
+		if len(pricelist) == 0 && len(errs) > 0 {
+			return pricelist, ErrUnpriced{Errors: errs}
+		}
+		return pricelist, nil
+	}
+
+	missing := assets
+	for _, provider := range f {
+		if len(missing) == 0 {
+			break
+		}
+		prices, err := provider.Prices(ctx, missing...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var still []rebalancer.Asset
+		for _, asset := range missing {
+			if price, ok := prices[asset]; ok {
+				pricelist[asset] = price
+				continue
+			}
+			still = append(still, asset)
+		}
+		missing = still
+	}
+	if len(missing) > 0 {
+		sort.Slice(missing, func(i, j int) bool {
+			return missing[i] < missing[j]
+		})
+		return pricelist, ErrUnpriced{Assets: missing, Errors: errs}
+	}
+	return pricelist, nil
+}
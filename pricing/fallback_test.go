@@ -0,0 +1,62 @@
+package pricing_test
+
+import (
+	"context"
+	"errors"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing"
+	"github.com/shopspring/decimal"
+	"reflect"
+	"testing"
+)
+
+// failing is a provider which always fails with err.
+func failing(err error) rebalancer.PriceProvider {
+	return rebalancer.PriceProviderFunc(func(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+		return nil, err
+	})
+}
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+	first := rebalancer.Pricelist{"BTC": decimal.NewFromFloat(5000)}
+	second := rebalancer.Pricelist{
+		"BTC": decimal.NewFromFloat(5100),
+		"ETH": decimal.NewFromFloat(200),
+	}
+	down := errors.New("down")
+
+	t.Run("assets are priced by the first provider which has them", func(t *testing.T) {
+		got, err := Fallback(first, failing(down), second).Prices(ctx, "BTC", "ETH")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got["BTC"].Equal(decimal.NewFromFloat(5000)) || !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want BTC from the first provider and ETH from the second", got)
+		}
+	})
+
+	t.Run("every price is merged when no assets are requested", func(t *testing.T) {
+		got, err := Fallback(first, second).Prices(ctx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || !got["BTC"].Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %v want BTC from the first provider and ETH from the second", got)
+		}
+	})
+
+	t.Run("assets no provider could price are listed", func(t *testing.T) {
+		got, err := Fallback(failing(down), first).Prices(ctx, "XRP", "BTC", "DOGE")
+
+		want := ErrUnpriced{Assets: []rebalancer.Asset{"DOGE", "XRP"}, Errors: []error{down}}
+		if !reflect.DeepEqual(err, want) {
+			t.Errorf("got %v want %v", err, want)
+		}
+		if len(got) != 1 || !got["BTC"].Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %v want the BTC price", got)
+		}
+	})
+}
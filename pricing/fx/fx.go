@@ -0,0 +1,157 @@
+// Package fx provides a rebalancer.PriceProvider of fiat exchange rates,
+// using the euro foreign exchange reference rates published daily by the
+// European Central Bank. The rates suit the Rates of a
+// rebalancer.CurrencyPricelist.
+package fx
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ECBURL is the address of the ECB's daily reference rates.
+const ECBURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// ErrUnknownCurrency is returned when the base currency has no reference
+// rate.
+var ErrUnknownCurrency = errors.New("fx: no reference rate for the base currency")
+
+// Rates are the reference rates of a single day, as the number of units of
+// each currency a euro buys.
+type Rates struct {
+	Date   time.Time
+	PerEUR map[rebalancer.Asset]decimal.Decimal
+}
+
+// In returns the price of every currency in base, including the euro and base
+// itself.
+func (r Rates) In(base rebalancer.Asset) (rebalancer.Pricelist, error) {
+	perBase, ok := r.PerEUR[base]
+	if !ok {
+		return nil, ErrUnknownCurrency
+	}
+	pricelist := rebalancer.Pricelist{}
+	for currency, perEUR := range r.PerEUR {
+		pricelist[currency] = perBase.Div(perEUR)
+	}
+	return pricelist, nil
+}
+
+// A Client fetches fiat exchange rates from the ECB and prices currencies in
+// a base currency, such as USD.
+type Client struct {
+	url        string
+	base       rebalancer.Asset
+	httpClient *http.Client
+}
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithURL fetches rates from url instead of ECBURL, for example the ECB's
+// history of rates over the last 90 days, of which the latest are used.
+func WithURL(url string) Option {
+	return func(c *Client) {
+		c.url = url
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// New returns a Client which prices currencies in base.
+func New(base rebalancer.Asset, opts ...Option) *Client {
+	c := &Client{
+		url:        ECBURL,
+		base:       base,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Prices returns the price of currencies in the base currency, or of every
+// currency with a reference rate when no currencies are given.
+func (c *Client) Prices(ctx context.Context, currencies ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	rates, err := c.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pricelist, err := rates.In(c.base)
+	if err != nil {
+		return nil, err
+	}
+	if len(currencies) == 0 {
+		return pricelist, nil
+	}
+	return pricelist.Prices(ctx, currencies...)
+}
+
+type envelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// Rates returns the latest reference rates.
+func (c *Client) Rates(ctx context.Context) (Rates, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url, nil)
+	if err != nil {
+		return Rates{}, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Rates{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Rates{}, fmt.Errorf("fx: %s", resp.Status)
+	}
+
+	var data envelope
+	if err := xml.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return Rates{}, err
+	}
+
+	rates := Rates{PerEUR: map[rebalancer.Asset]decimal.Decimal{"EUR": decimal.New(1, 0)}}
+	for _, day := range data.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return Rates{}, err
+		}
+		if !date.After(rates.Date) {
+			continue
+		}
+		rates.Date = date
+		rates.PerEUR = map[rebalancer.Asset]decimal.Decimal{"EUR": decimal.New(1, 0)}
+		for _, rate := range day.Rates {
+			perEUR, err := decimal.NewFromString(rate.Rate)
+			if err != nil {
+				return Rates{}, err
+			}
+			if perEUR.IsPositive() {
+				rates.PerEUR[rebalancer.Asset(strings.ToUpper(rate.Currency))] = perEUR
+			}
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,77 @@
+package fx_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing/fx"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// PriceProvider asserts that Client implements rebalancer.PriceProvider.
+var _ rebalancer.PriceProvider = &Client{}
+
+const rates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2019-01-03">
+			<Cube currency="USD" rate="1.25"/>
+			<Cube currency="GBP" rate="0.8"/>
+		</Cube>
+		<Cube time="2019-01-04">
+			<Cube currency="USD" rate="1.2"/>
+			<Cube currency="GBP" rate="0.9"/>
+			<Cube currency="JPY" rate="120"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, rates)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	t.Run("the latest rates are used", func(t *testing.T) {
+		got, err := New("USD", WithURL(server.URL)).Rates(ctx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Date.Equal(time.Date(2019, 1, 4, 0, 0, 0, 0, time.UTC)) || len(got.PerEUR) != 4 {
+			t.Errorf("got %v want the rates of 2019-01-04", got)
+		}
+	})
+
+	t.Run("currencies are priced in the base currency", func(t *testing.T) {
+		got, err := New("USD", WithURL(server.URL)).Prices(ctx, "EUR", "GBP", "JPY")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		want := rebalancer.Pricelist{
+			"EUR": decimal.NewFromFloat(1.2),
+			"GBP": decimal.New(12, 0).Div(decimal.New(9, 0)),
+			"JPY": decimal.NewFromFloat(0.01),
+		}
+		for currency, price := range want {
+			if !got[currency].Equal(price) {
+				t.Errorf("got %s for %s want %s", got[currency], currency, price)
+			}
+		}
+	})
+
+	t.Run("a base currency without a rate is an error", func(t *testing.T) {
+		_, err := New("XXX", WithURL(server.URL)).Prices(ctx)
+
+		if err != ErrUnknownCurrency {
+			t.Errorf("got %v want %v", err, ErrUnknownCurrency)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package pricing
+
+import (
+	"context"
+	"github.com/pdbrito/rebalancer"
+	"sync"
+	"time"
+)
+
+// A RateOption configures a provider returned by RateLimited.
+type RateOption func(*rateLimited)
+
+// BatchSize splits requests for more than size assets into several requests
+// of at most size assets, each paced by the rate limit.
+func BatchSize(size int) RateOption {
+	return func(r *rateLimited) {
+		r.batchSize = size
+	}
+}
+
+type rateLimited struct {
+	provider  rebalancer.PriceProvider
+	interval  time.Duration
+	batchSize int
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// RateLimited returns a provider which sends requests to provider at most
+// once every interval, such as 2*time.Second for an API allowing 30 calls a
+// minute, waiting for its turn when requests come faster, so that providers
+// backed by public APIs are not banned for pricing large universes.
+func RateLimited(provider rebalancer.PriceProvider, interval time.Duration, opts ...RateOption) rebalancer.PriceProvider {
+	r := &rateLimited{
+		provider: provider,
+		interval: interval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Prices returns the prices of assets, requesting them in batches paced by
+// the rate limit.
+func (r *rateLimited) Prices(ctx context.Context, assets ...rebalancer.Asset) (rebalancer.Pricelist, error) {
+	if len(assets) == 0 || r.batchSize < 1 || len(assets) <= r.batchSize {
+		if err := r.wait(ctx); err != nil {
+			return nil, err
+		}
+		return r.provider.Prices(ctx, assets...)
+	}
+
+	pricelist := rebalancer.Pricelist{}
+	for start := 0; start < len(assets); start += r.batchSize {
+		end := start + r.batchSize
+		if end > len(assets) {
+			end = len(assets)
+		}
+		if err := r.wait(ctx); err != nil {
+			return pricelist, err
+		}
+		prices, err := r.provider.Prices(ctx, assets[start:end]...)
+		for asset, price := range prices {
+			pricelist[asset] = price
+		}
+		if err != nil {
+			return pricelist, err
+		}
+	}
+	return pricelist, nil
+}
+
+// wait blocks until the next request may be sent, or ctx is done.
+func (r *rateLimited) wait(ctx context.Context) error {
+	r.mu.Lock()
+	now := time.Now()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
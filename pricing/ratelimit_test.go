@@ -0,0 +1,50 @@
+package pricing_test
+
+import (
+	"context"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/pricing"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestRateLimited(t *testing.T) {
+	ctx := context.Background()
+	assets := []rebalancer.Asset{"BTC", "ETH", "XRP", "LTC", "DOGE"}
+
+	t.Run("large requests are split into paced batches", func(t *testing.T) {
+		provider := &countingProvider{}
+		limited := RateLimited(provider, 20*time.Millisecond, BatchSize(2))
+
+		start := time.Now()
+		got, err := limited.Prices(ctx, assets...)
+		elapsed := time.Since(start)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if provider.count() != 3 || len(got) != 5 {
+			t.Errorf("got %v from %d requests want 5 prices from 3", got, provider.count())
+		}
+		if !got["DOGE"].Equal(decimal.New(3, 0)) {
+			t.Errorf("got %s want DOGE from the third batch", got["DOGE"])
+		}
+		if elapsed < 40*time.Millisecond {
+			t.Errorf("got 3 requests in %s want them 20ms apart", elapsed)
+		}
+	})
+
+	t.Run("waiting requests give up when their context is done", func(t *testing.T) {
+		limited := RateLimited(&countingProvider{}, time.Minute)
+		limited.Prices(ctx, "BTC")
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := limited.Prices(ctx, "BTC")
+
+		if err != context.DeadlineExceeded {
+			t.Errorf("got %v want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
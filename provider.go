@@ -0,0 +1,94 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+)
+
+// A PriceProvider supplies prices from a source such as an exchange or a
+// market data API.
+type PriceProvider interface {
+	// Prices returns the prices of assets, or of every asset the provider
+	// knows when no assets are given. Assets the provider cannot price are
+	// left out of the result.
+	Prices(ctx context.Context, assets ...Asset) (Pricelist, error)
+}
+
+// PriceProviderFunc adapts a function to the PriceProvider interface.
+type PriceProviderFunc func(ctx context.Context, assets ...Asset) (Pricelist, error)
+
+// Prices calls f.
+func (f PriceProviderFunc) Prices(ctx context.Context, assets ...Asset) (Pricelist, error) {
+	return f(ctx, assets...)
+}
+
+// Prices returns the prices of assets in the pricelist, or a copy of the whole
+// pricelist when no assets are given, so a Pricelist can stand in for a
+// PriceProvider.
+func (p Pricelist) Prices(ctx context.Context, assets ...Asset) (Pricelist, error) {
+	pricelist := Pricelist{}
+	if len(assets) == 0 {
+		for asset, price := range p {
+			pricelist[asset] = price
+		}
+		return pricelist, nil
+	}
+	for _, asset := range assets {
+		if price, ok := p[asset]; ok {
+			pricelist[asset] = price
+		}
+	}
+	return pricelist, nil
+}
+
+// SetPricelistFromProvider sets the global pricelist to the prices of assets
+// fetched from provider, or to every price it supplies when no assets are
+// given. ErrAssetMissingFromPricelist is returned if any of assets could not be
+// priced, in which case the global pricelist is left unchanged.
+func SetPricelistFromProvider(ctx context.Context, provider PriceProvider, assets ...Asset) error {
+	pricelist, err := fetchPrices(ctx, provider, assets)
+	if err != nil {
+		return err
+	}
+	return SetPricelist(pricelist)
+}
+
+// NewAccountWithProvider validates portfolio and then returns a new Account
+// priced with the prices of its assets, and of any other assets given, fetched
+// from provider. Assets which are not held but will be bought, such as those
+// of the target index, must be given for the account to be rebalanced onto
+// them.
+func NewAccountWithProvider(ctx context.Context, portfolio map[Asset]decimal.Decimal, provider PriceProvider, assets ...Asset) (Account, error) {
+	if len(portfolio) == 0 {
+		return Account{}, ErrEmptyPortfolio
+	}
+	priced := make([]Asset, 0, len(portfolio)+len(assets))
+	for asset := range portfolio {
+		priced = append(priced, asset)
+	}
+	for _, asset := range assets {
+		if _, ok := portfolio[asset]; !ok {
+			priced = append(priced, asset)
+		}
+	}
+	pricelist, err := fetchPrices(ctx, provider, priced)
+	if err != nil {
+		return Account{}, err
+	}
+	return NewAccountWithPricelist(portfolio, pricelist)
+}
+
+// fetchPrices returns the prices of assets from provider, failing if any of
+// them is missing.
+func fetchPrices(ctx context.Context, provider PriceProvider, assets []Asset) (Pricelist, error) {
+	pricelist, err := provider.Prices(ctx, assets...)
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range assets {
+		if _, ok := pricelist[asset]; !ok {
+			return nil, ErrAssetMissingFromPricelist
+		}
+	}
+	return pricelist, nil
+}
@@ -0,0 +1,111 @@
+package rebalancer_test
+
+import (
+	"context"
+	"errors"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestSetPricelistFromProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+		"XRP": decimal.NewFromFloat(0.3),
+	}
+	defer ClearGlobalPricelist()
+
+	t.Run("the global pricelist is set to the requested prices", func(t *testing.T) {
+		err := SetPricelistFromProvider(ctx, provider, "ETH", "BTC")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		got := GlobalPricelist()
+		if len(got) != 2 || !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want the ETH and BTC prices", got)
+		}
+	})
+
+	t.Run("every price is used when no assets are given", func(t *testing.T) {
+		err := SetPricelistFromProvider(ctx, provider)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if got := GlobalPricelist(); len(got) != 3 {
+			t.Errorf("got %v want all 3 prices", got)
+		}
+	})
+
+	t.Run("assets the provider cannot price are an error", func(t *testing.T) {
+		err := SetPricelistFromProvider(ctx, provider, "ETH", "DOGE")
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v want %v", err, ErrAssetMissingFromPricelist)
+		}
+	})
+
+	t.Run("provider errors are returned", func(t *testing.T) {
+		want := errors.New("unavailable")
+		failing := PriceProviderFunc(func(ctx context.Context, assets ...Asset) (Pricelist, error) {
+			return nil, want
+		})
+
+		if err := SetPricelistFromProvider(ctx, failing, "ETH"); err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+}
+
+func TestNewAccountWithProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+		"XRP": decimal.NewFromFloat(0.3),
+	}
+	portfolio := map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(10),
+		"BTC": decimal.NewFromFloat(1),
+	}
+
+	t.Run("the account is priced with the provider's prices", func(t *testing.T) {
+		account, err := NewAccountWithProvider(ctx, portfolio, provider)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !account.Value().Equal(decimal.NewFromFloat(7000)) {
+			t.Errorf("got %s want 7000", account.Value())
+		}
+	})
+	t.Run("assets which are not held can be priced to rebalance onto them", func(t *testing.T) {
+		index := Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"XRP": decimal.NewFromFloat(0.5),
+		}
+		account, err := NewAccountWithProvider(ctx, portfolio, provider, "ETH", "XRP")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if trade := plan.Trades()["XRP"]; trade.Action != Buy || !trade.Value.Round(8).Equal(decimal.NewFromFloat(3500)) {
+			t.Errorf("got %v want a buy of 3500 worth of XRP", trade)
+		}
+	})
+	t.Run("given assets the provider cannot price are an error", func(t *testing.T) {
+		_, err := NewAccountWithProvider(ctx, portfolio, provider, "DOGE")
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v want %v", err, ErrAssetMissingFromPricelist)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// A Quote holds the best price an asset can be sold at, its Bid, and the best
+// price it can be bought at, its Ask.
+type Quote struct {
+	Bid decimal.Decimal
+	Ask decimal.Decimal
+}
+
+// Mid returns the price halfway between the quote's bid and ask.
+func (q Quote) Mid() decimal.Decimal {
+	return q.Bid.Add(q.Ask).Div(decimal.New(2, 0))
+}
+
+// Quotelist contains a map of Assets and their current quote.
+type Quotelist map[Asset]Quote
+
+// ErrCrossedQuote indicates a quote whose bid is above its ask.
+var ErrCrossedQuote = errors.New("quote bid must not be above its ask")
+
+// globalQuotelist contains the quotelist used by accounts created with
+// NewAccount, if one has been set with SetQuotelist. It is guarded by
+// globalPricelistMu.
+var globalQuotelist Quotelist
+
+// NewQuotelist validates and returns a copy of quotes as a new Quotelist.
+func NewQuotelist(quotes map[Asset]Quote) (Quotelist, error) {
+	if len(quotes) == 0 {
+		return nil, ErrEmptyPricelist
+	}
+	result := Quotelist{}
+	for asset, quote := range quotes {
+		if _, err := NewPricelist(Pricelist{asset: quote.Bid}); err != nil {
+			return nil, err
+		}
+		if _, err := NewPricelist(Pricelist{asset: quote.Ask}); err != nil {
+			return nil, err
+		}
+		if quote.Bid.GreaterThan(quote.Ask) {
+			return nil, ErrCrossedQuote
+		}
+		result[asset] = quote
+	}
+	return result, nil
+}
+
+// Mids returns a Pricelist of the mid price of every quote.
+func (q Quotelist) Mids() Pricelist {
+	pricelist := Pricelist{}
+	for asset, quote := range q {
+		pricelist[asset] = quote.Mid()
+	}
+	return pricelist
+}
+
+// SetQuotelist validates and sets a new global Quotelist. Accounts created
+// with NewAccount afterwards are valued at the quotes' mid prices, and their
+// rebalances sell at the bid and buy at the ask. Calling SetPricelist removes
+// the global quotelist.
+func SetQuotelist(quotes map[Asset]Quote) error {
+	validated, err := NewQuotelist(quotes)
+	if err != nil {
+		return err
+	}
+	globalPricelistMu.Lock()
+	globalQuotelist = validated
+	globalPricelist = validated.Mids()
+	pricelist := globalPricelist
+	globalPricelistMu.Unlock()
+	GlobalEvents.Publish(PricelistUpdated{Time: time.Now(), Pricelist: pricelist})
+	return nil
+}
+
+// NewAccountWithQuotelist validates portfolio and quotes and then returns a
+// new Account valued at the quotes' mid prices, whose rebalances sell at the
+// bid and buy at the ask.
+func NewAccountWithQuotelist(portfolio map[Asset]decimal.Decimal, quotes map[Asset]Quote) (Account, error) {
+	validated, err := NewQuotelist(quotes)
+	if err != nil {
+		return Account{}, err
+	}
+	account, err := NewAccountWithPricelist(portfolio, validated.Mids())
+	if err != nil {
+		return Account{}, err
+	}
+	account.quotes = validated
+	return account, nil
+}
@@ -0,0 +1,103 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestNewQuotelist(t *testing.T) {
+	t.Run("quotes cannot have a bid above their ask", func(t *testing.T) {
+		_, err := NewQuotelist(map[Asset]Quote{
+			"ETH": {Bid: decimal.NewFromFloat(201), Ask: decimal.NewFromFloat(199)},
+		})
+
+		if err != ErrCrossedQuote {
+			t.Errorf("got %v, want %s", err, ErrCrossedQuote)
+		}
+	})
+	t.Run("quotes must have positive prices", func(t *testing.T) {
+		_, err := NewQuotelist(map[Asset]Quote{
+			"ETH": {Bid: decimal.Zero, Ask: decimal.NewFromFloat(199)},
+		})
+
+		want := ErrInvalidAssetAmount{Asset: "ETH", Amount: decimal.Zero}
+
+		if err != want {
+			t.Errorf("got %v, want %s", err, want)
+		}
+	})
+	t.Run("a quotelist provides mid prices", func(t *testing.T) {
+		quotes, err := NewQuotelist(map[Asset]Quote{
+			"ETH": {Bid: decimal.NewFromFloat(199), Ask: decimal.NewFromFloat(201)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if got := quotes.Mids()["ETH"]; !got.Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %s want 200", got)
+		}
+	})
+}
+
+func TestSetQuotelist(t *testing.T) {
+	t.Run("the global pricelist is set to the mid prices", func(t *testing.T) {
+		err := SetQuotelist(map[Asset]Quote{
+			"ETH": {Bid: decimal.NewFromFloat(199), Ask: decimal.NewFromFloat(201)},
+			"BTC": {Bid: decimal.NewFromFloat(4990), Ask: decimal.NewFromFloat(5010)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if got := GlobalPricelist()["BTC"]; !got.Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %s want 5000", got)
+		}
+	})
+}
+
+func TestAccount_Rebalance_withQuotes(t *testing.T) {
+	t.Run("sells are valued at the bid and buys at the ask", func(t *testing.T) {
+		account, err := NewAccountWithQuotelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, map[Asset]Quote{
+			"ETH": {Bid: decimal.NewFromFloat(199), Ask: decimal.NewFromFloat(201)},
+			"BTC": {Bid: decimal.NewFromFloat(4990), Ask: decimal.NewFromFloat(5010)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		index := Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}
+
+		plan, err := account.Rebalance(index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		trades := plan.Trades()
+
+		if !trades["ETH"].Price.Equal(decimal.NewFromFloat(199)) {
+			t.Errorf("got a sell price of %s want the bid of 199", trades["ETH"].Price)
+		}
+
+		proceeds := trades["ETH"].Value
+		cost := trades["BTC"].Value
+		if !proceeds.Sub(cost).Abs().LessThan(decimal.NewFromFloat(0.000001)) {
+			t.Errorf("got proceeds %s and cost %s, want them to match", proceeds, cost)
+		}
+
+		if !AchievesIndex(account, trades, index, decimal.NewFromFloat(0.000001)) {
+			t.Errorf("got trades %v which do not achieve the target index", trades)
+		}
+	})
+}
@@ -5,10 +5,15 @@
 package rebalancer
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // An Asset is a string type used to identify your assets. It must be uppercase.
@@ -28,45 +33,72 @@ func (e ErrInvalidAssetAmount) Error() string {
 	return fmt.Sprintf("%s must be positive, not %s", e.Asset, e.Amount)
 }
 
-// globalPricelist contains the current pricelist used for all calculations.
+// globalPricelist contains the pricelist used by accounts created with
+// NewAccount.
 var globalPricelist = Pricelist{}
 
+// globalPricelistMu guards globalPricelist.
+var globalPricelistMu sync.RWMutex
+
 // Pricelist contains a map of Assets and their current price.
 type Pricelist map[Asset]decimal.Decimal
 
 // ErrEmptyPricelist indicates an empty pricelist was passed to NewPricelist.
 var ErrEmptyPricelist = errors.New("pricelist must not be empty")
 
-// SetPricelist validates and sets a new Pricelist.
-func SetPricelist(pricelist map[Asset]decimal.Decimal) error {
+// NewPricelist validates and returns a copy of pricelist as a new Pricelist.
+func NewPricelist(pricelist map[Asset]decimal.Decimal) (Pricelist, error) {
 	if len(pricelist) == 0 {
-		return ErrEmptyPricelist
+		return nil, ErrEmptyPricelist
 	}
+	result := Pricelist{}
 	for asset, price := range pricelist {
 		if price.LessThan(decimal.Zero) || price.Equal(decimal.Zero) {
-			return ErrInvalidAssetAmount{Asset: asset, Amount: price}
+			return nil, ErrInvalidAssetAmount{Asset: asset, Amount: price}
 		}
 		if string(asset) != strings.ToUpper(string(asset)) {
-			return ErrInvalidAsset
+			return nil, ErrInvalidAsset
 		}
+		result[asset] = price
 	}
-	globalPricelist = pricelist
+	return result, nil
+}
+
+// SetPricelist validates and sets a new global Pricelist. The global pricelist
+// is kept for compatibility; prefer passing a Pricelist to
+// NewAccountWithPricelist so each account carries its own prices.
+func SetPricelist(pricelist map[Asset]decimal.Decimal) error {
+	validated, err := NewPricelist(pricelist)
+	if err != nil {
+		return err
+	}
+	globalPricelistMu.Lock()
+	globalPricelist = validated
+	globalQuotelist = nil
+	globalPricelistMu.Unlock()
+	GlobalEvents.Publish(PricelistUpdated{Time: time.Now(), Pricelist: validated})
 	return nil
 }
 
 // GlobalPricelist returns the current value of the global pricelist.
 func GlobalPricelist() Pricelist {
+	globalPricelistMu.RLock()
+	defer globalPricelistMu.RUnlock()
 	return globalPricelist
 }
 
 // ClearGlobalPricelist clears the global pricelist.
 func ClearGlobalPricelist() {
+	globalPricelistMu.Lock()
 	globalPricelist = Pricelist{}
+	globalQuotelist = nil
+	globalPricelistMu.Unlock()
+	GlobalEvents.Publish(PricelistUpdated{Time: time.Now(), Pricelist: Pricelist{}})
 }
 
 // ErrAssetMissingFromPricelist indicates an asset without a matching entry in
-// the global pricelist.
-var ErrAssetMissingFromPricelist = errors.New("asset missing from global pricelist")
+// the pricelist.
+var ErrAssetMissingFromPricelist = errors.New("asset missing from pricelist")
 
 // Portfolio contains a map of Assets and their current amount.
 type Portfolio map[Asset]decimal.Decimal
@@ -74,8 +106,13 @@ type Portfolio map[Asset]decimal.Decimal
 // ErrEmptyPortfolio indicates an empty portfolio was passed to NewPortfolio.
 var ErrEmptyPortfolio = errors.New("portfolio must not be empty")
 
-// NewPortfolio validates and returns a new Portfolio type.
+// NewPortfolio validates and returns a new Portfolio type whose assets are all
+// priced by the global pricelist.
 func NewPortfolio(portfolio map[Asset]decimal.Decimal) (Portfolio, error) {
+	return newPortfolio(portfolio, GlobalPricelist())
+}
+
+func newPortfolio(portfolio map[Asset]decimal.Decimal, pricelist Pricelist) (Portfolio, error) {
 	if len(portfolio) == 0 {
 		return nil, ErrEmptyPortfolio
 	}
@@ -83,7 +120,7 @@ func NewPortfolio(portfolio map[Asset]decimal.Decimal) (Portfolio, error) {
 		if string(asset) != strings.ToUpper(string(asset)) {
 			return nil, ErrInvalidAsset
 		}
-		if _, ok := globalPricelist[asset]; !ok {
+		if _, ok := pricelist[asset]; !ok {
 			return nil, ErrAssetMissingFromPricelist
 		}
 		if amount.LessThan(decimal.Zero) || amount.Equal(decimal.Zero) {
@@ -96,23 +133,95 @@ func NewPortfolio(portfolio map[Asset]decimal.Decimal) (Portfolio, error) {
 // An Account has portfolio, a pricelist and a calculated total value.
 type Account struct {
 	portfolio Portfolio
-	value     decimal.Decimal
+	pricelist Pricelist
+	quotes    Quotelist
+	lots      Lots
+	// priceTimes holds when each price was observed, if known.
+	priceTimes map[Asset]time.Time
+	value      decimal.Decimal
 }
 
-// NewAccount validates portfolio and then returns a new Account struct.
+// NewAccount validates portfolio and then returns a new Account struct priced
+// with the global pricelist, or the global quotelist if one was set, as it is
+// at the time of the call.
 func NewAccount(portfolio map[Asset]decimal.Decimal) (Account, error) {
-	if len(globalPricelist) == 0 {
-		return Account{}, ErrEmptyPricelist
+	globalPricelistMu.RLock()
+	quotes := globalQuotelist
+	globalPricelistMu.RUnlock()
+	if quotes != nil {
+		return NewAccountWithQuotelist(portfolio, quotes)
+	}
+	return NewAccountWithPricelist(portfolio, GlobalPricelist())
+}
+
+// NewAccountWithPricelist validates portfolio and pricelist and then returns a
+// new Account struct which uses pricelist for all of its calculations.
+func NewAccountWithPricelist(portfolio map[Asset]decimal.Decimal, pricelist map[Asset]decimal.Decimal) (Account, error) {
+	prices, err := NewPricelist(pricelist)
+	if err != nil {
+		return Account{}, err
 	}
-	portfolio, err := NewPortfolio(portfolio)
+	portfolio, err = newPortfolio(portfolio, prices)
 	if err != nil {
 		return Account{}, err
 	}
 	totalValue := decimal.Zero
 	for asset, amount := range portfolio {
-		totalValue = totalValue.Add(globalPricelist[asset].Mul(amount))
+		totalValue = totalValue.Add(prices[asset].Mul(amount))
+	}
+	return Account{portfolio: portfolio, pricelist: prices, value: totalValue}, nil
+}
+
+// Pricelist returns a copy of the pricelist used by the account.
+func (a Account) Pricelist() Pricelist {
+	pricelist := Pricelist{}
+	for asset, price := range a.pricelist {
+		pricelist[asset] = price
+	}
+	return pricelist
+}
+
+// Holdings returns a copy of the account's portfolio.
+func (a Account) Holdings() Portfolio {
+	holdings := Portfolio{}
+	for asset, amount := range a.portfolio {
+		holdings[asset] = amount
+	}
+	return holdings
+}
+
+// Value returns the total value of the account's portfolio, valued with its
+// pricelist.
+func (a Account) Value() decimal.Decimal {
+	return a.value
+}
+
+// ValueIn returns the value of the account in units of quote. fx holds the
+// price of quote in the currency of the account's pricelist, for instance the
+// USD price of a EUR for an account priced in USD; quotes absent from fx are
+// priced with the account's pricelist, so an account can be valued in BTC.
+func (a Account) ValueIn(quote Asset, fx Pricelist) (decimal.Decimal, error) {
+	price, ok := fx[quote]
+	if !ok {
+		price, ok = a.pricelist[quote]
 	}
-	return Account{portfolio: portfolio, value: totalValue}, nil
+	if !ok {
+		return decimal.Zero, ErrAssetMissingFromPricelist
+	}
+	if !price.IsPositive() {
+		return decimal.Zero, ErrInvalidAssetAmount{Asset: quote, Amount: price}
+	}
+	return a.value.Div(price), nil
+}
+
+// Allocation returns the fraction of the account's value held in each asset
+// of its portfolio, valued with its pricelist.
+func (a Account) Allocation() Index {
+	allocation := Index{}
+	for asset := range a.portfolio {
+		allocation[asset] = a.weight(asset)
+	}
+	return allocation
 }
 
 // Index contains a map of Assets and their values. Indexes values must
@@ -126,8 +235,13 @@ var ErrEmptyIndex = errors.New("index must not be empty")
 // equal to 1.
 var ErrIndexSumIncorrect = errors.New("index values must sum to 1")
 
-// NewIndex validates and returns a new Index type whose values must sum to 1.
+// NewIndex validates and returns a new Index type whose values must sum to 1
+// and whose assets are all priced by the global pricelist.
 func NewIndex(index map[Asset]decimal.Decimal) (Index, error) {
+	return newIndex(index, GlobalPricelist())
+}
+
+func newIndex(index map[Asset]decimal.Decimal, pricelist Pricelist) (Index, error) {
 	if len(index) == 0 {
 		return nil, ErrEmptyIndex
 	}
@@ -136,7 +250,7 @@ func NewIndex(index map[Asset]decimal.Decimal) (Index, error) {
 		if string(asset) != strings.ToUpper(string(asset)) {
 			return nil, ErrInvalidAsset
 		}
-		if _, ok := globalPricelist[asset]; !ok {
+		if _, ok := pricelist[asset]; !ok {
 			return nil, ErrAssetMissingFromPricelist
 		}
 		if percentage.LessThan(decimal.Zero) || percentage.Equal(decimal.Zero) {
@@ -150,36 +264,290 @@ func NewIndex(index map[Asset]decimal.Decimal) (Index, error) {
 	return index, nil
 }
 
+// A TradeAction is the direction of a Trade.
+type TradeAction string
+
+const (
+	// Buy is the TradeAction of a trade which increases a holding.
+	Buy TradeAction = "buy"
+	// Sell is the TradeAction of a trade which decreases a holding.
+	Sell TradeAction = "sell"
+)
+
+// ErrInvalidTradeAction indicates a TradeAction which is neither Buy nor Sell.
+var ErrInvalidTradeAction = errors.New("trade action must be buy or sell")
+
+// String returns the lowercase name of the action.
+func (t TradeAction) String() string {
+	return string(t)
+}
+
+// ParseTradeAction returns the action named s, "buy" or "sell" in any case,
+// for code which still holds actions as plain strings.
+func ParseTradeAction(s string) (TradeAction, error) {
+	action := TradeAction(strings.ToLower(s))
+	if action != Buy && action != Sell {
+		return "", ErrInvalidTradeAction
+	}
+	return action, nil
+}
+
+// MarshalJSON encodes the action as a JSON string. The zero action of an
+// unset Trade is encoded as "".
+func (t TradeAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+// UnmarshalJSON decodes a JSON string into the action, rejecting anything
+// other than "buy", "sell" or the "" of the zero action.
+func (t *TradeAction) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*t = ""
+		return nil
+	}
+	action, err := ParseTradeAction(s)
+	if err != nil {
+		return err
+	}
+	*t = action
+	return nil
+}
+
+// An OrderType is the kind of order a Trade should be placed as.
+type OrderType string
+
+const (
+	// Market orders execute immediately at the best available price.
+	Market OrderType = "market"
+	// Limit orders execute only at their LimitPrice or better.
+	Limit OrderType = "limit"
+)
+
 // A Trade represents a buy or sell action of a certain amount.
 type Trade struct {
-	Action string
+	// Action was previously a plain string. Since TradeAction is string
+	// based, comparisons against "buy" and "sell" continue to work but are
+	// deprecated in favour of the Buy and Sell constants, and plain strings
+	// can be converted with ParseTradeAction.
+	Action TradeAction
 	Amount decimal.Decimal
+	Asset  Asset
+	// ID identifies the trade stably, derived from the hash of its plan and
+	// its asset. ExecutePlan's orders carry it so executors can use it as a
+	// client order ID, making retried orders safe to resubmit. The hash
+	// covers the plan's Time, so planning again after a failure gives new
+	// IDs: save the plan before placing its orders, and execute the saved
+	// plan when resuming, for orders already placed to be recognised.
+	ID string
+	// Price is the price the trade is expected to execute at, and Value its
+	// notional value at that price.
+	Price decimal.Decimal
+	Value decimal.Decimal
+	// OrderType is the kind of order the trade should be placed as, and
+	// LimitPrice the suggested price of a Limit order.
+	OrderType  OrderType
+	LimitPrice decimal.Decimal
+	// Fee is the estimated fee of the trade, set when rebalancing WithFees.
+	Fee decimal.Decimal
+	// Residual is the value of the trade which could not be allocated, for
+	// instance because its amount was rounded to an exchange's step size or
+	// cut short by a turnover or gains budget. It is negative when the trade was
+	// rounded up.
+	Residual decimal.Decimal
+	// Lots are the lots a sell is taken from, set when the account tracks
+	// lots.
+	Lots []Lot
+	// ShortTermGain and LongTermGain are the estimated gains realised by
+	// selling Lots at Price. Losses are negative.
+	ShortTermGain decimal.Decimal
+	LongTermGain  decimal.Decimal
+}
+
+// Notional returns the value of the trade at the price of its asset in
+// pricelist.
+func (t Trade) Notional(pricelist Pricelist) decimal.Decimal {
+	return t.Amount.Mul(pricelist[t.Asset])
+}
+
+// SortTrades returns trades as a slice in a stable order: sells before buys,
+// then by notional value (amount × price in pricelist) descending, then by
+// asset. Plans sorted this way can be diffed and compared against golden
+// files.
+func SortTrades(trades map[Asset]Trade, pricelist Pricelist) []Trade {
+	sorted := make([]Trade, 0, len(trades))
+	for asset, trade := range trades {
+		trade.Asset = asset
+		sorted = append(sorted, trade)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Action != b.Action {
+			return a.Action == Sell
+		}
+		notionalA := a.Notional(pricelist)
+		notionalB := b.Notional(pricelist)
+		if !notionalA.Equal(notionalB) {
+			return notionalA.GreaterThan(notionalB)
+		}
+		return a.Asset < b.Asset
+	})
+	return sorted
+}
+
+// Rebalance will return a RebalancePlan whose trades will balance the
+// account's portfolio to match the supplied target index. Holdings absent
+// from the target index are sold in full unless the KeepUnlisted option is
+// given.
+func (a Account) Rebalance(targetIndex map[Asset]decimal.Decimal, opts ...RebalanceOption) (RebalancePlan, error) {
+	return a.rebalance(context.Background(), targetIndex, decimal.Zero, opts)
+}
+
+// RebalanceContext is like Rebalance, but gives up with ctx's error once ctx
+// is done, for planning against large universes under a deadline. ctx is also
+// passed to the tracer and notifiers.
+func (a Account) RebalanceContext(ctx context.Context, targetIndex map[Asset]decimal.Decimal, opts ...RebalanceOption) (RebalancePlan, error) {
+	return a.rebalance(ctx, targetIndex, decimal.Zero, opts)
 }
 
-// Rebalance will return a map[Asset]Trade which will balance the account's
-// portfolio to match the supplied target index.
-func (a Account) Rebalance(targetIndex map[Asset]decimal.Decimal) (map[Asset]Trade, error) {
-	targetIndex, err := NewIndex(targetIndex)
+// rebalance returns the plan of the configured strategy which allocates the
+// account's investable value plus contribution according to targetIndex,
+// tracing it and notifying the configured notifiers.
+func (a Account) rebalance(ctx context.Context, targetIndex map[Asset]decimal.Decimal, contribution decimal.Decimal, opts []RebalanceOption) (RebalancePlan, error) {
+	config := newRebalanceConfig(opts)
+	ctx, span := config.startSpan(ctx, "rebalancer.Rebalance")
+	span.SetAttribute("rebalancer.assets", len(targetIndex))
+	plan, err := a.draftPlan(ctx, targetIndex, contribution, opts)
 	if err != nil {
-		return nil, err
+		span.RecordError(err)
+		span.End()
+		return RebalancePlan{}, err
+	}
+	span.SetAttribute("rebalancer.trades", len(plan.trades))
+	span.SetAttribute("rebalancer.turnover", plan.Turnover.String())
+	span.End()
+
+	if config.journal != nil {
+		if err := config.journal.RecordPlan(plan, config.journalReason); err != nil {
+			return RebalancePlan{}, err
+		}
+	}
+	config.notify(ctx, plan)
+	config.events.Publish(PlanGenerated{Time: plan.Time, Plan: plan})
+	return plan, nil
+}
+
+// draftPlan returns the plan of the configured strategy which allocates the
+// account's investable value plus contribution according to targetIndex,
+// without journalling, notifying or publishing it, for plans which are only
+// hypothetical or candidates.
+func (a Account) draftPlan(ctx context.Context, targetIndex map[Asset]decimal.Decimal, contribution decimal.Decimal, opts []RebalanceOption) (RebalancePlan, error) {
+	opts = append(opts[:len(opts):len(opts)], withContribution(contribution))
+	return newRebalanceConfig(opts).planner().Plan(ctx, a, targetIndex, append(opts, WithStrategy(nil))...)
+}
+
+// planRebalance calculates the plan of the Proportional strategy.
+func (a Account) planRebalance(ctx context.Context, targetIndex map[Asset]decimal.Decimal, config rebalanceConfig) (RebalancePlan, error) {
+	targetIndex, err := newIndex(targetIndex, a.pricelist)
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+	if err := a.checkPriceAges(targetIndex, config); err != nil {
+		return RebalancePlan{}, err
+	}
+
+	_, span := config.startSpan(ctx, "rebalancer.constraints")
+	targetIndex, err = constrainIndex(targetIndex, config)
+	span.End()
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return RebalancePlan{}, err
 	}
 
-	trades := map[Asset]Trade{}
-	amountRequired := decimal.Zero
+	_, span = config.startSpan(ctx, "rebalancer.trades")
+	defer span.End()
+	frozen := a.frozenAssets(targetIndex, config)
+	investable := a.investable(frozen).Add(config.contribution)
+	if investable.IsNegative() {
+		return RebalancePlan{}, ErrInsufficientValue
+	}
+	investable, err = withoutCashReserve(investable, config)
+	if err != nil {
+		return RebalancePlan{}, err
+	}
 
-	for asset, percentage := range targetIndex {
-		amountRequired = a.value.Mul(percentage).Div(globalPricelist[asset])
+	var trades map[Asset]Trade
+	if config.fees != nil || config.slippage != nil || a.quotes != nil {
+		trades = a.costAwareTrades(targetIndex, frozen, investable, config)
+	} else {
+		trades = a.proportionalTrades(targetIndex, frozen, investable)
+	}
+	if err := ctx.Err(); err != nil {
+		return RebalancePlan{}, err
+	}
 
-		if portfolioAmount, ok := a.portfolio[asset]; ok {
-			amountRequired = amountRequired.Sub(portfolioAmount)
+	if config.minimizeTrades {
+		minimizeTrades(trades, a.pricelist, a.portfolio, a.value.Mul(config.tolerance))
+	}
+	if budget, ok := config.turnoverBudget(a.value); ok {
+		if err := limitTurnover(trades, a.pricelist, budget); err != nil {
+			return RebalancePlan{}, err
+		}
+	}
+	if config.gainsBudget {
+		if err := a.limitGains(trades, config); err != nil {
+			return RebalancePlan{}, err
 		}
+	}
+	if config.minTradeValue.IsPositive() {
+		dropDustTrades(trades, a.pricelist, config.minTradeValue)
+	}
+	if config.exchangeRules != nil {
+		applyExchangeRules(trades, a.pricelist, config.exchangeRules, a.portfolio)
+	}
+	if err := ctx.Err(); err != nil {
+		return RebalancePlan{}, err
+	}
 
-		if amountRequired.IsNegative() {
-			trades[asset] = Trade{"sell", amountRequired.Abs()}
-			continue
+	for asset, trade := range trades {
+		if err := ctx.Err(); err != nil {
+			return RebalancePlan{}, err
+		}
+		trade.Price = a.executionPrice(trade, config)
+		trade.Value = trade.Amount.Mul(trade.Price)
+		trade.OrderType = Market
+		if config.limitOrders {
+			trade.OrderType = Limit
+			trade.LimitPrice = a.limitPrice(trade, config)
+		}
+		if config.fees != nil {
+			trade.Fee = config.fees.Fee(trade, trade.Price)
 		}
-		trades[asset] = Trade{"buy", amountRequired.Abs()}
+		if a.lots != nil && trade.Action == Sell {
+			trade.Lots = config.selector().SelectLots(a.lots[asset], trade.Amount, trade.Price)
+			trade.ShortTermGain, trade.LongTermGain = lotGains(trade.Lots, trade.Price, config.asOf(), config.longTerm)
+		}
+		trades[asset] = trade
 	}
 
-	return trades, nil
+	return newRebalancePlan(trades, targetIndex, a, config.contribution, config.asOf()).withTradeIDs(), nil
+}
+
+// constrainIndex applies the asset filters and weight constraints of config
+// to targetIndex.
+func constrainIndex(targetIndex Index, config rebalanceConfig) (Index, error) {
+	targetIndex, err := filterAssets(targetIndex, config)
+	if err != nil {
+		return nil, err
+	}
+	targetIndex, err = floorWeights(targetIndex, config)
+	if err != nil {
+		return nil, err
+	}
+	return capWeights(targetIndex, config)
 }
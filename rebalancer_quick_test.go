@@ -32,17 +32,13 @@ func TestRebalance_ResultingIndexEqualToTargetIndex(t *testing.T) {
 	assertion := func(f fakeAccount) bool {
 		_ = SetPricelist(f.Pricelist)
 		account, _ := NewAccount(f.Portfolio)
-		trades, err := account.Rebalance(f.TargetIndex)
+		plan, err := account.Rebalance(f.TargetIndex)
 
 		if err != nil {
 			return false
 		}
 
-		portfolioAfter := execute(trades, f.Portfolio)
-
-		resultingIndex := calculateIndex(portfolioAfter)
-
-		return indexesAreEqual(f.TargetIndex, resultingIndex)
+		return AchievesIndex(account, plan.Trades(), f.TargetIndex, decimal.Zero)
 	}
 
 	if err := quick.Check(assertion, nil); err != nil {
@@ -61,25 +57,22 @@ func TestRebalance_ResultingIndexEqualToTargetIndex(t *testing.T) {
 	}
 }
 
-func calculateIndex(portfolio map[Asset]decimal.Decimal) map[Asset]decimal.Decimal {
-	index := map[Asset]decimal.Decimal{}
-	value := calculateValueOfPortfolio(portfolio)
-	for asset, amount := range portfolio {
-		index[asset] = GlobalPricelist()[asset].Mul(amount).Div(value)
-	}
-	return index
-}
+func TestRebalance_ConservesValue(t *testing.T) {
+	assertion := func(f fakeAccount) bool {
+		_ = SetPricelist(f.Pricelist)
+		account, _ := NewAccount(f.Portfolio)
+		plan, err := account.Rebalance(f.TargetIndex)
 
-func indexesAreEqual(i1, i2 map[Asset]decimal.Decimal) bool {
-	if len(i1) != len(i2) {
-		return false
-	}
-	for asset, percentage := range i1 {
-		if percentage2, ok := i2[asset]; !ok || !percentage.Equal(percentage2) {
+		if err != nil {
 			return false
 		}
+
+		return ConservesValue(account, plan.Trades(), GlobalPricelist())
+	}
+
+	if err := quick.Check(assertion, nil); err != nil {
+		t.Error(err)
 	}
-	return true
 }
 
 func generatePortfolio(n int) map[Asset]decimal.Decimal {
@@ -106,31 +99,6 @@ func generateTargetIndexForPortfolio(portfolio map[Asset]decimal.Decimal) map[As
 	return targetIndex
 }
 
-func calculateValueOfPortfolio(portfolio map[Asset]decimal.Decimal) (sum decimal.Decimal) {
-	for asset, amount := range portfolio {
-		sum = sum.Add(amount.Mul(GlobalPricelist()[asset]))
-	}
-	return sum
-}
-
-func execute(trades map[Asset]Trade, portfolio map[Asset]decimal.Decimal) map[Asset]decimal.Decimal {
-	res := map[Asset]decimal.Decimal{}
-
-	for asset, trade := range trades {
-
-		modifier := decimal.New(1, 0)
-		if trade.Action == "sell" {
-			modifier = decimal.New(-1, 0)
-		}
-
-		quantityAfterTrade := portfolio[asset].Add(trade.Amount.Mul(modifier))
-
-		res[asset] = quantityAfterTrade
-
-	}
-	return res
-}
-
 func generatePricelistForPortfolio(portfolio map[Asset]decimal.Decimal) map[Asset]decimal.Decimal {
 	pricelist := map[Asset]decimal.Decimal{}
 	for asset := range portfolio {
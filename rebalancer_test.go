@@ -1,6 +1,8 @@
 package rebalancer_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	. "github.com/pdbrito/rebalancer"
 	"github.com/shopspring/decimal"
@@ -25,6 +27,68 @@ func TestErrInvalidAssetAmount_Error(t *testing.T) {
 	}
 }
 
+func TestTradeAction(t *testing.T) {
+	t.Run("actions print as lowercase names", func(t *testing.T) {
+		got := fmt.Sprintf("%s %s", Buy, Sell)
+		want := "buy sell"
+
+		if got != want {
+			t.Errorf("got %s want %s", got, want)
+		}
+	})
+	t.Run("trades round trip through JSON", func(t *testing.T) {
+		trade := Trade{Action: Sell, Amount: decimal.NewFromFloat(3.75)}
+
+		data, err := json.Marshal(trade)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		var got Trade
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if got.Action != Sell || !got.Amount.Equal(trade.Amount) {
+			t.Errorf("got %v want %v", got, trade)
+		}
+	})
+	t.Run("the zero action round trips through JSON", func(t *testing.T) {
+		data, err := json.Marshal(Trade{})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		var got Trade
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if got.Action != "" {
+			t.Errorf("got %s want the zero action", got.Action)
+		}
+	})
+	t.Run("plain strings are parsed into actions", func(t *testing.T) {
+		got, err := ParseTradeAction("SELL")
+
+		if err != nil || got != Sell {
+			t.Errorf("got %s %v want %s", got, err, Sell)
+		}
+		if _, err := ParseTradeAction("hold"); err != ErrInvalidTradeAction {
+			t.Errorf("got %v, want %s", err, ErrInvalidTradeAction)
+		}
+	})
+	t.Run("unknown actions cannot be decoded", func(t *testing.T) {
+		var got TradeAction
+		err := json.Unmarshal([]byte(`"hold"`), &got)
+
+		if err != ErrInvalidTradeAction {
+			t.Errorf("got %v, want %s", err, ErrInvalidTradeAction)
+		}
+	})
+}
+
 func TestSetPricelist(t *testing.T) {
 	t.Run("a new pricelist can be set", func(t *testing.T) {
 		err := SetPricelist(map[Asset]decimal.Decimal{
@@ -273,6 +337,67 @@ func TestNewAccount(t *testing.T) {
 	})
 }
 
+func TestNewAccountWithPricelist(t *testing.T) {
+	t.Run("account cannot be created with an empty pricelist", func(t *testing.T) {
+		_, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(5),
+		}, Pricelist{})
+
+		if err != ErrEmptyPricelist {
+			t.Errorf("got %v, want %s", err, ErrEmptyPricelist)
+		}
+	})
+	t.Run("account cannot contain assets missing from its pricelist", func(t *testing.T) {
+		_, err := NewAccountWithPricelist(Portfolio{
+			"BTC": decimal.NewFromFloat(0.5),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+		})
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v, want %s", err, ErrAssetMissingFromPricelist)
+		}
+	})
+	t.Run("account is unaffected by changes to the global pricelist", func(t *testing.T) {
+		ClearGlobalPricelist()
+
+		pricelist := Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		}
+
+		account, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, pricelist)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		_ = SetPricelist(Pricelist{"ETH": decimal.NewFromFloat(1)})
+		pricelist["BTC"] = decimal.NewFromFloat(1)
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: "sell", Amount: decimal.NewFromFloat(3.75)},
+			"BTC": {Action: "buy", Amount: decimal.NewFromFloat(0.15)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+}
+
 func TestNewIndex(t *testing.T) {
 	t.Run("index cannot contain an empty map", func(t *testing.T) {
 		_, err := NewIndex(map[Asset]decimal.Decimal{})
@@ -521,7 +646,7 @@ func TestAccount_Rebalance(t *testing.T) {
 			t.Errorf("unexpected error: %s", err)
 		}
 
-		got, err := account.Rebalance(Index{
+		plan, err := account.Rebalance(Index{
 			"ETH": decimal.NewFromFloat(0.3),
 			"BTC": decimal.NewFromFloat(0.7),
 		})
@@ -530,6 +655,8 @@ func TestAccount_Rebalance(t *testing.T) {
 			t.Errorf("unexpected error: %s", err)
 		}
 
+		got := plan.Trades()
+
 		want := map[Asset]Trade{
 			"ETH": {Action: "sell", Amount: decimal.NewFromFloat(10.25)},
 			"BTC": {Action: "buy", Amount: decimal.NewFromFloat(0.41)},
@@ -568,12 +695,14 @@ func TestAccount_Rebalance(t *testing.T) {
 			t.Errorf("unexpected error: %s", err)
 		}
 
-		got, err := account.Rebalance(targetIndex)
+		plan, err := account.Rebalance(targetIndex)
 
 		if err != nil {
 			t.Errorf("unexpected error: %s", err)
 		}
 
+		got := plan.Trades()
+
 		want := map[Asset]Trade{
 			"ETH":  {Action: "sell", Amount: decimal.NewFromFloat(33.6)},
 			"BTC":  {Action: "buy", Amount: decimal.NewFromFloat(0.84)},
@@ -582,10 +711,252 @@ func TestAccount_Rebalance(t *testing.T) {
 			"XLM":  {Action: "buy", Amount: decimal.NewFromFloat(8400)},
 		}
 
+		assertSameTrades(t, got, want)
+	})
+	t.Run("rebalance sells holdings absent from the target index", func(t *testing.T) {
+		err := SetPricelist(map[Asset]decimal.Decimal{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+			"XLM": decimal.NewFromFloat(0.2),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		account, err := NewAccount(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+			"XLM": decimal.NewFromFloat(5000),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: "sell", Amount: decimal.NewFromFloat(1.25)},
+			"BTC": {Action: "buy", Amount: decimal.NewFromFloat(0.25)},
+			"XLM": {Action: "sell", Amount: decimal.NewFromFloat(5000)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("rebalance can ignore holdings absent from the target index", func(t *testing.T) {
+		err := SetPricelist(map[Asset]decimal.Decimal{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+			"XLM": decimal.NewFromFloat(0.2),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		account, err := NewAccount(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+			"XLM": decimal.NewFromFloat(5000),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, IgnoreUnlisted())
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		if _, ok := got["XLM"]; ok {
+			t.Errorf("got a trade for XLM, want it ignored")
+		}
+
+		want := map[Asset]Trade{
+			"ETH": {Action: "sell", Amount: decimal.NewFromFloat(3.75)},
+			"BTC": {Action: "buy", Amount: decimal.NewFromFloat(0.15)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("rebalance can keep holdings absent from the target index", func(t *testing.T) {
+		err := SetPricelist(map[Asset]decimal.Decimal{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+			"XLM": decimal.NewFromFloat(0.2),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		account, err := NewAccount(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+			"XLM": decimal.NewFromFloat(5000),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, KeepUnlisted())
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: "sell", Amount: decimal.NewFromFloat(3.75)},
+			"BTC": {Action: "buy", Amount: decimal.NewFromFloat(0.15)},
+		}
+
 		assertSameTrades(t, got, want)
 	})
 }
 
+func TestAccount_RebalanceContext(t *testing.T) {
+	account, err := NewAccountWithPricelist(map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("it plans like Rebalance while the context is live", func(t *testing.T) {
+		plan, err := account.RebalanceContext(context.Background(), index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want, _ := account.Rebalance(index)
+		assertSameTrades(t, plan.Trades(), want.Trades())
+	})
+
+	t.Run("it returns the context's error once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		notified := false
+		notifier := NotifierFunc(func(ctx context.Context, n Notification) error {
+			notified = true
+			return nil
+		})
+
+		_, err := account.RebalanceContext(ctx, index, WithNotifier(notifier, nil))
+
+		if err != context.Canceled || notified {
+			t.Errorf("got %v and notified %v want %v without a notification", err, notified, context.Canceled)
+		}
+	})
+}
+
+func TestTrade_Notional(t *testing.T) {
+	t.Run("notional is the amount at the pricelist's price", func(t *testing.T) {
+		trade := Trade{Action: Buy, Amount: decimal.NewFromFloat(0.15), Asset: "BTC"}
+
+		got := trade.Notional(Pricelist{"BTC": decimal.NewFromFloat(5000)})
+
+		if !got.Equal(decimal.NewFromFloat(750)) {
+			t.Errorf("got %s want 750", got)
+		}
+	})
+	t.Run("rebalance records the price and value of each trade", func(t *testing.T) {
+		account, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		trades := plan.Trades()
+
+		got := trades["BTC"]
+		if !got.Price.Equal(decimal.NewFromFloat(5000)) || !got.Value.Equal(decimal.NewFromFloat(750)) {
+			t.Errorf("got price %s and value %s, want 5000 and 750", got.Price, got.Value)
+		}
+	})
+}
+
+func TestSortTrades(t *testing.T) {
+	t.Run("trades are ordered sells first, by notional then by asset", func(t *testing.T) {
+		pricelist := Pricelist{
+			"ETH":  decimal.NewFromFloat(200),
+			"BTC":  decimal.NewFromFloat(2000),
+			"IOTA": decimal.NewFromFloat(0.3),
+			"BAT":  decimal.NewFromFloat(0.12),
+			"XLM":  decimal.NewFromFloat(0.2),
+		}
+
+		trades := map[Asset]Trade{
+			"ETH":  {Action: Sell, Amount: decimal.NewFromFloat(33.6)},
+			"BTC":  {Action: Buy, Amount: decimal.NewFromFloat(0.84)},
+			"IOTA": {Action: Buy, Amount: decimal.NewFromFloat(5600)},
+			"BAT":  {Action: Buy, Amount: decimal.NewFromFloat(14000)},
+			"XLM":  {Action: Buy, Amount: decimal.NewFromFloat(7500)},
+		}
+
+		got := SortTrades(trades, pricelist)
+
+		want := []Asset{"ETH", "BAT", "BTC", "IOTA", "XLM"}
+
+		if len(got) != len(want) {
+			t.Fatalf("got %d trades want %d", len(got), len(want))
+		}
+		for i, trade := range got {
+			if trade.Asset != want[i] {
+				t.Errorf("got %s at position %d want %s", trade.Asset, i, want[i])
+			}
+		}
+	})
+}
+
 func assertSameTrades(t *testing.T, got map[Asset]Trade, want map[Asset]Trade) {
 	t.Helper()
 
@@ -641,13 +1012,13 @@ func ExampleAccount_Rebalance() {
 		"BTC": decimal.NewFromFloat(0.5),
 	}
 
-	requiredTrades, err := account.Rebalance(targetIndex)
+	plan, err := account.Rebalance(targetIndex)
 
 	if err != nil {
 		log.Fatalf("unexpected error whilst balancing account: %v", err)
 	}
 
-	for asset, trade := range requiredTrades {
+	for asset, trade := range plan.Trades() {
 		fmt.Printf("%s %s %s\n", trade.Action, trade.Amount, asset)
 	}
 
@@ -685,13 +1056,13 @@ func ExampleAccount_Rebalance_intoNewAssets() {
 		"XLM":  decimal.NewFromFloat(0.2),
 	}
 
-	requiredTrades, err := account.Rebalance(targetIndex)
+	plan, err := account.Rebalance(targetIndex)
 
 	if err != nil {
 		log.Fatalf("unexpected error whilst balancing account: %v", err)
 	}
 
-	for asset, trade := range requiredTrades {
+	for asset, trade := range plan.Trades() {
 		fmt.Printf("%s %s %s\n", trade.Action, trade.Amount, asset)
 	}
 
@@ -724,3 +1095,104 @@ func BenchmarkRebalance(b *testing.B) {
 		_, _ = Account.Rebalance(targetIndex)
 	}
 }
+
+func TestAccount_Allocation(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(8000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	got := account.Allocation()
+	want := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+	for asset, weight := range want {
+		if !got[asset].Equal(weight) {
+			t.Errorf("got %v want %v for asset %s", got[asset], weight, asset)
+		}
+	}
+}
+
+func TestAccount_Holdings(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("the holdings are returned as a copy", func(t *testing.T) {
+		holdings := account.Holdings()
+		holdings["ETH"] = decimal.NewFromFloat(1)
+
+		if got := account.Holdings()["ETH"]; !got.Equal(decimal.NewFromFloat(20)) {
+			t.Errorf("got %v want %v", got, 20)
+		}
+	})
+	t.Run("the value of the holdings is returned", func(t *testing.T) {
+		if got := account.Value(); !got.Equal(decimal.NewFromFloat(6500)) {
+			t.Errorf("got %v want %v", got, 6500)
+		}
+	})
+}
+
+func TestAccount_ValueIn(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	fx := Pricelist{"EUR": decimal.NewFromFloat(1.25)}
+
+	t.Run("the account is valued in a quote currency", func(t *testing.T) {
+		got, err := account.ValueIn("EUR", fx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Equal(decimal.NewFromFloat(5200)) {
+			t.Errorf("got %v want %v", got, 5200)
+		}
+	})
+	t.Run("the account is valued in one of its own assets", func(t *testing.T) {
+		got, err := account.ValueIn("BTC", fx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Equal(decimal.NewFromFloat(1.3)) {
+			t.Errorf("got %v want %v", got, 1.3)
+		}
+	})
+	t.Run("the quote must be priced", func(t *testing.T) {
+		_, err := account.ValueIn("GBP", fx)
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v, want %s", err, ErrAssetMissingFromPricelist)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownPricelist indicates a name without a registered pricelist.
+var ErrUnknownPricelist = errors.New("no pricelist registered under that name")
+
+// namedPricelists holds the pricelists registered with SetNamedPricelist.
+var namedPricelists = map[string]Pricelist{}
+
+// namedPricelistsMu guards namedPricelists.
+var namedPricelistsMu sync.RWMutex
+
+// SetNamedPricelist validates pricelist and registers it under name, replacing
+// any pricelist already registered under it. Named pricelists let the prices
+// of different venues or scenarios coexist in one process, alongside the
+// global pricelist set with SetPricelist.
+func SetNamedPricelist(name string, pricelist map[Asset]decimal.Decimal) error {
+	validated, err := NewPricelist(pricelist)
+	if err != nil {
+		return err
+	}
+	namedPricelistsMu.Lock()
+	namedPricelists[name] = validated
+	namedPricelistsMu.Unlock()
+	return nil
+}
+
+// NamedPricelist returns a copy of the pricelist registered under name,
+// reporting false if there is none.
+func NamedPricelist(name string) (Pricelist, bool) {
+	namedPricelistsMu.RLock()
+	defer namedPricelistsMu.RUnlock()
+	registered, ok := namedPricelists[name]
+	if !ok {
+		return nil, false
+	}
+	pricelist := Pricelist{}
+	for asset, price := range registered {
+		pricelist[asset] = price
+	}
+	return pricelist, true
+}
+
+// RemoveNamedPricelist removes the pricelist registered under name.
+func RemoveNamedPricelist(name string) {
+	namedPricelistsMu.Lock()
+	delete(namedPricelists, name)
+	namedPricelistsMu.Unlock()
+}
+
+// PricelistNames returns the names of the registered pricelists in order.
+func PricelistNames() []string {
+	namedPricelistsMu.RLock()
+	defer namedPricelistsMu.RUnlock()
+	names := make([]string, 0, len(namedPricelists))
+	for name := range namedPricelists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAccountWithNamedPricelist validates portfolio and then returns a new
+// Account priced with the pricelist registered under name, as it is at the
+// time of the call.
+func NewAccountWithNamedPricelist(portfolio map[Asset]decimal.Decimal, name string) (Account, error) {
+	pricelist, ok := NamedPricelist(name)
+	if !ok {
+		return Account{}, ErrUnknownPricelist
+	}
+	return NewAccountWithPricelist(portfolio, pricelist)
+}
@@ -0,0 +1,61 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"reflect"
+	"testing"
+)
+
+func TestNamedPricelists(t *testing.T) {
+	defer RemoveNamedPricelist("binance")
+	defer RemoveNamedPricelist("kraken")
+	portfolio := map[Asset]decimal.Decimal{"ETH": decimal.NewFromFloat(10)}
+
+	if err := SetNamedPricelist("binance", Pricelist{"ETH": decimal.NewFromFloat(200)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := SetNamedPricelist("kraken", Pricelist{"ETH": decimal.NewFromFloat(210)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("accounts are priced with the named pricelist", func(t *testing.T) {
+		binance, err := NewAccountWithNamedPricelist(portfolio, "binance")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		kraken, err := NewAccountWithNamedPricelist(portfolio, "kraken")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if !binance.Value().Equal(decimal.NewFromFloat(2000)) || !kraken.Value().Equal(decimal.NewFromFloat(2100)) {
+			t.Errorf("got %s and %s want 2000 and 2100", binance.Value(), kraken.Value())
+		}
+	})
+
+	t.Run("registered names are listed", func(t *testing.T) {
+		if got := PricelistNames(); !reflect.DeepEqual(got, []string{"binance", "kraken"}) {
+			t.Errorf("got %v want binance and kraken", got)
+		}
+	})
+
+	t.Run("invalid pricelists are rejected", func(t *testing.T) {
+		err := SetNamedPricelist("binance", Pricelist{"ETH": decimal.Zero})
+
+		if _, ok := err.(ErrInvalidAssetAmount); !ok {
+			t.Errorf("got %v want ErrInvalidAssetAmount", err)
+		}
+		if got, _ := NamedPricelist("binance"); !got["ETH"].Equal(decimal.NewFromFloat(200)) {
+			t.Errorf("got %v want the previous pricelist kept", got)
+		}
+	})
+
+	t.Run("unknown names are an error", func(t *testing.T) {
+		_, err := NewAccountWithNamedPricelist(portfolio, "ftx")
+
+		if err != ErrUnknownPricelist {
+			t.Errorf("got %v want %v", err, ErrUnknownPricelist)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// ErrEventOutOfOrder is returned by ReplayAccount for an event which happened
+// before the event preceding it.
+var ErrEventOutOfOrder = errors.New("account events must be in time order")
+
+// An AccountEvent is something which happened to an account: one of
+// Deposited, Withdrawn, TradeFilled or PriceMarked. Replaying an account's
+// events with ReplayAccount rebuilds it.
+type AccountEvent interface {
+	eventTime() time.Time
+	apply(l *ledger) error
+}
+
+// Deposited records Amount of Asset deposited into the account.
+type Deposited struct {
+	Time   time.Time
+	Asset  Asset
+	Amount decimal.Decimal
+}
+
+// Withdrawn records Amount of Asset withdrawn from the account.
+type Withdrawn struct {
+	Time   time.Time
+	Asset  Asset
+	Amount decimal.Decimal
+}
+
+// TradeFilled records Quantity of Asset bought or sold at Price. When Cash is
+// given, Price and Fee are in units of Cash, and the cost of a buy or the
+// proceeds of a sell, less the fee, are debited or credited to it.
+type TradeFilled struct {
+	Time     time.Time
+	Asset    Asset
+	Action   TradeAction
+	Quantity decimal.Decimal
+	Price    decimal.Decimal
+	Fee      decimal.Decimal
+	Cash     Asset
+}
+
+// PriceMarked records the price of Asset observed at Time.
+type PriceMarked struct {
+	Time  time.Time
+	Asset Asset
+	Price decimal.Decimal
+}
+
+// ledger is the state of an account being replayed.
+type ledger struct {
+	balances Portfolio
+	prices   TimedPricelist
+}
+
+// credit adds amount to the balance of asset, failing with
+// ErrInsufficientHoldings if the balance would go negative.
+func (l *ledger) credit(asset Asset, amount decimal.Decimal) error {
+	balance := l.balances[asset].Add(amount)
+	if balance.IsNegative() {
+		return ErrInsufficientHoldings
+	}
+	if balance.IsZero() {
+		delete(l.balances, asset)
+		return nil
+	}
+	l.balances[asset] = balance
+	return nil
+}
+
+func (e Deposited) eventTime() time.Time { return e.Time }
+
+func (e Deposited) apply(l *ledger) error {
+	if !e.Amount.IsPositive() {
+		return ErrInvalidAssetAmount{Asset: e.Asset, Amount: e.Amount}
+	}
+	return l.credit(e.Asset, e.Amount)
+}
+
+func (e Withdrawn) eventTime() time.Time { return e.Time }
+
+func (e Withdrawn) apply(l *ledger) error {
+	if !e.Amount.IsPositive() {
+		return ErrInvalidAssetAmount{Asset: e.Asset, Amount: e.Amount}
+	}
+	return l.credit(e.Asset, e.Amount.Neg())
+}
+
+func (e TradeFilled) eventTime() time.Time { return e.Time }
+
+func (e TradeFilled) apply(l *ledger) error {
+	if !e.Quantity.IsPositive() {
+		return ErrInvalidAssetAmount{Asset: e.Asset, Amount: e.Quantity}
+	}
+	quantity, cash := e.Quantity, e.Quantity.Mul(e.Price).Neg()
+	if e.Action == Sell {
+		quantity, cash = quantity.Neg(), cash.Neg()
+	}
+	if err := l.credit(e.Asset, quantity); err != nil {
+		return err
+	}
+	if e.Cash == "" {
+		return nil
+	}
+	return l.credit(e.Cash, cash.Sub(e.Fee))
+}
+
+func (e PriceMarked) eventTime() time.Time { return e.Time }
+
+func (e PriceMarked) apply(l *ledger) error {
+	if !e.Price.IsPositive() {
+		return ErrInvalidAssetAmount{Asset: e.Asset, Amount: e.Price}
+	}
+	l.prices[e.Asset] = TimedPrice{Price: e.Price, Time: e.Time}
+	return nil
+}
+
+// ReplayAccount rebuilds an account from its events, given in the order they
+// happened, so that the events rather than externally maintained balances are
+// the source of truth. The account holds the balances left by the deposits,
+// withdrawals and fills, valued with the latest price marked for each asset,
+// and records when each price was marked. Every asset held must have been
+// marked. ErrInsufficientHoldings is returned if any balance goes negative.
+func ReplayAccount(events ...AccountEvent) (Account, error) {
+	l := &ledger{balances: Portfolio{}, prices: TimedPricelist{}}
+	var last time.Time
+	for _, event := range events {
+		if event.eventTime().Before(last) {
+			return Account{}, ErrEventOutOfOrder
+		}
+		last = event.eventTime()
+		if err := event.apply(l); err != nil {
+			return Account{}, err
+		}
+	}
+	return NewAccountWithTimedPricelist(l.balances, l.prices)
+}
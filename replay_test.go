@@ -0,0 +1,69 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestReplayAccount(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []AccountEvent{
+		PriceMarked{Time: start, Asset: "USD", Price: decimal.NewFromFloat(1)},
+		Deposited{Time: start, Asset: "USD", Amount: decimal.NewFromFloat(10000)},
+		TradeFilled{Time: start.Add(time.Hour), Asset: "BTC", Action: Buy, Quantity: decimal.NewFromFloat(1), Price: decimal.NewFromFloat(5000), Fee: decimal.NewFromFloat(10), Cash: "USD"},
+		TradeFilled{Time: start.Add(2 * time.Hour), Asset: "ETH", Action: Buy, Quantity: decimal.NewFromFloat(20), Price: decimal.NewFromFloat(200), Cash: "USD"},
+		TradeFilled{Time: start.Add(3 * time.Hour), Asset: "ETH", Action: Sell, Quantity: decimal.NewFromFloat(5), Price: decimal.NewFromFloat(220), Cash: "USD"},
+		Withdrawn{Time: start.Add(4 * time.Hour), Asset: "USD", Amount: decimal.NewFromFloat(990)},
+		PriceMarked{Time: start.Add(5 * time.Hour), Asset: "BTC", Price: decimal.NewFromFloat(6000)},
+		PriceMarked{Time: start.Add(5 * time.Hour), Asset: "ETH", Price: decimal.NewFromFloat(250)},
+	}
+
+	t.Run("balances are rebuilt from deposits, fills and withdrawals", func(t *testing.T) {
+		got, err := ReplayAccount(events...)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		holdings := got.Holdings()
+		if !holdings["BTC"].Equal(decimal.NewFromFloat(1)) || !holdings["ETH"].Equal(decimal.NewFromFloat(15)) || !holdings["USD"].Equal(decimal.NewFromFloat(1100)) {
+			t.Errorf("got %v want 1 BTC, 15 ETH and 1100 USD", holdings)
+		}
+		if !got.Value().Equal(decimal.NewFromFloat(10850)) {
+			t.Errorf("got value %v want %v", got.Value(), 10850)
+		}
+	})
+
+	t.Run("prices are the latest marks", func(t *testing.T) {
+		got, _ := ReplayAccount(events...)
+
+		if !got.PriceTimes()["ETH"].Equal(start.Add(5 * time.Hour)) {
+			t.Errorf("got %v want ETH marked at the last mark", got.PriceTimes())
+		}
+	})
+
+	t.Run("overdrawn balances are an error", func(t *testing.T) {
+		_, err := ReplayAccount(append(events, Withdrawn{Time: start.Add(6 * time.Hour), Asset: "USD", Amount: decimal.NewFromFloat(2000)})...)
+
+		if err != ErrInsufficientHoldings {
+			t.Errorf("got %v want %v", err, ErrInsufficientHoldings)
+		}
+	})
+
+	t.Run("events out of order are an error", func(t *testing.T) {
+		_, err := ReplayAccount(events[6], events[1])
+
+		if err != ErrEventOutOfOrder {
+			t.Errorf("got %v want %v", err, ErrEventOutOfOrder)
+		}
+	})
+
+	t.Run("held assets without a mark are an error", func(t *testing.T) {
+		_, err := ReplayAccount(events[:4]...)
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v want %v", err, ErrAssetMissingFromPricelist)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"sync"
+)
+
+// An OrderState is the state of an order tracked by an ExecutionReport.
+type OrderState string
+
+const (
+	// Open orders have been accepted but not filled yet.
+	Open OrderState = "open"
+	// PartiallyFilled orders have filled some of their quantity and may fill
+	// more.
+	PartiallyFilled OrderState = "partially filled"
+	// Filled orders have filled their whole quantity.
+	Filled OrderState = "filled"
+	// Closed orders were cancelled or expired before filling completely.
+	Closed OrderState = "closed"
+	// Rejected orders were never accepted by the executor.
+	Rejected OrderState = "rejected"
+)
+
+// An OrderReport is the latest known state of an order.
+type OrderReport struct {
+	Order Order
+	Fill  Fill
+	State OrderState
+	// Err is the error the order was rejected with.
+	Err error
+}
+
+// An ExecutionReport tracks the orders submitted by ExecutePlan, their fills
+// and rejections. It is safe for concurrent use.
+type ExecutionReport struct {
+	mu     sync.Mutex
+	orders []OrderReport
+}
+
+// NewExecutionReport returns an empty ExecutionReport.
+func NewExecutionReport() *ExecutionReport {
+	return &ExecutionReport{}
+}
+
+// WithExecutionReport records every order ExecutePlan submits, and every fill
+// it observes, in report.
+func WithExecutionReport(report *ExecutionReport) ExecutionOption {
+	return func(c *executionConfig) {
+		c.report = report
+	}
+}
+
+// Submitted records that order was accepted with orderID.
+func (r *ExecutionReport) Submitted(order Order, orderID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders = append(r.orders, OrderReport{
+		Order: order,
+		Fill:  Fill{OrderID: orderID, Asset: order.Asset, Action: order.Trade.Action, Ordered: order.Trade.Amount, Quantity: decimal.Zero},
+		State: Open,
+	})
+}
+
+// Rejected records that order was rejected with err.
+func (r *ExecutionReport) Rejected(order Order, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders = append(r.orders, OrderReport{
+		Order: order,
+		Fill:  Fill{Asset: order.Asset, Action: order.Trade.Action, Ordered: order.Trade.Amount, Quantity: decimal.Zero},
+		State: Rejected,
+		Err:   err,
+	})
+}
+
+// Update records the latest fill of a submitted order. Fills of orders which
+// were not submitted are ignored.
+func (r *ExecutionReport) Update(fill Fill) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, report := range r.orders {
+		if report.State == Rejected || report.Fill.OrderID != fill.OrderID {
+			continue
+		}
+		report.Fill.Quantity = fill.Quantity
+		report.Fill.Price = fill.Price
+		report.Fill.Done = fill.Done
+		report.State = orderState(report.Fill)
+		r.orders[i] = report
+	}
+}
+
+// Refresh requests the status of every order which is not done yet from
+// executor and records it.
+func (r *ExecutionReport) Refresh(ctx context.Context, executor Executor) error {
+	for _, report := range r.Orders() {
+		if report.State == Rejected || report.Fill.Done {
+			continue
+		}
+		fill, err := executor.OrderStatus(ctx, report.Fill.OrderID)
+		if err != nil {
+			return err
+		}
+		r.Update(fill)
+	}
+	return nil
+}
+
+// Orders returns the reports of every order in the order they were submitted.
+func (r *ExecutionReport) Orders() []OrderReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]OrderReport(nil), r.orders...)
+}
+
+// Fills returns the latest fill of every accepted order, for Reconcile.
+func (r *ExecutionReport) Fills() []Fill {
+	var fills []Fill
+	for _, report := range r.Orders() {
+		if report.State != Rejected {
+			fills = append(fills, report.Fill)
+		}
+	}
+	return fills
+}
+
+// orderState returns the state of an accepted order with fill.
+func orderState(fill Fill) OrderState {
+	switch {
+	case fill.Quantity.GreaterThanOrEqual(fill.Ordered):
+		return Filled
+	case fill.Done:
+		return Closed
+	case fill.Quantity.IsPositive():
+		return PartiallyFilled
+	default:
+		return Open
+	}
+}
+
+// A Reconciliation compares the fills of an executed plan with its trades.
+type Reconciliation struct {
+	// Filled is the quantity of each asset traded by the fills.
+	Filled map[Asset]decimal.Decimal
+	// Unfilled is the quantity of each asset's trade which was not filled.
+	Unfilled map[Asset]decimal.Decimal
+	// Drift is how far each asset is left from the plan's index, assuming the
+	// plan would have reached it in full.
+	Drift map[Asset]Drift
+	// FollowUp is a plan trading the unfilled quantities, whose trades have
+	// IDs of their own so they are not mistaken for retries of the originals.
+	FollowUp RebalancePlan
+}
+
+// Reconcile matches fills against the trades of plan, returning the residual
+// drift left by the quantities which did not fill and a follow-up plan to
+// trade them. Fills are matched to trades by asset; several fills of the same
+// asset, such as the children of a split trade, are added together.
+func Reconcile(plan RebalancePlan, fills []Fill) Reconciliation {
+	filled := map[Asset]decimal.Decimal{}
+	for _, fill := range fills {
+		trade, ok := plan.trades[fill.Asset]
+		if !ok || (fill.Action != "" && fill.Action != trade.Action) {
+			continue
+		}
+		filled[fill.Asset] = filled[fill.Asset].Add(fill.Quantity)
+	}
+
+	total := plan.Value.Add(plan.Contribution)
+	reconciliation := Reconciliation{
+		Filled:   map[Asset]decimal.Decimal{},
+		Unfilled: map[Asset]decimal.Decimal{},
+		Drift:    map[Asset]Drift{},
+	}
+	followUp := map[Asset]Trade{}
+	for asset, target := range plan.Index {
+		reconciliation.Drift[asset] = newDrift(target, target)
+	}
+	for asset, trade := range plan.trades {
+		reconciliation.Filled[asset] = filled[asset]
+		unfilled := trade.Amount.Sub(filled[asset])
+		reconciliation.Unfilled[asset] = unfilled
+
+		// An unfilled sell leaves the asset overweight and an unfilled buy
+		// leaves it underweight.
+		excess := unfilled.Mul(plan.Pricelist[asset])
+		if trade.Action == Buy {
+			excess = excess.Neg()
+		}
+		target := plan.Index[asset]
+		current := target
+		if total.IsPositive() {
+			current = current.Add(excess.Div(total))
+		}
+		reconciliation.Drift[asset] = newDrift(current, target)
+
+		if unfilled.IsZero() {
+			continue
+		}
+		next := trade
+		next.Amount = unfilled.Abs()
+		if unfilled.IsNegative() {
+			next.Action = opposite(trade.Action)
+		}
+		next.Value = next.Amount.Mul(trade.Price)
+		next.Fee = decimal.Zero
+		next.Residual = decimal.Zero
+		next.Lots = nil
+		next.ShortTermGain = decimal.Zero
+		next.LongTermGain = decimal.Zero
+		followUp[asset] = next
+	}
+
+	reconciliation.FollowUp = RebalancePlan{
+		Time:          plan.Time,
+		Index:         plan.Index,
+		Pricelist:     plan.Pricelist,
+		Holdings:      filledHoldings(plan, filled),
+		Value:         plan.Value,
+		Contribution:  decimal.Zero,
+		Turnover:      decimal.Zero,
+		Fees:          decimal.Zero,
+		Residual:      decimal.Zero,
+		ShortTermGain: decimal.Zero,
+		LongTermGain:  decimal.Zero,
+		trades:        followUp,
+	}
+	reconciliation.FollowUp = reconciliation.FollowUp.withTradeIDs()
+	for _, trade := range followUp {
+		reconciliation.FollowUp.Turnover = reconciliation.FollowUp.Turnover.Add(trade.Notional(plan.Pricelist))
+	}
+	return reconciliation
+}
+
+// opposite returns the action which undoes action.
+func opposite(action TradeAction) TradeAction {
+	if action == Sell {
+		return Buy
+	}
+	return Sell
+}
+
+// filledHoldings returns the holdings of plan after the filled quantities
+// have been traded, or nil if the plan has no holdings.
+func filledHoldings(plan RebalancePlan, filled map[Asset]decimal.Decimal) Portfolio {
+	if plan.Holdings == nil {
+		return nil
+	}
+	trades := map[Asset]Trade{}
+	for asset, trade := range plan.trades {
+		trade.Amount = filled[asset]
+		trades[asset] = trade
+	}
+	return applyTrades(plan.Holdings, trades)
+}
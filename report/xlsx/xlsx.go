@@ -0,0 +1,293 @@
+// Package xlsx writes rebalancing reports as Excel workbooks, using only the
+// standard library.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Styles of the cells, as indexes into the cellXfs of styles.xml.
+const (
+	general = iota
+	header
+	quantity
+	money
+	percent
+)
+
+// A cell is a value of a worksheet; numbers are stored as numbers so that
+// spreadsheets can calculate with them.
+type cell struct {
+	value  string
+	number bool
+	style  int
+}
+
+// text returns a cell holding s.
+func text(s string) cell {
+	return cell{value: s}
+}
+
+// num returns a cell holding d, formatted with style.
+func num(d decimal.Decimal, style int) cell {
+	return cell{value: d.String(), number: true, style: style}
+}
+
+// A sheet is a named worksheet whose first row is a header.
+type sheet struct {
+	name   string
+	widths []int
+	rows   [][]cell
+}
+
+// Write writes a workbook describing account and plan to w, with three
+// sheets: the account's holdings, its current allocation against the plan's
+// target index, and the plan's trades in execution order.
+func Write(w io.Writer, account rebalancer.Account, plan rebalancer.RebalancePlan) error {
+	sheets := []sheet{
+		holdingsSheet(account),
+		allocationSheet(account, plan),
+		tradesSheet(plan),
+	}
+
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes(len(sheets))},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", workbook(sheets)},
+		{"xl/_rels/workbook.xml.rels", workbookRels(len(sheets))},
+		{"xl/styles.xml", styles},
+	}
+	for i, s := range sheets {
+		parts = append(parts, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), worksheet(s)})
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// holdingsSheet lists the holdings of account by value, largest first.
+func holdingsSheet(account rebalancer.Account) sheet {
+	holdings := account.Holdings()
+	pricelist := account.Pricelist()
+	allocation := account.Allocation()
+	assets := make([]rebalancer.Asset, 0, len(holdings))
+	for asset := range holdings {
+		assets = append(assets, asset)
+	}
+	value := func(asset rebalancer.Asset) decimal.Decimal {
+		return holdings[asset].Mul(pricelist[asset])
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if !value(assets[i]).Equal(value(assets[j])) {
+			return value(assets[i]).GreaterThan(value(assets[j]))
+		}
+		return assets[i] < assets[j]
+	})
+
+	s := sheet{
+		name:   "Holdings",
+		widths: []int{12, 18, 14, 16, 10},
+		rows:   [][]cell{headerRow("Asset", "Amount", "Price", "Value", "Weight")},
+	}
+	for _, asset := range assets {
+		s.rows = append(s.rows, []cell{
+			text(string(asset)),
+			num(holdings[asset], quantity),
+			num(pricelist[asset], quantity),
+			num(value(asset), money),
+			num(allocation[asset], percent),
+		})
+	}
+	s.rows = append(s.rows, []cell{
+		{value: "Total", style: header}, {}, {}, num(account.Value(), money), num(decimal.New(1, 0), percent),
+	})
+	return s
+}
+
+// allocationSheet compares the current weight of every asset held or in the
+// plan's index with its target weight.
+func allocationSheet(account rebalancer.Account, plan rebalancer.RebalancePlan) sheet {
+	current := account.Allocation()
+	weights := map[rebalancer.Asset]decimal.Decimal{}
+	for asset, weight := range current {
+		weights[asset] = weight
+	}
+	for asset, weight := range plan.Index {
+		weights[asset] = weight
+	}
+	assets := make([]rebalancer.Asset, 0, len(weights))
+	for asset := range weights {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i] < assets[j]
+	})
+
+	s := sheet{
+		name:   "Allocation",
+		widths: []int{12, 12, 12, 12},
+		rows:   [][]cell{headerRow("Asset", "Current", "Target", "Drift")},
+	}
+	for _, asset := range assets {
+		s.rows = append(s.rows, []cell{
+			text(string(asset)),
+			num(current[asset], percent),
+			num(plan.Index[asset], percent),
+			num(current[asset].Sub(plan.Index[asset]), percent),
+		})
+	}
+	return s
+}
+
+// tradesSheet lists the trades of plan in the order of SortedTrades.
+func tradesSheet(plan rebalancer.RebalancePlan) sheet {
+	s := sheet{
+		name:   "Trades",
+		widths: []int{12, 8, 18, 14, 16, 12},
+		rows:   [][]cell{headerRow("Asset", "Action", "Quantity", "Price", "Notional", "Fee")},
+	}
+	for _, trade := range plan.SortedTrades() {
+		s.rows = append(s.rows, []cell{
+			text(string(trade.Asset)),
+			text(string(trade.Action)),
+			num(trade.Amount, quantity),
+			num(plan.Pricelist[trade.Asset], quantity),
+			num(trade.Notional(plan.Pricelist), money),
+			num(trade.Fee, money),
+		})
+	}
+	return s
+}
+
+// headerRow returns a row of header cells.
+func headerRow(titles ...string) []cell {
+	row := make([]cell, len(titles))
+	for i, title := range titles {
+		row[i] = cell{value: title, style: header}
+	}
+	return row
+}
+
+// column returns the letters naming the zero based column i, such as AA.
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// escape returns s escaped for use in XML text and attributes.
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// worksheet renders s with its header row frozen.
+func worksheet(s sheet) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<cols>`)
+	for i, width := range s.widths {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+	}
+	b.WriteString(`</cols><sheetData>`)
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := fmt.Sprintf("%s%d", column(c), r+1)
+			switch {
+			case cell.number:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, cell.value)
+			case cell.value != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t>%s</t></is></c>`, ref, cell.style, escape(cell.value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// workbook renders the workbook part listing sheets.
+func workbook(sheets []sheet) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+// workbookRels renders the relationships of the workbook to its n sheets and
+// its styles.
+func workbookRels(n int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, n+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// contentTypes renders the content types of a workbook with n sheets.
+func contentTypes(n int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+// rootRels points the package at its workbook.
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// styles defines the cell styles, in the order of the style constants: the
+// default, a bold shaded header, quantities, money and percentages.
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0.########"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9D9D9"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="10" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs></styleSheet>`
@@ -0,0 +1,137 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/report/xlsx"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// readSheet returns the values of the cells of the named part of workbook,
+// row by row.
+func readSheet(t *testing.T, workbook *zip.Reader, name string) [][]string {
+	for _, f := range workbook.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var sheet struct {
+			Rows []struct {
+				Cells []struct {
+					Value string `xml:"v"`
+					Text  string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		if err := xml.Unmarshal(data, &sheet); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var rows [][]string
+		for _, row := range sheet.Rows {
+			var values []string
+			for _, c := range row.Cells {
+				values = append(values, c.Value+c.Text)
+			}
+			rows = append(rows, values)
+		}
+		return rows
+	}
+	t.Fatalf("got no %s part", name)
+	return nil
+}
+
+func TestWrite(t *testing.T) {
+	account, err := rebalancer.NewAccountWithPricelist(rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, rebalancer.Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+		"XLM": decimal.NewFromFloat(0.25),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	plan, err := account.Rebalance(rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.25),
+		"XLM": decimal.NewFromFloat(0.25),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, account, plan); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	workbook, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("the workbook has the parts spreadsheets expect", func(t *testing.T) {
+		var got []string
+		for _, f := range workbook.File {
+			got = append(got, f.Name)
+		}
+		want := []string{
+			"[Content_Types].xml",
+			"_rels/.rels",
+			"xl/workbook.xml",
+			"xl/_rels/workbook.xml.rels",
+			"xl/styles.xml",
+			"xl/worksheets/sheet1.xml",
+			"xl/worksheets/sheet2.xml",
+			"xl/worksheets/sheet3.xml",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("holdings are listed by value with a total", func(t *testing.T) {
+		got := readSheet(t, workbook, "xl/worksheets/sheet1.xml")
+
+		want := [][]string{
+			{"Asset", "Amount", "Price", "Value", "Weight"},
+			{"ETH", "20", "200", "4000", "0.6153846153846154"},
+			{"BTC", "0.5", "5000", "2500", "0.3846153846153846"},
+			{"Total", "6500", "1"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("the current allocation is compared with the target", func(t *testing.T) {
+		got := readSheet(t, workbook, "xl/worksheets/sheet2.xml")
+
+		if len(got) != 4 || !reflect.DeepEqual(got[3], []string{"XLM", "0", "0.25", "-0.25"}) {
+			t.Errorf("got %v want XLM 25%% underweight last", got)
+		}
+	})
+	t.Run("trades are listed in execution order", func(t *testing.T) {
+		got := readSheet(t, workbook, "xl/worksheets/sheet3.xml")
+
+		want := [][]string{
+			{"Asset", "Action", "Quantity", "Price", "Notional", "Fee"},
+			{"BTC", "sell", "0.175", "5000", "875", "0"},
+			{"ETH", "sell", "3.75", "200", "750", "0"},
+			{"XLM", "buy", "6500", "0.25", "1625", "0"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+}
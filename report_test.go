@@ -0,0 +1,135 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestExecutionReport(t *testing.T) {
+	ctx := context.Background()
+	trades := map[Asset]Trade{
+		"BTC": {Action: Sell, Amount: decimal.NewFromFloat(0.3)},
+		"ETH": {Action: Buy, Amount: decimal.NewFromFloat(5)},
+		"XRP": {Action: Buy, Amount: decimal.NewFromFloat(100)},
+	}
+	executor := NewSimExecutor(newSimAccount(t), SimPartialFills(decimal.NewFromFloat(0.5)))
+	report := NewExecutionReport()
+
+	_, err := ExecutePlan(ctx, executor, trades, WithExecutionReport(report))
+
+	if err != ErrAssetMissingFromPricelist {
+		t.Errorf("got %v want %v", err, ErrAssetMissingFromPricelist)
+	}
+
+	t.Run("submitted orders are open until refreshed", func(t *testing.T) {
+		orders := report.Orders()
+
+		if len(orders) != 3 {
+			t.Fatalf("got %d orders want 3", len(orders))
+		}
+		if orders[0].State != Open || orders[1].State != Open {
+			t.Errorf("got %s and %s want open orders", orders[0].State, orders[1].State)
+		}
+	})
+
+	t.Run("rejected orders are recorded with their error", func(t *testing.T) {
+		rejected := report.Orders()[2]
+
+		if rejected.Order.Asset != "XRP" || rejected.State != Rejected || rejected.Err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v want a rejected XRP order", rejected)
+		}
+	})
+
+	t.Run("refreshing records the fills of open orders", func(t *testing.T) {
+		if err := report.Refresh(ctx, executor); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		fills := report.Fills()
+		if len(fills) != 2 {
+			t.Fatalf("got %d fills want 2", len(fills))
+		}
+		for _, order := range report.Orders()[:2] {
+			if order.State != Closed || !order.Fill.Ratio().Equal(decimal.NewFromFloat(0.5)) {
+				t.Errorf("got %s filled to %s want closed at 0.5", order.State, order.Fill.Ratio())
+			}
+		}
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	account := newSimAccount(t)
+	plan, err := account.Rebalance(map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("a fully filled plan leaves no drift", func(t *testing.T) {
+		executor := NewSimExecutor(account)
+		report := NewExecutionReport()
+		if _, err := ExecutePlan(ctx, executor, plan.Trades(), WithExecutionReport(report)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		report.Refresh(ctx, executor)
+
+		got := Reconcile(plan, report.Fills())
+
+		if len(got.FollowUp.Trades()) != 0 {
+			t.Errorf("got %v want no follow-up trades", got.FollowUp.Trades())
+		}
+		for asset, drift := range got.Drift {
+			if !drift.Absolute.IsZero() {
+				t.Errorf("got drift %s for %s want 0", drift.Absolute, asset)
+			}
+		}
+	})
+
+	t.Run("unfilled quantities are drift and follow-up trades", func(t *testing.T) {
+		executor := NewSimExecutor(account, SimPartialFills(decimal.NewFromFloat(0.5)))
+		report := NewExecutionReport()
+		if _, err := ExecutePlan(ctx, executor, plan.Trades(), WithExecutionReport(report)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		report.Refresh(ctx, executor)
+
+		got := Reconcile(plan, report.Fills())
+
+		if !got.Unfilled["BTC"].Equal(decimal.NewFromFloat(0.15)) || !got.Unfilled["ETH"].Equal(decimal.NewFromFloat(3.75)) {
+			t.Errorf("got %v want 0.15 BTC and 3.75 ETH unfilled", got.Unfilled)
+		}
+		want := decimal.New(750, 0).Div(decimal.New(7000, 0))
+		if !got.Drift["BTC"].Absolute.Equal(want) || !got.Drift["ETH"].Absolute.Equal(want.Neg()) {
+			t.Errorf("got %v want BTC %s overweight and ETH underweight", got.Drift, want)
+		}
+		assertSameTrades(t, got.FollowUp.Trades(), map[Asset]Trade{
+			"BTC": {Action: Sell, Amount: decimal.NewFromFloat(0.15)},
+			"ETH": {Action: Buy, Amount: decimal.NewFromFloat(3.75)},
+		})
+		for asset, trade := range plan.Trades() {
+			want := plan.Holdings[asset].Add(got.Filled[asset])
+			if trade.Action == Sell {
+				want = plan.Holdings[asset].Sub(got.Filled[asset])
+			}
+			if !got.FollowUp.Holdings[asset].Equal(want) {
+				t.Errorf("got %v %s held after the fills want %v", got.FollowUp.Holdings[asset], asset, want)
+			}
+		}
+	})
+
+	t.Run("overfilled trades are reversed", func(t *testing.T) {
+		got := Reconcile(plan, []Fill{
+			{Asset: "BTC", Action: Sell, Quantity: decimal.NewFromFloat(0.3)},
+			{Asset: "ETH", Action: Buy, Quantity: decimal.NewFromFloat(8)},
+		})
+
+		assertSameTrades(t, got.FollowUp.Trades(), map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(0.5)},
+		})
+	})
+}
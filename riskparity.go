@@ -0,0 +1,113 @@
+package rebalancer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"math"
+	"sort"
+)
+
+// ErrInvalidVolatility indicates an asset given to RiskParity or
+// TargetVolatility without a positive volatility, or given to
+// RiskParityCovariance without a positive variance.
+type ErrInvalidVolatility struct {
+	Asset      Asset
+	Volatility decimal.Decimal
+}
+
+// Error formats the error message for ErrInvalidVolatility.
+func (e ErrInvalidVolatility) Error() string {
+	return fmt.Sprintf("volatility of %s must be positive, got %s", e.Asset, e.Volatility)
+}
+
+// ErrRiskParityDiverged is returned by RiskParityCovariance when the weights
+// fail to converge, which happens when the covariances are not those of any
+// set of returns.
+var ErrRiskParityDiverged = errors.New("risk parity weights did not converge")
+
+// RiskParity returns the Index weighting each asset by the inverse of its
+// volatility, so that every asset contributes the same risk when their
+// returns are uncorrelated.
+func RiskParity(volatilities map[Asset]decimal.Decimal) (Index, error) {
+	if len(volatilities) == 0 {
+		return nil, ErrEmptyIndex
+	}
+	one := decimal.New(1, 0)
+	weights := Index{}
+	for asset, volatility := range volatilities {
+		if !volatility.IsPositive() {
+			return nil, ErrInvalidVolatility{Asset: asset, Volatility: volatility}
+		}
+		weights[asset] = one.Div(volatility)
+	}
+	return scaleWeights(weights, one), nil
+}
+
+// RiskParityCovariance returns the Index under which every asset contributes
+// the same share of the portfolio's variance, given the covariances of the
+// assets' returns. covariances[a][b] need only be given once for each pair of
+// assets, and is taken as zero when it is missing; covariances[a][a] is the
+// variance of a and must be positive.
+func RiskParityCovariance(covariances map[Asset]map[Asset]decimal.Decimal) (Index, error) {
+	if len(covariances) == 0 {
+		return nil, ErrEmptyIndex
+	}
+	assets := make([]Asset, 0, len(covariances))
+	for asset := range covariances {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i] < assets[j]
+	})
+
+	n := len(assets)
+	cov := make([][]float64, n)
+	for i, a := range assets {
+		cov[i] = make([]float64, n)
+		for j, b := range assets {
+			c, ok := covariances[a][b]
+			if !ok {
+				c = covariances[b][a]
+			}
+			cov[i][j], _ = c.Float64()
+		}
+		if variance := covariances[a][a]; !variance.IsPositive() {
+			return nil, ErrInvalidVolatility{Asset: a, Volatility: variance}
+		}
+	}
+
+	// Cyclical coordinate descent: each weight in turn is set to the positive
+	// root of the quadratic which equalises its risk contribution with the
+	// budget, given the other weights, until the weights stop changing.
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 1 / math.Sqrt(cov[i][i])
+	}
+	budget := 1 / float64(n)
+	for iteration := 0; iteration < 1000; iteration++ {
+		change := 0.0
+		for i := range w {
+			b := 0.0
+			for j := range w {
+				if j != i {
+					b += cov[i][j] * w[j]
+				}
+			}
+			next := (-b + math.Sqrt(b*b+4*cov[i][i]*budget)) / (2 * cov[i][i])
+			change = math.Max(change, math.Abs(next-w[i]))
+			w[i] = next
+		}
+		if change < 1e-12 {
+			weights := Index{}
+			for i, asset := range assets {
+				weights[asset] = decimal.NewFromFloat(w[i])
+			}
+			return scaleWeights(weights, decimal.New(1, 0)), nil
+		}
+		if math.IsNaN(change) || math.IsInf(change, 0) {
+			break
+		}
+	}
+	return nil, ErrRiskParityDiverged
+}
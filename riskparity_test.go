@@ -0,0 +1,122 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestRiskParity(t *testing.T) {
+	t.Run("assets are weighted by the inverse of their volatility", func(t *testing.T) {
+		got, err := RiskParity(map[Asset]decimal.Decimal{
+			"ETH": decimal.NewFromFloat(0.8),
+			"BTC": decimal.NewFromFloat(0.4),
+			"USD": decimal.NewFromFloat(0.2),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := Index{
+			"ETH": decimal.RequireFromString("0.1428571428571429"),
+			"BTC": decimal.RequireFromString("0.2857142857142857"),
+			"USD": decimal.RequireFromString("0.5714285714285714"),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("volatilities must be positive", func(t *testing.T) {
+		_, err := RiskParity(map[Asset]decimal.Decimal{"ETH": decimal.Zero})
+
+		want := ErrInvalidVolatility{Asset: "ETH", Volatility: decimal.Zero}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+}
+
+func TestRiskParityCovariance(t *testing.T) {
+	t.Run("uncorrelated assets are weighted by the inverse of their volatility", func(t *testing.T) {
+		got, err := RiskParityCovariance(map[Asset]map[Asset]decimal.Decimal{
+			"ETH": {"ETH": decimal.NewFromFloat(0.64)},
+			"BTC": {"BTC": decimal.NewFromFloat(0.16)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		assertWeightsNear(t, got, map[Asset]float64{"ETH": 1.0 / 3, "BTC": 2.0 / 3})
+	})
+	t.Run("correlated assets contribute equal risk", func(t *testing.T) {
+		covariances := map[Asset]map[Asset]decimal.Decimal{
+			"ETH": {"ETH": decimal.NewFromFloat(0.64), "BTC": decimal.NewFromFloat(0.16)},
+			"BTC": {"BTC": decimal.NewFromFloat(0.16)},
+			"USD": {"USD": decimal.NewFromFloat(0.01)},
+		}
+		got, err := RiskParityCovariance(covariances)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		contribution := func(a Asset) decimal.Decimal {
+			marginal := decimal.Zero
+			for b, weight := range got {
+				c, ok := covariances[a][b]
+				if !ok {
+					c = covariances[b][a]
+				}
+				marginal = marginal.Add(c.Mul(weight))
+			}
+			return got[a].Mul(marginal)
+		}
+		eth, btc, usd := contribution("ETH"), contribution("BTC"), contribution("USD")
+		if eth.Sub(btc).Abs().GreaterThan(decimal.New(1, -9)) || eth.Sub(usd).Abs().GreaterThan(decimal.New(1, -9)) {
+			t.Errorf("got risk contributions %s, %s and %s want them equal", eth, btc, usd)
+		}
+	})
+	t.Run("variances must be positive", func(t *testing.T) {
+		_, err := RiskParityCovariance(map[Asset]map[Asset]decimal.Decimal{
+			"ETH": {"BTC": decimal.NewFromFloat(0.1)},
+			"BTC": {"BTC": decimal.NewFromFloat(0.1)},
+		})
+
+		if _, ok := err.(ErrInvalidVolatility); !ok {
+			t.Errorf("got %v want an ErrInvalidVolatility", err)
+		}
+	})
+}
+
+func assertSameIndex(t *testing.T, got, want Index) {
+	t.Helper()
+	sum := decimal.Zero
+	for _, weight := range got {
+		sum = sum.Add(weight)
+	}
+	if !sum.Equal(decimal.NewFromFloat(1)) || len(got) != len(want) {
+		t.Errorf("got %v summing to %s want %v", got, sum, want)
+	}
+	for asset, weight := range want {
+		if !got[asset].Equal(weight) {
+			t.Errorf("got %s want %s for %s", got[asset], weight, asset)
+		}
+	}
+}
+
+func assertWeightsNear(t *testing.T, got Index, want map[Asset]float64) {
+	t.Helper()
+	sum := decimal.Zero
+	for _, weight := range got {
+		sum = sum.Add(weight)
+	}
+	if !sum.Equal(decimal.NewFromFloat(1)) || len(got) != len(want) {
+		t.Errorf("got %v summing to %s want %v", got, sum, want)
+	}
+	for asset, weight := range want {
+		if got[asset].Sub(decimal.NewFromFloat(weight)).Abs().GreaterThan(decimal.New(1, -9)) {
+			t.Errorf("got %s want %v for %s", got[asset], weight, asset)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// AssetRules describes the precision an exchange accepts when trading an
+// asset. Zero values impose no restriction.
+type AssetRules struct {
+	// StepSize is the increment every quantity must be a multiple of.
+	StepSize decimal.Decimal
+	// TickSize is the increment every price must be a multiple of.
+	TickSize decimal.Decimal
+	// MinQuantity is the smallest quantity which can be traded.
+	MinQuantity decimal.Decimal
+	// Integral marks assets, such as most stocks and ETFs, which can only be
+	// traded in whole units.
+	Integral bool
+}
+
+// ExchangeRules contains a map of Assets and the rules for trading them.
+type ExchangeRules map[Asset]AssetRules
+
+// RoundQuantity rounds quantity to the nearest multiple of the step size, or
+// down to a whole number for integral assets, or to zero if the result is
+// below the minimum quantity.
+func (r AssetRules) RoundQuantity(quantity decimal.Decimal) decimal.Decimal {
+	rounded := roundToStep(quantity, r.StepSize)
+	if r.Integral {
+		rounded = quantity.Floor()
+	}
+	if rounded.LessThan(r.MinQuantity) {
+		return decimal.Zero
+	}
+	return rounded
+}
+
+// RoundPrice rounds price to the nearest multiple of the tick size.
+func (r AssetRules) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return roundToStep(price, r.TickSize)
+}
+
+// floorQuantity rounds quantity down to a multiple of the step size, or to a
+// whole number for integral assets, or to zero if the result is below the
+// minimum quantity.
+func (r AssetRules) floorQuantity(quantity decimal.Decimal) decimal.Decimal {
+	floored := quantity
+	if r.StepSize.IsPositive() {
+		floored = quantity.Div(r.StepSize).Floor().Mul(r.StepSize)
+	}
+	if r.Integral {
+		floored = quantity.Floor()
+	}
+	if floored.LessThan(r.MinQuantity) {
+		return decimal.Zero
+	}
+	return floored
+}
+
+func roundToStep(value, step decimal.Decimal) decimal.Decimal {
+	if !step.IsPositive() {
+		return value
+	}
+	return value.Div(step).Round(0).Mul(step)
+}
+
+// TotalResidual returns the sum of the residual value of trades.
+func TotalResidual(trades map[Asset]Trade) decimal.Decimal {
+	total := decimal.Zero
+	for _, trade := range trades {
+		total = total.Add(trade.Residual)
+	}
+	return total
+}
+
+// applyExchangeRules rounds the amount of every trade to a quantity allowed
+// by rules, recording the value lost or gained by rounding in Trade.Residual.
+// Sells are never rounded above the holding they are taken from, so a full
+// liquidation rounds down rather than selling more than is held. If rounding
+// leaves the buys costing more than before relative to the sells, the largest
+// buys are reduced a step at a time until they are funded again.
+func applyExchangeRules(trades map[Asset]Trade, pricelist Pricelist, rules ExchangeRules, holdings Portfolio) {
+	want := netProceeds(trades, pricelist)
+	intended := map[Asset]decimal.Decimal{}
+	for asset, trade := range trades {
+		if _, ok := rules[asset]; ok {
+			intended[asset] = trade.Amount
+		}
+	}
+
+	allocateIntegralAssets(trades, pricelist, rules)
+
+	for asset, trade := range trades {
+		rule, ok := rules[asset]
+		if !ok || rule.Integral {
+			continue
+		}
+		trade.Amount = rule.RoundQuantity(trade.Amount)
+		if trade.Action == Sell && trade.Amount.GreaterThan(holdings[asset]) {
+			trade.Amount = rule.floorQuantity(holdings[asset])
+		}
+		trades[asset] = trade
+	}
+
+	for netProceeds(trades, pricelist).LessThan(want) {
+		var largest Trade
+		for asset, trade := range trades {
+			step := rules[asset].StepSize
+			if rules[asset].Integral {
+				step = decimal.New(1, 0)
+			}
+			if trade.Action != Buy || !step.IsPositive() || trade.Amount.LessThan(step) {
+				continue
+			}
+			if trade.Notional(pricelist).GreaterThan(largest.Notional(pricelist)) {
+				largest = trade
+			}
+		}
+		if largest.Asset == "" {
+			break
+		}
+		rule := rules[largest.Asset]
+		step := rule.StepSize
+		if rule.Integral {
+			step = decimal.New(1, 0)
+		}
+		largest.Amount = rule.RoundQuantity(largest.Amount.Sub(step))
+		trades[largest.Asset] = largest
+	}
+
+	for asset, amount := range intended {
+		trade := trades[asset]
+		trade.Residual = trade.Residual.Add(amount.Sub(trade.Amount).Mul(pricelist[asset]))
+		trades[asset] = trade
+	}
+}
+
+// allocateIntegralAssets floors the amount of every trade of an integral
+// asset. The cash this frees is spent with a largest-remainder pass, buying
+// one more unit of the integral assets whose buys lost the largest fraction
+// while it remains affordable, and whatever is left over is shared among the
+// buys of fractional assets in proportion to their value.
+func allocateIntegralAssets(trades map[Asset]Trade, pricelist Pricelist, rules ExchangeRules) {
+	want := netProceeds(trades, pricelist)
+	remainders := map[Asset]decimal.Decimal{}
+	var integralBuys []Asset
+
+	for asset, trade := range trades {
+		if !rules[asset].Integral {
+			continue
+		}
+		floored := trade.Amount.Floor()
+		remainders[asset] = trade.Amount.Sub(floored)
+		trade.Amount = floored
+		trades[asset] = trade
+		if trade.Action == Buy {
+			integralBuys = append(integralBuys, asset)
+		}
+	}
+	if len(remainders) == 0 {
+		return
+	}
+
+	sort.Slice(integralBuys, func(i, j int) bool {
+		a, b := integralBuys[i], integralBuys[j]
+		if !remainders[a].Equal(remainders[b]) {
+			return remainders[a].GreaterThan(remainders[b])
+		}
+		return a < b
+	})
+
+	leftover := netProceeds(trades, pricelist).Sub(want)
+	for _, asset := range integralBuys {
+		price := pricelist[asset]
+		if remainders[asset].IsZero() || leftover.LessThan(price) {
+			continue
+		}
+		trade := trades[asset]
+		trade.Amount = trade.Amount.Add(decimal.New(1, 0))
+		trades[asset] = trade
+		leftover = leftover.Sub(price)
+	}
+
+	fractional := decimal.Zero
+	for asset, trade := range trades {
+		if trade.Action == Buy && !rules[asset].Integral {
+			fractional = fractional.Add(trade.Notional(pricelist))
+		}
+	}
+	if !fractional.IsPositive() {
+		return
+	}
+	if leftover.Neg().GreaterThan(fractional) {
+		leftover = fractional.Neg()
+	}
+	for asset, trade := range trades {
+		if trade.Action == Buy && !rules[asset].Integral {
+			trade.Amount = trade.Amount.Mul(fractional.Add(leftover)).Div(fractional)
+			trades[asset] = trade
+		}
+	}
+}
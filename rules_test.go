@@ -0,0 +1,197 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestAssetRules(t *testing.T) {
+	rules := AssetRules{
+		StepSize:    decimal.NewFromFloat(0.01),
+		TickSize:    decimal.NewFromFloat(0.5),
+		MinQuantity: decimal.NewFromFloat(0.05),
+	}
+
+	t.Run("quantities are rounded to the nearest step", func(t *testing.T) {
+		got := rules.RoundQuantity(decimal.NewFromFloat(0.1274))
+
+		if !got.Equal(decimal.NewFromFloat(0.13)) {
+			t.Errorf("got %s want 0.13", got)
+		}
+	})
+	t.Run("quantities below the minimum are rounded to zero", func(t *testing.T) {
+		got := rules.RoundQuantity(decimal.NewFromFloat(0.04))
+
+		if !got.IsZero() {
+			t.Errorf("got %s want 0", got)
+		}
+	})
+	t.Run("prices are rounded to the nearest tick", func(t *testing.T) {
+		got := rules.RoundPrice(decimal.NewFromFloat(200.7))
+
+		if !got.Equal(decimal.NewFromFloat(200.5)) {
+			t.Errorf("got %s want 200.5", got)
+		}
+	})
+}
+
+func TestWithExchangeRules(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("trades are rounded to the step size and residuals reported", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithExchangeRules(ExchangeRules{
+			"ETH": {StepSize: decimal.NewFromFloat(0.1)},
+		}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(3.8)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.15)},
+		}
+
+		assertSameTrades(t, got, want)
+
+		if residual := TotalResidual(got); !residual.Equal(decimal.NewFromFloat(-10)) {
+			t.Errorf("got a residual of %s want -10", residual)
+		}
+	})
+	t.Run("buys rounded beyond the available funds are reduced", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithExchangeRules(ExchangeRules{
+			"ETH": {StepSize: decimal.NewFromFloat(0.1)},
+			"BTC": {StepSize: decimal.NewFromFloat(0.1)},
+		}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(3.8)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.1)},
+		}
+
+		assertSameTrades(t, got, want)
+
+		if residual := got["BTC"].Residual; !residual.Equal(decimal.NewFromFloat(250)) {
+			t.Errorf("got a BTC residual of %s want 250", residual)
+		}
+	})
+	t.Run("full liquidations are never rounded above the holding", func(t *testing.T) {
+		account, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(1.06),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{"ETH": decimal.NewFromFloat(1)}, WithExchangeRules(ExchangeRules{
+			"BTC": {StepSize: decimal.NewFromFloat(0.1)},
+		}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if got := plan.Trades()["BTC"]; got.Action != Sell || !got.Amount.Equal(decimal.NewFromFloat(1)) {
+			t.Errorf("got %v want 1 BTC sold", got)
+		}
+		if _, err := account.ApplyTrades(plan); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+}
+
+func TestWithExchangeRules_integralAssets(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"USD": decimal.NewFromFloat(10000),
+	}, Pricelist{
+		"USD": decimal.NewFromFloat(1),
+		"SPY": decimal.NewFromFloat(400),
+		"VTI": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	rules := WithExchangeRules(ExchangeRules{
+		"SPY": {Integral: true},
+		"VTI": {Integral: true},
+	})
+
+	t.Run("leftover cash is reallocated to fractional assets", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"USD": decimal.NewFromFloat(0.2),
+			"SPY": decimal.NewFromFloat(0.45),
+			"VTI": decimal.NewFromFloat(0.3),
+			"BTC": decimal.NewFromFloat(0.05),
+		}, rules)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"USD": {Action: Sell, Amount: decimal.NewFromFloat(8000)},
+			"SPY": {Action: Buy, Amount: decimal.NewFromFloat(11)},
+			"VTI": {Action: Buy, Amount: decimal.NewFromFloat(15)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.12)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("whole units go to the largest remainders first", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"USD": decimal.NewFromFloat(0.2),
+			"SPY": decimal.NewFromFloat(0.47),
+			"VTI": decimal.NewFromFloat(0.31),
+			"BTC": decimal.NewFromFloat(0.02),
+		}, rules)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"USD": {Action: Sell, Amount: decimal.NewFromFloat(8000)},
+			"SPY": {Action: Buy, Amount: decimal.NewFromFloat(12)},
+			"VTI": {Action: Buy, Amount: decimal.NewFromFloat(15)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.04)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+}
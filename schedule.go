@@ -0,0 +1,187 @@
+package rebalancer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Schedule decides when a Scheduler checks whether to rebalance.
+type Schedule interface {
+	// Next returns the first time after t the schedule fires.
+	Next(t time.Time) time.Time
+}
+
+// interval fires every d.
+type interval time.Duration
+
+// Every returns a Schedule which fires every d, or never when d is not
+// positive.
+func Every(d time.Duration) Schedule {
+	return interval(d)
+}
+
+// Next returns t plus the interval, or the zero time when the interval is not
+// positive.
+func (i interval) Next(t time.Time) time.Time {
+	if i <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(i))
+}
+
+// ErrInvalidCron indicates a cron expression ParseCron cannot parse.
+type ErrInvalidCron struct {
+	Spec   string
+	Reason string
+}
+
+// Error formats the error message for ErrInvalidCron.
+func (e ErrInvalidCron) Error() string {
+	return fmt.Sprintf("invalid cron expression %q: %s", e.Spec, e.Reason)
+}
+
+// cron is a Schedule parsed from a cron expression. Each field is the set of
+// values it matches.
+type cron struct {
+	minute, hour, dom, month, dow map[int]bool
+	// anyDOM and anyDOW record whether the day fields were *, since a day
+	// matches either restricted field when both are restricted.
+	anyDOM, anyDOW bool
+}
+
+// cronFields are the names and ranges of the fields of a cron expression.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// cronAliases are the shorthands ParseCron accepts.
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard five field cron expression, "minute hour
+// day-of-month month day-of-week", such as "0 9 * * 1-5" for 9am on weekdays.
+// Fields accept *, numbers, ranges such as 1-5, lists such as 1,15 and steps
+// such as */15; Sunday is 0 or 7. The shorthands @hourly, @daily, @weekly,
+// @monthly and @yearly are accepted too. Times are matched in the location of
+// the time given to Next.
+func ParseCron(spec string) (Schedule, error) {
+	expr := spec
+	if alias, ok := cronAliases[strings.TrimSpace(spec)]; ok {
+		expr = alias
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, ErrInvalidCron{Spec: spec, Reason: "want 5 fields"}
+	}
+	sets := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, ErrInvalidCron{Spec: spec, Reason: cronFields[i].name + ": " + err.Error()}
+		}
+		sets[i] = set
+	}
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+	return cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDOM: fields[2] == "*",
+		anyDOW: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField returns the set of values between min and max matched by
+// field.
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// matchesDay reports whether the day of t matches the day fields.
+func (c cron) matchesDay(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first minute after t the expression matches, or the zero
+// time if it matches none in the next five years, as for February 30th.
+func (c cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !c.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
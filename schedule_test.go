@@ -0,0 +1,56 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// 2019-01-01 was a Tuesday.
+	from := time.Date(2019, 1, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		spec string
+		want time.Time
+	}{
+		{"every minute fires on the next minute", "* * * * *", time.Date(2019, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"steps fire on their multiples", "*/15 * * * *", time.Date(2019, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"fixed times fire the next day once passed", "0 9 * * *", time.Date(2019, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"weekday ranges skip the weekend", "0 9 * * 6-7", time.Date(2019, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{"lists of days of the month fire on each", "0 0 1,15 * *", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"restricted days fire on either day field", "0 0 31 * 3", time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"shorthands expand to their expression", "@monthly", time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseCron(test.spec)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := schedule.Next(from); !got.Equal(test.want) {
+				t.Errorf("got %s want %s", got, test.want)
+			}
+		})
+	}
+
+	t.Run("invalid expressions are an error", func(t *testing.T) {
+		for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *"} {
+			if _, err := ParseCron(spec); err == nil {
+				t.Errorf("got no error for %q", spec)
+			}
+		}
+	})
+
+	t.Run("intervals fire a duration later", func(t *testing.T) {
+		if got := Every(time.Hour).Next(from); !got.Equal(from.Add(time.Hour)) {
+			t.Errorf("got %s want %s", got, from.Add(time.Hour))
+		}
+	})
+	t.Run("intervals which are not positive never fire", func(t *testing.T) {
+		if got := Every(0).Next(from); !got.IsZero() {
+			t.Errorf("got %s want the zero time", got)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// A Trigger decides whether account warrants rebalancing onto targetIndex.
+type Trigger func(account Account, targetIndex Index) bool
+
+// DriftTrigger warrants a rebalance when the absolute drift of any asset from
+// its target weight exceeds threshold.
+func DriftTrigger(threshold decimal.Decimal) Trigger {
+	return func(account Account, targetIndex Index) bool {
+		for _, drift := range account.Drift(targetIndex) {
+			if drift.Absolute.Abs().GreaterThan(threshold) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// A Scheduler checks whether a portfolio warrants rebalancing on a schedule,
+// pricing it with fresh prices each time, and hands the plans it warrants to
+// a callback or an executor.
+type Scheduler struct {
+	schedule    Schedule
+	provider    PriceProvider
+	holdings    func(ctx context.Context) (Portfolio, error)
+	targetIndex Index
+	trigger     Trigger
+	opts        []RebalanceOption
+	onPlan      func(ctx context.Context, plan RebalancePlan) error
+	executor    Executor
+	execOpts    []ExecutionOption
+	onError     func(error)
+}
+
+// A SchedulerOption configures a Scheduler.
+type SchedulerOption func(*Scheduler)
+
+// WithTrigger only warrants a plan when trigger does. Without it every check
+// produces a plan.
+func WithTrigger(trigger Trigger) SchedulerOption {
+	return func(s *Scheduler) {
+		s.trigger = trigger
+	}
+}
+
+// WithSchedulerRebalanceOptions rebalances with opts.
+func WithSchedulerRebalanceOptions(opts ...RebalanceOption) SchedulerOption {
+	return func(s *Scheduler) {
+		s.opts = append(s.opts, opts...)
+	}
+}
+
+// OnPlan calls f with every plan the scheduler warrants, before it is
+// executed. If f fails the plan is not executed.
+func OnPlan(f func(ctx context.Context, plan RebalancePlan) error) SchedulerOption {
+	return func(s *Scheduler) {
+		s.onPlan = f
+	}
+}
+
+// WithScheduledExecutor executes every plan the scheduler warrants with
+// executor and opts.
+func WithScheduledExecutor(executor Executor, opts ...ExecutionOption) SchedulerOption {
+	return func(s *Scheduler) {
+		s.executor = executor
+		s.execOpts = opts
+	}
+}
+
+// OnSchedulerError calls f with the error of every failed check. Checks which
+// fail are skipped, and the scheduler carries on with the next one.
+func OnSchedulerError(f func(error)) SchedulerOption {
+	return func(s *Scheduler) {
+		s.onError = f
+	}
+}
+
+// NewScheduler returns a Scheduler which checks on schedule whether the
+// portfolio returned by holdings warrants rebalancing onto targetIndex,
+// pricing it with provider. holdings is called at every check, so it can
+// reflect trades executed since the last one.
+func NewScheduler(schedule Schedule, provider PriceProvider, holdings func(ctx context.Context) (Portfolio, error), targetIndex Index, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		schedule:    schedule,
+		provider:    provider,
+		holdings:    holdings,
+		targetIndex: targetIndex,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run checks on the scheduler's schedule until ctx is done, returning the
+// context's error.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		now := time.Now()
+		next := s.schedule.Next(now)
+		if next.IsZero() {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if _, _, err := s.Check(ctx); err != nil && s.onError != nil {
+			s.onError(err)
+		}
+	}
+}
+
+// Check prices the portfolio and, if the trigger warrants it, rebalances it
+// and hands the plan to the callback and executor. It reports whether a plan
+// was warranted.
+func (s *Scheduler) Check(ctx context.Context) (RebalancePlan, bool, error) {
+	portfolio, err := s.holdings(ctx)
+	if err != nil {
+		return RebalancePlan{}, false, err
+	}
+	assets := make([]Asset, 0, len(portfolio)+len(s.targetIndex))
+	for asset := range portfolio {
+		assets = append(assets, asset)
+	}
+	for asset := range s.targetIndex {
+		if _, ok := portfolio[asset]; !ok {
+			assets = append(assets, asset)
+		}
+	}
+	pricelist, err := fetchPrices(ctx, s.provider, assets)
+	if err != nil {
+		return RebalancePlan{}, false, err
+	}
+	account, err := NewAccountWithPricelist(portfolio, pricelist)
+	if err != nil {
+		return RebalancePlan{}, false, err
+	}
+	if s.trigger != nil && !s.trigger(account, s.targetIndex) {
+		return RebalancePlan{}, false, nil
+	}
+
+	plan, err := account.RebalanceContext(ctx, s.targetIndex, s.opts...)
+	if err != nil {
+		return RebalancePlan{}, false, err
+	}
+	if s.onPlan != nil {
+		if err := s.onPlan(ctx, plan); err != nil {
+			return plan, true, err
+		}
+	}
+	if s.executor != nil {
+		if _, err := ExecutePlan(ctx, s.executor, plan.Trades(), s.execOpts...); err != nil {
+			return plan, true, err
+		}
+	}
+	return plan, true, nil
+}
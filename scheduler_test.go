@@ -0,0 +1,99 @@
+package rebalancer_test
+
+import (
+	"context"
+	"errors"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	holdings := func(ctx context.Context) (Portfolio, error) {
+		return Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, nil
+	}
+	prices := Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	}
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+	ctx := context.Background()
+
+	t.Run("plans beyond the trigger are handed to the callback and executor", func(t *testing.T) {
+		var plans []RebalancePlan
+		executor := &fakeExecutor{sellFill: decimal.NewFromFloat(1)}
+		scheduler := NewScheduler(Every(time.Hour), prices, holdings, index,
+			WithTrigger(DriftTrigger(decimal.NewFromFloat(0.05))),
+			OnPlan(func(ctx context.Context, plan RebalancePlan) error {
+				plans = append(plans, plan)
+				return nil
+			}),
+			WithScheduledExecutor(executor),
+		)
+
+		_, warranted, err := scheduler.Check(ctx)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !warranted || len(plans) != 1 || len(executor.placed) != 2 {
+			t.Errorf("got %v, %d plans and %d orders want a plan with 2 orders", warranted, len(plans), len(executor.placed))
+		}
+	})
+
+	t.Run("drift within the trigger warrants no plan", func(t *testing.T) {
+		scheduler := NewScheduler(Every(time.Hour), prices, holdings, index,
+			WithTrigger(DriftTrigger(decimal.NewFromFloat(0.2))))
+
+		if _, warranted, err := scheduler.Check(ctx); warranted || err != nil {
+			t.Errorf("got %v %v want no plan", warranted, err)
+		}
+	})
+
+	t.Run("checks run on the schedule until the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		checks := 0
+		scheduler := NewScheduler(Every(time.Millisecond), prices, holdings, index,
+			OnPlan(func(ctx context.Context, plan RebalancePlan) error {
+				checks++
+				if checks == 3 {
+					cancel()
+				}
+				return nil
+			}))
+
+		err := scheduler.Run(ctx)
+
+		if err != context.Canceled || checks != 3 {
+			t.Errorf("got %v after %d checks want %v after 3", err, checks, context.Canceled)
+		}
+	})
+
+	t.Run("failed checks are reported and the scheduler carries on", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		var errs []error
+		failing := PriceProviderFunc(func(ctx context.Context, assets ...Asset) (Pricelist, error) {
+			return nil, errors.New("unavailable")
+		})
+		scheduler := NewScheduler(Every(time.Millisecond), failing, holdings, index,
+			OnSchedulerError(func(err error) {
+				errs = append(errs, err)
+				if len(errs) == 2 {
+					cancel()
+				}
+			}))
+
+		scheduler.Run(ctx)
+
+		if len(errs) != 2 || errs[0].Error() != "unavailable" {
+			t.Errorf("got %v want the provider's error twice", errs)
+		}
+	})
+}
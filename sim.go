@@ -0,0 +1,261 @@
+package rebalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sync"
+	"time"
+)
+
+// ErrUnknownOrder is returned by SimExecutor for an order ID it did not issue.
+var ErrUnknownOrder = errors.New("unknown order")
+
+// A SimExecutor is an Executor which fills orders against an account's
+// holdings and prices without touching a real exchange, for paper trading and
+// testing the execution of plans.
+type SimExecutor struct {
+	latency  time.Duration
+	ratio    decimal.Decimal
+	slippage SlippageModel
+	cash     Asset
+	now      func() time.Time
+
+	mu        sync.Mutex
+	portfolio Portfolio
+	pricelist Pricelist
+	orders    []*simOrder
+}
+
+type simOrder struct {
+	order  Order
+	placed time.Time
+	fill   Fill
+}
+
+// A SimOption configures a SimExecutor.
+type SimOption func(*SimExecutor)
+
+// SimLatency delays the fill of every order until latency after it was
+// placed. Until then its status reports nothing filled.
+func SimLatency(latency time.Duration) SimOption {
+	return func(s *SimExecutor) {
+		s.latency = latency
+	}
+}
+
+// SimPartialFills fills only ratio of every order, a value between 0 and 1,
+// leaving the rest unfilled.
+func SimPartialFills(ratio decimal.Decimal) SimOption {
+	return func(s *SimExecutor) {
+		s.ratio = ratio
+	}
+}
+
+// SimSlippage fills orders at a price moved against the trader by the
+// slippage model estimates.
+func SimSlippage(model SlippageModel) SimOption {
+	return func(s *SimExecutor) {
+		s.slippage = model
+	}
+}
+
+// SimCash settles fills against the holding of cash: sells credit it with
+// their proceeds and buys are paid out of it, as by ApplyTradesWithCash. Cash
+// is valued at its price in the account's pricelist, or at 1 when it has none.
+// Buys are filled only as far as the cash goes when they fill, after the
+// sells placed before them.
+func SimCash(cash Asset) SimOption {
+	return func(s *SimExecutor) {
+		s.cash = cash
+	}
+}
+
+// NewSimExecutor returns a SimExecutor which fills orders against a copy of
+// the account's holdings at its prices.
+func NewSimExecutor(account Account, opts ...SimOption) *SimExecutor {
+	s := &SimExecutor{
+		ratio:     decimal.New(1, 0),
+		now:       time.Now,
+		portfolio: account.Holdings(),
+		pricelist: Pricelist{},
+	}
+	for asset, price := range account.pricelist {
+		s.pricelist[asset] = price
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetPrices updates the prices which orders filled from now on execute at.
+func (s *SimExecutor) SetPrices(pricelist Pricelist) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for asset, price := range pricelist {
+		s.pricelist[asset] = price
+	}
+}
+
+// Holdings returns the simulated holdings, including every fill so far.
+func (s *SimExecutor) Holdings() Portfolio {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fillDue()
+	portfolio := Portfolio{}
+	for asset, amount := range s.portfolio {
+		portfolio[asset] = amount
+	}
+	return portfolio
+}
+
+// FindOrder returns the ID of the order placed for the trade of order, or
+// ErrOrderNotFound.
+func (s *SimExecutor) FindOrder(ctx context.Context, order Order) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, placed := range s.orders {
+		if order.Trade.ID != "" && placed.order.Trade.ID == order.Trade.ID {
+			return placed.fill.OrderID, nil
+		}
+	}
+	return "", ErrOrderNotFound
+}
+
+// PlaceOrder accepts order, returning ErrAssetMissingFromPricelist if its
+// asset has no price or ErrInsufficientHoldings if it sells more than is held.
+// An order for a trade whose ID was already placed is not placed again; the
+// ID of the existing order is returned instead.
+func (s *SimExecutor) PlaceOrder(ctx context.Context, order Order) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if order.Trade.ID != "" {
+		for _, placed := range s.orders {
+			if placed.order.Trade.ID == order.Trade.ID {
+				return placed.fill.OrderID, nil
+			}
+		}
+	}
+	if _, ok := s.pricelist[order.Asset]; !ok {
+		return "", ErrAssetMissingFromPricelist
+	}
+	if order.Trade.Action == Sell && order.Trade.Amount.GreaterThan(s.portfolio[order.Asset]) {
+		return "", ErrInsufficientHoldings
+	}
+
+	id := fmt.Sprintf("sim-%d", len(s.orders)+1)
+	s.orders = append(s.orders, &simOrder{
+		order:  order,
+		placed: s.now(),
+		fill: Fill{
+			OrderID:  id,
+			Asset:    order.Asset,
+			Action:   order.Trade.Action,
+			Ordered:  order.Trade.Amount,
+			Quantity: decimal.Zero,
+			Price:    decimal.Zero,
+		},
+	})
+	return id, nil
+}
+
+// OrderStatus reports the fill of the order with orderID, which is filled in
+// one go once the configured latency has passed.
+func (s *SimExecutor) OrderStatus(ctx context.Context, orderID string) (Fill, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, err := s.order(orderID)
+	if err != nil {
+		return Fill{}, err
+	}
+	s.fillDue()
+	return order.fill, nil
+}
+
+// CancelOrder cancels the order with orderID if it has not filled yet.
+func (s *SimExecutor) CancelOrder(ctx context.Context, orderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, err := s.order(orderID)
+	if err != nil {
+		return err
+	}
+	order.fill.Done = true
+	return nil
+}
+
+func (s *SimExecutor) order(orderID string) (*simOrder, error) {
+	for _, order := range s.orders {
+		if order.fill.OrderID == orderID {
+			return order, nil
+		}
+	}
+	return nil, ErrUnknownOrder
+}
+
+// cashPrice returns the price of the cash holding, 1 when it is not priced.
+func (s *SimExecutor) cashPrice() decimal.Decimal {
+	if price, ok := s.pricelist[s.cash]; ok && price.IsPositive() {
+		return price
+	}
+	return decimal.New(1, 0)
+}
+
+// affordable returns the quantity of asset the cash holding pays for at price.
+func (s *SimExecutor) affordable(asset Asset, price decimal.Decimal) decimal.Decimal {
+	if !price.IsPositive() {
+		return decimal.Zero
+	}
+	return s.portfolio[s.cash].Mul(s.cashPrice()).Div(price)
+}
+
+// fillDue fills every open order whose latency has passed, at the current
+// prices adjusted for slippage, and applies it to the holdings, settling it
+// against the cash holding if there is one. Holdings left at zero are
+// removed, as by ApplyTrades.
+func (s *SimExecutor) fillDue() {
+	now := s.now()
+	for _, order := range s.orders {
+		if order.fill.Done || now.Sub(order.placed) < s.latency {
+			continue
+		}
+		trade := order.order.Trade
+		trade.Asset = order.order.Asset
+		quantity := trade.Amount.Mul(s.ratio)
+		price := s.pricelist[order.order.Asset]
+		if s.slippage != nil {
+			slippage := s.slippage.Slippage(trade, price)
+			if trade.Action == Sell {
+				slippage = slippage.Neg()
+			}
+			price = price.Mul(decimal.New(1, 0).Add(slippage))
+		}
+
+		asset := order.order.Asset
+		if trade.Action == Sell {
+			if quantity.GreaterThan(s.portfolio[asset]) {
+				quantity = s.portfolio[asset]
+			}
+			s.portfolio[asset] = s.portfolio[asset].Sub(quantity)
+			if s.cash != "" {
+				s.portfolio[s.cash] = s.portfolio[s.cash].Add(quantity.Mul(price).Div(s.cashPrice()))
+			}
+		} else {
+			if s.cash != "" {
+				quantity = decimal.Min(quantity, s.affordable(asset, price))
+				s.portfolio[s.cash] = s.portfolio[s.cash].Sub(quantity.Mul(price).Div(s.cashPrice()))
+			}
+			s.portfolio[asset] = s.portfolio[asset].Add(quantity)
+		}
+		for _, held := range []Asset{asset, s.cash} {
+			if amount, ok := s.portfolio[held]; ok && !amount.IsPositive() {
+				delete(s.portfolio, held)
+			}
+		}
+		order.fill.Quantity = quantity
+		order.fill.Price = price
+		order.fill.Done = true
+	}
+}
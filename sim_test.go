@@ -0,0 +1,151 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func newSimAccount(t *testing.T) Account {
+	t.Helper()
+	account, err := NewAccountWithPricelist(
+		map[Asset]decimal.Decimal{
+			"ETH": decimal.NewFromFloat(10),
+			"BTC": decimal.NewFromFloat(1),
+		},
+		map[Asset]decimal.Decimal{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return account
+}
+
+func TestSimExecutor(t *testing.T) {
+	ctx := context.Background()
+	trades := map[Asset]Trade{
+		"ETH": {Action: Sell, Amount: decimal.NewFromFloat(5)},
+		"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.2)},
+	}
+
+	t.Run("plans are executed against the account's holdings", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t))
+
+		fills, err := ExecutePlan(ctx, executor, trades)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(fills) != 2 {
+			t.Fatalf("got %d fills want 2", len(fills))
+		}
+		holdings := executor.Holdings()
+		if !holdings["ETH"].Equal(decimal.NewFromFloat(5)) || !holdings["BTC"].Equal(decimal.NewFromFloat(1.2)) {
+			t.Errorf("got %v want 5 ETH and 1.2 BTC", holdings)
+		}
+	})
+
+	t.Run("orders fill at the current price adjusted for slippage", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t), SimSlippage(ConstantSlippage{BasisPoints: decimal.NewFromFloat(100)}))
+		executor.SetPrices(Pricelist{"ETH": decimal.NewFromFloat(100)})
+
+		sell, _ := executor.PlaceOrder(ctx, Order{Asset: "ETH", Trade: trades["ETH"]})
+		buy, _ := executor.PlaceOrder(ctx, Order{Asset: "BTC", Trade: trades["BTC"]})
+
+		got, _ := executor.OrderStatus(ctx, sell)
+		if !got.Price.Equal(decimal.NewFromFloat(99)) {
+			t.Errorf("got %s want 99", got.Price)
+		}
+		got, _ = executor.OrderStatus(ctx, buy)
+		if !got.Price.Equal(decimal.NewFromFloat(5050)) {
+			t.Errorf("got %s want 5050", got.Price)
+		}
+	})
+
+	t.Run("orders fill partially", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t), SimPartialFills(decimal.NewFromFloat(0.5)))
+
+		id, _ := executor.PlaceOrder(ctx, Order{Asset: "ETH", Trade: trades["ETH"]})
+		got, err := executor.OrderStatus(ctx, id)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Done || !got.Ratio().Equal(decimal.NewFromFloat(0.5)) {
+			t.Errorf("got %v want a completed fill of half the order", got)
+		}
+	})
+
+	t.Run("orders fill once the latency has passed", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t), SimLatency(20*time.Millisecond))
+
+		id, _ := executor.PlaceOrder(ctx, Order{Asset: "ETH", Trade: trades["ETH"]})
+		before, _ := executor.OrderStatus(ctx, id)
+		time.Sleep(30 * time.Millisecond)
+		after, _ := executor.OrderStatus(ctx, id)
+
+		if before.Done || !before.Quantity.IsZero() {
+			t.Errorf("got %v want an unfilled order", before)
+		}
+		if !after.Done || !after.Quantity.Equal(decimal.NewFromFloat(5)) {
+			t.Errorf("got %v want a filled order", after)
+		}
+	})
+
+	t.Run("cancelled orders do not fill", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t), SimLatency(time.Hour))
+
+		id, _ := executor.PlaceOrder(ctx, Order{Asset: "ETH", Trade: trades["ETH"]})
+		err := executor.CancelOrder(ctx, id)
+		got, _ := executor.OrderStatus(ctx, id)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Done || !got.Quantity.IsZero() {
+			t.Errorf("got %v want a cancelled order", got)
+		}
+	})
+
+	t.Run("fills are settled against cash", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t), SimCash("USD"))
+
+		_, err := ExecutePlan(ctx, executor, map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(10)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.5)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		holdings := executor.Holdings()
+		if _, ok := holdings["ETH"]; ok || !holdings["BTC"].Equal(decimal.NewFromFloat(1.4)) || !holdings["USD"].IsZero() {
+			t.Errorf("got %v want the ETH proceeds spent on 0.4 BTC", holdings)
+		}
+	})
+
+	t.Run("sells of more than is held are rejected", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t))
+
+		_, err := executor.PlaceOrder(ctx, Order{Asset: "ETH", Trade: Trade{Action: Sell, Amount: decimal.NewFromFloat(11)}})
+
+		if err != ErrInsufficientHoldings {
+			t.Errorf("got %v want %v", err, ErrInsufficientHoldings)
+		}
+	})
+
+	t.Run("unknown orders are rejected", func(t *testing.T) {
+		executor := NewSimExecutor(newSimAccount(t))
+
+		_, err := executor.OrderStatus(ctx, "sim-1")
+
+		if err != ErrUnknownOrder {
+			t.Errorf("got %v want %v", err, ErrUnknownOrder)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+)
+
+// A SlippageModel estimates the fraction by which executing trade at price
+// will move the price against the trader, for example 0.001 for 10 basis
+// points.
+type SlippageModel interface {
+	Slippage(trade Trade, price decimal.Decimal) decimal.Decimal
+}
+
+// ConstantSlippage estimates the same slippage of BasisPoints for every trade.
+type ConstantSlippage struct {
+	BasisPoints decimal.Decimal
+}
+
+// Slippage returns the slippage of trade.
+func (s ConstantSlippage) Slippage(trade Trade, price decimal.Decimal) decimal.Decimal {
+	return s.BasisPoints.Div(decimal.New(10000, 0))
+}
+
+// SquareRootImpact estimates slippage with the square-root market impact
+// model: Coefficient multiplied by the square root of the trade's amount as a
+// fraction of the asset's traded Volume. Assets without a volume are assumed
+// to trade without slippage.
+type SquareRootImpact struct {
+	Coefficient decimal.Decimal
+	Volume      map[Asset]decimal.Decimal
+}
+
+// Slippage returns the slippage of trade.
+func (s SquareRootImpact) Slippage(trade Trade, price decimal.Decimal) decimal.Decimal {
+	volume, ok := s.Volume[trade.Asset]
+	if !ok || !volume.IsPositive() {
+		return decimal.Zero
+	}
+	participation, _ := trade.Amount.Div(volume).Float64()
+	return s.Coefficient.Mul(decimal.NewFromFloat(math.Sqrt(participation)))
+}
@@ -0,0 +1,70 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestSlippageModels(t *testing.T) {
+	t.Run("constant slippage is expressed in basis points", func(t *testing.T) {
+		model := ConstantSlippage{BasisPoints: decimal.NewFromFloat(25)}
+
+		got := model.Slippage(Trade{Action: Buy, Amount: decimal.NewFromFloat(1)}, decimal.NewFromFloat(200))
+
+		if !got.Equal(decimal.NewFromFloat(0.0025)) {
+			t.Errorf("got %s want 0.0025", got)
+		}
+	})
+	t.Run("square root impact grows with the share of volume traded", func(t *testing.T) {
+		model := SquareRootImpact{
+			Coefficient: decimal.NewFromFloat(0.1),
+			Volume:      map[Asset]decimal.Decimal{"ETH": decimal.NewFromFloat(10000)},
+		}
+
+		got := model.Slippage(Trade{Action: Buy, Amount: decimal.NewFromFloat(100), Asset: "ETH"}, decimal.NewFromFloat(200))
+
+		if !got.Equal(decimal.NewFromFloat(0.01)) {
+			t.Errorf("got %s want 0.01", got)
+		}
+
+		got = model.Slippage(Trade{Action: Buy, Amount: decimal.NewFromFloat(100), Asset: "BTC"}, decimal.NewFromFloat(5000))
+
+		if !got.IsZero() {
+			t.Errorf("got %s want 0 for an asset without volume", got)
+		}
+	})
+}
+
+func TestWithSlippage(t *testing.T) {
+	t.Run("trades remain self-financing after slippage", func(t *testing.T) {
+		account, err := NewAccountWithPricelist(Portfolio{
+			"ETH": decimal.NewFromFloat(20),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, WithSlippage(ConstantSlippage{BasisPoints: decimal.NewFromFloat(50)}))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		trades := plan.Trades()
+
+		proceeds := trades["ETH"].Amount.Mul(decimal.NewFromFloat(199))
+		cost := trades["BTC"].Amount.Mul(decimal.NewFromFloat(5025))
+		if !proceeds.Sub(cost).Abs().LessThan(decimal.NewFromFloat(0.000001)) {
+			t.Errorf("got proceeds %s and cost %s, want them to match", proceeds, cost)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package rebalancer
+
+import (
+	"github.com/shopspring/decimal"
+	"strconv"
+	"time"
+)
+
+// A ChildTrade is one of the smaller trades a large trade is split into to
+// reduce its market impact.
+type ChildTrade struct {
+	Trade
+	// Sequence numbers the child among the Of children of its trade, from 1.
+	Sequence int
+	Of       int
+	// After is the suggested delay, from the start of execution, before the
+	// child is placed.
+	After time.Duration
+}
+
+// Split breaks every trade of the plan into chunks equal child trades placed
+// interval apart, in the manner of a TWAP order.
+func (p RebalancePlan) Split(chunks int, interval time.Duration) []ChildTrade {
+	return splitTrades(p.ExecutionOrder(), p.Contribution, interval, func(Trade) int {
+		return chunks
+	})
+}
+
+// SplitBySize breaks every trade of the plan whose Value is above max into as
+// few equal child trades worth no more than max as possible, placed interval
+// apart, in the manner of an iceberg order.
+func (p RebalancePlan) SplitBySize(max decimal.Decimal, interval time.Duration) []ChildTrade {
+	return splitTrades(p.ExecutionOrder(), p.Contribution, interval, func(trade Trade) int {
+		if !max.IsPositive() {
+			return 1
+		}
+		return int(trade.Value.Div(max).Ceil().IntPart())
+	})
+}
+
+// splitTrades splits each of trades into the number of children given by
+// chunks. Amount, Value and Fee are shared equally between the children, with
+// any rounding left on the last, which also carries the trade's Residual, Lots
+// and gains so they are only counted once. Each child is given its own ID,
+// derived from its trade's ID and its sequence number.
+//
+// The children of the sells are returned the first child of every sell, in
+// order, then the second and so on, and the children of the buys likewise,
+// but each buy child only once the cash, starting at contribution when it is
+// positive, and the sell children before it can pay for it, so the children
+// keep the cash feasibility of trades. A buy child is placed no sooner than
+// the last sell child before it.
+func splitTrades(trades []Trade, contribution decimal.Decimal, interval time.Duration, chunks func(Trade) int) []ChildTrade {
+	var sells, buys [][]ChildTrade
+	for _, trade := range trades {
+		n := chunks(trade)
+		if n < 1 {
+			n = 1
+		}
+
+		count := decimal.New(int64(n), 0)
+		child := trade
+		child.Amount = trade.Amount.Div(count)
+		child.Value = trade.Value.Div(count)
+		child.Fee = trade.Fee.Div(count)
+		child.Residual = decimal.Zero
+		child.Lots = nil
+		child.ShortTermGain = decimal.Zero
+		child.LongTermGain = decimal.Zero
+
+		var children []ChildTrade
+		for s := 1; s <= n; s++ {
+			c := child
+			if s == n {
+				last := decimal.New(int64(n-1), 0)
+				c = trade
+				c.Amount = trade.Amount.Sub(child.Amount.Mul(last))
+				c.Value = trade.Value.Sub(child.Value.Mul(last))
+				c.Fee = trade.Fee.Sub(child.Fee.Mul(last))
+			}
+			if trade.ID != "" {
+				c.ID = tradeID(trade.ID, strconv.Itoa(s))
+			}
+			children = append(children, ChildTrade{
+				Trade:    c,
+				Sequence: s,
+				Of:       n,
+				After:    time.Duration(s-1) * interval,
+			})
+		}
+		if trade.Action == Sell {
+			sells = append(sells, children)
+		} else {
+			buys = append(buys, children)
+		}
+	}
+
+	cash := decimal.Zero
+	if contribution.IsPositive() {
+		cash = contribution
+	}
+	var split []ChildTrade
+	var after time.Duration
+	pending := interleave(buys)
+	release := func(all bool) {
+		for len(pending) > 0 {
+			child := pending[0]
+			cost := child.Value.Add(child.Fee)
+			if !all && cost.GreaterThan(cash) {
+				return
+			}
+			if child.After < after {
+				child.After = after
+			}
+			split = append(split, child)
+			cash = cash.Sub(cost)
+			pending = pending[1:]
+		}
+	}
+
+	release(false)
+	for _, child := range interleave(sells) {
+		split = append(split, child)
+		cash = cash.Add(child.Value).Sub(child.Fee)
+		after = child.After
+		release(false)
+	}
+	release(true)
+	return split
+}
+
+// interleave returns the first child of every trade, in order, then the
+// second and so on.
+func interleave(children [][]ChildTrade) []ChildTrade {
+	rounds := 0
+	for _, trade := range children {
+		if len(trade) > rounds {
+			rounds = len(trade)
+		}
+	}
+	var interleaved []ChildTrade
+	for round := 0; round < rounds; round++ {
+		for _, trade := range children {
+			if round < len(trade) {
+				interleaved = append(interleaved, trade[round])
+			}
+		}
+	}
+	return interleaved
+}
@@ -0,0 +1,111 @@
+package rebalancer_test
+
+import (
+	"encoding/json"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestRebalancePlan_Split(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	plan, err := account.Rebalance(Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("trades are split into equal children placed an interval apart", func(t *testing.T) {
+		got := plan.Split(3, time.Minute)
+
+		if len(got) != 6 {
+			t.Fatalf("got %d children want 6", len(got))
+		}
+		if got[0].Asset != "ETH" || got[1].Asset != "BTC" || got[0].Sequence != 1 || got[0].Of != 3 {
+			t.Errorf("got %v want the first ETH child followed by the first BTC child", got[:2])
+		}
+		if got[5].Sequence != 3 || got[5].After != 2*time.Minute {
+			t.Errorf("got child %d after %s want child 3 after 2m", got[5].Sequence, got[5].After)
+		}
+
+		total := decimal.Zero
+		for _, child := range got {
+			if child.Asset == "ETH" {
+				total = total.Add(child.Amount)
+			}
+		}
+		if !total.Equal(decimal.NewFromFloat(3.75)) {
+			t.Errorf("got %v want the children to sum to %v", total, 3.75)
+		}
+	})
+	t.Run("children have IDs of their own", func(t *testing.T) {
+		seen := map[string]bool{}
+		for _, child := range plan.Split(3, time.Minute) {
+			if child.ID == "" || seen[child.ID] {
+				t.Errorf("got duplicate or missing ID %q", child.ID)
+			}
+			seen[child.ID] = true
+		}
+	})
+	t.Run("trades are split into children no larger than a maximum value", func(t *testing.T) {
+		got := plan.SplitBySize(decimal.NewFromFloat(400), time.Minute)
+
+		if len(got) != 4 {
+			t.Fatalf("got %d children want 4", len(got))
+		}
+		for _, child := range got {
+			if child.Value.GreaterThan(decimal.NewFromFloat(400)) {
+				t.Errorf("got a child worth %v want no more than 400", child.Value)
+			}
+		}
+	})
+	t.Run("buy children are placed only once the sells before them pay for them", func(t *testing.T) {
+		var plan RebalancePlan
+		err := json.Unmarshal([]byte(`{
+			"Pricelist": {"ETH": "200", "BTC": "5000", "XLM": "0.2"},
+			"Trades": {
+				"ETH": {"Action": "sell", "Asset": "ETH", "Amount": "4", "Value": "800"},
+				"BTC": {"Action": "buy", "Asset": "BTC", "Amount": "0.08", "Value": "400"},
+				"XLM": {"Action": "buy", "Asset": "XLM", "Amount": "2000", "Value": "400"}
+			}
+		}`), &plan)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.SplitBySize(decimal.NewFromFloat(400), time.Minute)
+		if len(got) != 4 {
+			t.Fatalf("got %d children want 4", len(got))
+		}
+		cash := decimal.Zero
+		for _, child := range got {
+			if child.Action == Sell {
+				cash = cash.Add(child.Value)
+			} else {
+				cash = cash.Sub(child.Value)
+			}
+			if cash.IsNegative() {
+				t.Fatalf("got %v want no buy before the sell funding it", got)
+			}
+		}
+		if got[3].After != time.Minute {
+			t.Errorf("got %s want the last buy placed after the second sell", got[3].After)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package rebalancer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+// A TimedPrice is a price along with the time it was observed.
+type TimedPrice struct {
+	Price decimal.Decimal
+	Time  time.Time
+}
+
+// A TimedPricelist contains a map of Assets and their latest timestamped
+// price.
+type TimedPricelist map[Asset]TimedPrice
+
+// Pricelist returns the prices without their timestamps.
+func (t TimedPricelist) Pricelist() Pricelist {
+	pricelist := Pricelist{}
+	for asset, price := range t {
+		pricelist[asset] = price.Price
+	}
+	return pricelist
+}
+
+// ErrStalePrice indicates that the price of Asset was Age old, older than
+// allowed: by WithMaxPriceAge when the plan was made, or by the maximum age of
+// a price cache which could not refresh it.
+type ErrStalePrice struct {
+	Asset Asset
+	Age   time.Duration
+}
+
+// Error formats the error message for ErrStalePrice.
+func (e ErrStalePrice) Error() string {
+	return fmt.Sprintf("price of %s is stale: %s old", e.Asset, e.Age)
+}
+
+// ErrMissingPriceTime indicates a price without a timestamp when rebalancing
+// WithMaxPriceAge.
+var ErrMissingPriceTime = errors.New("price has no timestamp")
+
+// NewAccountWithTimedPricelist is like NewAccountWithPricelist, but also
+// records when each price was observed, so that rebalancing WithMaxPriceAge
+// can refuse to plan with stale prices.
+func NewAccountWithTimedPricelist(portfolio map[Asset]decimal.Decimal, prices TimedPricelist) (Account, error) {
+	account, err := NewAccountWithPricelist(portfolio, prices.Pricelist())
+	if err != nil {
+		return Account{}, err
+	}
+	account.priceTimes = map[Asset]time.Time{}
+	for asset, price := range prices {
+		account.priceTimes[asset] = price.Time
+	}
+	return account, nil
+}
+
+// PriceTimes returns a copy of the times the account's prices were observed,
+// or nil if they are not known.
+func (a Account) PriceTimes() map[Asset]time.Time {
+	if a.priceTimes == nil {
+		return nil
+	}
+	times := map[Asset]time.Time{}
+	for asset, t := range a.priceTimes {
+		times[asset] = t
+	}
+	return times
+}
+
+// checkPriceAges returns an ErrStalePrice for the first asset, in asset order,
+// of the target index or the portfolio whose price is older than the
+// configured maximum age, or ErrMissingPriceTime if one has no timestamp.
+func (a Account) checkPriceAges(targetIndex Index, config rebalanceConfig) error {
+	if !config.maxPriceAge {
+		return nil
+	}
+	constituents := map[Asset]bool{}
+	for asset := range targetIndex {
+		constituents[asset] = true
+	}
+	for asset := range a.portfolio {
+		constituents[asset] = true
+	}
+	assets := make([]Asset, 0, len(constituents))
+	for asset := range constituents {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i] < assets[j]
+	})
+
+	now := config.asOf()
+	for _, asset := range assets {
+		observed, ok := a.priceTimes[asset]
+		if !ok || observed.IsZero() {
+			return ErrMissingPriceTime
+		}
+		if age := now.Sub(observed); age > config.priceAge {
+			return ErrStalePrice{Asset: asset, Age: age}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestWithMaxPriceAge(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	account, err := NewAccountWithTimedPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, TimedPricelist{
+		"ETH": {Price: decimal.NewFromFloat(200), Time: now.Add(-time.Minute)},
+		"BTC": {Price: decimal.NewFromFloat(5000), Time: now.Add(-time.Hour)},
+		"XRP": {Price: decimal.NewFromFloat(0.3), Time: now.Add(-time.Second)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	index := Index{"ETH": decimal.NewFromFloat(0.5), "BTC": decimal.NewFromFloat(0.5)}
+
+	t.Run("plans with fresh prices are made", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithMaxPriceAge(2*time.Hour), AsOf(now))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("plans with stale prices are refused", func(t *testing.T) {
+		_, err := account.Rebalance(index, WithMaxPriceAge(10*time.Minute), AsOf(now))
+
+		want := ErrStalePrice{Asset: "BTC", Age: time.Hour}
+		if err != want {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+
+	t.Run("prices without timestamps are refused", func(t *testing.T) {
+		untimed, _ := NewAccountWithPricelist(account.Holdings(), account.Pricelist())
+
+		_, err := untimed.Rebalance(index, WithMaxPriceAge(time.Hour), AsOf(now))
+
+		if err != ErrMissingPriceTime {
+			t.Errorf("got %v want %v", err, ErrMissingPriceTime)
+		}
+	})
+
+	t.Run("price times survive applying trades", func(t *testing.T) {
+		plan, _ := account.Rebalance(index, AsOf(now))
+
+		after, err := account.ApplyTrades(plan)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if got := after.PriceTimes()["BTC"]; !got.Equal(now.Add(-time.Hour)) {
+			t.Errorf("got %s want %s", got, now.Add(-time.Hour))
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package rebalancer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store asked to load an account or pricelist it
+// does not hold.
+var ErrNotFound = errors.New("not found in store")
+
+// A Store persists accounts, pricelists, plans, execution history and
+// snapshots, so that state survives between runs. Accounts, and the plans,
+// executions and snapshots made of them, are keyed by an account ID chosen by
+// the caller; pricelists are keyed by name, like the pricelists of
+// SetNamedPricelist.
+type Store interface {
+	// SaveAccount saves account under id, replacing any account saved
+	// under it.
+	SaveAccount(ctx context.Context, id string, account Account) error
+	// LoadAccount returns the account saved under id, or ErrNotFound.
+	LoadAccount(ctx context.Context, id string) (Account, error)
+	// SavePricelist saves pricelist under name, replacing any pricelist
+	// saved under it.
+	SavePricelist(ctx context.Context, name string, pricelist Pricelist) error
+	// LoadPricelist returns the pricelist saved under name, or ErrNotFound.
+	LoadPricelist(ctx context.Context, name string) (Pricelist, error)
+	// SavePlan appends plan to the plans of the account with id.
+	SavePlan(ctx context.Context, id string, plan RebalancePlan) error
+	// LoadPlans returns the plans of the account with id in the order they
+	// were saved.
+	LoadPlans(ctx context.Context, id string) ([]RebalancePlan, error)
+	// SaveExecution appends execution to the execution history of the
+	// account with id.
+	SaveExecution(ctx context.Context, id string, execution ExecutionRecord) error
+	// LoadExecutions returns the execution history of the account with id in
+	// the order it was saved.
+	LoadExecutions(ctx context.Context, id string) ([]ExecutionRecord, error)
+	// SaveSnapshot adds snapshot to the history of the account with id.
+	SaveSnapshot(ctx context.Context, id string, snapshot Snapshot) error
+	// LoadHistory returns the history of the account with id.
+	LoadHistory(ctx context.Context, id string) (History, error)
+}
+
+// An AccountState holds everything an Account is made of, in a form a Store
+// can encode.
+type AccountState struct {
+	Holdings   Portfolio
+	Pricelist  Pricelist
+	Quotes     Quotelist           `json:",omitempty"`
+	Lots       Lots                `json:",omitempty"`
+	PriceTimes map[Asset]time.Time `json:",omitempty"`
+}
+
+// State returns the state of the account.
+func (a Account) State() AccountState {
+	state := AccountState{
+		Holdings:   a.Holdings(),
+		Pricelist:  a.Pricelist(),
+		Lots:       a.Lots(),
+		PriceTimes: a.PriceTimes(),
+	}
+	if a.quotes != nil {
+		state.Quotes = Quotelist{}
+		for asset, quote := range a.quotes {
+			state.Quotes[asset] = quote
+		}
+	}
+	return state
+}
+
+// NewAccountFromState validates state and then returns the Account it
+// describes. Accounts with quotes are valued at their mid prices.
+func NewAccountFromState(state AccountState) (Account, error) {
+	var account Account
+	var err error
+	switch {
+	case state.Lots != nil:
+		account, err = NewAccountWithLots(state.Lots, state.Pricelist)
+	case state.Quotes != nil:
+		account, err = NewAccountWithQuotelist(state.Holdings, state.Quotes)
+	default:
+		account, err = NewAccountWithPricelist(state.Holdings, state.Pricelist)
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	if state.Lots != nil && state.Quotes != nil {
+		account.quotes, err = NewQuotelist(state.Quotes)
+		if err != nil {
+			return Account{}, err
+		}
+	}
+	if state.PriceTimes != nil {
+		account.priceTimes = map[Asset]time.Time{}
+		for asset, t := range state.PriceTimes {
+			account.priceTimes[asset] = t
+		}
+	}
+	return account, nil
+}
+
+// An OrderRecord is an OrderReport with its error kept as a message, so that
+// it can be stored.
+type OrderRecord struct {
+	Order Order
+	Fill  Fill
+	State OrderState
+	Error string `json:",omitempty"`
+}
+
+// An ExecutionRecord records the orders placed to execute a plan.
+type ExecutionRecord struct {
+	Time time.Time
+	// PlanHash is the Hash of the plan executed.
+	PlanHash string
+	Orders   []OrderRecord
+}
+
+// NewExecutionRecord returns the record of the orders in report, placed at
+// time t to execute plan.
+func NewExecutionRecord(t time.Time, plan RebalancePlan, report *ExecutionReport) ExecutionRecord {
+	record := ExecutionRecord{Time: t, PlanHash: plan.Hash()}
+	for _, order := range report.Orders() {
+		r := OrderRecord{Order: order.Order, Fill: order.Fill, State: order.State}
+		if order.Err != nil {
+			r.Error = order.Err.Error()
+		}
+		record.Orders = append(record.Orders, r)
+	}
+	return record
+}
+
+// A MemoryStore is a Store which holds its state in memory, for tests and
+// short-lived processes. The zero value is empty and ready to use, and it is
+// safe for concurrent use.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	accounts   map[string]AccountState
+	pricelists map[string]Pricelist
+	plans      map[string][]RebalancePlan
+	executions map[string][]ExecutionRecord
+	snapshots  map[string][]Snapshot
+}
+
+// SaveAccount saves account under id.
+func (s *MemoryStore) SaveAccount(ctx context.Context, id string, account Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accounts == nil {
+		s.accounts = map[string]AccountState{}
+	}
+	s.accounts[id] = account.State()
+	return nil
+}
+
+// LoadAccount returns the account saved under id.
+func (s *MemoryStore) LoadAccount(ctx context.Context, id string) (Account, error) {
+	s.mu.RLock()
+	state, ok := s.accounts[id]
+	s.mu.RUnlock()
+	if !ok {
+		return Account{}, ErrNotFound
+	}
+	return NewAccountFromState(state)
+}
+
+// SavePricelist saves a copy of pricelist under name.
+func (s *MemoryStore) SavePricelist(ctx context.Context, name string, pricelist Pricelist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pricelists == nil {
+		s.pricelists = map[string]Pricelist{}
+	}
+	saved := Pricelist{}
+	for asset, price := range pricelist {
+		saved[asset] = price
+	}
+	s.pricelists[name] = saved
+	return nil
+}
+
+// LoadPricelist returns a copy of the pricelist saved under name.
+func (s *MemoryStore) LoadPricelist(ctx context.Context, name string) (Pricelist, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	saved, ok := s.pricelists[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	pricelist := Pricelist{}
+	for asset, price := range saved {
+		pricelist[asset] = price
+	}
+	return pricelist, nil
+}
+
+// SavePlan appends plan to the plans of the account with id.
+func (s *MemoryStore) SavePlan(ctx context.Context, id string, plan RebalancePlan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.plans == nil {
+		s.plans = map[string][]RebalancePlan{}
+	}
+	s.plans[id] = append(s.plans[id], plan)
+	return nil
+}
+
+// LoadPlans returns the plans of the account with id.
+func (s *MemoryStore) LoadPlans(ctx context.Context, id string) ([]RebalancePlan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]RebalancePlan(nil), s.plans[id]...), nil
+}
+
+// SaveExecution appends execution to the execution history of the account
+// with id.
+func (s *MemoryStore) SaveExecution(ctx context.Context, id string, execution ExecutionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.executions == nil {
+		s.executions = map[string][]ExecutionRecord{}
+	}
+	s.executions[id] = append(s.executions[id], execution)
+	return nil
+}
+
+// LoadExecutions returns the execution history of the account with id.
+func (s *MemoryStore) LoadExecutions(ctx context.Context, id string) ([]ExecutionRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]ExecutionRecord(nil), s.executions[id]...), nil
+}
+
+// SaveSnapshot adds snapshot to the history of the account with id.
+func (s *MemoryStore) SaveSnapshot(ctx context.Context, id string, snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshots == nil {
+		s.snapshots = map[string][]Snapshot{}
+	}
+	s.snapshots[id] = append(s.snapshots[id], snapshot)
+	return nil
+}
+
+// LoadHistory returns the history of the account with id.
+func (s *MemoryStore) LoadHistory(ctx context.Context, id string) (History, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return NewHistory(s.snapshots[id]...), nil
+}
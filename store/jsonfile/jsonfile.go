@@ -0,0 +1,239 @@
+// Package jsonfile provides a rebalancer.Store backed by JSON files in a
+// directory, for running the rebalancer from cron jobs and scripts which need
+// to keep state between runs without a database.
+//
+// Each account is kept in a file of its own, named after its ID, holding the
+// account along with its plans, execution history and snapshots; pricelists
+// are kept together in pricelists.json. Files are replaced atomically, so a crash
+// mid-write leaves the previous version intact. A Store is safe for
+// concurrent use, but not by several processes sharing a directory.
+package jsonfile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/pdbrito/rebalancer"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidID is returned for account IDs and pricelist names which cannot
+// be used in a file name.
+var ErrInvalidID = errors.New("jsonfile: IDs must be non-empty and must not contain path separators")
+
+// pricelistsFile is the name of the file holding the pricelists.
+const pricelistsFile = "pricelists.json"
+
+// accountFile is the content of an account's file.
+type accountFile struct {
+	Account    *rebalancer.AccountState     `json:",omitempty"`
+	Plans      []rebalancer.RebalancePlan   `json:",omitempty"`
+	Executions []rebalancer.ExecutionRecord `json:",omitempty"`
+	Snapshots  []rebalancer.Snapshot        `json:",omitempty"`
+}
+
+// A Store keeps accounts, pricelists, plans and execution history in JSON
+// files in a directory.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// New returns a Store keeping its files in dir, creating it if it does not
+// exist.
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// SaveAccount saves account under id.
+func (s *Store) SaveAccount(ctx context.Context, id string, account rebalancer.Account) error {
+	state := account.State()
+	return s.updateAccount(id, func(f *accountFile) {
+		f.Account = &state
+	})
+}
+
+// LoadAccount returns the account saved under id, or rebalancer.ErrNotFound.
+func (s *Store) LoadAccount(ctx context.Context, id string) (rebalancer.Account, error) {
+	f, err := s.readAccount(id)
+	if err != nil {
+		return rebalancer.Account{}, err
+	}
+	if f.Account == nil {
+		return rebalancer.Account{}, rebalancer.ErrNotFound
+	}
+	return rebalancer.NewAccountFromState(*f.Account)
+}
+
+// SavePricelist saves pricelist under name.
+func (s *Store) SavePricelist(ctx context.Context, name string, pricelist rebalancer.Pricelist) error {
+	if !validID(name) {
+		return ErrInvalidID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pricelists, err := s.readPricelists()
+	if err != nil {
+		return err
+	}
+	pricelists[name] = pricelist
+	return s.write(pricelistsFile, pricelists)
+}
+
+// LoadPricelist returns the pricelist saved under name, or
+// rebalancer.ErrNotFound.
+func (s *Store) LoadPricelist(ctx context.Context, name string) (rebalancer.Pricelist, error) {
+	if !validID(name) {
+		return nil, ErrInvalidID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pricelists, err := s.readPricelists()
+	if err != nil {
+		return nil, err
+	}
+	pricelist, ok := pricelists[name]
+	if !ok {
+		return nil, rebalancer.ErrNotFound
+	}
+	return pricelist, nil
+}
+
+// SavePlan appends plan to the plans of the account with id.
+func (s *Store) SavePlan(ctx context.Context, id string, plan rebalancer.RebalancePlan) error {
+	return s.updateAccount(id, func(f *accountFile) {
+		f.Plans = append(f.Plans, plan)
+	})
+}
+
+// LoadPlans returns the plans of the account with id.
+func (s *Store) LoadPlans(ctx context.Context, id string) ([]rebalancer.RebalancePlan, error) {
+	f, err := s.readAccount(id)
+	if err == rebalancer.ErrNotFound {
+		return nil, nil
+	}
+	return f.Plans, err
+}
+
+// SaveExecution appends execution to the execution history of the account
+// with id.
+func (s *Store) SaveExecution(ctx context.Context, id string, execution rebalancer.ExecutionRecord) error {
+	return s.updateAccount(id, func(f *accountFile) {
+		f.Executions = append(f.Executions, execution)
+	})
+}
+
+// LoadExecutions returns the execution history of the account with id.
+func (s *Store) LoadExecutions(ctx context.Context, id string) ([]rebalancer.ExecutionRecord, error) {
+	f, err := s.readAccount(id)
+	if err == rebalancer.ErrNotFound {
+		return nil, nil
+	}
+	return f.Executions, err
+}
+
+// SaveSnapshot adds snapshot to the history of the account with id.
+func (s *Store) SaveSnapshot(ctx context.Context, id string, snapshot rebalancer.Snapshot) error {
+	return s.updateAccount(id, func(f *accountFile) {
+		f.Snapshots = append(f.Snapshots, snapshot)
+	})
+}
+
+// LoadHistory returns the history of the account with id.
+func (s *Store) LoadHistory(ctx context.Context, id string) (rebalancer.History, error) {
+	f, err := s.readAccount(id)
+	if err == rebalancer.ErrNotFound {
+		return nil, nil
+	}
+	return rebalancer.NewHistory(f.Snapshots...), err
+}
+
+// readAccount reads the file of the account with id, returning
+// rebalancer.ErrNotFound if there is none.
+func (s *Store) readAccount(id string) (accountFile, error) {
+	if !validID(id) {
+		return accountFile{}, ErrInvalidID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var f accountFile
+	err := s.read(id+".json", &f)
+	return f, err
+}
+
+// updateAccount applies update to the file of the account with id, creating
+// it if there is none.
+func (s *Store) updateAccount(id string, update func(*accountFile)) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var f accountFile
+	if err := s.read(id+".json", &f); err != nil && err != rebalancer.ErrNotFound {
+		return err
+	}
+	update(&f)
+	return s.write(id+".json", f)
+}
+
+// readPricelists reads the pricelists file, which is empty if it does not
+// exist yet.
+func (s *Store) readPricelists() (map[string]rebalancer.Pricelist, error) {
+	pricelists := map[string]rebalancer.Pricelist{}
+	if err := s.read(pricelistsFile, &pricelists); err != nil && err != rebalancer.ErrNotFound {
+		return nil, err
+	}
+	return pricelists, nil
+}
+
+// read decodes the file name into v, returning rebalancer.ErrNotFound if it
+// does not exist.
+func (s *Store) read(name string, v interface{}) error {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return rebalancer.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// write replaces the file name with v encoded as JSON. It writes a temporary
+// file and renames it over name, so that readers never see a partial write.
+func (s *Store) write(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+// validID reports whether id can be used as a file name.
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`) && id+".json" != pricelistsFile
+}
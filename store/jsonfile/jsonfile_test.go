@@ -0,0 +1,104 @@
+package jsonfile_test
+
+import (
+	"context"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/store/jsonfile"
+	"github.com/shopspring/decimal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Store asserts that Store implements rebalancer.Store.
+var _ rebalancer.Store = &Store{}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "jsonfile")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := New(filepath.Join(dir, "state"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	account, err := rebalancer.NewAccountWithPricelist(rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, rebalancer.Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	plan, err := account.Rebalance(rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("state survives reopening the store", func(t *testing.T) {
+		store.SaveAccount(ctx, "main", account)
+		store.SavePlan(ctx, "main", plan)
+		store.SaveExecution(ctx, "main", rebalancer.NewExecutionRecord(time.Now(), plan, rebalancer.NewExecutionReport()))
+		store.SavePricelist(ctx, "close", account.Pricelist())
+
+		reopened, err := New(filepath.Join(dir, "state"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		got, err := reopened.LoadAccount(ctx, "main")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		plans, _ := reopened.LoadPlans(ctx, "main")
+		executions, _ := reopened.LoadExecutions(ctx, "main")
+		pricelist, _ := reopened.LoadPricelist(ctx, "close")
+
+		if !got.Value().Equal(account.Value()) {
+			t.Errorf("got value %v want %v", got.Value(), account.Value())
+		}
+		if len(plans) != 1 || plans[0].Hash() != plan.Hash() {
+			t.Errorf("got %d plans want the saved plan", len(plans))
+		}
+		if len(executions) != 1 || executions[0].PlanHash != plan.Hash() {
+			t.Errorf("got %v want the saved execution", executions)
+		}
+		if !pricelist["BTC"].Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %v want the saved pricelist", pricelist)
+		}
+	})
+
+	t.Run("each account is kept in its own file", func(t *testing.T) {
+		store.SaveAccount(ctx, "other", account)
+
+		files, _ := filepath.Glob(filepath.Join(dir, "state", "*.json"))
+
+		if len(files) != 3 {
+			t.Errorf("got %v want main.json, other.json and pricelists.json", files)
+		}
+	})
+
+	t.Run("loading what was never saved is ErrNotFound", func(t *testing.T) {
+		if _, err := store.LoadAccount(ctx, "missing"); err != rebalancer.ErrNotFound {
+			t.Errorf("got %v want %v", err, rebalancer.ErrNotFound)
+		}
+		if plans, err := store.LoadPlans(ctx, "missing"); err != nil || len(plans) != 0 {
+			t.Errorf("got %v %v want no plans", plans, err)
+		}
+	})
+
+	t.Run("IDs which are not file names are rejected", func(t *testing.T) {
+		if err := store.SaveAccount(ctx, "../escape", account); err != ErrInvalidID {
+			t.Errorf("got %v want %v", err, ErrInvalidID)
+		}
+	})
+}
@@ -0,0 +1,514 @@
+// Package sql provides a rebalancer.Store backed by a SQL database, Postgres
+// or SQLite, for services which rebalance many accounts and need to keep
+// everything durably.
+//
+// The Store works with any database/sql driver for those databases, which the
+// caller registers and opens. Migrate creates or upgrades the schema:
+//
+//	db, err := sql.Open("postgres", dsn)
+//	store := rsql.New(db, rsql.Postgres)
+//	err = store.Migrate(ctx)
+//
+// Quantities and prices are kept as decimal strings, so that no precision is
+// lost, and times as RFC 3339 strings in UTC. Plans, execution records and
+// snapshots are kept as JSON alongside the columns they are queried by.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Dialect is the flavour of SQL spoken by the database.
+type Dialect int
+
+const (
+	// Postgres numbers its placeholders $1, $2 and so on.
+	Postgres Dialect = iota
+	// SQLite uses ? for its placeholders.
+	SQLite
+)
+
+// migrations holds the statements of each version of the schema, in order.
+// Released migrations must never change; add a new one instead.
+var migrations = [][]string{
+	{
+		`CREATE TABLE accounts (
+			id TEXT PRIMARY KEY,
+			tracks_lots INTEGER NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE holdings (
+			account_id TEXT NOT NULL REFERENCES accounts (id),
+			asset TEXT NOT NULL,
+			amount TEXT NOT NULL,
+			PRIMARY KEY (account_id, asset)
+		)`,
+		`CREATE TABLE account_prices (
+			account_id TEXT NOT NULL REFERENCES accounts (id),
+			asset TEXT NOT NULL,
+			price TEXT NOT NULL,
+			bid TEXT,
+			ask TEXT,
+			observed_at TEXT,
+			PRIMARY KEY (account_id, asset)
+		)`,
+		`CREATE TABLE lots (
+			account_id TEXT NOT NULL REFERENCES accounts (id),
+			asset TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			quantity TEXT NOT NULL,
+			cost_basis TEXT NOT NULL,
+			acquired TEXT NOT NULL,
+			PRIMARY KEY (account_id, asset, seq)
+		)`,
+		`CREATE TABLE pricelist_snapshots (
+			name TEXT NOT NULL,
+			taken_at TEXT NOT NULL,
+			asset TEXT NOT NULL,
+			price TEXT NOT NULL,
+			PRIMARY KEY (name, taken_at, asset)
+		)`,
+		`CREATE TABLE plans (
+			account_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			created_at TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			plan TEXT NOT NULL,
+			PRIMARY KEY (account_id, seq)
+		)`,
+		`CREATE TABLE executions (
+			account_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			executed_at TEXT NOT NULL,
+			plan_hash TEXT NOT NULL,
+			record TEXT NOT NULL,
+			PRIMARY KEY (account_id, seq)
+		)`,
+	},
+	{
+		`CREATE TABLE snapshots (
+			account_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			taken_at TEXT NOT NULL,
+			snapshot TEXT NOT NULL,
+			PRIMARY KEY (account_id, seq)
+		)`,
+	},
+}
+
+// A Store keeps accounts, pricelist snapshots, plans and execution history in
+// a SQL database. Each save is a transaction of its own.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+	now     func() time.Time
+}
+
+// New returns a Store keeping its state in db, which speaks dialect.
+func New(db *sql.DB, dialect Dialect) *Store {
+	return &Store{db: db, dialect: dialect, now: time.Now}
+}
+
+// Migrate brings the schema of the database up to date, applying each
+// migration it has not applied yet in a transaction of its own.
+func (s *Store) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+	var version int
+	row := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`)
+	if err := row.Scan(&version); err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		err := s.transact(ctx, func(tx *sql.Tx) error {
+			for _, statement := range migrations[version] {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return err
+				}
+			}
+			return s.exec(ctx, tx, `INSERT INTO schema_migrations (version) VALUES (?)`, version+1)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveAccount saves account under id, replacing its holdings, prices and
+// lots.
+func (s *Store) SaveAccount(ctx context.Context, id string, account rebalancer.Account) error {
+	state := account.State()
+	return s.transact(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"holdings", "account_prices", "lots"} {
+			if err := s.exec(ctx, tx, `DELETE FROM `+table+` WHERE account_id = ?`, id); err != nil {
+				return err
+			}
+		}
+		if err := s.exec(ctx, tx, `DELETE FROM accounts WHERE id = ?`, id); err != nil {
+			return err
+		}
+		tracksLots := 0
+		if state.Lots != nil {
+			tracksLots = 1
+		}
+		if err := s.exec(ctx, tx, `INSERT INTO accounts (id, tracks_lots, updated_at) VALUES (?, ?, ?)`, id, tracksLots, formatTime(s.now())); err != nil {
+			return err
+		}
+		for asset, amount := range state.Holdings {
+			if err := s.exec(ctx, tx, `INSERT INTO holdings (account_id, asset, amount) VALUES (?, ?, ?)`, id, string(asset), amount.String()); err != nil {
+				return err
+			}
+		}
+		for asset, price := range state.Pricelist {
+			var bid, ask, observed sql.NullString
+			if quote, ok := state.Quotes[asset]; ok {
+				bid = sql.NullString{String: quote.Bid.String(), Valid: true}
+				ask = sql.NullString{String: quote.Ask.String(), Valid: true}
+			}
+			if t, ok := state.PriceTimes[asset]; ok {
+				observed = sql.NullString{String: formatTime(t), Valid: true}
+			}
+			err := s.exec(ctx, tx, `INSERT INTO account_prices (account_id, asset, price, bid, ask, observed_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				id, string(asset), price.String(), bid, ask, observed)
+			if err != nil {
+				return err
+			}
+		}
+		for asset, lots := range state.Lots {
+			for seq, lot := range lots {
+				err := s.exec(ctx, tx, `INSERT INTO lots (account_id, asset, seq, quantity, cost_basis, acquired) VALUES (?, ?, ?, ?, ?, ?)`,
+					id, string(asset), seq, lot.Quantity.String(), lot.CostBasis.String(), formatTime(lot.Acquired))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// LoadAccount returns the account saved under id, or rebalancer.ErrNotFound.
+func (s *Store) LoadAccount(ctx context.Context, id string) (rebalancer.Account, error) {
+	var tracksLots int
+	err := s.queryRow(ctx, `SELECT tracks_lots FROM accounts WHERE id = ?`, id).Scan(&tracksLots)
+	if err == sql.ErrNoRows {
+		return rebalancer.Account{}, rebalancer.ErrNotFound
+	}
+	if err != nil {
+		return rebalancer.Account{}, err
+	}
+
+	state := rebalancer.AccountState{Holdings: rebalancer.Portfolio{}, Pricelist: rebalancer.Pricelist{}}
+	err = s.query(ctx, `SELECT asset, amount FROM holdings WHERE account_id = ?`, []interface{}{id}, func(rows *sql.Rows) error {
+		var asset, amount string
+		if err := rows.Scan(&asset, &amount); err != nil {
+			return err
+		}
+		value, err := decimal.NewFromString(amount)
+		state.Holdings[rebalancer.Asset(asset)] = value
+		return err
+	})
+	if err != nil {
+		return rebalancer.Account{}, err
+	}
+
+	err = s.query(ctx, `SELECT asset, price, bid, ask, observed_at FROM account_prices WHERE account_id = ?`, []interface{}{id}, func(rows *sql.Rows) error {
+		var asset, price string
+		var bid, ask, observed sql.NullString
+		if err := rows.Scan(&asset, &price, &bid, &ask, &observed); err != nil {
+			return err
+		}
+		a := rebalancer.Asset(asset)
+		value, err := decimal.NewFromString(price)
+		if err != nil {
+			return err
+		}
+		state.Pricelist[a] = value
+		if bid.Valid && ask.Valid {
+			var quote rebalancer.Quote
+			if quote.Bid, err = decimal.NewFromString(bid.String); err != nil {
+				return err
+			}
+			if quote.Ask, err = decimal.NewFromString(ask.String); err != nil {
+				return err
+			}
+			if state.Quotes == nil {
+				state.Quotes = rebalancer.Quotelist{}
+			}
+			state.Quotes[a] = quote
+		}
+		if observed.Valid {
+			t, err := time.Parse(timeFormat, observed.String)
+			if err != nil {
+				return err
+			}
+			if state.PriceTimes == nil {
+				state.PriceTimes = map[rebalancer.Asset]time.Time{}
+			}
+			state.PriceTimes[a] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return rebalancer.Account{}, err
+	}
+
+	if tracksLots == 1 {
+		state.Lots = rebalancer.Lots{}
+		err = s.query(ctx, `SELECT asset, quantity, cost_basis, acquired FROM lots WHERE account_id = ? ORDER BY asset, seq`, []interface{}{id}, func(rows *sql.Rows) error {
+			var asset, quantity, costBasis, acquired string
+			if err := rows.Scan(&asset, &quantity, &costBasis, &acquired); err != nil {
+				return err
+			}
+			var lot rebalancer.Lot
+			var err error
+			if lot.Quantity, err = decimal.NewFromString(quantity); err != nil {
+				return err
+			}
+			if lot.CostBasis, err = decimal.NewFromString(costBasis); err != nil {
+				return err
+			}
+			if lot.Acquired, err = time.Parse(timeFormat, acquired); err != nil {
+				return err
+			}
+			state.Lots[rebalancer.Asset(asset)] = append(state.Lots[rebalancer.Asset(asset)], lot)
+			return nil
+		})
+		if err != nil {
+			return rebalancer.Account{}, err
+		}
+	}
+	return rebalancer.NewAccountFromState(state)
+}
+
+// SavePricelist saves a snapshot of pricelist under name. Earlier snapshots
+// are kept.
+func (s *Store) SavePricelist(ctx context.Context, name string, pricelist rebalancer.Pricelist) error {
+	takenAt := formatTime(s.now())
+	return s.transact(ctx, func(tx *sql.Tx) error {
+		for asset, price := range pricelist {
+			err := s.exec(ctx, tx, `INSERT INTO pricelist_snapshots (name, taken_at, asset, price) VALUES (?, ?, ?, ?)`,
+				name, takenAt, string(asset), price.String())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LoadPricelist returns the latest snapshot of the pricelist saved under name,
+// or rebalancer.ErrNotFound.
+func (s *Store) LoadPricelist(ctx context.Context, name string) (rebalancer.Pricelist, error) {
+	pricelist := rebalancer.Pricelist{}
+	err := s.query(ctx, `SELECT asset, price FROM pricelist_snapshots WHERE name = ? AND taken_at = (SELECT MAX(taken_at) FROM pricelist_snapshots WHERE name = ?)`,
+		[]interface{}{name, name}, func(rows *sql.Rows) error {
+			var asset, price string
+			if err := rows.Scan(&asset, &price); err != nil {
+				return err
+			}
+			value, err := decimal.NewFromString(price)
+			pricelist[rebalancer.Asset(asset)] = value
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+	if len(pricelist) == 0 {
+		return nil, rebalancer.ErrNotFound
+	}
+	return pricelist, nil
+}
+
+// SavePlan appends plan to the plans of the account with id.
+func (s *Store) SavePlan(ctx context.Context, id string, plan rebalancer.RebalancePlan) error {
+	encoded, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	return s.append(ctx, "plans", []string{"created_at", "hash", "plan"},
+		id, formatTime(plan.Time), plan.Hash(), string(encoded))
+}
+
+// LoadPlans returns the plans of the account with id in the order they were
+// saved.
+func (s *Store) LoadPlans(ctx context.Context, id string) ([]rebalancer.RebalancePlan, error) {
+	var plans []rebalancer.RebalancePlan
+	err := s.query(ctx, `SELECT plan FROM plans WHERE account_id = ? ORDER BY seq`, []interface{}{id}, func(rows *sql.Rows) error {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return err
+		}
+		var plan rebalancer.RebalancePlan
+		if err := json.Unmarshal([]byte(encoded), &plan); err != nil {
+			return err
+		}
+		plans = append(plans, plan)
+		return nil
+	})
+	return plans, err
+}
+
+// SaveExecution appends execution to the execution history of the account
+// with id.
+func (s *Store) SaveExecution(ctx context.Context, id string, execution rebalancer.ExecutionRecord) error {
+	encoded, err := json.Marshal(execution)
+	if err != nil {
+		return err
+	}
+	return s.append(ctx, "executions", []string{"executed_at", "plan_hash", "record"},
+		id, formatTime(execution.Time), execution.PlanHash, string(encoded))
+}
+
+// LoadExecutions returns the execution history of the account with id in the
+// order it was saved.
+func (s *Store) LoadExecutions(ctx context.Context, id string) ([]rebalancer.ExecutionRecord, error) {
+	var executions []rebalancer.ExecutionRecord
+	err := s.query(ctx, `SELECT record FROM executions WHERE account_id = ? ORDER BY seq`, []interface{}{id}, func(rows *sql.Rows) error {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return err
+		}
+		var execution rebalancer.ExecutionRecord
+		if err := json.Unmarshal([]byte(encoded), &execution); err != nil {
+			return err
+		}
+		executions = append(executions, execution)
+		return nil
+	})
+	return executions, err
+}
+
+// SaveSnapshot adds snapshot to the history of the account with id.
+func (s *Store) SaveSnapshot(ctx context.Context, id string, snapshot rebalancer.Snapshot) error {
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return s.append(ctx, "snapshots", []string{"taken_at", "snapshot"},
+		id, formatTime(snapshot.Time), string(encoded))
+}
+
+// LoadHistory returns the history of the account with id.
+func (s *Store) LoadHistory(ctx context.Context, id string) (rebalancer.History, error) {
+	var snapshots []rebalancer.Snapshot
+	err := s.query(ctx, `SELECT snapshot FROM snapshots WHERE account_id = ? ORDER BY taken_at, seq`, []interface{}{id}, func(rows *sql.Rows) error {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return err
+		}
+		var snapshot rebalancer.Snapshot
+		if err := json.Unmarshal([]byte(encoded), &snapshot); err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rebalancer.NewHistory(snapshots...), nil
+}
+
+// appendAttempts is how many times append tries to insert a row before giving
+// up on writers racing it for the same number.
+const appendAttempts = 5
+
+// append inserts a row with args for columns into table, numbering it after
+// the last row of the account with id. The number is worked out by the insert
+// itself, so the database's lock serialises writers; where it does not, as
+// under Postgres' default isolation, two writers can pick the same number and
+// the primary key on (account_id, seq) rejects the second, which is tried
+// again with the next number.
+func (s *Store) append(ctx context.Context, table string, columns []string, id string, args ...interface{}) error {
+	insert := `INSERT INTO ` + table + ` (account_id, seq, ` + strings.Join(columns, ", ") + `)
+		SELECT ?, COALESCE(MAX(seq), 0) + 1` + strings.Repeat(", ?", len(columns)) + ` FROM ` + table + ` WHERE account_id = ?`
+	args = append(append([]interface{}{id}, args...), id)
+	var err error
+	for attempt := 0; attempt < appendAttempts; attempt++ {
+		err = s.transact(ctx, func(tx *sql.Tx) error {
+			return s.exec(ctx, tx, insert, args...)
+		})
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return err
+}
+
+// transact runs f in a transaction, committing it if f succeeds and rolling
+// it back otherwise.
+func (s *Store) transact(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// exec executes query, written with ? placeholders, in tx.
+func (s *Store) exec(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) error {
+	_, err := tx.ExecContext(ctx, s.rebind(query), args...)
+	return err
+}
+
+// queryRow runs query, written with ? placeholders, expecting a single row.
+func (s *Store) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.rebind(query), args...)
+}
+
+// query runs query, written with ? placeholders, calling scan for each row.
+func (s *Store) query(ctx context.Context, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// rebind rewrites the ? placeholders of query for the store's dialect.
+func (s *Store) rebind(query string) string {
+	if s.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// timeFormat is RFC 3339 with a fixed number of fractional digits, so that
+// times sort as strings.
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// formatTime formats t in UTC for storage.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
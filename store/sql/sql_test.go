@@ -0,0 +1,343 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pdbrito/rebalancer"
+	. "github.com/pdbrito/rebalancer/store/sql"
+	"github.com/shopspring/decimal"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Store asserts that Store implements rebalancer.Store.
+var _ rebalancer.Store = &Store{}
+
+// fakeDriver records the statements executed through it. Queries return the
+// value of the first entry of results whose key they contain, or no rows.
+type fakeDriver struct {
+	mu         sync.Mutex
+	statements []string
+	args       [][]driver.Value
+	results    map[string]int64
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+func (d *fakeDriver) record(query string, args []driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, strings.Join(strings.Fields(query), " "))
+	d.args = append(d.args, args)
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.record(s.query, args)
+	for key, value := range s.d.results {
+		if strings.Contains(s.query, key) {
+			return &fakeRows{values: []driver.Value{value}}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+	read   bool
+}
+
+func (r *fakeRows) Columns() []string { return make([]string, len(r.values)) }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read || r.values == nil {
+		return io.EOF
+	}
+	r.read = true
+	copy(dest, r.values)
+	return nil
+}
+
+// open returns a database recording its statements in a new fakeDriver.
+func open(t *testing.T, name string, results map[string]int64) (*sql.DB, *fakeDriver) {
+	d := &fakeDriver{results: results}
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return db, d
+}
+
+func TestStore_Migrate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("an empty database is migrated to the latest schema", func(t *testing.T) {
+		db, d := open(t, "fake-empty", map[string]int64{"MAX(version)": 0})
+		defer db.Close()
+
+		if err := New(db, Postgres).Migrate(ctx); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		created := 0
+		for _, statement := range d.statements {
+			if strings.HasPrefix(statement, "CREATE TABLE ") {
+				created++
+			}
+		}
+		if created != 9 {
+			t.Errorf("got %d tables created want schema_migrations and 8 more", created)
+		}
+		last := d.statements[len(d.statements)-1]
+		if last != "INSERT INTO schema_migrations (version) VALUES ($1)" || d.args[len(d.args)-1][0] != int64(2) {
+			t.Errorf("got %q %v want version 2 recorded", last, d.args[len(d.args)-1])
+		}
+	})
+
+	t.Run("applied migrations are not applied again", func(t *testing.T) {
+		db, d := open(t, "fake-migrated", map[string]int64{"MAX(version)": 2})
+		defer db.Close()
+
+		if err := New(db, SQLite).Migrate(ctx); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if len(d.statements) != 2 {
+			t.Errorf("got %v want only the version checked", d.statements)
+		}
+	})
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	account, err := rebalancer.NewAccountWithPricelist(rebalancer.Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, rebalancer.Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("an account's holdings and prices replace the saved ones", func(t *testing.T) {
+		db, d := open(t, "fake-accounts", nil)
+		defer db.Close()
+
+		if err := New(db, Postgres).SaveAccount(ctx, "main", account); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		var deletes, holdings, prices int
+		for _, statement := range d.statements {
+			switch {
+			case strings.HasPrefix(statement, "DELETE FROM"):
+				deletes++
+			case strings.HasPrefix(statement, "INSERT INTO holdings"):
+				holdings++
+			case strings.HasPrefix(statement, "INSERT INTO account_prices"):
+				prices++
+			}
+		}
+		if deletes != 4 || holdings != 2 || prices != 2 {
+			t.Errorf("got %d deletes, %d holdings and %d prices want 4, 2 and 2", deletes, holdings, prices)
+		}
+	})
+
+	t.Run("loading what was never saved is ErrNotFound", func(t *testing.T) {
+		db, _ := open(t, "fake-missing", nil)
+		defer db.Close()
+		store := New(db, SQLite)
+
+		if _, err := store.LoadAccount(ctx, "main"); err != rebalancer.ErrNotFound {
+			t.Errorf("got %v want %v", err, rebalancer.ErrNotFound)
+		}
+		if _, err := store.LoadPricelist(ctx, "close"); err != rebalancer.ErrNotFound {
+			t.Errorf("got %v want %v", err, rebalancer.ErrNotFound)
+		}
+	})
+}
+
+// openSQLite returns a migrated Store in a new SQLite database, and a function
+// removing it.
+func openSQLite(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "sqlstore")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(dir, "state.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	store := New(db, SQLite)
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return store, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStore_SQLite(t *testing.T) {
+	ctx := context.Background()
+	acquired := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	observed := time.Date(2019, 6, 1, 9, 30, 0, 500, time.UTC)
+	account, err := rebalancer.NewAccountFromState(rebalancer.AccountState{
+		Pricelist: rebalancer.Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+			"BTC": decimal.NewFromFloat(5000),
+		},
+		Quotes: rebalancer.Quotelist{
+			"ETH": {Bid: decimal.NewFromFloat(199), Ask: decimal.NewFromFloat(201)},
+		},
+		Lots: rebalancer.Lots{
+			"ETH": {
+				{Quantity: decimal.NewFromFloat(12), CostBasis: decimal.NewFromFloat(150), Acquired: acquired},
+				{Quantity: decimal.NewFromFloat(8), CostBasis: decimal.NewFromFloat(250), Acquired: acquired.AddDate(0, 1, 0)},
+			},
+			"BTC": {
+				{Quantity: decimal.NewFromFloat(0.5), CostBasis: decimal.NewFromFloat(4000), Acquired: acquired},
+			},
+		},
+		PriceTimes: map[rebalancer.Asset]time.Time{"ETH": observed},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	plan, err := account.Rebalance(rebalancer.Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("an account reads back as it was saved", func(t *testing.T) {
+		store, remove := openSQLite(t)
+		defer remove()
+
+		if err := store.SaveAccount(ctx, "main", account); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		got, err := store.LoadAccount(ctx, "main")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		state := got.State()
+		if !state.Holdings["ETH"].Equal(decimal.NewFromFloat(20)) || !state.Pricelist["BTC"].Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %v and %v want the saved holdings and prices", state.Holdings, state.Pricelist)
+		}
+		if quote := state.Quotes["ETH"]; !quote.Bid.Equal(decimal.NewFromFloat(199)) || !quote.Ask.Equal(decimal.NewFromFloat(201)) {
+			t.Errorf("got %v want the saved quote", quote)
+		}
+		if lots := state.Lots["ETH"]; len(lots) != 2 || !lots[1].CostBasis.Equal(decimal.NewFromFloat(250)) || !lots[0].Acquired.Equal(acquired) {
+			t.Errorf("got %v want the saved lots in order", lots)
+		}
+		if !state.PriceTimes["ETH"].Equal(observed) {
+			t.Errorf("got %v want %v", state.PriceTimes["ETH"], observed)
+		}
+	})
+
+	t.Run("plans, executions and snapshots read back in the order they were saved", func(t *testing.T) {
+		store, remove := openSQLite(t)
+		defer remove()
+		first := rebalancer.Snapshot{Time: acquired, Portfolio: account.Holdings(), Pricelist: account.Pricelist()}
+		second := rebalancer.Snapshot{Time: observed, Portfolio: account.Holdings(), Pricelist: account.Pricelist(), Flow: decimal.NewFromFloat(100)}
+
+		for _, err := range []error{
+			store.SavePlan(ctx, "main", plan),
+			store.SavePlan(ctx, "main", rebalancer.RebalancePlan{}),
+			store.SaveExecution(ctx, "main", rebalancer.NewExecutionRecord(observed, plan, rebalancer.NewExecutionReport())),
+			store.SaveSnapshot(ctx, "main", second),
+			store.SaveSnapshot(ctx, "main", first),
+		} {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}
+		plans, err := store.LoadPlans(ctx, "main")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		executions, err := store.LoadExecutions(ctx, "main")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		history, err := store.LoadHistory(ctx, "main")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if len(plans) != 2 || plans[0].Hash() != plan.Hash() || len(plans[1].Trades()) != 0 {
+			t.Errorf("got %v want the saved plans in order", plans)
+		}
+		if len(executions) != 1 || executions[0].PlanHash != plan.Hash() {
+			t.Errorf("got %v want the saved execution", executions)
+		}
+		if len(history) != 2 || !history[0].Time.Equal(acquired) || !history[1].Flow.Equal(decimal.NewFromFloat(100)) {
+			t.Errorf("got %v want the snapshots in time order", history)
+		}
+	})
+
+	t.Run("plans saved at once are each numbered after the last", func(t *testing.T) {
+		store, remove := openSQLite(t)
+		defer remove()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- store.SavePlan(ctx, "main", plan)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}
+
+		if plans, _ := store.LoadPlans(ctx, "main"); len(plans) != 8 {
+			t.Errorf("got %d plans want 8", len(plans))
+		}
+	})
+}
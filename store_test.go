@@ -0,0 +1,115 @@
+package rebalancer_test
+
+import (
+	"context"
+	"errors"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+// MemoryStore asserts that MemoryStore implements Store.
+var _ Store = &MemoryStore{}
+
+func TestAccountState(t *testing.T) {
+	acquired := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	account, err := NewAccountWithLots(map[Asset][]Lot{
+		"ETH": {{Quantity: decimal.NewFromFloat(20), CostBasis: decimal.NewFromFloat(150), Acquired: acquired}},
+		"BTC": {{Quantity: decimal.NewFromFloat(0.5), CostBasis: decimal.NewFromFloat(4000), Acquired: acquired}},
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("an account is rebuilt from its state", func(t *testing.T) {
+		got, err := NewAccountFromState(account.State())
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Value().Equal(account.Value()) {
+			t.Errorf("got value %v want %v", got.Value(), account.Value())
+		}
+		if lots := got.Lots(); len(lots["ETH"]) != 1 || !lots["ETH"][0].Acquired.Equal(acquired) {
+			t.Errorf("got lots %v want the lots of the account", lots)
+		}
+	})
+
+	t.Run("an invalid state is an error", func(t *testing.T) {
+		_, err := NewAccountFromState(AccountState{Holdings: Portfolio{"ETH": decimal.NewFromFloat(1)}})
+
+		if err != ErrEmptyPricelist {
+			t.Errorf("got %v want %v", err, ErrEmptyPricelist)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{}
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("accounts are loaded as they were saved", func(t *testing.T) {
+		if err := store.SaveAccount(ctx, "main", account); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got, err := store.LoadAccount(ctx, "main")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !got.Holdings()["BTC"].Equal(decimal.NewFromFloat(0.5)) {
+			t.Errorf("got %v want the holdings of the account", got.Holdings())
+		}
+	})
+
+	t.Run("loading what was never saved is ErrNotFound", func(t *testing.T) {
+		if _, err := store.LoadAccount(ctx, "other"); err != ErrNotFound {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+		if _, err := store.LoadPricelist(ctx, "other"); err != ErrNotFound {
+			t.Errorf("got %v want %v", err, ErrNotFound)
+		}
+	})
+
+	t.Run("plans and executions are loaded in the order they were saved", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		report := NewExecutionReport()
+		report.Rejected(Order{Asset: "ETH"}, errors.New("insufficient funds"))
+
+		store.SavePlan(ctx, "main", plan)
+		store.SavePlan(ctx, "main", RebalancePlan{})
+		store.SaveExecution(ctx, "main", NewExecutionRecord(time.Now(), plan, report))
+		plans, _ := store.LoadPlans(ctx, "main")
+		executions, _ := store.LoadExecutions(ctx, "main")
+
+		if len(plans) != 2 || plans[0].Hash() != plan.Hash() {
+			t.Errorf("got %d plans want 2 starting with the plan", len(plans))
+		}
+		if len(executions) != 1 || executions[0].PlanHash != plan.Hash() || executions[0].Orders[0].Error != "insufficient funds" {
+			t.Errorf("got %v want the execution of the plan", executions)
+		}
+	})
+}
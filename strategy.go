@@ -0,0 +1,70 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+)
+
+// A Strategy plans the trades which rebalance an account onto a target
+// index, such as trading every asset onto its weight, only trading assets
+// outside their drift bands or deriving the weights from the account's value.
+//
+// Rebalance plans with the Strategy given to WithStrategy, passing it the
+// options it was given. Strategies are expected to honour those options,
+// usually by delegating to another strategy, such as Proportional, with
+// options of their own appended; they must not call Rebalance themselves,
+// which would notify and journal the plan twice.
+type Strategy interface {
+	Plan(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error)
+}
+
+// StrategyFunc adapts a function to a Strategy.
+type StrategyFunc func(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error)
+
+// Plan calls f.
+func (f StrategyFunc) Plan(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error) {
+	return f(ctx, account, targetIndex, opts...)
+}
+
+// Proportional is the default Strategy. It trades every asset onto its
+// target weight, subject to the constraints of the options it is given.
+var Proportional Strategy = StrategyFunc(func(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error) {
+	return account.planRebalance(ctx, targetIndex, newRebalanceConfig(opts))
+})
+
+// FiveTwentyFive is Larry Swedroe's 5/25 rule: an asset is only rebalanced
+// once its weight drifts 5 percentage points from its target weight, or by 25%
+// of its target weight, whichever comes first. It is BandStrategy(0.05, 0.25).
+var FiveTwentyFive = BandStrategy(decimal.New(5, -2), decimal.New(25, -2))
+
+// BandStrategy returns a Strategy which leaves assets within absolute or
+// relative drift bands alone and rebalances the rest amongst themselves, as
+// Proportional does when given WithDriftBands(absolute, relative).
+func BandStrategy(absolute, relative decimal.Decimal) Strategy {
+	return StrategyFunc(func(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error) {
+		return Proportional.Plan(ctx, account, targetIndex, append(opts[:len(opts):len(opts)], WithDriftBands(absolute, relative))...)
+	})
+}
+
+// WithStrategy plans rebalances with strategy instead of Proportional.
+func WithStrategy(strategy Strategy) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.strategy = strategy
+	}
+}
+
+// withContribution adds contribution to the value the strategy allocates,
+// for RebalanceWithContribution.
+func withContribution(contribution decimal.Decimal) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.contribution = contribution
+	}
+}
+
+// planner returns the strategy of the config.
+func (c rebalanceConfig) planner() Strategy {
+	if c.strategy == nil {
+		return Proportional
+	}
+	return c.strategy
+}
@@ -0,0 +1,136 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestWithStrategy(t *testing.T) {
+	account, err := NewAccountWithPricelist(map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("Rebalance plans with the Proportional strategy by default", func(t *testing.T) {
+		plan, err := account.Rebalance(index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want, err := Proportional.Plan(context.Background(), account, index)
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		assertSameTrades(t, plan.Trades(), want.Trades())
+	})
+
+	t.Run("Rebalance plans with the strategy given, passing it the options", func(t *testing.T) {
+		strategy := StrategyFunc(func(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error) {
+			return Proportional.Plan(ctx, account, Index{"BTC": decimal.NewFromFloat(1)}, opts...)
+		})
+		notified := 0
+		notifier := NotifierFunc(func(ctx context.Context, n Notification) error {
+			notified++
+			return nil
+		})
+
+		plan, err := account.Rebalance(index, WithStrategy(strategy), WithCashReserve(decimal.NewFromFloat(1500)), WithNotifier(notifier, nil))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(20)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.5)},
+		}
+
+		assertSameTrades(t, plan.Trades(), want)
+
+		if notified != 1 {
+			t.Errorf("got %d notifications want 1", notified)
+		}
+	})
+
+	t.Run("contributions reach the strategy", func(t *testing.T) {
+		plan, err := account.RebalanceWithContribution(index, decimal.NewFromFloat(1000), WithStrategy(Proportional))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(1.25)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.25)},
+		}
+
+		assertSameTrades(t, plan.Trades(), want)
+	})
+}
+
+func TestFiveTwentyFive(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(40),
+		"BTC": decimal.NewFromFloat(30),
+		"XLM": decimal.NewFromFloat(30),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XLM": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("only assets outside their bands are traded", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.3),
+			"BTC": decimal.NewFromFloat(0.3),
+			"XLM": decimal.NewFromFloat(0.4),
+		}, WithStrategy(FiveTwentyFive))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(10)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(10)},
+		}
+
+		assertSameTrades(t, plan.Trades(), want)
+	})
+	t.Run("nothing is traded while every asset is within its bands", func(t *testing.T) {
+		plan, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.38),
+			"BTC": decimal.NewFromFloat(0.31),
+			"XLM": decimal.NewFromFloat(0.31),
+		}, WithStrategy(FiveTwentyFive))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if got := plan.Trades(); len(got) != 0 {
+			t.Errorf("got %v want no trades", got)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"sync"
+)
+
+// A PriceStream streams live prices from a source such as an exchange's
+// websocket feed.
+type PriceStream interface {
+	// Stream sends a Pricelist of the assets whose prices changed, each time
+	// some do, until ctx is done or the stream fails, when the channel is
+	// closed.
+	Stream(ctx context.Context, assets ...Asset) (<-chan Pricelist, error)
+}
+
+// A LivePricelist is a pricelist kept up to date by a PriceStream. It is safe
+// for concurrent use.
+type LivePricelist struct {
+	mu        sync.RWMutex
+	prices    Pricelist
+	snapshots chan Pricelist
+	done      chan struct{}
+}
+
+// WatchPrices starts streaming the prices of assets from stream into a
+// LivePricelist, which starts out with a copy of initial. It is updated until
+// ctx is done or the stream ends.
+func WatchPrices(ctx context.Context, stream PriceStream, initial Pricelist, assets ...Asset) (*LivePricelist, error) {
+	updates, err := stream.Stream(ctx, assets...)
+	if err != nil {
+		return nil, err
+	}
+	live := &LivePricelist{
+		prices:    Pricelist{},
+		snapshots: make(chan Pricelist, 1),
+		done:      make(chan struct{}),
+	}
+	for asset, price := range initial {
+		live.prices[asset] = price
+	}
+	go live.watch(updates)
+	return live, nil
+}
+
+func (l *LivePricelist) watch(updates <-chan Pricelist) {
+	defer close(l.done)
+	defer close(l.snapshots)
+	for update := range updates {
+		l.mu.Lock()
+		for asset, price := range update {
+			if price.IsPositive() {
+				l.prices[asset] = price
+			}
+		}
+		l.mu.Unlock()
+
+		// Only the latest snapshot is kept for slow readers.
+		snapshot := l.Pricelist()
+		select {
+		case <-l.snapshots:
+		default:
+		}
+		l.snapshots <- snapshot
+	}
+}
+
+// Pricelist returns a snapshot of the current prices.
+func (l *LivePricelist) Pricelist() Pricelist {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	pricelist := Pricelist{}
+	for asset, price := range l.prices {
+		pricelist[asset] = price
+	}
+	return pricelist
+}
+
+// Snapshots returns a channel which receives a snapshot of the prices after
+// every update. A reader which falls behind only receives the latest one. The
+// channel is closed when the stream ends.
+func (l *LivePricelist) Snapshots() <-chan Pricelist {
+	return l.snapshots
+}
+
+// Done returns a channel which is closed when the stream ends.
+func (l *LivePricelist) Done() <-chan struct{} {
+	return l.done
+}
+
+// NewAccount validates portfolio and returns a new Account priced with a
+// snapshot of the current prices.
+func (l *LivePricelist) NewAccount(portfolio map[Asset]decimal.Decimal) (Account, error) {
+	return NewAccountWithPricelist(portfolio, l.Pricelist())
+}
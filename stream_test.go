@@ -0,0 +1,61 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// chanStream streams the pricelists sent on its channel.
+type chanStream chan Pricelist
+
+func (s chanStream) Stream(ctx context.Context, assets ...Asset) (<-chan Pricelist, error) {
+	return s, nil
+}
+
+func TestWatchPrices(t *testing.T) {
+	stream := make(chanStream)
+	live, err := WatchPrices(context.Background(), stream, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	}, "ETH", "BTC")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("updates are applied to the pricelist", func(t *testing.T) {
+		stream <- Pricelist{"ETH": decimal.NewFromFloat(250)}
+		got := <-live.Snapshots()
+
+		if !got["ETH"].Equal(decimal.NewFromFloat(250)) || !got["BTC"].Equal(decimal.NewFromFloat(5000)) {
+			t.Errorf("got %v want ETH 250 and BTC 5000", got)
+		}
+	})
+
+	t.Run("accounts are priced at the current prices", func(t *testing.T) {
+		account, err := live.NewAccount(map[Asset]decimal.Decimal{"ETH": decimal.NewFromFloat(2)})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if !account.Value().Equal(decimal.NewFromFloat(500)) {
+			t.Errorf("got %s want 500", account.Value())
+		}
+	})
+
+	t.Run("slow readers receive the latest snapshot", func(t *testing.T) {
+		stream <- Pricelist{"BTC": decimal.NewFromFloat(5100)}
+		stream <- Pricelist{"BTC": decimal.NewFromFloat(5200)}
+		close(stream)
+		<-live.Done()
+
+		var last Pricelist
+		for snapshot := range live.Snapshots() {
+			last = snapshot
+		}
+		if !last["BTC"].Equal(decimal.NewFromFloat(5200)) {
+			t.Errorf("got %v want BTC 5200", last)
+		}
+	})
+}
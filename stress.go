@@ -0,0 +1,91 @@
+package rebalancer
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidShock indicates a shock which would leave an asset without a
+// positive price, a fall of 100% or more.
+type ErrInvalidShock struct {
+	Asset Asset
+	Shock decimal.Decimal
+}
+
+// Error formats the error message for ErrInvalidShock.
+func (e ErrInvalidShock) Error() string {
+	return fmt.Sprintf("shock of %s to %s must be greater than -1", e.Shock, e.Asset)
+}
+
+// A StressResult is the effect of hypothetical price shocks on an account.
+type StressResult struct {
+	// Account is the account valued at the shocked prices.
+	Account Account
+	// Allocation is the weight of each asset after the shocks.
+	Allocation Index
+	// Value is the value of the account after the shocks, and Change the
+	// difference from its value before them.
+	Value  decimal.Decimal
+	Change decimal.Decimal
+	// Plan is the plan which rebalances the shocked account onto the target
+	// index.
+	Plan RebalancePlan
+}
+
+// Stress applies hypothetical price shocks to the account, moving the price
+// of each asset by its shock as a fraction, for example -0.5 for a 50% fall,
+// and returns the resulting allocation and the plan which would rebalance it
+// onto targetIndex with opts. Assets without a shock keep their price. The
+// account itself and the global pricelist are left untouched, and since the
+// plan is hypothetical it is not journalled, notified or published.
+func (a Account) Stress(shocks map[Asset]decimal.Decimal, targetIndex map[Asset]decimal.Decimal, opts ...RebalanceOption) (StressResult, error) {
+	shocked, err := a.shock(shocks)
+	if err != nil {
+		return StressResult{}, err
+	}
+	plan, err := shocked.draftPlan(context.Background(), targetIndex, decimal.Zero, opts)
+	if err != nil {
+		return StressResult{}, err
+	}
+	return StressResult{
+		Account:    shocked,
+		Allocation: shocked.Allocation(),
+		Value:      shocked.value,
+		Change:     shocked.value.Sub(a.value),
+		Plan:       plan,
+	}, nil
+}
+
+// shock returns a copy of the account with its prices, and quotes if it has
+// any, moved by shocks.
+func (a Account) shock(shocks map[Asset]decimal.Decimal) (Account, error) {
+	one := decimal.New(1, 0)
+	factors := map[Asset]decimal.Decimal{}
+	for asset, shock := range shocks {
+		if _, ok := a.pricelist[asset]; !ok {
+			return Account{}, ErrAssetMissingFromPricelist
+		}
+		if shock.LessThanOrEqual(one.Neg()) {
+			return Account{}, ErrInvalidShock{Asset: asset, Shock: shock}
+		}
+		factors[asset] = one.Add(shock)
+	}
+
+	shocked := a
+	shocked.pricelist = a.Pricelist()
+	for asset, factor := range factors {
+		shocked.pricelist[asset] = shocked.pricelist[asset].Mul(factor)
+	}
+	if a.quotes != nil {
+		shocked.quotes = Quotelist{}
+		for asset, quote := range a.quotes {
+			if factor, ok := factors[asset]; ok {
+				quote = Quote{Bid: quote.Bid.Mul(factor), Ask: quote.Ask.Mul(factor)}
+			}
+			shocked.quotes[asset] = quote
+		}
+	}
+	shocked.value, _ = valuePortfolio(a.portfolio, shocked.pricelist)
+	return shocked, nil
+}
@@ -0,0 +1,89 @@
+package rebalancer_test
+
+import (
+	"context"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestAccount_Stress(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(25),
+		"BTC": decimal.NewFromFloat(1),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("shocks move prices and the allocation", func(t *testing.T) {
+		got, err := account.Stress(map[Asset]decimal.Decimal{"BTC": decimal.NewFromFloat(-0.5)}, index)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !got.Value.Equal(decimal.NewFromFloat(7500)) || !got.Change.Equal(decimal.NewFromFloat(-2500)) {
+			t.Errorf("got value %v change %v want 7500 and -2500", got.Value, got.Change)
+		}
+		if !got.Allocation["ETH"].Equal(decimal.NewFromFloat(5000).Div(decimal.NewFromFloat(7500))) {
+			t.Errorf("got %v want ETH at two thirds", got.Allocation)
+		}
+	})
+
+	t.Run("the plan rebalances the shocked account", func(t *testing.T) {
+		got, _ := account.Stress(map[Asset]decimal.Decimal{"BTC": decimal.NewFromFloat(-0.5)}, index)
+
+		trade := got.Plan.Trades()["BTC"]
+		if trade.Action != Buy || !trade.Amount.Equal(decimal.NewFromFloat(0.5)) {
+			t.Errorf("got %v want 0.5 BTC bought", trade)
+		}
+	})
+
+	t.Run("the hypothetical plan is not notified", func(t *testing.T) {
+		notified := 0
+		notifier := NotifierFunc(func(ctx context.Context, n Notification) error {
+			notified++
+			return nil
+		})
+
+		account.Stress(map[Asset]decimal.Decimal{"BTC": decimal.NewFromFloat(-0.5)}, index, WithNotifier(notifier, nil))
+
+		if notified != 0 {
+			t.Errorf("got %d notifications want 0", notified)
+		}
+	})
+
+	t.Run("the account is left untouched", func(t *testing.T) {
+		account.Stress(map[Asset]decimal.Decimal{"BTC": decimal.NewFromFloat(-0.5)}, index)
+
+		if !account.Pricelist()["BTC"].Equal(decimal.NewFromFloat(5000)) || !account.Value().Equal(decimal.NewFromFloat(10000)) {
+			t.Errorf("got %v worth %v want the original prices", account.Pricelist(), account.Value())
+		}
+	})
+
+	t.Run("shocks of 100% or more are an error", func(t *testing.T) {
+		_, err := account.Stress(map[Asset]decimal.Decimal{"ETH": decimal.NewFromFloat(-1)}, index)
+
+		want := ErrInvalidShock{Asset: "ETH", Shock: decimal.NewFromFloat(-1)}
+		if got, ok := err.(ErrInvalidShock); !ok || got.Asset != want.Asset {
+			t.Errorf("got %v want %v", err, want)
+		}
+	})
+
+	t.Run("shocks to unpriced assets are an error", func(t *testing.T) {
+		_, err := account.Stress(map[Asset]decimal.Decimal{"XRP": decimal.NewFromFloat(-0.1)}, index)
+
+		if err != ErrAssetMissingFromPricelist {
+			t.Errorf("got %v want %v", err, ErrAssetMissingFromPricelist)
+		}
+	})
+}
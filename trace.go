@@ -0,0 +1,121 @@
+package rebalancer
+
+import (
+	"context"
+)
+
+// A Tracer starts spans timing the work of the rebalancer. It mirrors the
+// tracers of tracing libraries such as OpenTelemetry, which can be adapted to
+// it in a few lines.
+type Tracer interface {
+	// Start starts a span named name, as a child of any span in ctx, and
+	// returns a context holding it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A Span is a timed operation started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// noopSpan is the Span used when no Tracer is configured.
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// startSpan starts a span with tracer, or a span which does nothing if tracer
+// is nil.
+func startSpan(ctx context.Context, tracer Tracer, name string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, name)
+}
+
+// WithTracer traces Rebalance with tracer: a rebalancer.Rebalance span with
+// rebalancer.constraints and rebalancer.trades spans for the time spent
+// applying weight constraints and calculating trades.
+func WithTracer(tracer Tracer) RebalanceOption {
+	return func(c *rebalanceConfig) {
+		c.tracer = tracer
+	}
+}
+
+// startSpan starts a span with the config's tracer.
+func (c rebalanceConfig) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	return startSpan(ctx, c.tracer, name)
+}
+
+// TracedProvider returns a PriceProvider which traces each call to provider
+// in a rebalancer.Prices span.
+func TracedProvider(provider PriceProvider, tracer Tracer) PriceProvider {
+	return PriceProviderFunc(func(ctx context.Context, assets ...Asset) (Pricelist, error) {
+		ctx, span := startSpan(ctx, tracer, "rebalancer.Prices")
+		defer span.End()
+		span.SetAttribute("rebalancer.assets", len(assets))
+		pricelist, err := provider.Prices(ctx, assets...)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		span.SetAttribute("rebalancer.prices", len(pricelist))
+		return pricelist, nil
+	})
+}
+
+// tracedExecutor traces the calls to an Executor.
+type tracedExecutor struct {
+	executor Executor
+	tracer   Tracer
+}
+
+// TracedExecutor returns an Executor which traces each call to executor in a
+// rebalancer.PlaceOrder, rebalancer.OrderStatus or rebalancer.CancelOrder
+// span.
+func TracedExecutor(executor Executor, tracer Tracer) Executor {
+	return tracedExecutor{executor: executor, tracer: tracer}
+}
+
+func (e tracedExecutor) PlaceOrder(ctx context.Context, order Order) (string, error) {
+	ctx, span := startSpan(ctx, e.tracer, "rebalancer.PlaceOrder")
+	defer span.End()
+	span.SetAttribute("rebalancer.asset", string(order.Asset))
+	span.SetAttribute("rebalancer.action", string(order.Trade.Action))
+	span.SetAttribute("rebalancer.amount", order.Trade.Amount.String())
+	span.SetAttribute("rebalancer.trade_id", order.Trade.ID)
+	id, err := e.executor.PlaceOrder(ctx, order)
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+	span.SetAttribute("rebalancer.order_id", id)
+	return id, nil
+}
+
+func (e tracedExecutor) OrderStatus(ctx context.Context, orderID string) (Fill, error) {
+	ctx, span := startSpan(ctx, e.tracer, "rebalancer.OrderStatus")
+	defer span.End()
+	span.SetAttribute("rebalancer.order_id", orderID)
+	fill, err := e.executor.OrderStatus(ctx, orderID)
+	if err != nil {
+		span.RecordError(err)
+		return Fill{}, err
+	}
+	span.SetAttribute("rebalancer.filled", fill.Quantity.String())
+	return fill, nil
+}
+
+func (e tracedExecutor) CancelOrder(ctx context.Context, orderID string) error {
+	ctx, span := startSpan(ctx, e.tracer, "rebalancer.CancelOrder")
+	defer span.End()
+	span.SetAttribute("rebalancer.order_id", orderID)
+	err := e.executor.CancelOrder(ctx, orderID)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
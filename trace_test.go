@@ -0,0 +1,161 @@
+package rebalancer_test
+
+import (
+	"context"
+	"errors"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// recordingTracer records the spans it starts.
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+type spanKey struct{}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: map[string]interface{}{}}
+	r.spans = append(r.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.err = err }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+func TestWithTracer(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(20),
+		"BTC": decimal.NewFromFloat(0.5),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	t.Run("a rebalance is traced with child spans", func(t *testing.T) {
+		tracer := &recordingTracer{}
+
+		_, err := account.Rebalance(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}, WithTracer(tracer))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if len(tracer.spans) != 3 {
+			t.Fatalf("got %d spans want 3", len(tracer.spans))
+		}
+		root := tracer.spans[0]
+		if root.name != "rebalancer.Rebalance" || root.attributes["rebalancer.trades"] != 2 {
+			t.Errorf("got %s %v want rebalancer.Rebalance with 2 trades", root.name, root.attributes)
+		}
+		for _, span := range tracer.spans {
+			if !span.ended {
+				t.Errorf("got span %s left open want it ended", span.name)
+			}
+			if span != root && span.parent != root {
+				t.Errorf("got span %s outside rebalancer.Rebalance", span.name)
+			}
+		}
+	})
+
+	t.Run("a failed rebalance records its error", func(t *testing.T) {
+		tracer := &recordingTracer{}
+
+		_, err := account.Rebalance(Index{"ETH": decimal.NewFromFloat(0.5)}, WithTracer(tracer))
+
+		if err == nil || tracer.spans[0].err != err {
+			t.Errorf("got %v want the span to record %v", tracer.spans[0].err, err)
+		}
+	})
+}
+
+func TestTracedProvider(t *testing.T) {
+	t.Run("price requests are traced", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		provider := TracedProvider(Pricelist{
+			"ETH": decimal.NewFromFloat(200),
+		}, tracer)
+
+		_, err := provider.Prices(context.Background(), "ETH", "BTC")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		span := tracer.spans[0]
+		if span.name != "rebalancer.Prices" || span.attributes["rebalancer.assets"] != 2 || span.attributes["rebalancer.prices"] != 1 {
+			t.Errorf("got %s %v want rebalancer.Prices for 2 assets with 1 price", span.name, span.attributes)
+		}
+	})
+
+	t.Run("failed price requests record their error", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		want := errors.New("unavailable")
+		provider := TracedProvider(PriceProviderFunc(func(ctx context.Context, assets ...Asset) (Pricelist, error) {
+			return nil, want
+		}), tracer)
+
+		_, err := provider.Prices(context.Background(), "ETH")
+
+		if err != want || tracer.spans[0].err != want {
+			t.Errorf("got %v want %v", tracer.spans[0].err, want)
+		}
+	})
+}
+
+func TestTracedExecutor(t *testing.T) {
+	tracer := &recordingTracer{}
+	executor := TracedExecutor(&fakeExecutor{sellFill: decimal.NewFromFloat(1)}, tracer)
+	ctx := context.Background()
+
+	t.Run("orders are traced", func(t *testing.T) {
+		id, err := executor.PlaceOrder(ctx, Order{
+			Asset: "ETH",
+			Trade: Trade{Action: Sell, Amount: decimal.NewFromFloat(10)},
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		span := tracer.spans[0]
+		if span.name != "rebalancer.PlaceOrder" || span.attributes["rebalancer.asset"] != "ETH" || span.attributes["rebalancer.order_id"] != id {
+			t.Errorf("got %s %v want rebalancer.PlaceOrder of ETH as %s", span.name, span.attributes, id)
+		}
+	})
+
+	t.Run("order statuses are traced", func(t *testing.T) {
+		_, err := executor.OrderStatus(ctx, "order-1")
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		span := tracer.spans[1]
+		if span.name != "rebalancer.OrderStatus" || span.attributes["rebalancer.filled"] != "10" {
+			t.Errorf("got %s %v want rebalancer.OrderStatus filled 10", span.name, span.attributes)
+		}
+	})
+
+	t.Run("failed calls record their error", func(t *testing.T) {
+		_, err := executor.OrderStatus(ctx, "unknown")
+
+		if err == nil || tracer.spans[2].err != err {
+			t.Errorf("got %v want the span to record %v", tracer.spans[2].err, err)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// ErrTurnoverTooLow is returned when a turnover budget is smaller than the
+// cash the trades must raise or deploy, such as a contribution or reserve.
+var ErrTurnoverTooLow = errors.New("turnover budget cannot cover the net cash flow of the trades")
+
+// turnoverBudget returns the most value the trades of an account worth value
+// may turn over, if it is limited.
+func (c rebalanceConfig) turnoverBudget(value decimal.Decimal) (decimal.Decimal, bool) {
+	budget, ok := c.maxTurnover, c.maxTurnover.IsPositive()
+	if c.turnoverRatio.IsPositive() {
+		ratio := value.Mul(c.turnoverRatio)
+		if !ok || ratio.LessThan(budget) {
+			budget, ok = ratio, true
+		}
+	}
+	return budget, ok
+}
+
+// limitTurnover shrinks trades so that their combined notional value, buys and
+// sells alike, is no more than budget. The budget is split between buys and
+// sells so that the trades raise or deploy the same net cash as before, and
+// each side spends its share on its largest trades first. The value each
+// trade falls short by is added to Trade.Residual.
+func limitTurnover(trades map[Asset]Trade, pricelist Pricelist, budget decimal.Decimal) error {
+	total := decimal.Zero
+	for _, trade := range trades {
+		total = total.Add(trade.Notional(pricelist))
+	}
+	if total.LessThanOrEqual(budget) {
+		return nil
+	}
+
+	net := netProceeds(trades, pricelist)
+	if budget.LessThan(net.Abs()) {
+		return ErrTurnoverTooLow
+	}
+	two := decimal.New(2, 0)
+	spendBudget(trades, pricelist, Sell, budget.Add(net).Div(two))
+	spendBudget(trades, pricelist, Buy, budget.Sub(net).Div(two))
+	return nil
+}
+
+// spendBudget keeps the trades with the given action, largest first, for as
+// long as their notional value fits within budget, then shrinks the next
+// trade to use up what remains and the rest to nothing.
+func spendBudget(trades map[Asset]Trade, pricelist Pricelist, action TradeAction, budget decimal.Decimal) {
+	var side []Trade
+	for asset, trade := range trades {
+		if trade.Action == action {
+			trade.Asset = asset
+			side = append(side, trade)
+		}
+	}
+	sort.Slice(side, func(i, j int) bool {
+		if c := side[i].Notional(pricelist).Cmp(side[j].Notional(pricelist)); c != 0 {
+			return c > 0
+		}
+		return side[i].Asset < side[j].Asset
+	})
+
+	for _, trade := range side {
+		notional := trade.Notional(pricelist)
+		if notional.LessThanOrEqual(budget) {
+			budget = budget.Sub(notional)
+			continue
+		}
+		amount := trade.Amount.Mul(budget).Div(notional)
+		trade.Residual = trade.Residual.Add(trade.Amount.Sub(amount).Mul(pricelist[trade.Asset]))
+		trade.Amount = amount
+		trades[trade.Asset] = trade
+		budget = decimal.Zero
+	}
+}
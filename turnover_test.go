@@ -0,0 +1,92 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestWithMaxTurnover(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(70),
+		"BTC": decimal.NewFromFloat(20),
+		"XLM": decimal.NewFromFloat(10),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(1),
+		"BTC": decimal.NewFromFloat(1),
+		"XLM": decimal.NewFromFloat(1),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.4),
+		"BTC": decimal.NewFromFloat(0.3),
+		"XLM": decimal.NewFromFloat(0.3),
+	}
+
+	t.Run("the budget is spent on the largest drifts first", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithMaxTurnover(decimal.NewFromFloat(40)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(20)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(20)},
+		}
+
+		assertSameTrades(t, got, want)
+
+		if !TotalResidual(got).Equal(decimal.NewFromFloat(20)) {
+			t.Errorf("got %v want %v", TotalResidual(got), 20)
+		}
+	})
+	t.Run("the budget may be a ratio of the account's value", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithMaxTurnoverRatio(decimal.NewFromFloat(0.5)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(25)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(5)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(20)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("a budget which is not exhausted leaves the trades untouched", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithMaxTurnover(decimal.NewFromFloat(100)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		got := plan.Trades()
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Sell, Amount: decimal.NewFromFloat(30)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(10)},
+			"XLM": {Action: Buy, Amount: decimal.NewFromFloat(20)},
+		}
+
+		assertSameTrades(t, got, want)
+	})
+	t.Run("the budget must cover a contribution", func(t *testing.T) {
+		_, err := account.RebalanceWithContribution(index, decimal.NewFromFloat(50), WithMaxTurnover(decimal.NewFromFloat(40)))
+
+		if err != ErrTurnoverTooLow {
+			t.Errorf("got %v, want %s", err, ErrTurnoverTooLow)
+		}
+	})
+}
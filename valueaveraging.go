@@ -0,0 +1,61 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// A ValuePath is the value a ValueAveraging account should be worth at each
+// point in time.
+type ValuePath func(t time.Time) decimal.Decimal
+
+// LinearValuePath returns the ValuePath which is worth initial at start and
+// grows by step every time schedule fires after it. A Schedule firing monthly
+// with a step of 500 is a value path growing by 500 a month. The path stops
+// growing once schedule stops firing, that is once Next returns the zero time
+// or a time which is not after the one it was given.
+func LinearValuePath(start time.Time, initial, step decimal.Decimal, schedule Schedule) ValuePath {
+	return func(t time.Time) decimal.Decimal {
+		periods := int64(0)
+		for prev, next := start, schedule.Next(start); next.After(prev) && !next.After(t); prev, next = next, schedule.Next(next) {
+			periods++
+		}
+		return initial.Add(step.Mul(decimal.New(periods, 0)))
+	}
+}
+
+// ValueAveraging is a Strategy which deposits whatever brings the account's
+// value up to its value path at the time of the plan, rather than a fixed
+// amount each period: more after the account has fallen and less, or even a
+// withdrawal, after it has risen. The deposit is spread over the assets of the
+// target index like a RebalanceWithContribution, and replaces any
+// contribution the plan was given.
+type ValueAveraging struct {
+	Path ValuePath
+	// MaxContribution caps each deposit when positive.
+	MaxContribution decimal.Decimal
+	// AllowWithdrawals sells assets when the account is worth more than its
+	// path. Otherwise the deposit is never negative.
+	AllowWithdrawals bool
+}
+
+// Contribution returns the deposit which brings account onto the value path
+// at t, or a withdrawal when it is negative.
+func (v ValueAveraging) Contribution(account Account, t time.Time) decimal.Decimal {
+	contribution := v.Path(t).Sub(account.Value())
+	if v.MaxContribution.IsPositive() {
+		contribution = decimal.Min(contribution, v.MaxContribution)
+	}
+	if !v.AllowWithdrawals {
+		contribution = decimal.Max(contribution, decimal.Zero)
+	}
+	return contribution
+}
+
+// Plan rebalances account together with its Contribution at the time of the
+// plan using Proportional.
+func (v ValueAveraging) Plan(ctx context.Context, account Account, targetIndex Index, opts ...RebalanceOption) (RebalancePlan, error) {
+	contribution := v.Contribution(account, newRebalanceConfig(opts).asOf())
+	return Proportional.Plan(ctx, account, targetIndex, append(opts[:len(opts):len(opts)], withContribution(contribution))...)
+}
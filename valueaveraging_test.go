@@ -0,0 +1,96 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestLinearValuePath(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	path := LinearValuePath(start, decimal.NewFromFloat(1000), decimal.NewFromFloat(500), Every(24*time.Hour))
+
+	tests := []struct {
+		t    time.Time
+		want float64
+	}{
+		{start, 1000},
+		{start.Add(23 * time.Hour), 1000},
+		{start.Add(24 * time.Hour), 1500},
+		{start.Add(10 * 24 * time.Hour), 6000},
+	}
+
+	for _, tt := range tests {
+		if got := path(tt.t); !got.Equal(decimal.NewFromFloat(tt.want)) {
+			t.Errorf("got %s want %v at %s", got, tt.want, tt.t)
+		}
+	}
+
+	never := LinearValuePath(start, decimal.NewFromFloat(1000), decimal.NewFromFloat(500), Every(0))
+	if got := never(start.Add(24 * time.Hour)); !got.Equal(decimal.NewFromFloat(1000)) {
+		t.Errorf("got %s want 1000 from a schedule which never fires", got)
+	}
+}
+
+func TestValueAveraging(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(5),
+		"BTC": decimal.NewFromFloat(0.2),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+	path := LinearValuePath(start, decimal.NewFromFloat(1000), decimal.NewFromFloat(1000), Every(24*time.Hour))
+
+	t.Run("the deposit brings the account onto its value path", func(t *testing.T) {
+		plan, err := account.Rebalance(index, WithStrategy(ValueAveraging{Path: path}), AsOf(start.Add(48*time.Hour)))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		if !plan.Contribution.Equal(decimal.NewFromFloat(1000)) {
+			t.Errorf("got %s want a contribution of 1000", plan.Contribution)
+		}
+
+		want := map[Asset]Trade{
+			"ETH": {Action: Buy, Amount: decimal.NewFromFloat(2.5)},
+			"BTC": {Action: Buy, Amount: decimal.NewFromFloat(0.1)},
+		}
+
+		assertSameTrades(t, plan.Trades(), want)
+	})
+
+	t.Run("deposits are capped", func(t *testing.T) {
+		va := ValueAveraging{Path: path, MaxContribution: decimal.NewFromFloat(400)}
+
+		if got := va.Contribution(account, start.Add(48*time.Hour)); !got.Equal(decimal.NewFromFloat(400)) {
+			t.Errorf("got %s want 400", got)
+		}
+	})
+
+	t.Run("the account is only withdrawn from when withdrawals are allowed", func(t *testing.T) {
+		va := ValueAveraging{Path: path}
+
+		if got := va.Contribution(account, start); !got.IsZero() {
+			t.Errorf("got %s want 0", got)
+		}
+
+		va.AllowWithdrawals = true
+
+		if got := va.Contribution(account, start); !got.Equal(decimal.NewFromFloat(-1000)) {
+			t.Errorf("got %s want -1000", got)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidTargetVolatility is returned by TargetVolatility when the target
+// volatility is not positive.
+var ErrInvalidTargetVolatility = errors.New("target volatility must be positive")
+
+// TargetVolatility returns targetIndex scaled down towards cash so that its
+// estimated volatility is no more than target, given the volatility of each
+// of its assets. The volatility is estimated as the weighted sum of the
+// assets' volatilities, which assumes their returns are perfectly correlated
+// and so never underestimates it. Cash, which need not be in targetIndex, is
+// taken to have no volatility. The index is returned unscaled when it is
+// already within target, and the result always sums to 1, including cash.
+func TargetVolatility(targetIndex Index, volatilities map[Asset]decimal.Decimal, cash Asset, target decimal.Decimal) (Index, error) {
+	if len(targetIndex) == 0 {
+		return nil, ErrEmptyIndex
+	}
+	if !target.IsPositive() {
+		return nil, ErrInvalidTargetVolatility
+	}
+
+	risky := Index{}
+	riskyWeight := decimal.Zero
+	volatility := decimal.Zero
+	for asset, weight := range targetIndex {
+		if asset == cash {
+			continue
+		}
+		if !volatilities[asset].IsPositive() {
+			return nil, ErrInvalidVolatility{Asset: asset, Volatility: volatilities[asset]}
+		}
+		risky[asset] = weight
+		riskyWeight = riskyWeight.Add(weight)
+		volatility = volatility.Add(weight.Mul(volatilities[asset]))
+	}
+
+	if volatility.GreaterThan(target) {
+		riskyWeight = riskyWeight.Mul(target).Div(volatility)
+	}
+	index := scaleWeights(risky, riskyWeight)
+	if rest := decimal.New(1, 0).Sub(riskyWeight); rest.IsPositive() {
+		index[cash] = rest
+	}
+	return index, nil
+}
@@ -0,0 +1,74 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestTargetVolatility(t *testing.T) {
+	volatilities := map[Asset]decimal.Decimal{
+		"ETH": decimal.NewFromFloat(0.8),
+		"BTC": decimal.NewFromFloat(0.4),
+	}
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("the index is scaled towards cash to hit the target", func(t *testing.T) {
+		got, err := TargetVolatility(index, volatilities, "USD", decimal.NewFromFloat(0.3))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := Index{
+			"ETH": decimal.NewFromFloat(0.25),
+			"BTC": decimal.NewFromFloat(0.25),
+			"USD": decimal.NewFromFloat(0.5),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("cash already in the index is topped up", func(t *testing.T) {
+		got, err := TargetVolatility(Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"USD": decimal.NewFromFloat(0.5),
+		}, volatilities, "USD", decimal.NewFromFloat(0.2))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := Index{
+			"ETH": decimal.NewFromFloat(0.25),
+			"USD": decimal.NewFromFloat(0.75),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("an index within the target is left alone", func(t *testing.T) {
+		got, err := TargetVolatility(index, volatilities, "USD", decimal.NewFromFloat(0.9))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		assertSameIndex(t, got, index)
+	})
+	t.Run("every risky asset needs a volatility", func(t *testing.T) {
+		_, err := TargetVolatility(Index{"XLM": decimal.NewFromFloat(1)}, volatilities, "USD", decimal.NewFromFloat(0.3))
+
+		if _, ok := err.(ErrInvalidVolatility); !ok {
+			t.Errorf("got %v want an ErrInvalidVolatility", err)
+		}
+	})
+	t.Run("the target must be positive", func(t *testing.T) {
+		_, err := TargetVolatility(index, volatilities, "USD", decimal.Zero)
+
+		if err != ErrInvalidTargetVolatility {
+			t.Errorf("got %v want %v", err, ErrInvalidTargetVolatility)
+		}
+	})
+}
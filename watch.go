@@ -0,0 +1,139 @@
+package rebalancer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// A RebalanceSignal reports that an account watched by WatchDrift drifted
+// beyond its threshold.
+type RebalanceSignal struct {
+	Time time.Time
+	// Account is the account valued at the prices which breached the
+	// threshold.
+	Account Account
+	// Drift is the drift of every asset from its target weight.
+	Drift map[Asset]Drift
+	// Plan is the candidate plan which rebalances the account.
+	Plan RebalancePlan
+}
+
+type watchConfig struct {
+	interval time.Duration
+	opts     []RebalanceOption
+	onError  func(error)
+}
+
+// A WatchOption configures WatchDrift.
+type WatchOption func(*watchConfig)
+
+// WatchInterval re-prices the account every interval. It defaults to one
+// minute, which intervals that are not positive leave in place.
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// WatchRebalanceOptions makes the candidate plans of the signals with opts.
+// Candidate plans are not journalled, notified or published.
+func WatchRebalanceOptions(opts ...RebalanceOption) WatchOption {
+	return func(c *watchConfig) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// OnWatchError calls f with the error of every check which failed, for
+// instance because provider was unavailable. Failed checks are skipped.
+func OnWatchError(f func(error)) WatchOption {
+	return func(c *watchConfig) {
+		c.onError = f
+	}
+}
+
+// WatchDrift re-prices account with provider straight away and then at every
+// interval, sending a RebalanceSignal on the returned channel each time the
+// absolute drift of any asset from targetIndex exceeds threshold. The channel
+// is closed once ctx is done. The account keeps its holdings and lots; only
+// its prices change.
+func WatchDrift(ctx context.Context, account Account, targetIndex map[Asset]decimal.Decimal, threshold decimal.Decimal, provider PriceProvider, opts ...WatchOption) <-chan RebalanceSignal {
+	config := watchConfig{interval: time.Minute}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	assets := make([]Asset, 0, len(account.portfolio)+len(targetIndex))
+	for asset := range account.portfolio {
+		assets = append(assets, asset)
+	}
+	for asset := range targetIndex {
+		if _, ok := account.portfolio[asset]; !ok {
+			assets = append(assets, asset)
+		}
+	}
+
+	signals := make(chan RebalanceSignal)
+	go func() {
+		defer close(signals)
+		ticker := time.NewTicker(config.interval)
+		defer ticker.Stop()
+		for {
+			signal, breached, err := checkDrift(ctx, account, targetIndex, threshold, provider, assets, config)
+			if err != nil && config.onError != nil {
+				config.onError(err)
+			}
+			if breached {
+				select {
+				case signals <- signal:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return signals
+}
+
+// checkDrift re-prices account and reports whether it drifted beyond
+// threshold, along with the signal to send if it did.
+func checkDrift(ctx context.Context, account Account, targetIndex map[Asset]decimal.Decimal, threshold decimal.Decimal, provider PriceProvider, assets []Asset, config watchConfig) (RebalanceSignal, bool, error) {
+	pricelist, err := fetchPrices(ctx, provider, assets)
+	if err != nil {
+		return RebalanceSignal{}, false, err
+	}
+	repriced, err := account.withPricelist(pricelist)
+	if err != nil {
+		return RebalanceSignal{}, false, err
+	}
+	drifts := repriced.Drift(targetIndex)
+	breached := false
+	for _, drift := range drifts {
+		breached = breached || drift.Absolute.Abs().GreaterThan(threshold)
+	}
+	if !breached {
+		return RebalanceSignal{}, false, nil
+	}
+	plan, err := repriced.draftPlan(ctx, targetIndex, decimal.Zero, config.opts)
+	if err != nil {
+		return RebalanceSignal{}, false, err
+	}
+	return RebalanceSignal{Time: time.Now(), Account: repriced, Drift: drifts, Plan: plan}, true, nil
+}
+
+// withPricelist returns the account valued at pricelist instead, keeping its
+// holdings and lots.
+func (a Account) withPricelist(pricelist Pricelist) (Account, error) {
+	repriced, err := NewAccountWithPricelist(a.portfolio, pricelist)
+	if err != nil {
+		return Account{}, err
+	}
+	repriced.lots = a.Lots()
+	return repriced, nil
+}
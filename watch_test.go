@@ -0,0 +1,91 @@
+package rebalancer_test
+
+import (
+	"context"
+	"errors"
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestWatchDrift(t *testing.T) {
+	account, err := NewAccountWithPricelist(Portfolio{
+		"ETH": decimal.NewFromFloat(25),
+		"BTC": decimal.NewFromFloat(1),
+	}, Pricelist{
+		"ETH": decimal.NewFromFloat(200),
+		"BTC": decimal.NewFromFloat(5000),
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	index := Index{
+		"ETH": decimal.NewFromFloat(0.5),
+		"BTC": decimal.NewFromFloat(0.5),
+	}
+
+	t.Run("a signal is sent once prices move the account beyond the threshold", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		checks := 0
+		provider := PriceProviderFunc(func(ctx context.Context, assets ...Asset) (Pricelist, error) {
+			checks++
+			eth := 200.0
+			if checks >= 3 {
+				eth = 400
+			}
+			return Pricelist{"ETH": decimal.NewFromFloat(eth), "BTC": decimal.NewFromFloat(5000)}, nil
+		})
+
+		signals := WatchDrift(ctx, account, index, decimal.NewFromFloat(0.1), provider, WatchInterval(time.Millisecond))
+		got := <-signals
+
+		if checks != 3 {
+			t.Errorf("got a signal after %d checks want 3", checks)
+		}
+		if !got.Account.Value().Equal(decimal.NewFromFloat(15000)) || !got.Drift["ETH"].Absolute.GreaterThan(decimal.NewFromFloat(0.1)) {
+			t.Errorf("got %v worth %v want the repriced account", got.Drift, got.Account.Value())
+		}
+		if trade := got.Plan.Trades()["ETH"]; trade.Action != Sell || !trade.Amount.Equal(decimal.NewFromFloat(6.25)) {
+			t.Errorf("got %v want 6.25 ETH sold", trade)
+		}
+	})
+
+	t.Run("the channel is closed once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var errs []error
+		failing := PriceProviderFunc(func(ctx context.Context, assets ...Asset) (Pricelist, error) {
+			return nil, errors.New("unavailable")
+		})
+
+		signals := WatchDrift(ctx, account, index, decimal.NewFromFloat(0.1), failing,
+			WatchInterval(time.Millisecond), OnWatchError(func(err error) {
+				errs = append(errs, err)
+				if len(errs) == 2 {
+					cancel()
+				}
+			}))
+
+		if _, ok := <-signals; ok || len(errs) != 2 {
+			t.Errorf("got %v and %d errors want the channel closed after 2", ok, len(errs))
+		}
+	})
+	t.Run("a zero interval falls back to the default", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		failing := PriceProviderFunc(func(ctx context.Context, assets ...Asset) (Pricelist, error) {
+			return nil, errors.New("unavailable")
+		})
+
+		signals := WatchDrift(ctx, account, index, decimal.NewFromFloat(0.1), failing,
+			WatchInterval(0), OnWatchError(func(err error) {
+				cancel()
+			}))
+
+		if _, ok := <-signals; ok {
+			t.Error("got a signal want the channel closed")
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package rebalancer
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strings"
+)
+
+// EqualWeight returns the Index splitting 1 equally across assets. Shares are
+// worked out to decimal.DivisionPrecision places, and the units left over when
+// 1 does not divide exactly are given one each to the first assets in
+// alphabetical order, so the index always sums to exactly 1. Duplicate assets
+// are counted once, and no assets give an empty index.
+func EqualWeight(assets ...Asset) Index {
+	unique := map[Asset]bool{}
+	for _, asset := range assets {
+		unique[asset] = true
+	}
+	sorted := make([]Asset, 0, len(unique))
+	for asset := range unique {
+		sorted = append(sorted, asset)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	index := Index{}
+	if len(sorted) == 0 {
+		return index
+	}
+	precision := int32(decimal.DivisionPrecision)
+	units := decimal.New(1, precision).IntPart()
+	share, remainder := units/int64(len(sorted)), units%int64(len(sorted))
+	for i, asset := range sorted {
+		n := share
+		if int64(i) < remainder {
+			n++
+		}
+		index[asset] = decimal.New(n, -precision)
+	}
+	return index
+}
+
+// NewIndexFromPercents returns the Index with the given whole percentages of
+// each asset, such as 60 and 40, which must be positive and total 100. Unlike
+// NewIndex, the assets are not checked against the global pricelist; Rebalance
+// checks them against the account's.
+func NewIndexFromPercents(percents map[Asset]int) (Index, error) {
+	return indexFromUnits(percents, 2)
+}
+
+// NewIndexFromBps is like NewIndexFromPercents, but takes basis points, which
+// must total 10000.
+func NewIndexFromBps(bps map[Asset]int) (Index, error) {
+	return indexFromUnits(bps, 4)
+}
+
+// indexFromUnits returns the Index with the given weights in units of
+// 10^-exp, which must total 10^exp.
+func indexFromUnits(units map[Asset]int, exp int32) (Index, error) {
+	if len(units) == 0 {
+		return nil, ErrEmptyIndex
+	}
+	index := Index{}
+	want := int(decimal.New(1, exp).IntPart())
+	total := 0
+	for asset, n := range units {
+		if string(asset) != strings.ToUpper(string(asset)) {
+			return nil, ErrInvalidAsset
+		}
+		weight := decimal.New(int64(n), -exp)
+		if n <= 0 {
+			return nil, ErrInvalidAssetAmount{Asset: asset, Amount: weight}
+		}
+		if n > want {
+			return nil, ErrIndexSumIncorrect
+		}
+		index[asset] = weight
+		total += n
+	}
+	if total != want {
+		return nil, ErrIndexSumIncorrect
+	}
+	return index, nil
+}
+
+// Normalize returns the index with its weights scaled to sum to exactly 1, so
+// that raw scores or amounts can be used as weights. The rounding residue left
+// by division is added to the largest weight, breaking ties by asset. Every
+// weight must be positive.
+func (i Index) Normalize() (Index, error) {
+	if err := checkWeights(i); err != nil {
+		return nil, err
+	}
+	return scaleWeights(i, decimal.New(1, 0)), nil
+}
+
+// ErrInvalidBlendWeight is returned by Blend when the weight of the first
+// index is outside [0, 1].
+var ErrInvalidBlendWeight = errors.New("blend weight must be between 0 and 1")
+
+// Blend returns the mixture of a and b weighting a by wa and b by 1 - wa, such
+// as a market cap index blended 0.7 with an equal weight index. Both indexes
+// must sum to 1, and so does the blend.
+func Blend(a, b Index, wa decimal.Decimal) (Index, error) {
+	one := decimal.New(1, 0)
+	if wa.IsNegative() || wa.GreaterThan(one) {
+		return nil, ErrInvalidBlendWeight
+	}
+	for _, index := range []Index{a, b} {
+		if err := checkWeights(index); err != nil {
+			return nil, err
+		}
+		total := decimal.Zero
+		for _, weight := range index {
+			total = total.Add(weight)
+		}
+		if !total.Equal(one) {
+			return nil, ErrIndexSumIncorrect
+		}
+	}
+
+	blend := Index{}
+	add := func(index Index, weight decimal.Decimal) {
+		if !weight.IsPositive() {
+			return
+		}
+		for asset, w := range index {
+			blend[asset] = blend[asset].Add(w.Mul(weight))
+		}
+	}
+	add(a, wa)
+	add(b, one.Sub(wa))
+	return blend, nil
+}
+
+// checkWeights checks that index is not empty and that every weight is
+// positive.
+func checkWeights(index Index) error {
+	if len(index) == 0 {
+		return ErrEmptyIndex
+	}
+	for asset, weight := range index {
+		if !weight.IsPositive() {
+			return ErrInvalidAssetAmount{Asset: asset, Amount: weight}
+		}
+	}
+	return nil
+}
+
+// scaleWeights returns the positive weights of index scaled to sum to exactly
+// total. Division can leave a rounding residue, which is added to the largest
+// weight, breaking ties by asset.
+func scaleWeights(index Index, total decimal.Decimal) Index {
+	scaled := Index{}
+	sum := decimal.Zero
+	for _, weight := range index {
+		if weight.IsPositive() {
+			sum = sum.Add(weight)
+		}
+	}
+	if !sum.IsPositive() || !total.IsPositive() {
+		return scaled
+	}
+
+	assets := make([]Asset, 0, len(index))
+	residue := total
+	for asset, weight := range index {
+		if weight.IsPositive() {
+			assets = append(assets, asset)
+			scaled[asset] = weight.Mul(total).Div(sum)
+			residue = residue.Sub(scaled[asset])
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if !scaled[assets[i]].Equal(scaled[assets[j]]) {
+			return scaled[assets[i]].GreaterThan(scaled[assets[j]])
+		}
+		return assets[i] < assets[j]
+	})
+	scaled[assets[0]] = scaled[assets[0]].Add(residue)
+	return scaled
+}
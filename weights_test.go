@@ -0,0 +1,234 @@
+package rebalancer_test
+
+import (
+	. "github.com/pdbrito/rebalancer"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestEqualWeight(t *testing.T) {
+	t.Run("1 is split equally when it divides exactly", func(t *testing.T) {
+		got := EqualWeight("ETH", "BTC", "XLM", "LTC")
+
+		want := Index{
+			"ETH": decimal.NewFromFloat(0.25),
+			"BTC": decimal.NewFromFloat(0.25),
+			"XLM": decimal.NewFromFloat(0.25),
+			"LTC": decimal.NewFromFloat(0.25),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("the remainder goes to the first assets alphabetically", func(t *testing.T) {
+		got := EqualWeight("XLM", "ETH", "BTC")
+
+		want := Index{
+			"BTC": decimal.RequireFromString("0.3333333333333334"),
+			"ETH": decimal.RequireFromString("0.3333333333333333"),
+			"XLM": decimal.RequireFromString("0.3333333333333333"),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("the index is valid for any number of assets", func(t *testing.T) {
+		assets := make([]Asset, 0, 7)
+		for _, asset := range []Asset{"A", "B", "C", "D", "E", "F", "G"} {
+			assets = append(assets, asset)
+			index := EqualWeight(assets...)
+			pricelist := Pricelist{}
+			for asset := range index {
+				pricelist[asset] = decimal.NewFromFloat(1)
+			}
+			account, err := NewAccountWithPricelist(Portfolio{"A": decimal.NewFromFloat(1)}, pricelist)
+
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			if _, err := account.Rebalance(index); err != nil {
+				t.Errorf("unexpected error for %d assets: %s", len(assets), err)
+			}
+		}
+	})
+	t.Run("duplicates are counted once", func(t *testing.T) {
+		got := EqualWeight("ETH", "BTC", "ETH")
+
+		want := Index{
+			"ETH": decimal.NewFromFloat(0.5),
+			"BTC": decimal.NewFromFloat(0.5),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+}
+
+func TestNewIndexFromPercents(t *testing.T) {
+	t.Run("percentages become weights", func(t *testing.T) {
+		got, err := NewIndexFromPercents(map[Asset]int{"ETH": 60, "BTC": 40})
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := Index{
+			"ETH": decimal.NewFromFloat(0.6),
+			"BTC": decimal.NewFromFloat(0.4),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+
+	tests := []struct {
+		name     string
+		percents map[Asset]int
+		want     error
+	}{
+		{"percentages must total 100", map[Asset]int{"ETH": 60, "BTC": 30}, ErrIndexSumIncorrect},
+		{"assets must be upper case", map[Asset]int{"eth": 100}, ErrInvalidAsset},
+		{"the index must not be empty", map[Asset]int{}, ErrEmptyIndex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIndexFromPercents(tt.percents)
+
+			if err != tt.want {
+				t.Errorf("got %v want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("percentages must be positive", func(t *testing.T) {
+		_, err := NewIndexFromPercents(map[Asset]int{"ETH": 100, "BTC": 0})
+
+		if e, ok := err.(ErrInvalidAssetAmount); !ok || e.Asset != "BTC" {
+			t.Errorf("got %v want an ErrInvalidAssetAmount for BTC", err)
+		}
+	})
+}
+
+func TestNewIndexFromBps(t *testing.T) {
+	got, err := NewIndexFromBps(map[Asset]int{"ETH": 6025, "BTC": 3975})
+
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	want := Index{
+		"ETH": decimal.NewFromFloat(0.6025),
+		"BTC": decimal.NewFromFloat(0.3975),
+	}
+
+	assertSameIndex(t, got, want)
+
+	if _, err := NewIndexFromBps(map[Asset]int{"ETH": 60, "BTC": 40}); err != ErrIndexSumIncorrect {
+		t.Errorf("got %v want %v", err, ErrIndexSumIncorrect)
+	}
+}
+
+func TestIndex_Normalize(t *testing.T) {
+	t.Run("weights are scaled to sum to 1", func(t *testing.T) {
+		got, err := Index{
+			"ETH": decimal.NewFromFloat(3000),
+			"BTC": decimal.NewFromFloat(1000),
+		}.Normalize()
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := Index{
+			"ETH": decimal.NewFromFloat(0.75),
+			"BTC": decimal.NewFromFloat(0.25),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("the rounding residue goes to the largest weight", func(t *testing.T) {
+		got, err := Index{
+			"ETH": decimal.NewFromFloat(2),
+			"BTC": decimal.NewFromFloat(2),
+			"XLM": decimal.NewFromFloat(3),
+		}.Normalize()
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := Index{
+			"ETH": decimal.RequireFromString("0.2857142857142857"),
+			"BTC": decimal.RequireFromString("0.2857142857142857"),
+			"XLM": decimal.RequireFromString("0.4285714285714286"),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("weights must be positive", func(t *testing.T) {
+		_, err := Index{"ETH": decimal.NewFromFloat(-1)}.Normalize()
+
+		if e, ok := err.(ErrInvalidAssetAmount); !ok || e.Asset != "ETH" {
+			t.Errorf("got %v want an ErrInvalidAssetAmount for ETH", err)
+		}
+	})
+	t.Run("the index must not be empty", func(t *testing.T) {
+		if _, err := (Index{}).Normalize(); err != ErrEmptyIndex {
+			t.Errorf("got %v want %v", err, ErrEmptyIndex)
+		}
+	})
+}
+
+func TestBlend(t *testing.T) {
+	marketCap := Index{
+		"BTC": decimal.NewFromFloat(0.7),
+		"ETH": decimal.NewFromFloat(0.3),
+	}
+	equal := EqualWeight("BTC", "ETH", "XLM", "LTC")
+
+	t.Run("the indexes are mixed by weight", func(t *testing.T) {
+		got, err := Blend(marketCap, equal, decimal.NewFromFloat(0.6))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		want := Index{
+			"BTC": decimal.NewFromFloat(0.52),
+			"ETH": decimal.NewFromFloat(0.28),
+			"XLM": decimal.NewFromFloat(0.1),
+			"LTC": decimal.NewFromFloat(0.1),
+		}
+
+		assertSameIndex(t, got, want)
+	})
+	t.Run("a weight of 1 leaves out the second index", func(t *testing.T) {
+		got, err := Blend(marketCap, equal, decimal.NewFromFloat(1))
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		assertSameIndex(t, got, marketCap)
+	})
+
+	tests := []struct {
+		name string
+		a    Index
+		wa   float64
+		want error
+	}{
+		{"the weight must not exceed 1", marketCap, 1.5, ErrInvalidBlendWeight},
+		{"the weight must not be negative", marketCap, -0.5, ErrInvalidBlendWeight},
+		{"the indexes must sum to 1", Index{"BTC": decimal.NewFromFloat(0.5)}, 0.5, ErrIndexSumIncorrect},
+		{"the indexes must not be empty", Index{}, 0.5, ErrEmptyIndex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Blend(tt.a, equal, decimal.NewFromFloat(tt.wa))
+
+			if err != tt.want {
+				t.Errorf("got %v want %v", err, tt.want)
+			}
+		})
+	}
+}